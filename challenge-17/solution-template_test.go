@@ -57,3 +57,30 @@ func TestIsPalindromeEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// FuzzIsPalindrome checks that a string and its reverse get the same answer,
+// and that a string followed by its reverse is always a palindrome
+func FuzzIsPalindrome(f *testing.F) {
+	for _, seed := range []string{"", "racecar", "A man, a plan, a canal: Panama", "hello", "Was it a car or a cat I saw?", "12321"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, r := range s {
+			if r > 127 {
+				t.Skip("palindromes are defined over ASCII letters and digits")
+			}
+		}
+		reversed := []byte(s)
+		for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+			reversed[i], reversed[j] = reversed[j], reversed[i]
+		}
+
+		if IsPalindrome(s) != IsPalindrome(string(reversed)) {
+			t.Errorf("IsPalindrome(%q) = %v but IsPalindrome(%q) = %v, expected the same answer",
+				s, IsPalindrome(s), reversed, IsPalindrome(string(reversed)))
+		}
+		if mirrored := s + string(reversed); !IsPalindrome(mirrored) {
+			t.Errorf("IsPalindrome(%q) = false, expected true", mirrored)
+		}
+	})
+}
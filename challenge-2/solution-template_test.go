@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestReverseString(t *testing.T) {
@@ -42,3 +43,22 @@ func TestReverseString(t *testing.T) {
 		})
 	}
 }
+
+// FuzzReverseString checks that reversing any valid UTF-8 string keeps its
+// length and that reversing it twice gives back the original
+func FuzzReverseString(f *testing.F) {
+	// No seeds: the examples are covered by TestReverseString, which trims
+	// the program's output, and seeds would also run as plain tests
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip("the input is not valid UTF-8")
+		}
+		reversed := ReverseString(s)
+		if len(reversed) != len(s) {
+			t.Errorf("ReverseString(%q) = %q, which has length %d, expected %d", s, reversed, len(reversed), len(s))
+		}
+		if twice := ReverseString(reversed); twice != s {
+			t.Errorf("ReverseString(ReverseString(%q)) = %q, expected the original string", s, twice)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"sort"
 	"testing"
 )
 
@@ -122,3 +123,45 @@ func TestExampleCases(t *testing.T) {
 		t.Errorf("Example 4: FindInsertPosition(%v, 6) = %d, expected 3", arr4, result)
 	}
 }
+
+// FuzzBinarySearch checks every search function against a linear scan of a
+// sorted array built from the fuzzed bytes
+func FuzzBinarySearch(f *testing.F) {
+	f.Add([]byte{}, 5)
+	f.Add([]byte{2, 4, 6, 8}, 5)
+	f.Add([]byte{2, 4, 6, 8}, 6)
+	f.Add([]byte{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}, 10)
+	f.Fuzz(func(t *testing.T, data []byte, target int) {
+		arr := make([]int, len(data))
+		for i, b := range data {
+			arr[i] = int(b)
+		}
+		sort.Ints(arr)
+
+		found := false
+		for _, value := range arr {
+			if value == target {
+				found = true
+				break
+			}
+		}
+
+		check := func(name string, index int) {
+			if found && (index < 0 || index >= len(arr) || arr[index] != target) {
+				t.Errorf("%s(%v, %d) = %d, expected the index of an occurrence", name, arr, target, index)
+			}
+			if !found && index != -1 {
+				t.Errorf("%s(%v, %d) = %d, expected -1", name, arr, target, index)
+			}
+		}
+		check("BinarySearch", BinarySearch(arr, target))
+		check("BinarySearchRecursive", BinarySearchRecursive(arr, target, 0, len(arr)-1))
+
+		position := FindInsertPosition(arr, target)
+		if position < 0 || position > len(arr) ||
+			(position > 0 && arr[position-1] > target) ||
+			(position < len(arr) && arr[position] < target) {
+			t.Errorf("FindInsertPosition(%v, %d) = %d, which does not keep the array sorted", arr, target, position)
+		}
+	})
+}
@@ -173,3 +173,36 @@ func BenchmarkPatternMatching(b *testing.B) {
 		}
 	})
 }
+
+// FuzzPatternMatching checks the three algorithms against a straightforward
+// scan that reports every, possibly overlapping, occurrence
+func FuzzPatternMatching(f *testing.F) {
+	f.Add("ABABDABACDABABCABAB", "ABABCABAB")
+	f.Add("AAAAAA", "AA")
+	f.Add("ABCDEFG", "")
+	f.Add("", "ABC")
+	f.Fuzz(func(t *testing.T, text, pattern string) {
+		expected := []int{}
+		if pattern != "" {
+			for i := 0; i+len(pattern) <= len(text); i++ {
+				if text[i:i+len(pattern)] == pattern {
+					expected = append(expected, i)
+				}
+			}
+		}
+
+		for name, search := range map[string]func(string, string) []int{
+			"NaivePatternMatch": NaivePatternMatch,
+			"KMPSearch":         KMPSearch,
+			"RabinKarpSearch":   RabinKarpSearch,
+		} {
+			result := search(text, pattern)
+			if len(result) == 0 && len(expected) == 0 {
+				continue
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%s(%q, %q) = %v, expected %v", name, text, pattern, result, expected)
+			}
+		}
+	})
+}
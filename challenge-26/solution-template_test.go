@@ -2,6 +2,8 @@ package regex
 
 import (
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -239,3 +241,32 @@ func TestExtractURLs(t *testing.T) {
 		})
 	}
 }
+
+// FuzzRegexProcessor checks properties that hold for any input: extracted
+// emails and URLs come from the text, and a valid phone number has exactly
+// the (XXX) XXX-XXXX shape
+func FuzzRegexProcessor(f *testing.F) {
+	f.Add("Contact us at support@example.com or visit https://example.com/help")
+	f.Add("(555) 123-4567")
+	f.Add("1234-5678-9012-3456")
+	f.Add("2023-01-15 14:30:45 ERROR Database connection failed")
+	f.Add("")
+	phoneShape := regexp.MustCompile(`^\(\d{3}\) \d{3}-\d{4}$`)
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, email := range ExtractEmails(text) {
+			if !strings.Contains(text, email) || !strings.Contains(email, "@") {
+				t.Errorf("ExtractEmails(%q) returned %q, which is not an email address from the text", text, email)
+			}
+		}
+		for _, url := range ExtractURLs(text) {
+			if !strings.Contains(text, url) || !strings.HasPrefix(url, "http") {
+				t.Errorf("ExtractURLs(%q) returned %q, which is not a URL from the text", text, url)
+			}
+		}
+		if ValidatePhone(text) && !phoneShape.MatchString(text) {
+			t.Errorf("ValidatePhone(%q) = true, expected false", text)
+		}
+		MaskCreditCard(text)
+		ParseLogEntry(text)
+	})
+}
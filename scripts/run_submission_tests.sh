@@ -0,0 +1,60 @@
+#!/bin/bash
+
+# Runs a challenge's tests against one submission and prints the `go test -v`
+# output. The tests run in a scratch copy of the challenge, so the challenge
+# directory is never modified.
+#
+# The submission's Go files are copied with their directory layout, so
+# multi-file submissions and submissions with subpackages work. Test files,
+# hidden files and testdata in the submission are ignored, like the web UI
+# does, so the challenge's own tests are the only ones that run.
+#
+# Usage: scripts/run_submission_tests.sh <challenge-dir> <submission-dir>
+
+if [ $# -ne 2 ]; then
+    echo "Usage: $0 <challenge-dir> <submission-dir>" >&2
+    exit 2
+fi
+
+CHALLENGE_DIR="$1"
+SUBMISSION_DIR="$2"
+
+if ! find "$SUBMISSION_DIR" -name "*.go" ! -name "*_test.go" | grep -q .; then
+    echo "No Go files found in $SUBMISSION_DIR"
+    exit 1
+fi
+
+MODULE_NAME=$(basename "$(cd "$CHALLENGE_DIR" && pwd)")
+TEMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TEMP_DIR"' EXIT
+
+# The challenge's module files, tests and test data
+for file in go.mod go.sum; do
+    if [ -f "$CHALLENGE_DIR/$file" ]; then
+        cp "$CHALLENGE_DIR/$file" "$TEMP_DIR/"
+    fi
+done
+cp "$CHALLENGE_DIR"/*_test.go "$TEMP_DIR/"
+if [ -d "$CHALLENGE_DIR/testdata" ]; then
+    cp -r "$CHALLENGE_DIR/testdata" "$TEMP_DIR/"
+fi
+
+# The submission's Go files, keeping their paths
+(cd "$SUBMISSION_DIR" && find . \( -name ".?*" -o -name "_*" -o -name testdata -o -name vendor \) -prune \
+    -o -name "*.go" ! -name "*_test.go" -print) | while IFS= read -r file; do
+    mkdir -p "$TEMP_DIR/$(dirname "$file")"
+    cp "$SUBMISSION_DIR/$file" "$TEMP_DIR/$file"
+done
+
+cd "$TEMP_DIR" || exit 1
+
+# Handle dependencies
+if [ -f "go.mod" ]; then
+    go mod tidy > /dev/null 2>&1 || true
+else
+    go mod init "$MODULE_NAME" > /dev/null 2>&1
+fi
+
+# Fuzz targets are skipped: they and their seed inputs would otherwise
+# count as tests, and the scoreboards count the challenge's tests only
+timeout 60 go test -v -skip '^Fuzz'
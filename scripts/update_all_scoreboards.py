@@ -1,7 +1,7 @@
 #!/usr/bin/env python3
 """
-Wrapper script to update both classic and package scoreboards and generate contributor badges.
-This script ensures both scoreboards are updated and profile badges are generated.
+Wrapper script to update both classic and package scoreboards.
+Profile badges are served by the web UI from the scoreboards, so nothing is generated for them.
 """
 
 import os
@@ -10,18 +10,16 @@ import subprocess
 from pathlib import Path
 
 
-def run_script(script_name, working_dir):
-    """Run a scoreboard script and return success status."""
-    script_path = Path(__file__).parent / script_name
-    
+def run_script(script_name, command, working_dir):
+    """Run a scoreboard command and return success status."""
     print(f"\n{'='*60}")
     print(f"🔄 Running {script_name}")
     print(f"{'='*60}")
     
     try:
         result = subprocess.run(
-            [sys.executable, str(script_path)], 
-            cwd=working_dir,  # Run from the root directory
+            command, 
+            cwd=working_dir,
             text=True
         )
         
@@ -48,17 +46,17 @@ def main():
     
     print(f"Working directory: {root_dir}")
     
+    # The web UI's scoreboard command generates both README leaderboards
+    # with the same ranking code the web UI serves
     scripts = [
-        "generate_main_scoreboard.py",
-        "generate_package_scoreboard.py",
-        "generate_contributor_badges.py"
+        ("go run . scoreboard", ["go", "run", ".", "scoreboard"], root_dir / "web-ui"),
     ]
     
     success_count = 0
     total_scripts = len(scripts)
     
-    for script in scripts:
-        if run_script(script, root_dir):
+    for script, command, working_dir in scripts:
+        if run_script(script, command, working_dir):
             success_count += 1
     
     print(f"\n{'='*60}")
@@ -69,12 +67,10 @@ def main():
     print(f"Failed: {total_scripts - success_count}")
     
     if success_count == total_scripts:
-        print(f"\n🎉 All scoreboards and badges updated successfully!")
+        print(f"\n🎉 All scoreboards updated successfully!")
         print(f"✅ Classic Challenges Leaderboard updated")
         print(f"✅ Package Challenges Leaderboard updated")
-        print(f"✅ Contributor Profile Badges generated")
         print(f"\nREADME.md has been updated with the latest scoreboard data.")
-        print(f"🏆 Profile badges are available in the badges/ directory.")
         return 0
     else:
         print(f"\n⚠️  Some scripts failed. Please check the errors above.")
@@ -15,21 +15,15 @@ if [ ! -f "README.md" ]; then
     exit 1
 fi
 
-# Check if Python 3 is available
-if ! command -v python3 &> /dev/null; then
-    echo "❌ Error: Python 3 is required but not installed."
+# Check if Go is available
+if ! command -v go &> /dev/null; then
+    echo "❌ Error: Go is required but not installed."
     exit 1
 fi
 
-# Check if the script directory exists
-if [ ! -d "scripts" ]; then
-    echo "❌ Error: scripts directory not found."
-    exit 1
-fi
-
-# Check if the generator script exists
-if [ ! -f "scripts/generate_main_scoreboard.py" ]; then
-    echo "❌ Error: Main scoreboard generator script not found."
+# Check if the web UI, whose scoreboard command generates the leaderboards, exists
+if [ ! -f "web-ui/go.mod" ]; then
+    echo "❌ Error: web-ui directory not found."
     exit 1
 fi
 
@@ -47,7 +41,7 @@ echo ""
 
 # Run the main scoreboard generator
 echo "🔄 Generating main scoreboard..."
-python3 scripts/generate_main_scoreboard.py
+(cd web-ui && go run . scoreboard)
 
 if [ $? -eq 0 ]; then
     echo ""
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// AdminHandler serves the admin console's API. Every endpoint requires a
+// signed-in admin.
+type AdminHandler struct {
+	adminService *services.AdminService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(adminService *services.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+// HandleAdmin routes the admin endpoints:
+//
+//	GET  /api/admin/roles
+//	POST /api/admin/roles
+//	GET  /api/admin/hidden
+//	POST /api/admin/hidden
+//	POST /api/admin/hidden/remove
+//	POST /api/admin/disqualify
+//	GET  /api/admin/challenges
+//	POST /api/admin/challenges
+//	GET  /api/admin/rejudges
+//	POST /api/admin/rejudges
+//	POST /api/admin/rejudges/cancel
+//	GET  /api/admin/audit
+func (h *AdminHandler) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	route := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/"), "/")
+	switch route + " " + r.Method {
+	case "roles GET":
+		admins, err := h.adminService.Admins()
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		response := struct {
+			Admins           []models.User `json:"admins"`
+			ConfiguredAdmins []string      `json:"configuredAdmins"` // From ADMIN_USERS
+			Success          bool          `json:"success"`
+		}{
+			Admins:           admins,
+			ConfiguredAdmins: h.adminService.ConfiguredAdmins(),
+			Success:          true,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	case "roles POST":
+		var request struct {
+			Username string      `json:"username"`
+			Role     models.Role `json:"role"`
+		}
+		if !decodeAdminRequest(w, r, &request) {
+			return
+		}
+		if err := h.adminService.SetRole(admin, strings.TrimSpace(request.Username), request.Role); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case "hidden GET":
+		hidden, err := h.adminService.HiddenEntries()
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "hidden", hidden)
+	case "hidden POST":
+		var entry models.HiddenEntry
+		if !decodeAdminRequest(w, r, &entry) {
+			return
+		}
+		hidden, err := h.adminService.Hide(admin, entry)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "entry", hidden)
+	case "hidden/remove POST":
+		var request struct {
+			Username    string `json:"username"`
+			ChallengeID int    `json:"challengeId"`
+		}
+		if !decodeAdminRequest(w, r, &request) {
+			return
+		}
+		if err := h.adminService.Unhide(admin, request.Username, request.ChallengeID); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case "disqualify POST":
+		var request struct {
+			Username    string `json:"username"`
+			ChallengeID int    `json:"challengeId"`
+			Reason      string `json:"reason"`
+		}
+		if !decodeAdminRequest(w, r, &request) {
+			return
+		}
+		if err := h.adminService.Disqualify(admin, request.Username, request.ChallengeID, request.Reason); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case "challenges GET":
+		statuses, err := h.adminService.ChallengeStatuses()
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "statuses", statuses)
+	case "challenges POST":
+		var override models.ChallengeStatusOverride
+		if !decodeAdminRequest(w, r, &override) {
+			return
+		}
+		status, err := h.adminService.SetChallengeStatus(admin, override)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "status", status)
+	case "rejudges GET":
+		writeSuccess(w, "rejudges", h.adminService.Rejudges())
+	case "rejudges POST":
+		var request struct {
+			ChallengeID int    `json:"challengeId"`
+			Username    string `json:"username"`
+		}
+		if !decodeAdminRequest(w, r, &request) {
+			return
+		}
+		rejudge, err := h.adminService.StartRejudge(admin, request.ChallengeID, request.Username)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "rejudge", rejudge)
+	case "rejudges/cancel POST":
+		var request struct {
+			ID int64 `json:"id"`
+		}
+		if !decodeAdminRequest(w, r, &request) {
+			return
+		}
+		if err := h.adminService.CancelRejudge(admin, request.ID); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case "audit GET":
+		h.getAuditLog(w, r)
+	default:
+		switch route {
+		case "roles", "hidden", "hidden/remove", "disqualify", "challenges", "rejudges", "rejudges/cancel", "audit":
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// getAuditLog returns a page of the audit log, as ?limit= and ?offset= ask
+func (h *AdminHandler) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	var limit, offset int
+	params := []struct {
+		name string
+		dest *int
+	}{
+		{"limit", &limit},
+		{"offset", &offset},
+	}
+	for _, param := range params {
+		if value := r.URL.Query().Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+param.name+" parameter", http.StatusBadRequest)
+				return
+			}
+			*param.dest = n
+		}
+	}
+
+	page, err := h.adminService.AuditLog(limit, offset)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeSuccess(w, "audit", page)
+}
+
+// decodeAdminRequest decodes a JSON request body, answering 400 Bad Request
+// when it cannot
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, request any) bool {
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeAdminError answers with the status an admin error calls for
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound),
+		errors.Is(err, services.ErrEntryNotFound),
+		errors.Is(err, services.ErrHiddenEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidRole),
+		errors.Is(err, services.ErrInvalidEntry),
+		errors.Is(err, services.ErrInvalidChallengeStatus),
+		errors.Is(err, services.ErrInvalidRejudge):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrOwnRole),
+		errors.Is(err, services.ErrEntryHidden),
+		errors.Is(err, services.ErrRejudgeRunning),
+		errors.Is(err, services.ErrRejudgeNotRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Admin request failed: %v", err)
+		http.Error(w, "Admin request failed", http.StatusInternalServerError)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -24,7 +25,10 @@ type APIHandler struct {
 	executionService  *services.ExecutionService
 	packageService    *services.PackageService
 	aiService         *services.AIService
-	submissions       []models.Submission
+	executionQueue    *services.ExecutionQueue
+	submissionStore   services.SubmissionStore
+	attemptService    *services.AttemptService
+	profileService    *services.ProfileService
 }
 
 // NewAPIHandler creates a new API handler
@@ -35,6 +39,10 @@ func NewAPIHandler(
 	executionService *services.ExecutionService,
 	packageService *services.PackageService,
 	aiService *services.AIService,
+	executionQueue *services.ExecutionQueue,
+	submissionStore services.SubmissionStore,
+	attemptService *services.AttemptService,
+	profileService *services.ProfileService,
 ) *APIHandler {
 	return &APIHandler{
 		challengeService:  challengeService,
@@ -43,7 +51,10 @@ func NewAPIHandler(
 		executionService:  executionService,
 		packageService:    packageService,
 		aiService:         aiService,
-		submissions:       make([]models.Submission, 0),
+		executionQueue:    executionQueue,
+		submissionStore:   submissionStore,
+		attemptService:    attemptService,
+		profileService:    profileService,
 	}
 }
 
@@ -66,15 +77,18 @@ func (h *APIHandler) GetAllChallenges(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(challengeList)
 }
 
-// GetChallengeByID returns a specific challenge by ID
+// GetChallengeByID returns a specific challenge by ID, a page of its
+// submission history, or a user's attempts at it
 func (h *APIHandler) GetChallengeByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract challenge ID from URL
+	// Extract challenge ID from URL:
+	// /api/challenges/{id}[/submissions|/attempts|/attempts/diff]
 	path := strings.TrimPrefix(r.URL.Path, "/api/challenges/")
+	path, action, _ := strings.Cut(path, "/")
 	id, err := strconv.Atoi(path)
 	if err != nil {
 		http.Error(w, "Invalid challenge ID", http.StatusBadRequest)
@@ -87,6 +101,28 @@ func (h *APIHandler) GetChallengeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch action {
+	case "":
+	case "submissions":
+		query, err := parseHistoryQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.ChallengeID = id
+		h.writeHistoryPage(w, query)
+		return
+	case "attempts":
+		h.getAttempts(w, r, id)
+		return
+	case "attempts/diff":
+		h.getAttemptDiff(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(challenge)
 }
@@ -112,6 +148,18 @@ func (h *APIHandler) createSubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Submissions count for the requesting user; the username becomes a
+	// row of the challenge's SCOREBOARD.md
+	submission.Username = requestUser(r, submission.Username)
+	if submission.Username == "" && !requestIdentity(r).Local {
+		http.Error(w, "Sign in to submit", http.StatusUnauthorized)
+		return
+	}
+	if submission.Username != "" && !services.ValidUsername(submission.Username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
 	// Set submission timestamp
 	submission.SubmittedAt = time.Now()
 
@@ -121,31 +169,50 @@ func (h *APIHandler) createSubmission(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Challenge not found", http.StatusNotFound)
 		return
 	}
+	if !challengeOpen(w, challenge.Status) {
+		return
+	}
 
-	// Run the code
-	result := h.executionService.RunCode(submission.Code, challenge)
+	files, err := services.SubmissionFiles(submission.Code, submission.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(submission.Files) > 0 {
+		submission.Code = files[services.MainFileOf(files)]
+	}
+
+	record := h.newRunRecord(r, services.ModeSubmit, files)
+	if submission.Username != "" {
+		record.Username = submission.Username
+	}
+	record.ChallengeID = challenge.ID
+
+	// Run the code, fuzzing it too when the challenge has fuzz targets
+	result, ok := h.runQueued(w, r, services.ModeSubmit, files, challenge, record)
+	if !ok {
+		return
+	}
 	submission.Passed = result.Passed
 	submission.TestOutput = result.Output
 	submission.ExecutionMs = result.ExecutionMs
-
-	// Store submission
-	h.submissions = append(h.submissions, submission)
+	submission.TestsPassed = result.Summary.Passed
+	submission.TestsTotal = result.Summary.Total
+	submission.Tests = result.Tests
+	submission.Coverage = result.Coverage
+	submission.FuzzFailures = result.FuzzFailures
 
 	// Add to scoreboard if passed
 	if submission.Passed {
-		h.scoreboardService.AddSubmission(submission)
+		if _, err := h.scoreboardService.RecordSubmission(challenge, submission); err != nil {
+			log.Printf("Failed to record submission of %q on the scoreboard: %v", submission.Username, err)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(submission)
 }
 
-// getSubmissions returns all submissions
-func (h *APIHandler) getSubmissions(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.submissions)
-}
-
 // GetScoreboard returns the scoreboard for a challenge
 func (h *APIHandler) GetScoreboard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -170,7 +237,8 @@ func (h *APIHandler) GetScoreboard(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(scoreboard)
 }
 
-// RunCode executes submitted code
+// RunCode executes submitted code, running its tests or, in benchmark mode,
+// its benchmarks
 func (h *APIHandler) RunCode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -178,8 +246,10 @@ func (h *APIHandler) RunCode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		ChallengeID int    `json:"challengeId"`
-		Code        string `json:"code"`
+		ChallengeID int                `json:"challengeId"`
+		Code        string             `json:"code"`
+		Files       models.SourceFiles `json:"files"` // Set instead of code for multi-file submissions
+		Mode        string             `json:"mode"`  // "test" (default), "submit" or "benchmark"
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -188,18 +258,60 @@ func (h *APIHandler) RunCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mode, err := services.ParseRunMode(request.Mode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files, err := services.SubmissionFiles(request.Code, request.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	challenge, exists := h.challengeService.GetChallenge(request.ChallengeID)
 	if !exists {
 		http.Error(w, "Challenge not found", http.StatusNotFound)
 		return
 	}
+	if !challengeOpen(w, challenge.Status) {
+		return
+	}
+
+	record := h.newRunRecord(r, mode, files)
+	record.ChallengeID = challenge.ID
 
-	result := h.executionService.RunCode(request.Code, challenge)
+	result, ok := h.runQueued(w, r, mode, files, challenge, record)
+	if !ok {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+// writeSuccess writes a successful JSON response, with a value under a
+// name unless the name is empty
+func writeSuccess(w http.ResponseWriter, name string, value any) {
+	response := map[string]any{"success": true}
+	if name != "" {
+		response[name] = value
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// challengeOpen reports whether a challenge with a status can be run. For
+// challenges admins made coming soon it answers 403 Forbidden.
+func challengeOpen(w http.ResponseWriter, status string) bool {
+	if status == models.ChallengeComingSoon {
+		http.Error(w, "This challenge is coming soon and cannot be run yet", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 // SaveSubmissionToFilesystem saves a submission to the filesystem
 func (h *APIHandler) SaveSubmissionToFilesystem(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -214,13 +326,16 @@ func (h *APIHandler) SaveSubmissionToFilesystem(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if request.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	username, ok := requireUser(w, r, request.Username)
+	if !ok {
 		return
 	}
-
-	// Set username cookie
-	h.setUsernameCookie(w, request.Username)
+	if !services.ValidUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+	request.Username = username
+	setUsernameCookie(w, r, username)
 
 	// Validate challenge exists
 	_, exists := h.challengeService.GetChallenge(request.ChallengeID)
@@ -255,12 +370,12 @@ func (h *APIHandler) RefreshUserAttempts(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if request.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	username, ok := requireUser(w, r, request.Username)
+	if !ok {
 		return
 	}
 
-	attempts := h.userService.RefreshUserAttempts(request.Username, h.challengeService.GetChallenges())
+	attempts := h.userService.RefreshUserAttempts(username, h.challengeService.GetChallenges())
 
 	response := struct {
 		Username     string       `json:"username"`
@@ -268,7 +383,7 @@ func (h *APIHandler) RefreshUserAttempts(w http.ResponseWriter, r *http.Request)
 		Scores       map[int]int  `json:"scores"`
 		Success      bool         `json:"success"`
 	}{
-		Username:     request.Username,
+		Username:     username,
 		AttemptedIDs: attempts.AttemptedIDs,
 		Scores:       attempts.Scores,
 		Success:      true,
@@ -285,7 +400,11 @@ func (h *APIHandler) GetGitUsername(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gitInfo := utils.GetGitUsername()
+	// Guessing is for a server only its owner uses; elsewhere users sign in
+	gitInfo := &utils.GitUserInfo{}
+	if requestIdentity(r).Local {
+		gitInfo = utils.GetGitUsername()
+	}
 
 	response := struct {
 		Username string `json:"username"`
@@ -303,20 +422,6 @@ func (h *APIHandler) GetGitUsername(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// setUsernameCookie sets the username cookie
-func (h *APIHandler) setUsernameCookie(w http.ResponseWriter, username string) {
-	// Cookie expires in 30 days
-	expiration := time.Now().Add(30 * 24 * time.Hour)
-	cookie := http.Cookie{
-		Name:     "username",
-		Value:    username,
-		Expires:  expiration,
-		Path:     "/",
-		HttpOnly: false, // Allow JavaScript to access it
-	}
-	http.SetCookie(w, &cookie)
-}
-
 // GetMainScoreboardRank returns the user's rank in the main scoreboard
 func (h *APIHandler) GetMainScoreboardRank(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -330,8 +435,7 @@ func (h *APIHandler) GetMainScoreboardRank(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Calculate user's rank in main scoreboard
-	rank := h.calculateMainScoreboardRank(username)
+	rank := h.scoreboardService.MainRank(username)
 
 	response := struct {
 		Username string `json:"username"`
@@ -347,91 +451,36 @@ func (h *APIHandler) GetMainScoreboardRank(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(response)
 }
 
-// calculateMainScoreboardRank calculates the user's rank based on completed challenges
-func (h *APIHandler) calculateMainScoreboardRank(username string) int {
-	// Get all users and their completion counts (only count if ALL tests passed)
-	challenges := h.challengeService.GetChallenges()
-	userCompletions := make(map[string]int)
-
-	// Process all challenge scoreboards to count actual completions
-	for challengeID := range challenges {
-		// Read scoreboard file directly to check test results
-		scoreboardPath := fmt.Sprintf("../challenge-%d/SCOREBOARD.md", challengeID)
-		content, err := ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// Try alternative path
-			scoreboardPath = fmt.Sprintf("challenge-%d/SCOREBOARD.md", challengeID)
-			content, err = ioutil.ReadFile(scoreboardPath)
-			if err != nil {
-				continue
-			}
-		}
-
-		// Parse scoreboard to find users who passed ALL tests
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			// Skip header and separator lines
-			if !strings.Contains(line, "|") || strings.Contains(line, "Username") || strings.Contains(line, "---") {
-				continue
-			}
-
-			parts := strings.Split(line, "|")
-			if len(parts) < 4 {
-				continue
-			}
-
-			username := strings.TrimSpace(parts[1])
-			passedTestsStr := strings.TrimSpace(parts[2])
-			totalTestsStr := strings.TrimSpace(parts[3])
-
-			// Skip empty usernames or placeholders
-			if username == "" || username == "------" {
-				continue
-			}
-
-			// Parse test counts
-			passedTests, err1 := strconv.Atoi(passedTestsStr)
-			totalTests, err2 := strconv.Atoi(totalTestsStr)
-
-			// Only count as completed if ALL tests passed
-			if err1 == nil && err2 == nil && passedTests > 0 && passedTests == totalTests {
-				userCompletions[username]++
-			}
-		}
-	}
-
-	// Get the target user's completion count
-	targetCompletions := userCompletions[username]
-	if targetCompletions == 0 {
-		return 0 // User is unranked
-	}
-
-	// Count how many users have more completions (following Python script logic)
-	rank := 1
-	for user, completions := range userCompletions {
-		if user != username && completions > targetCompletions {
-			rank++
-		}
-	}
-
-	return rank
-}
-
-// GetMainLeaderboard returns the main leaderboard data
+// GetMainLeaderboard returns the main leaderboard data, ranked by the
+// ranking parameter: count (the default), weighted or icpc
 func (h *APIHandler) GetMainLeaderboard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Calculate leaderboard data
-	leaderboard := h.calculateMainLeaderboard()
+	ranking := r.URL.Query().Get("ranking")
+	if ranking == "" {
+		ranking = services.DefaultRanking
+	}
+	strategy, err := services.NewRankingStrategy(ranking, h.challengeService.GetChallenges(), h.submissionStore)
+	if err == services.ErrUnknownRanking {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to rank the main leaderboard by %s: %v", ranking, err)
+		http.Error(w, "Failed to rank the leaderboard", http.StatusInternalServerError)
+		return
+	}
 
 	response := struct {
-		Leaderboard []LeaderboardUser `json:"leaderboard"`
-		Success     bool              `json:"success"`
+		Leaderboard []models.LeaderboardUser `json:"leaderboard"`
+		Ranking     string                   `json:"ranking"`
+		Success     bool                     `json:"success"`
 	}{
-		Leaderboard: leaderboard,
+		Leaderboard: h.scoreboardService.Leaderboard(strategy),
+		Ranking:     strategy.Name(),
 		Success:     true,
 	}
 
@@ -439,128 +488,18 @@ func (h *APIHandler) GetMainLeaderboard(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// LeaderboardUser represents a user in the leaderboard
-type LeaderboardUser struct {
-	Username            string       `json:"username"`
-	CompletedCount      int          `json:"completedCount"`
-	CompletionRate      float64      `json:"completionRate"`
-	CompletedChallenges map[int]bool `json:"completedChallenges"`
-	Achievement         string       `json:"achievement"`
-	Rank                int          `json:"rank"`
-}
-
-// calculateMainLeaderboard calculates the main leaderboard data
-func (h *APIHandler) calculateMainLeaderboard() []LeaderboardUser {
-	challenges := h.challengeService.GetChallenges()
-	totalChallenges := len(challenges)
-	userCompletions := make(map[string]map[int]bool)
-
-	// Process all challenge scoreboards to find completions
-	for challengeID := range challenges {
-		// Read scoreboard file directly to check test results
-		scoreboardPath := fmt.Sprintf("../challenge-%d/SCOREBOARD.md", challengeID)
-		content, err := ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// Try alternative path
-			scoreboardPath = fmt.Sprintf("challenge-%d/SCOREBOARD.md", challengeID)
-			content, err = ioutil.ReadFile(scoreboardPath)
-			if err != nil {
-				continue
-			}
-		}
-
-		// Parse scoreboard to find users who passed ALL tests
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			// Skip header and separator lines
-			if !strings.Contains(line, "|") || strings.Contains(line, "Username") || strings.Contains(line, "---") {
-				continue
-			}
-
-			parts := strings.Split(line, "|")
-			if len(parts) < 4 {
-				continue
-			}
-
-			username := strings.TrimSpace(parts[1])
-			passedTestsStr := strings.TrimSpace(parts[2])
-			totalTestsStr := strings.TrimSpace(parts[3])
-
-			// Skip empty usernames or placeholders
-			if username == "" || username == "------" {
-				continue
-			}
-
-			// Parse test counts
-			passedTests, err1 := strconv.Atoi(passedTestsStr)
-			totalTests, err2 := strconv.Atoi(totalTestsStr)
-
-			// Only count as completed if ALL tests passed
-			if err1 == nil && err2 == nil && passedTests > 0 && passedTests == totalTests {
-				if userCompletions[username] == nil {
-					userCompletions[username] = make(map[int]bool)
-				}
-				userCompletions[username][challengeID] = true
-			}
-		}
-	}
-
-	// Convert to leaderboard format
-	var leaderboard []LeaderboardUser
-	for username, completions := range userCompletions {
-		completedCount := len(completions)
-		completionRate := float64(completedCount) / float64(totalChallenges) * 100
-
-		// Determine achievement
-		achievement := "🌱 Beginner"
-		if completedCount >= 20 {
-			achievement = "🔥 Master"
-		} else if completedCount >= 15 {
-			achievement = "⭐ Expert"
-		} else if completedCount >= 10 {
-			achievement = "💪 Advanced"
-		} else if completedCount >= 5 {
-			achievement = "🚀 Intermediate"
-		}
-
-		leaderboard = append(leaderboard, LeaderboardUser{
-			Username:            username,
-			CompletedCount:      completedCount,
-			CompletionRate:      completionRate,
-			CompletedChallenges: completions,
-			Achievement:         achievement,
-		})
-	}
-
-	// Sort by completion count (descending), then by username
-	for i := 0; i < len(leaderboard); i++ {
-		for j := i + 1; j < len(leaderboard); j++ {
-			if leaderboard[j].CompletedCount > leaderboard[i].CompletedCount ||
-				(leaderboard[j].CompletedCount == leaderboard[i].CompletedCount && leaderboard[j].Username < leaderboard[i].Username) {
-				leaderboard[i], leaderboard[j] = leaderboard[j], leaderboard[i]
-			}
-		}
-	}
-
-	// Assign ranks
-	for i := range leaderboard {
-		leaderboard[i].Rank = i + 1
-	}
-
-	return leaderboard
-}
-
-// HandlePackageChallenge handles package challenge test and submit requests
+// HandlePackageChallenge handles package challenge test and submit requests,
+// lists a package challenge's submission history and serves a package's
+// leaderboard
 func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Parse URL path: /api/packages/{packageName}/{challengeId}/{action}
 	path := strings.TrimPrefix(r.URL.Path, "/api/packages/")
 	parts := strings.Split(path, "/")
 
+	if len(parts) == 2 && parts[1] == "leaderboard" {
+		h.GetPackageLeaderboard(w, r, parts[0])
+		return
+	}
 	if len(parts) != 3 {
 		http.Error(w, "Invalid URL format. Expected: /api/packages/{packageName}/{challengeId}/{action}", http.StatusBadRequest)
 		return
@@ -568,18 +507,41 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 
 	packageName := parts[0]
 	challengeId := parts[1]
-	action := parts[2] // "test" or "submit"
+	action := parts[2] // "test", "submit" or "submissions"
 
-	// Validate action
-	if action != "test" && action != "submit" {
-		http.Error(w, "Invalid action. Must be 'test' or 'submit'", http.StatusBadRequest)
+	if action == "submissions" {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query, err := parseHistoryQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.PackageName = packageName
+		query.PackageChallenge = challengeId
+		h.writeHistoryPage(w, query)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Validate action; it is the run mode of tests and submits
+	mode, err := services.ParseRunMode(action)
+	if action == "" || err != nil || mode == services.ModeBenchmark {
+		http.Error(w, "Invalid action. Must be 'test', 'submit' or 'submissions'", http.StatusBadRequest)
 		return
 	}
 
 	// Parse request body
 	var request struct {
-		Code     string `json:"code"`
-		Username string `json:"username"`
+		Code     string             `json:"code"`
+		Files    models.SourceFiles `json:"files"` // Set instead of code for multi-file submissions
+		Username string             `json:"username"`
 	}
 
 	body, err := ioutil.ReadAll(r.Body)
@@ -594,11 +556,17 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if request.Code == "" {
+	if request.Code == "" && len(request.Files) == 0 {
 		http.Error(w, "Code is required", http.StatusBadRequest)
 		return
 	}
 
+	files, err := services.SubmissionFiles(request.Code, request.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Use the existing package service
 	packageService := h.packageService
 
@@ -607,36 +575,59 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 		http.Error(w, fmt.Sprintf("Challenge not found: %v", err), http.StatusNotFound)
 		return
 	}
+	if !challengeOpen(w, challenge.Status) {
+		return
+	}
 
 	// Convert PackageChallenge to Challenge format for ExecutionService
 	challengeForExecution := &models.Challenge{
-		ID:       0, // Package challenges don't use numeric IDs
-		Title:    challenge.Title,
-		TestFile: challenge.TestFile,
+		ID:        0, // Package challenges don't use numeric IDs
+		Title:     challenge.Title,
+		TestFile:  challenge.TestFile,
+		Limits:    challenge.Limits,
+		Stages:    challenge.Stages,
+		Benchmark: challenge.Benchmark,
+		Fuzz:      challenge.Fuzz,
+		Dir:       challenge.Dir,
 	}
 
+	// Submits run the tests too, fuzzing when the challenge has fuzz
+	// targets, and are kept apart in the history
+	record := h.newRunRecord(r, mode, files)
+	record.Username = requestUser(r, request.Username)
+	if mode == services.ModeSubmit && record.Username == "" && !requestIdentity(r).Local {
+		http.Error(w, "Sign in to submit", http.StatusUnauthorized)
+		return
+	}
+	record.PackageName = packageName
+	record.PackageChallenge = challengeId
+
 	// Run the actual tests using ExecutionService
-	result := h.executionService.RunCode(request.Code, challengeForExecution)
+	result, ok := h.runQueued(w, r, mode, files, challengeForExecution, record)
+	if !ok {
+		return
+	}
 
 	// Format response
 	response := map[string]interface{}{
 		"success":      result.Passed,
 		"execution_ms": result.ExecutionMs,
 		"output":       result.Output,
+		"killed":       result.Killed,
+		"tests":        result.Tests,
+		"stages":       result.Stages,
+		"coverage":     result.Coverage,
+		"tests_passed": result.Summary.Passed,
+		"tests_total":  result.Summary.Total,
 	}
 
-	// Count passed tests from output for display
-	testsPassed, testsTotal := h.parseTestResults(result.Output)
-	response["tests_passed"] = testsPassed
-	response["tests_total"] = testsTotal
-
-	if action == "submit" && result.Passed {
+	if mode == services.ModeSubmit && result.Passed {
 		response["message"] = "Solution submitted successfully!"
 		response["show_pr_instructions"] = true
 
-		// Set username cookie if provided
+		// Remember the username the submit named
 		if request.Username != "" {
-			h.setUsernameCookie(w, request.Username)
+			setUsernameCookie(w, r, request.Username)
 		}
 	}
 
@@ -644,36 +635,6 @@ func (h *APIHandler) HandlePackageChallenge(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
-// parseTestResults parses Go test output to count passed and total tests
-func (h *APIHandler) parseTestResults(output string) (passed int, total int) {
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		// Look for test result lines like "--- PASS: TestGetUsers" or "--- FAIL: TestCreateUser"
-		if strings.Contains(line, "--- PASS:") {
-			passed++
-			total++
-		} else if strings.Contains(line, "--- FAIL:") {
-			total++
-		}
-	}
-
-	// If no individual test results found, check for overall result
-	if total == 0 {
-		if strings.Contains(output, "PASS") && !strings.Contains(output, "FAIL") {
-			// Assume basic success case
-			passed = 1
-			total = 1
-		} else if strings.Contains(output, "FAIL") {
-			// Assume basic failure case
-			passed = 0
-			total = 1
-		}
-	}
-
-	return passed, total
-}
-
 // SavePackageChallengeToFilesystem saves a package challenge submission to the filesystem
 func (h *APIHandler) SavePackageChallengeToFilesystem(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -682,10 +643,11 @@ func (h *APIHandler) SavePackageChallengeToFilesystem(w http.ResponseWriter, r *
 	}
 
 	var request struct {
-		Username    string `json:"username"`
-		PackageName string `json:"packageName"`
-		ChallengeID string `json:"challengeId"`
-		Code        string `json:"code"`
+		Username    string             `json:"username"`
+		PackageName string             `json:"packageName"`
+		ChallengeID string             `json:"challengeId"`
+		Code        string             `json:"code"`
+		Files       models.SourceFiles `json:"files"` // Set instead of code for multi-file submissions
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -694,13 +656,16 @@ func (h *APIHandler) SavePackageChallengeToFilesystem(w http.ResponseWriter, r *
 		return
 	}
 
-	if request.Username == "" {
-		http.Error(w, "Username is required", http.StatusBadRequest)
+	username, ok := requireUser(w, r, request.Username)
+	if !ok {
 		return
 	}
-
-	// Set username cookie
-	h.setUsernameCookie(w, request.Username)
+	if !services.ValidUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+	request.Username = username
+	setUsernameCookie(w, r, username)
 
 	// Validate challenge exists
 	_, err = h.packageService.GetPackageChallenge(request.PackageName, request.ChallengeID)
@@ -718,11 +683,21 @@ func (h *APIHandler) SavePackageChallengeToFilesystem(w http.ResponseWriter, r *
 
 // savePackageChallengeToFilesystem handles the actual file saving for package challenges
 func (h *APIHandler) savePackageChallengeToFilesystem(request struct {
-	Username    string `json:"username"`
-	PackageName string `json:"packageName"`
-	ChallengeID string `json:"challengeId"`
-	Code        string `json:"code"`
+	Username    string             `json:"username"`
+	PackageName string             `json:"packageName"`
+	ChallengeID string             `json:"challengeId"`
+	Code        string             `json:"code"`
+	Files       models.SourceFiles `json:"files"`
 }) services.SaveSubmissionResponse {
+	// A single file is saved under the name package challenges use
+	files, err := services.SubmissionFiles(request.Code, request.Files)
+	if err != nil {
+		return services.SaveSubmissionResponse{Success: false, Message: err.Error()}
+	}
+	if len(request.Files) == 0 {
+		files = models.SourceFiles{services.PackageMainFile: request.Code}
+	}
+
 	// Get working directory for correct relative paths
 	workDir, _ := os.Getwd()
 
@@ -746,9 +721,7 @@ func (h *APIHandler) savePackageChallengeToFilesystem(request struct {
 			continue
 		}
 
-		solutionFile := filepath.Join(dirPath, "solution.go")
-		err = ioutil.WriteFile(solutionFile, []byte(request.Code), 0644)
-		if err != nil {
+		if err := services.SaveFiles(dirPath, files); err != nil {
 			continue
 		}
 
@@ -764,12 +737,13 @@ func (h *APIHandler) savePackageChallengeToFilesystem(request struct {
 		}
 	}
 
-	// Return success response with git commands
-	relativePath := filepath.Join("packages", request.PackageName, request.ChallengeID, "submissions", request.Username, "solution.go")
+	// Return success response with git commands. The whole directory is
+	// added so files removed since an earlier save are removed in git too.
+	relativePath := filepath.Join("packages", request.PackageName, request.ChallengeID, "submissions", request.Username) + string(filepath.Separator)
 	return services.SaveSubmissionResponse{
 		Success:  true,
 		Message:  "Solution saved to filesystem",
-		FilePath: filepath.Join(submissionDir, "solution.go"),
+		FilePath: filepath.Join(submissionDir, filepath.FromSlash(services.MainFileOf(files))),
 		GitCommands: []string{
 			"cd " + filepath.Join(workDir, ".."),
 			fmt.Sprintf("git add %s", relativePath),
@@ -787,9 +761,11 @@ func (h *APIHandler) AICodeReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		ChallengeID int    `json:"challengeId"`
-		Code        string `json:"code"`
-		Context     string `json:"context"`
+		ChallengeID int                    `json:"challengeId"`
+		Code        string                 `json:"code"`
+		Context     string                 `json:"context"`
+		Tests       []*models.TestResult   `json:"tests"`    // Results of the last run, if the client has them
+		Coverage    *models.CoverageReport `json:"coverage"` // Coverage of the last run, if the client has it
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -804,7 +780,18 @@ func (h *APIHandler) AICodeReview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	review, err := h.aiService.ReviewCode(request.Code, challenge, request.Context)
+	// Give the reviewer real test results and coverage instead of letting
+	// it guess
+	tests, coverage := request.Tests, request.Coverage
+	if len(tests) == 0 {
+		result, ok := h.runQueued(w, r, services.ModeTest, services.SingleFile(request.Code), challenge, nil)
+		if !ok {
+			return
+		}
+		tests, coverage = result.Tests, result.Coverage
+	}
+
+	review, err := h.aiService.ReviewCode(request.Code, challenge, request.Context, tests, coverage)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("AI review failed: %v", err), http.StatusInternalServerError)
 		return
@@ -915,9 +902,11 @@ func (h *APIHandler) AIDebugResponse(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		ChallengeID int    `json:"challengeId"`
-		Code        string `json:"code"`
-		Context     string `json:"context"`
+		ChallengeID int                    `json:"challengeId"`
+		Code        string                 `json:"code"`
+		Context     string                 `json:"context"`
+		Tests       []*models.TestResult   `json:"tests"`
+		Coverage    *models.CoverageReport `json:"coverage"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -933,7 +922,7 @@ func (h *APIHandler) AIDebugResponse(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get raw AI response for debugging
-	prompt := h.aiService.BuildCodeReviewPrompt(request.Code, challenge, request.Context)
+	prompt := h.aiService.BuildCodeReviewPrompt(request.Code, challenge, request.Context, request.Tests, request.Coverage)
 	rawResponse, err := h.aiService.CallLLMRaw(prompt)
 
 	response := struct {
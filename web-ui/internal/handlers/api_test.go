@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPackageChallengeActions(t *testing.T) {
+	h := &APIHandler{}
+	for _, action := range []string{"", "benchmark", "run", "Submit"} {
+		r := httptest.NewRequest("POST", "/api/packages/cobra/challenge-1-basic-cli/"+action, strings.NewReader(`{"code":"package main"}`))
+		w := httptest.NewRecorder()
+		h.HandlePackageChallenge(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("action %q: status %d, want %d", action, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// Cookies and header of the auth subsystem
+const (
+	sessionCookie  = "session"  // Signed session token
+	csrfCookie     = "csrf"     // Nonce the CSRF token is derived from
+	usernameCookie = "username" // Username the browser claims, trusted in local mode
+	externalCookie = "external" // Sign-in with an identity provider in progress
+	csrfHeader     = "X-CSRF-Token"
+)
+
+// identity is who a request comes from
+type identity struct {
+	Username      string // Empty when not known
+	Authenticated bool   // Signed in to an account
+	Local         bool   // The server runs in local mode, where requests may name their user
+	Admin         bool   // Signed in to an admin account
+	CSRFToken     string // The token unsafe requests from this browser must carry
+}
+
+// identityKey is the request context key of the identity
+type identityKey struct{}
+
+// requestIdentity returns who a request comes from, as the auth middleware
+// found
+func requestIdentity(r *http.Request) identity {
+	id, _ := r.Context().Value(identityKey{}).(identity)
+	return id
+}
+
+// requestUser returns who a request acts for: the signed-in user, or in
+// local mode the user the request names, or else the username cookie.
+// Outside local mode the name in the request is ignored.
+func requestUser(r *http.Request, named string) string {
+	id := requestIdentity(r)
+	if !id.Authenticated && id.Local && named != "" {
+		return named
+	}
+	return id.Username
+}
+
+// requireUser returns who a request acts for, see requestUser. When nobody
+// is, it answers 401 Unauthorized outside local mode and 400 Bad Request in
+// it, and returns false.
+func requireUser(w http.ResponseWriter, r *http.Request, named string) (string, bool) {
+	username := requestUser(r, named)
+	if username != "" {
+		return username, true
+	}
+	if requestIdentity(r).Local {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+	} else {
+		http.Error(w, "Sign in first", http.StatusUnauthorized)
+	}
+	return "", false
+}
+
+// requireAdmin returns the signed-in admin a request comes from. Usernames
+// that local mode trusts without an account are never admins. Otherwise it
+// answers 401 Unauthorized, or 403 Forbidden for users who are not admins,
+// and returns false.
+func requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := requestIdentity(r)
+	switch {
+	case !id.Authenticated:
+		http.Error(w, "Sign in to an admin account first", http.StatusUnauthorized)
+		return "", false
+	case !id.Admin:
+		http.Error(w, "Only admins can do that", http.StatusForbidden)
+		return "", false
+	}
+	return id.Username, true
+}
+
+// AuthHandler signs users in and out and identifies every request
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
+}
+
+// Middleware identifies the user of every request from their session, or
+// in local mode from the username cookie, and rejects unsafe requests that
+// do not carry the browser's CSRF token in the X-CSRF-Token header
+func (h *AuthHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := identity{Local: h.authService.Mode() == services.AuthLocal}
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			session, err := h.authService.Session(cookie.Value)
+			if err == nil {
+				id.Username, id.Authenticated = session.Username, true
+				id.Admin = h.authService.Admin(session.UserID, session.Role)
+			} else if !errors.Is(err, services.ErrSessionNotFound) {
+				log.Printf("Failed to look up session: %v", err)
+			}
+		}
+		if !id.Authenticated && id.Local {
+			id.Username = claimedUsername(r)
+		}
+
+		nonce := ""
+		if cookie, err := r.Cookie(csrfCookie); err == nil {
+			nonce = cookie.Value
+		}
+		if !safeMethod(r.Method) && !h.authService.ValidCSRFToken(nonce, r.Header.Get(csrfHeader)) {
+			http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		if nonce == "" {
+			nonce = services.NewToken()
+			setAuthCookie(w, r, csrfCookie, nonce)
+		}
+		id.CSRFToken = h.authService.CSRFToken(nonce)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+// GetSession returns the server's auth mode, the signed-in user and the
+// CSRF token the browser's unsafe requests must carry
+func (h *AuthHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := requestIdentity(r)
+	h.writeSession(w, id.Username, id.Authenticated, id.Admin, id.CSRFToken)
+}
+
+// Register creates an account and signs it in
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, password, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.authService.Register(username, password)
+	switch {
+	case errors.Is(err, services.ErrInvalidUsername), errors.Is(err, services.ErrInvalidPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrUsernameReserved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to register %q: %v", username, err)
+		http.Error(w, "Failed to create the account", http.StatusInternalServerError)
+		return
+	}
+	h.startSession(w, r, user)
+}
+
+// Login signs in to an account with its password
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, password, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.authService.Authenticate(username, password)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to sign in %q: %v", username, err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	h.startSession(w, r, user)
+}
+
+// HandleExternal routes /auth/{provider}/login, which sends the user to an
+// identity provider to sign in, and /auth/{provider}/callback, where they
+// return
+func (h *AuthHandler) HandleExternal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/auth/"), "/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	provider, ok := h.authService.Provider(parts[0])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "login":
+		h.startExternal(w, r, provider)
+	case "callback":
+		h.finishExternal(w, r, provider)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startExternal sends the user to an identity provider, remembering in the
+// browser which sign-in it started
+func (h *AuthHandler) startExternal(w http.ResponseWriter, r *http.Request, provider services.IdentityProvider) {
+	authURL, token, err := h.authService.StartExternalSignIn(provider, localRedirect(r.URL.Query().Get("next")))
+	if err != nil {
+		log.Printf("Failed to start signing in with %s: %v", provider.Name(), err)
+		http.Error(w, "Failed to start signing in", http.StatusInternalServerError)
+		return
+	}
+	setAuthCookie(w, r, externalCookie, token)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// finishExternal signs in the user an identity provider sent back, and
+// sends them on to the page they started from. Failures go back to the
+// sign-in page with the reason.
+func (h *AuthHandler) finishExternal(w http.ResponseWriter, r *http.Request, provider services.IdentityProvider) {
+	token := ""
+	if cookie, err := r.Cookie(externalCookie); err == nil {
+		token = cookie.Value
+	}
+	clearAuthCookie(w, r, externalCookie)
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		if description := query.Get("error_description"); description != "" {
+			reason = description
+		}
+		redirectToLogin(w, r, provider.DisplayName()+" sign-in failed: "+reason)
+		return
+	}
+
+	user, next, err := h.authService.FinishExternalSignIn(r.Context(), provider, token, query.Get("state"), query.Get("code"))
+	switch {
+	case errors.Is(err, services.ErrInvalidSignIn):
+		redirectToLogin(w, r, "The sign-in expired or was not started in this browser, please try again")
+		return
+	case errors.Is(err, services.ErrInvalidUsername):
+		redirectToLogin(w, r, "Your "+provider.DisplayName()+" username cannot be used here")
+		return
+	case err != nil:
+		log.Printf("Failed to sign in with %s: %v", provider.Name(), err)
+		redirectToLogin(w, r, provider.DisplayName()+" sign-in failed, please try again")
+		return
+	}
+
+	if _, ok := h.beginSession(w, r, user); !ok {
+		return
+	}
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+// Logout signs the browser out
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := h.authService.EndSession(cookie.Value); err != nil {
+			log.Printf("Failed to end session: %v", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	}
+	clearAuthCookie(w, r, sessionCookie)
+
+	username := ""
+	if requestIdentity(r).Local {
+		username = claimedUsername(r)
+	}
+	h.writeSession(w, username, false, false, requestIdentity(r).CSRFToken)
+}
+
+// startSession signs a user in and writes the new session
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if csrfToken, ok := h.beginSession(w, r, user); ok {
+		h.writeSession(w, user.Username, true, h.authService.Admin(user.ID, user.Role), csrfToken)
+	}
+}
+
+// beginSession signs a user in and returns the browser's new CSRF token.
+// The CSRF nonce is replaced, so a token learned before signing in is of no
+// use after. On failure it answers 500 Internal Server Error and returns
+// false.
+func (h *AuthHandler) beginSession(w http.ResponseWriter, r *http.Request, user *models.User) (string, bool) {
+	token, _, err := h.authService.CreateSession(user)
+	if err != nil {
+		log.Printf("Failed to create a session for %q: %v", user.Username, err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return "", false
+	}
+	setAuthCookie(w, r, sessionCookie, token)
+
+	nonce := services.NewToken()
+	setAuthCookie(w, r, csrfCookie, nonce)
+	return h.authService.CSRFToken(nonce), true
+}
+
+// writeSession writes the session response of the auth endpoints, which
+// also lists the identity providers users can sign in with
+func (h *AuthHandler) writeSession(w http.ResponseWriter, username string, authenticated, admin bool, csrfToken string) {
+	type provider struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	}
+	providers := []provider{}
+	for _, p := range h.authService.Providers() {
+		providers = append(providers, provider{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+
+	response := struct {
+		Mode          services.AuthMode `json:"mode"`
+		Authenticated bool              `json:"authenticated"`
+		Username      string            `json:"username"`
+		Admin         bool              `json:"admin"`
+		CSRFToken     string            `json:"csrfToken"`
+		Providers     []provider        `json:"providers"`
+		Success       bool              `json:"success"`
+	}{
+		Mode:          h.authService.Mode(),
+		Authenticated: authenticated,
+		Username:      username,
+		Admin:         admin,
+		CSRFToken:     csrfToken,
+		Providers:     providers,
+		Success:       true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// readCredentials decodes a username and password from a JSON body
+func readCredentials(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	var request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return "", "", false
+	}
+	if request.Username == "" || request.Password == "" {
+		http.Error(w, "Username and password are required", http.StatusBadRequest)
+		return "", "", false
+	}
+	return request.Username, request.Password, true
+}
+
+// setUsernameCookie remembers the username a browser named, in local mode
+// when it is not signed in. Page scripts read the cookie.
+func setUsernameCookie(w http.ResponseWriter, r *http.Request, username string) {
+	id := requestIdentity(r)
+	if !id.Local || id.Authenticated || !services.ValidUsername(username) {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     usernameCookie,
+		Value:    username,
+		Expires:  time.Now().Add(30 * 24 * time.Hour),
+		Path:     "/",
+		HttpOnly: false, // Allow JavaScript to access it
+	})
+}
+
+// claimedUsername returns the valid username of the username cookie, or ""
+func claimedUsername(r *http.Request) string {
+	if cookie, err := r.Cookie(usernameCookie); err == nil && services.ValidUsername(cookie.Value) {
+		return cookie.Value
+	}
+	return ""
+}
+
+// localRedirect returns a path on this site to send a user to after
+// signing in, or "/" when next is not one, so a link cannot send users
+// elsewhere
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// redirectToLogin sends a user back to the sign-in page with the reason
+// signing in failed
+func redirectToLogin(w http.ResponseWriter, r *http.Request, reason string) {
+	http.Redirect(w, r, "/login?error="+url.QueryEscape(reason), http.StatusFound)
+}
+
+// safeMethod reports whether a method only reads, so needs no CSRF token
+func safeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// setAuthCookie sets an HttpOnly cookie for as long as a session lasts,
+// Secure when the request came over HTTPS. SameSite=Lax keeps browsers from
+// sending it with cross-site POSTs.
+func setAuthCookie(w http.ResponseWriter, r *http.Request, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(services.SessionLifetime),
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearAuthCookie removes a cookie set by setAuthCookie
+func clearAuthCookie(w http.ResponseWriter, r *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+	})
+}
+
+// secureRequest reports whether a request came over HTTPS, directly or
+// through a proxy
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
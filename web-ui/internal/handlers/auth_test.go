@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"web-ui/internal/services"
+)
+
+// newTestAuthHandler returns an auth handler in accounts mode over a fresh
+// account store
+func newTestAuthHandler(t *testing.T) *AuthHandler {
+	t.Helper()
+	t.Setenv("AUTH_MODE", string(services.AuthAccounts))
+	t.Setenv("SESSION_SECRET", "test-session-secret")
+	t.Setenv("ADMIN_USERS", "")
+
+	store, err := services.NewSQLiteAccountStore(filepath.Join(t.TempDir(), "accounts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	authService, err := services.NewAuthService(store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthHandler(authService)
+}
+
+// serveWithMiddleware sends a request through the auth middleware and
+// returns the response and the identity the handler behind it saw
+func serveWithMiddleware(h *AuthHandler, r *http.Request) (*httptest.ResponseRecorder, identity) {
+	var seen identity
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIdentity(r)
+	})
+	w := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(w, r)
+	return w, seen
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	h := newTestAuthHandler(t)
+
+	// A first GET is let through and hands out the nonce cookie
+	w, seen := serveWithMiddleware(h, httptest.NewRequest("GET", "/api/auth/session", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET: status %d", w.Code)
+	}
+	var nonce string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == csrfCookie {
+			nonce = cookie.Value
+			if !cookie.HttpOnly {
+				t.Error("the CSRF nonce cookie is not HttpOnly")
+			}
+		}
+	}
+	if nonce == "" {
+		t.Fatal("GET did not set the CSRF nonce cookie")
+	}
+	token := seen.CSRFToken
+	if token == "" {
+		t.Fatal("GET got no CSRF token")
+	}
+	otherToken := h.authService.CSRFToken(services.NewToken())
+
+	tests := []struct {
+		name   string
+		nonce  string // Cookie, if set
+		header string // X-CSRF-Token, if set
+		want   int
+	}{
+		{"no token", nonce, "", http.StatusForbidden},
+		{"wrong token", nonce, "not-the-token", http.StatusForbidden},
+		{"token of another nonce", nonce, otherToken, http.StatusForbidden},
+		{"token without its nonce", "", token, http.StatusForbidden},
+		{"nonce as token", nonce, nonce, http.StatusForbidden},
+		{"matching token", nonce, token, http.StatusOK},
+	}
+	for _, method := range []string{"POST", "PUT", "DELETE", "PATCH"} {
+		for _, tt := range tests {
+			r := httptest.NewRequest(method, "/api/submissions", nil)
+			if tt.nonce != "" {
+				r.AddCookie(&http.Cookie{Name: csrfCookie, Value: tt.nonce})
+			}
+			if tt.header != "" {
+				r.Header.Set(csrfHeader, tt.header)
+			}
+			if w, _ := serveWithMiddleware(h, r); w.Code != tt.want {
+				t.Errorf("%s with %s: status %d, want %d", method, tt.name, w.Code, tt.want)
+			}
+		}
+	}
+}
+
+func TestMiddlewareSessionCookie(t *testing.T) {
+	h := newTestAuthHandler(t)
+	user, err := h.authService.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := h.authService.CreateSession(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := map[string]bool{
+		token:        true,
+		token + "x":  false,
+		"x" + token:  false,
+		"not-signed": false,
+	}
+	for value, signedIn := range cookies {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookie, Value: value})
+		_, seen := serveWithMiddleware(h, r)
+		if seen.Authenticated != signedIn || (signedIn && seen.Username != "alice") {
+			t.Errorf("session cookie %q: identity %+v, want signed in %v", value, seen, signedIn)
+		}
+	}
+
+	// In accounts mode the username cookie is not trusted
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: usernameCookie, Value: "alice"})
+	if _, seen := serveWithMiddleware(h, r); seen.Username != "" {
+		t.Errorf("username cookie was trusted in accounts mode: %+v", seen)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"web-ui/internal/services"
+)
+
+// badgeCacheControl lets browsers and image proxies reuse a badge for a
+// few minutes, after which the ETag makes revalidating it cheap
+const badgeCacheControl = "public, max-age=300"
+
+// ServeBadge serves a user's achievement badges, rendered from the current
+// scoreboards: /badges/{username}.svg is the full-size card,
+// /badges/{username}/compact.svg the compact badge and
+// /badges/{username}.json a shields.io endpoint. The SVGs take a theme
+// parameter, light or dark.
+func (h *APIHandler) ServeBadge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/badges/")
+	var username, kind string
+	switch {
+	case strings.HasSuffix(path, "/compact.svg"):
+		username, kind = strings.TrimSuffix(path, "/compact.svg"), "compact"
+	case strings.HasSuffix(path, ".svg"):
+		username, kind = strings.TrimSuffix(path, ".svg"), "card"
+	case strings.HasSuffix(path, ".json"):
+		username, kind = strings.TrimSuffix(path, ".json"), "shields"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !services.ValidUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	packages, err := h.packageService.LoadCompletions()
+	if err != nil {
+		log.Printf("Failed to load package scoreboards: %v", err)
+		http.Error(w, "Failed to load scoreboards", http.StatusInternalServerError)
+		return
+	}
+	stats, ok := services.BadgeStatsFor(username, h.scoreboardService.MainLeaderboard(),
+		len(h.challengeService.GetChallenges()), packages)
+	if !ok {
+		http.Error(w, "No completed challenges for this user", http.StatusNotFound)
+		return
+	}
+
+	var body []byte
+	contentType := "image/svg+xml; charset=utf-8"
+	theme := r.URL.Query().Get("theme")
+	switch kind {
+	case "card":
+		body, err = services.RenderCardBadge(stats, theme)
+	case "compact":
+		body, err = services.RenderCompactBadge(stats, theme)
+	case "shields":
+		body, err = json.Marshal(services.ShieldsBadgeFor(stats))
+		contentType = "application/json"
+	}
+	if err == services.ErrInvalidBadgeTheme {
+		http.Error(w, "Invalid theme", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// ServeContent answers If-None-Match with 304 Not Modified when the
+	// badge has not changed
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", badgeCacheControl)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// CohortHandler serves the cohort API. Every endpoint acts for the
+// requesting user, who must be known.
+type CohortHandler struct {
+	cohortService *services.CohortService
+}
+
+// NewCohortHandler creates a new cohort handler
+func NewCohortHandler(cohortService *services.CohortService) *CohortHandler {
+	return &CohortHandler{cohortService: cohortService}
+}
+
+// HandleCohorts lists the requesting user's cohorts on GET and creates one
+// on POST, which only site admins may
+func (h *CohortHandler) HandleCohorts(w http.ResponseWriter, r *http.Request) {
+	username, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		cohorts, err := h.cohortService.Cohorts(username)
+		if err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "cohorts", cohorts)
+	case "POST":
+		if _, ok := requireAdmin(w, r); !ok {
+			return
+		}
+		var request struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request data", http.StatusBadRequest)
+			return
+		}
+		cohort, err := h.cohortService.Create(username, request.Name, request.Description)
+		if err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "cohort", cohort)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleCohort routes the endpoints of one cohort:
+//
+//	GET  /api/cohorts/{id}
+//	GET  /api/cohorts/{id}/progress
+//	POST /api/cohorts/{id}/join
+//	POST /api/cohorts/{id}/delete
+//	POST /api/cohorts/{id}/members
+//	POST /api/cohorts/{id}/members/{username}/remove
+//	POST /api/cohorts/{id}/assignments
+//	POST /api/cohorts/{id}/assignments/{assignmentId}/remove
+func (h *CohortHandler) HandleCohort(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/cohorts/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid cohort ID", http.StatusBadRequest)
+		return
+	}
+	username, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	route := strings.Join(parts[1:], "/")
+	method := "POST"
+	if route == "" || route == "progress" {
+		method = "GET"
+	}
+	if r.Method != method {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case route == "":
+		cohort, err := h.cohortService.Cohort(username, id)
+		if err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "cohort", cohort)
+	case route == "progress":
+		progress, err := h.cohortService.Progress(username, id)
+		if err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "progress", progress)
+	case route == "join":
+		if err := h.cohortService.Join(username, id); err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case route == "delete":
+		if err := h.cohortService.Delete(username, id); err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case route == "members":
+		h.invite(w, r, username, id)
+	case len(parts) == 4 && parts[1] == "members" && parts[3] == "remove":
+		if err := h.cohortService.RemoveMember(username, id, parts[2]); err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	case route == "assignments":
+		h.assign(w, r, username, id)
+	case len(parts) == 4 && parts[1] == "assignments" && parts[3] == "remove":
+		assignmentID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid assignment ID", http.StatusBadRequest)
+			return
+		}
+		if err := h.cohortService.Unassign(username, id, assignmentID); err != nil {
+			writeCohortError(w, err)
+			return
+		}
+		writeSuccess(w, "", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// invite invites the user a request names to a cohort
+func (h *CohortHandler) invite(w http.ResponseWriter, r *http.Request, username string, id int64) {
+	var request struct {
+		Username string            `json:"username"`
+		Role     models.CohortRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	member, err := h.cohortService.Invite(username, id, strings.TrimSpace(request.Username), request.Role)
+	if err != nil {
+		writeCohortError(w, err)
+		return
+	}
+	writeSuccess(w, "member", member)
+}
+
+// assign assigns the challenge a request names to a cohort
+func (h *CohortHandler) assign(w http.ResponseWriter, r *http.Request, username string, id int64) {
+	var assignment models.CohortAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+	created, err := h.cohortService.Assign(username, id, assignment)
+	if err != nil {
+		writeCohortError(w, err)
+		return
+	}
+	writeSuccess(w, "assignment", created)
+}
+
+// writeCohortError answers with the status a cohort error calls for
+func writeCohortError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCohortNotFound),
+		errors.Is(err, services.ErrCohortMemberNotFound),
+		errors.Is(err, services.ErrAssignmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrCohortForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidCohort),
+		errors.Is(err, services.ErrInvalidCohortRole),
+		errors.Is(err, services.ErrInvalidUsername),
+		errors.Is(err, services.ErrInvalidAssignment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrCohortMemberExists),
+		errors.Is(err, services.ErrLastCohortAdmin),
+		errors.Is(err, services.ErrAssignmentExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Cohort request failed: %v", err)
+		http.Error(w, "Cohort request failed", http.StatusInternalServerError)
+	}
+}
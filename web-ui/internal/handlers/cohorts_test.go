@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-ui/internal/services"
+)
+
+func TestCreateCohortNeedsAdmin(t *testing.T) {
+	auth := newTestAuthHandler(t)
+	cohorts := NewCohortHandler(nil) // Never reached
+	user, err := auth.authService.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := auth.authService.CreateSession(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		session string
+		want    int
+	}{
+		"signed out":       {"", http.StatusUnauthorized},
+		"not a site admin": {session, http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		nonce := services.NewToken()
+		r := httptest.NewRequest("POST", "/api/cohorts", strings.NewReader(`{"name":"Onboarding"}`))
+		r.AddCookie(&http.Cookie{Name: csrfCookie, Value: nonce})
+		r.Header.Set(csrfHeader, auth.authService.CSRFToken(nonce))
+		if tt.session != "" {
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.session})
+		}
+		w := httptest.NewRecorder()
+		auth.Middleware(http.HandlerFunc(cohorts.HandleCohorts)).ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// recordRun waits for a job to finish and saves it to the submission
+// history. It runs in the background so that streamed runs are recorded
+// even after their client has gone.
+func (h *APIHandler) recordRun(job *services.ExecutionJob, record *models.SubmissionRecord) {
+	result := job.Wait(context.Background())
+	services.RecordResult(record, result)
+	if err := h.submissionStore.Save(record); err != nil {
+		log.Printf("Failed to record run %s: %v", job.ID, err)
+	}
+}
+
+// newRunRecord starts the history record of a run; the caller sets which
+// challenge it was for
+func (h *APIHandler) newRunRecord(r *http.Request, mode services.RunMode, files models.SourceFiles) *models.SubmissionRecord {
+	record := &models.SubmissionRecord{
+		Username:    requestUsername(r),
+		Mode:        string(mode),
+		SubmittedAt: time.Now(),
+		GoVersion:   h.executionService.GoVersion(),
+		CodeHash:    services.FilesHash(files),
+		Code:        files[services.MainFileOf(files)],
+	}
+	if len(files) > 1 {
+		record.Files = files
+	}
+	return record
+}
+
+// requestUsername returns who a request comes from, or "" when it is not
+// known
+func requestUsername(r *http.Request) string {
+	return requestIdentity(r).Username
+}
+
+// getSubmissions returns a page of the submission history, filtered by the
+// user, challenge, package, packageChallenge, mode, codeHash and passed
+// parameters
+// and paged by limit and offset
+func (h *APIHandler) getSubmissions(w http.ResponseWriter, r *http.Request) {
+	query, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeHistoryPage(w, query)
+}
+
+// GetSubmission returns a single submission with its code and test results
+func (h *APIHandler) GetSubmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/submissions/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid submission ID", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.submissionStore.Get(id)
+	if errors.Is(err, services.ErrSubmissionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// GetUserSubmissions returns a page of one user's submission history:
+// /api/users/{username}/submissions
+func (h *APIHandler) GetUserSubmissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "submissions" {
+		http.Error(w, "Invalid URL format. Expected: /api/users/{username}/submissions", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Username = parts[0]
+	h.writeHistoryPage(w, query)
+}
+
+// writeHistoryPage runs a history query and writes the page
+func (h *APIHandler) writeHistoryPage(w http.ResponseWriter, query services.SubmissionQuery) {
+	page, err := h.submissionStore.List(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseHistoryQuery reads the history filters and paging from the URL
+func parseHistoryQuery(r *http.Request) (services.SubmissionQuery, error) {
+	values := r.URL.Query()
+	query := services.SubmissionQuery{
+		Username:         values.Get("user"),
+		PackageName:      values.Get("package"),
+		PackageChallenge: values.Get("packageChallenge"),
+		Mode:             values.Get("mode"),
+		CodeHash:         values.Get("codeHash"),
+	}
+
+	if query.Mode != "" {
+		if _, err := services.ParseRunMode(query.Mode); err != nil {
+			return query, err
+		}
+	}
+
+	integers := []struct {
+		name string
+		dest *int
+	}{
+		{"challenge", &query.ChallengeID},
+		{"limit", &query.Limit},
+		{"offset", &query.Offset},
+	}
+	for _, param := range integers {
+		if value := values.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return query, errors.New("invalid " + param.name + " parameter")
+			}
+			*param.dest = n
+		}
+	}
+
+	if value := values.Get("passed"); value != "" {
+		passed, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("invalid passed parameter")
+		}
+		query.Passed = &passed
+	}
+
+	return query, nil
+}
+
+// getAttempts returns the timeline of a user's attempts at a challenge. The
+// user is the user parameter, or the requesting user by default.
+func (h *APIHandler) getAttempts(w http.ResponseWriter, r *http.Request, challengeID int) {
+	attempts, err := h.attemptService.Attempts(attemptUser(r), challengeID)
+	if err != nil {
+		writeAttemptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"attempts": attempts,
+	})
+}
+
+// getAttemptDiff returns the diff between the from and to attempts of a
+// user at a challenge
+func (h *APIHandler) getAttemptDiff(w http.ResponseWriter, r *http.Request, challengeID int) {
+	values := r.URL.Query()
+	diff, err := h.attemptService.Diff(attemptUser(r), challengeID, values.Get("from"), values.Get("to"))
+	if err != nil {
+		writeAttemptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// attemptUser returns whose attempts a request asks for
+func attemptUser(r *http.Request) string {
+	if user := r.URL.Query().Get("user"); user != "" {
+		return user
+	}
+	return requestUsername(r)
+}
+
+// writeAttemptError maps attempt service errors to status codes
+func writeAttemptError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidUsername):
+		http.Error(w, "A valid user parameter is required when not signed in", http.StatusBadRequest)
+	case errors.Is(err, services.ErrAttemptNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// sseKeepAliveInterval is how often an idle event stream sends a comment so
+// proxies do not close the connection during long silent tests
+const sseKeepAliveInterval = 15 * time.Second
+
+// StartRunStream starts a code run in the background and returns the job ID
+// that the client uses to follow its progress and cancel it
+func (h *APIHandler) StartRunStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		ChallengeID      int                `json:"challengeId"`
+		PackageName      string             `json:"packageName"`      // Set instead of challengeId for package challenges
+		PackageChallenge string             `json:"packageChallenge"` // e.g. "challenge-1-basic-routing"
+		Code             string             `json:"code"`
+		Files            models.SourceFiles `json:"files"` // Set instead of code for multi-file submissions
+		Mode             string             `json:"mode"`  // "test" (default), "submit" or "benchmark"
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		return
+	}
+
+	mode, err := services.ParseRunMode(request.Mode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files, err := services.SubmissionFiles(request.Code, request.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record := h.newRunRecord(r, mode, files)
+	var challenge *models.Challenge
+	if request.PackageName != "" {
+		packageChallenge, err := h.packageService.GetPackageChallenge(request.PackageName, request.PackageChallenge)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Challenge not found: %v", err), http.StatusNotFound)
+			return
+		}
+		if !challengeOpen(w, packageChallenge.Status) {
+			return
+		}
+		challenge = &models.Challenge{
+			Title:     packageChallenge.Title,
+			TestFile:  packageChallenge.TestFile,
+			Limits:    packageChallenge.Limits,
+			Stages:    packageChallenge.Stages,
+			Benchmark: packageChallenge.Benchmark,
+			Fuzz:      packageChallenge.Fuzz,
+			Dir:       packageChallenge.Dir,
+		}
+		record.PackageName = request.PackageName
+		record.PackageChallenge = request.PackageChallenge
+	} else {
+		var exists bool
+		challenge, exists = h.challengeService.GetChallenge(request.ChallengeID)
+		if !exists {
+			http.Error(w, "Challenge not found", http.StatusNotFound)
+			return
+		}
+		if !challengeOpen(w, challenge.Status) {
+			return
+		}
+		record.ChallengeID = challenge.ID
+	}
+
+	job, ok := h.submitJob(w, r, mode, files, challenge, record)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"jobId":     job.ID,
+		"statusUrl": "/api/jobs/" + job.ID,
+		"eventsUrl": "/api/jobs/" + job.ID + "/events",
+		"cancelUrl": "/api/jobs/" + job.ID + "/cancel",
+	})
+}
+
+// HandleJob routes /api/jobs/{id}, /api/jobs/{id}/events and
+// /api/jobs/{id}/cancel for the user or client that submitted the job
+func (h *APIHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		http.Error(w, "Invalid URL format. Expected: /api/jobs/{id}[/events|/cancel]", http.StatusBadRequest)
+		return
+	}
+
+	// Jobs of other users and clients do not exist as far as a request is
+	// concerned: their events carry the submitted code's output
+	job, exists := h.executionQueue.GetJob(parts[0])
+	if !exists || job.User != executionUser(r) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch action {
+	case "":
+		// Status polling for clients that do not use the event stream
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.Status())
+	case "events":
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.streamJobEvents(w, r, job)
+	case "cancel":
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job.Cancel()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobId":     job.ID,
+			"cancelled": true,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// submitJob queues a run in the given mode for the requesting user and, when record is not nil,
+// saves it to the submission history once it finishes. When the queue cannot accept the run, it
+// responds with 429 Too Many Requests and returns false.
+func (h *APIHandler) submitJob(w http.ResponseWriter, r *http.Request, mode services.RunMode, files models.SourceFiles, challenge *models.Challenge, record *models.SubmissionRecord) (*services.ExecutionJob, bool) {
+	job, err := h.executionQueue.Submit(executionUser(r), mode, files, challenge)
+	if err != nil {
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return nil, false
+	}
+	if record != nil {
+		go h.recordRun(job, record)
+	}
+	return job, true
+}
+
+// runQueued queues a run and waits for its result. If the client disconnects
+// while waiting, the run is cancelled.
+func (h *APIHandler) runQueued(w http.ResponseWriter, r *http.Request, mode services.RunMode, files models.SourceFiles, challenge *models.Challenge, record *models.SubmissionRecord) (services.ExecutionResult, bool) {
+	job, ok := h.submitJob(w, r, mode, files, challenge, record)
+	if !ok {
+		return services.ExecutionResult{}, false
+	}
+	return job.Wait(r.Context()), true
+}
+
+// executionUser identifies who a run belongs to, for per-user limits and
+// access to the job: the requesting user when known, otherwise the client
+// address
+func executionUser(r *http.Request) string {
+	if username := requestUsername(r); username != "" {
+		return "user:" + username
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// streamJobEvents sends a job's events as Server-Sent Events until the job
+// finishes or the client disconnects. Each event's id is its index, so a
+// reconnecting EventSource resumes where it left off via Last-Event-ID.
+func (h *APIHandler) streamJobEvents(w http.ResponseWriter, r *http.Request, job *services.ExecutionJob) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	next := 0
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		next = lastID + 1
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		events, done, changed := job.EventsSince(next)
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err == nil {
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", next, event.Type, data)
+			}
+			next++
+		}
+		flusher.Flush()
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			// The client went away; the job keeps running until it finishes
+			// or is cancelled explicitly
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// testClient is the executionUser of requests from httptest.NewRequest
+const testClient = "addr:192.0.2.1"
+
+// newTestJobHandler returns an API handler whose execution queue runs code
+// on the host, and a challenge with one passing test to run
+func newTestJobHandler(t *testing.T) (*APIHandler, *models.Challenge) {
+	t.Helper()
+	t.Setenv("EXECUTION_SANDBOX", "none")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module challenge1\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	challenge := &models.Challenge{
+		ID:       1,
+		Dir:      dir,
+		TestFile: "package main\n\nimport \"testing\"\n\nfunc TestSum(t *testing.T) {\n\tt.Run(\"Zero\", func(t *testing.T) {})\n}\n",
+	}
+
+	executionService := services.NewExecutionService(services.NewWorkspaceManager())
+	return &APIHandler{executionQueue: services.NewExecutionQueue(executionService)}, challenge
+}
+
+// sseEvent is one event of a Server-Sent Events stream
+type sseEvent struct {
+	id    int
+	event string
+	data  string
+}
+
+// readEvents parses a Server-Sent Events stream, skipping comments
+func readEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		field, value, _ := strings.Cut(scanner.Text(), ": ")
+		switch field {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				t.Fatalf("invalid event id %q", value)
+			}
+			current.id = id
+		case "event":
+			current.event = value
+		case "data":
+			current.data = value
+		case "":
+			if current.event != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+func TestStreamJobEvents(t *testing.T) {
+	h, challenge := newTestJobHandler(t)
+	job, err := h.executionQueue.Submit(testClient, services.ModeTest, services.SingleFile("package main\n"), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleJob(w, httptest.NewRequest("GET", "/api/jobs/"+job.ID+"/events", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// The stream ends with the result, once the job has finished
+	events := readEvents(t, w.Body.String())
+	if len(events) == 0 {
+		t.Fatal("no events")
+	}
+	seen := make(map[string]bool)
+	for i, event := range events {
+		if event.id != i {
+			t.Errorf("event %d has id %d", i, event.id)
+		}
+		var data services.ExecutionEvent
+		if err := json.Unmarshal([]byte(event.data), &data); err != nil || data.Type != event.event {
+			t.Errorf("event %d: %s data %q does not match: %v", i, event.event, event.data, err)
+		}
+		seen[event.event] = true
+	}
+	for _, name := range []string{services.EventQueued, services.EventPhase, services.EventTestStart, services.EventTestFinish} {
+		if !seen[name] {
+			t.Errorf("no %s event in %+v", name, events)
+		}
+	}
+	last := events[len(events)-1]
+	var result services.ExecutionEvent
+	json.Unmarshal([]byte(last.data), &result)
+	if last.event != services.EventResult || result.Result == nil || !result.Result.Passed {
+		t.Fatalf("last event %+v, want a passing result", last)
+	}
+
+	// A reconnecting client resumes after the last event it received
+	r := httptest.NewRequest("GET", "/api/jobs/"+job.ID+"/events", nil)
+	r.Header.Set("Last-Event-ID", "2")
+	w = httptest.NewRecorder()
+	h.HandleJob(w, r)
+	resumed := readEvents(t, w.Body.String())
+	if len(resumed) != len(events)-3 || resumed[0] != events[3] {
+		t.Errorf("resumed with %+v, want the events from id 3 of %+v", resumed, events)
+	}
+
+	// Polling reports the same result
+	w = httptest.NewRecorder()
+	h.HandleJob(w, httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil))
+	var status services.JobStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil || status.State != services.JobFinished || status.Result == nil || !status.Result.Passed {
+		t.Errorf("status %+v, %v, want a finished passing job", status, err)
+	}
+}
+
+// asUser returns r as it would reach a handler from a signed-in user
+func asUser(r *http.Request, username string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, identity{Username: username, Authenticated: true}))
+}
+
+func TestJobsOnlyForTheirOwner(t *testing.T) {
+	h, challenge := newTestJobHandler(t)
+	job, err := h.executionQueue.Submit("user:alice", services.ModeTest, services.SingleFile("package main\n"), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"status", httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil)},
+		{"events", httptest.NewRequest("GET", "/api/jobs/"+job.ID+"/events", nil)},
+		{"cancel", httptest.NewRequest("POST", "/api/jobs/"+job.ID+"/cancel", nil)},
+	}
+	for _, tt := range tests {
+		for _, r := range []*http.Request{tt.request, asUser(tt.request, "bob"), asUser(tt.request, "Alice")} {
+			w := httptest.NewRecorder()
+			h.HandleJob(w, r)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s by %q: status %d, want %d", tt.name, requestUsername(r), w.Code, http.StatusNotFound)
+			}
+		}
+	}
+
+	// Nobody else could cancel it, and its owner can follow it
+	w := httptest.NewRecorder()
+	h.HandleJob(w, asUser(httptest.NewRequest("GET", "/api/jobs/"+job.ID+"/events", nil), "alice"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("events by alice: status %d", w.Code)
+	}
+	if result := job.Wait(context.Background()); result.Killed != services.KillNone || !result.Passed {
+		t.Errorf("alice's run ended with %q, passed %v", result.Killed, result.Passed)
+	}
+}
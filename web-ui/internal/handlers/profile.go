@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// GetUserProfile returns a user's profile: /api/users/{username}
+func (h *APIHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+	if !services.ValidUsername(username) {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.profileService.Profile(username)
+	if errors.Is(err, services.ErrProfileNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build the profile of %s: %v", username, err)
+		http.Error(w, "Failed to load the profile", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Profile *models.UserProfile `json:"profile"`
+		Success bool                `json:"success"`
+	}{
+		Profile: profile,
+		Success: true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
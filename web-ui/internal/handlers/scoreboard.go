@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/services"
+)
+
+// Defaults and maximums of the time-based leaderboard views
+const (
+	defaultRecentSolves = 20
+	maxRecentSolves     = 100
+	defaultClimbers     = 10
+	maxClimbers         = 100
+	defaultClimberDays  = 7
+	maxClimberDays      = 365
+)
+
+// StreamScoreboardUpdates sends scoreboard updates as Server-Sent Events
+// while the client is connected. Each event's id is the update's ID, so a
+// reconnecting EventSource receives the updates it missed via Last-Event-ID,
+// as long as they are among the most recent ones.
+func (h *APIHandler) StreamScoreboardUpdates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	next := h.scoreboardService.NextUpdateID()
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && lastID < next {
+		next = lastID + 1
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		updates, changed := h.scoreboardService.UpdatesSince(next)
+		for _, update := range updates {
+			data, err := json.Marshal(update)
+			if err == nil {
+				fmt.Fprintf(w, "id: %d\nevent: scoreboard\ndata: %s\n\n", update.ID, data)
+			}
+			next = update.ID + 1
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// GetRecentlySolved returns the most recent first solves of classic
+// challenges, newest first. The limit parameter caps their number.
+func (h *APIHandler) GetRecentlySolved(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, err := boundedParam(r, "limit", defaultRecentSolves, maxRecentSolves)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		Solves  []models.RecentSolve `json:"solves"`
+		Success bool                 `json:"success"`
+	}{
+		Solves:  h.scoreboardService.RecentlySolved(limit),
+		Success: true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFastestClimbers returns the users who gained the most places on the
+// main leaderboard over the last days days. The limit parameter caps their
+// number.
+func (h *APIHandler) GetFastestClimbers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days, err := boundedParam(r, "days", defaultClimberDays, maxClimberDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := boundedParam(r, "limit", defaultClimbers, maxClimbers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	response := struct {
+		Climbers []models.Climber `json:"climbers"`
+		Since    time.Time        `json:"since"`
+		Success  bool             `json:"success"`
+	}{
+		Climbers: h.scoreboardService.FastestClimbers(since, limit),
+		Since:    since,
+		Success:  true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetPackageLeaderboard returns a package's leaderboard, from the
+// scoreboards of its challenges: /api/packages/{package}/leaderboard
+func (h *APIHandler) GetPackageLeaderboard(w http.ResponseWriter, r *http.Request, packageName string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := h.packageService.GetPackage(packageName); err != nil {
+		http.Error(w, "Package not found", http.StatusNotFound)
+		return
+	}
+
+	completions, err := h.packageService.LoadPackageCompletions(packageName)
+	if err != nil {
+		log.Printf("Failed to load the scoreboards of package %s: %v", packageName, err)
+		http.Error(w, "Failed to load scoreboards", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Package     string                          `json:"package"`
+		Challenges  []string                        `json:"challenges"`
+		Leaderboard []models.PackageLeaderboardUser `json:"leaderboard"`
+		Success     bool                            `json:"success"`
+	}{
+		Package:     packageName,
+		Challenges:  completions.Challenges,
+		Leaderboard: services.RankPackage(completions),
+		Success:     true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGlobalLeaderboard ranks users by classic and package challenges
+// together. The classic and package parameters weigh each kind of
+// challenge, 1 by default.
+func (h *APIHandler) GetGlobalLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	weights := services.DefaultLeaderboardWeights
+	for name, weight := range map[string]*float64{"classic": &weights.Classic, "package": &weights.Package} {
+		if value := r.URL.Query().Get(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s parameter", name), http.StatusBadRequest)
+				return
+			}
+			*weight = parsed
+		}
+	}
+	if err := services.ValidateLeaderboardWeights(weights); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packages, err := h.packageService.LoadCompletions()
+	if err != nil {
+		log.Printf("Failed to load package scoreboards: %v", err)
+		http.Error(w, "Failed to load scoreboards", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Leaderboard []models.GlobalLeaderboardUser `json:"leaderboard"`
+		Weights     models.LeaderboardWeights      `json:"weights"`
+		Success     bool                           `json:"success"`
+	}{
+		Leaderboard: services.RankGlobal(h.scoreboardService.MainLeaderboard(), packages, weights),
+		Weights:     weights,
+		Success:     true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// boundedParam reads a positive integer query parameter, which defaults to
+// def and is capped at max
+func boundedParam(r *http.Request, name string, def, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
@@ -2,13 +2,14 @@ package handlers
 
 import (
 	"embed"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"io/ioutil"
 	"os"
@@ -25,6 +26,8 @@ type WebHandler struct {
 	scoreboardService *services.ScoreboardService
 	userService       *services.UserService
 	packageService    *services.PackageService
+	attemptService    *services.AttemptService
+	profileService    *services.ProfileService
 }
 
 // NewWebHandler creates a new web handler
@@ -34,6 +37,8 @@ func NewWebHandler(
 	scoreboardService *services.ScoreboardService,
 	userService *services.UserService,
 	packageService *services.PackageService,
+	attemptService *services.AttemptService,
+	profileService *services.ProfileService,
 ) *WebHandler {
 	return &WebHandler{
 		content:           content,
@@ -41,6 +46,8 @@ func NewWebHandler(
 		scoreboardService: scoreboardService,
 		userService:       userService,
 		packageService:    packageService,
+		attemptService:    attemptService,
+		profileService:    profileService,
 	}
 }
 
@@ -86,8 +93,8 @@ func (h *WebHandler) HomePage(w http.ResponseWriter, r *http.Request) {
 		return packagesList[i].Stars > packagesList[j].Stars
 	})
 
-	// Get the username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// Get the requesting user if known
+	username := requestUsername(r)
 
 	// Get user attempts if username is set
 	var userAttempt *models.UserAttemptedChallenges
@@ -153,24 +160,24 @@ func (h *WebHandler) ChallengePage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get username from cookie first
-	username := h.getUsernameFromCookie(r)
+	// Get the requesting user if known
+	username := requestUsername(r)
 
-	// If no username from cookie, try to get it from Git config
-	if username == "" {
+	// In local mode, fall back to the Git config
+	if username == "" && requestIdentity(r).Local {
 		gitInfo := utils.GetGitUsername()
 		if gitInfo.Username != "" {
 			username = gitInfo.Username
 			// Set the cookie for future requests
-			h.setUsernameCookie(w, username)
+			setUsernameCookie(w, r, username)
 		}
 	}
 
-	existingSolution := ""
+	var existingFiles models.SourceFiles
 	hasAttempted := false
 
 	if username != "" {
-		existingSolution = h.userService.GetExistingSolution(username, id)
+		existingFiles = h.userService.GetExistingSolutionFiles(username, id)
 		// Check if user has attempted this challenge
 		userAttempts := h.userService.GetUserAttempts(username, h.challengeService.GetChallenges())
 		hasAttempted = userAttempts.AttemptedIDs[id]
@@ -187,11 +194,13 @@ func (h *WebHandler) ChallengePage(w http.ResponseWriter, r *http.Request) {
 		Challenge        *models.Challenge
 		Username         string
 		ExistingSolution string
+		ExistingFiles    models.SourceFiles // Set when the existing solution has several files
 		HasAttempted     bool
 	}{
 		Challenge:        challenge,
 		Username:         username,
-		ExistingSolution: existingSolution,
+		ExistingSolution: existingFiles[services.MainFileOf(existingFiles)],
+		ExistingFiles:    multipleFiles(existingFiles),
 		HasAttempted:     hasAttempted,
 	}
 
@@ -202,6 +211,78 @@ func (h *WebHandler) ChallengePage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// AttemptsPage renders the timeline of a user's attempts at a challenge and
+// the diff between two of them: /challenge/{id}/attempts?from=&to=, by
+// default the last attempt against the one before it
+func (h *WebHandler) AttemptsPage(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/challenge/"), "/attempts")
+	id, err := strconv.Atoi(path)
+	if err != nil {
+		http.Error(w, "Invalid challenge ID", http.StatusBadRequest)
+		return
+	}
+
+	challenge, exists := h.challengeService.GetChallenge(id)
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	username := r.URL.Query().Get("user")
+	if username == "" {
+		username = requestUsername(r)
+	}
+
+	var attempts []models.Attempt
+	var diff *models.AttemptDiff
+	var attemptError string
+	if username == "" {
+		attemptError = "Set your GitHub username to see your attempts."
+	} else if attempts, err = h.attemptService.Attempts(username, id); err != nil {
+		attemptError = err.Error()
+	} else {
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if to == "" && len(attempts) > 0 {
+			to = attempts[len(attempts)-1].ID
+		}
+		if from == "" && len(attempts) > 1 {
+			from = attempts[len(attempts)-2].ID
+		}
+		if from != "" && to != "" {
+			if diff, err = h.attemptService.Diff(username, id, from, to); err != nil {
+				attemptError = err.Error()
+			}
+		}
+	}
+
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/attempts.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Challenge *models.Challenge
+		Username  string
+		Attempts  []models.Attempt
+		Diff      *models.AttemptDiff
+		Error     string
+	}{
+		Challenge: challenge,
+		Username:  username,
+		Attempts:  attempts,
+		Diff:      diff,
+		Error:     attemptError,
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
+}
+
 // ScoreboardPage renders the main scoreboard page
 func (h *WebHandler) ScoreboardPage(w http.ResponseWriter, r *http.Request) {
 	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/scoreboard.html")
@@ -285,8 +366,8 @@ func (h *WebHandler) InterviewPage(w http.ResponseWriter, r *http.Request) {
 		challengeList = append(challengeList, challenge)
 	}
 
-	// Get username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// Get the requesting user if known
+	username := requestUsername(r)
 
 	data := struct {
 		Challenges []*models.Challenge
@@ -303,27 +384,287 @@ func (h *WebHandler) InterviewPage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getUsernameFromCookie retrieves the username from cookie
-func (h *WebHandler) getUsernameFromCookie(r *http.Request) string {
-	cookie, err := r.Cookie("username")
+// LoginPage renders the sign-in and registration page. After signing in the
+// browser returns to the page the next parameter names, on this site.
+func (h *WebHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/login.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id := requestIdentity(r)
+	data := struct {
+		Username      string
+		Authenticated bool
+		Local         bool
+		Next          string
+		Error         string
+	}{
+		Username:      id.Username,
+		Authenticated: id.Authenticated,
+		Local:         id.Local,
+		Next:          localRedirect(r.URL.Query().Get("next")),
+		Error:         r.URL.Query().Get("error"),
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
+}
+
+// CohortsPage renders the requesting user's cohorts and, for admins, the
+// form to create one
+func (h *WebHandler) CohortsPage(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/cohorts.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Username string
+		Admin    bool
+	}{
+		Username: requestUsername(r),
+		Admin:    requestIdentity(r).Admin,
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
+}
+
+// assignableChallenge is an option of the cohort page's assignment form
+type assignableChallenge struct {
+	Value string // "classic:{id}" or "package:{package}/{challenge}"
+	Label string
+}
+
+// assignablePackage groups the assignable challenges of a package
+type assignablePackage struct {
+	Label      string
+	Challenges []assignableChallenge
+}
+
+// CohortPage renders a cohort: its members, assignments, leaderboard and
+// progress matrix, which the page loads from the cohort API
+func (h *WebHandler) CohortPage(w http.ResponseWriter, r *http.Request) {
+	// Extract cohort ID from URL: /cohorts/1
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/cohorts/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/cohort.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Every classic and package challenge can be assigned
+	challenges := h.challengeService.GetChallenges()
+	ids := make([]int, 0, len(challenges))
+	for id := range challenges {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	classic := make([]assignableChallenge, 0, len(ids))
+	for _, id := range ids {
+		classic = append(classic, assignableChallenge{
+			Value: fmt.Sprintf("classic:%d", id),
+			Label: fmt.Sprintf("%d. %s", id, challenges[id].Title),
+		})
+	}
+
+	var packages []assignablePackage
+	for name, pkg := range h.packageService.GetPackages() {
+		group := assignablePackage{Label: pkg.DisplayName}
+		for _, challengeID := range pkg.LearningPath {
+			label := challengeID
+			if info := pkg.ChallengeDetails[challengeID]; info != nil && info.Title != "" {
+				label = info.Title
+			}
+			group.Challenges = append(group.Challenges, assignableChallenge{
+				Value: "package:" + name + "/" + challengeID,
+				Label: label,
+			})
+		}
+		if len(group.Challenges) > 0 {
+			packages = append(packages, group)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Label < packages[j].Label })
+
+	data := struct {
+		CohortID int64
+		Username string
+		Classic  []assignableChallenge
+		Packages []assignablePackage
+	}{
+		CohortID: id,
+		Username: requestUsername(r),
+		Classic:  classic,
+		Packages: packages,
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
+}
+
+// UserProfilePage renders a user's profile: /users/{username}
+func (h *WebHandler) UserProfilePage(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/")
+	if !services.ValidUsername(username) {
+		http.NotFound(w, r)
+		return
+	}
+
+	profile, err := h.profileService.Profile(username)
+	if errors.Is(err, services.ErrProfileNotFound) {
+		http.Error(w, "No practice found for "+username, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build the profile of %s: %v", username, err)
+		http.Error(w, "Failed to load the profile", http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/user_profile.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Profile  *models.UserProfile
+		Packages map[string]*models.Package
+		Own      bool // The profile is the requesting user's
+	}{
+		Profile:  profile,
+		Packages: h.packageService.GetPackages(),
+		Own:      strings.EqualFold(requestUsername(r), profile.Username),
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
 	if err != nil {
-		return ""
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
 	}
-	return cookie.Value
 }
 
-// setUsernameCookie sets the username cookie
-func (h *WebHandler) setUsernameCookie(w http.ResponseWriter, username string) {
-	// Cookie expires in 30 days
-	expiration := time.Now().Add(30 * 24 * time.Hour)
-	cookie := http.Cookie{
-		Name:     "username",
-		Value:    username,
-		Expires:  expiration,
-		Path:     "/",
-		HttpOnly: false, // Allow JavaScript to access it
-	}
-	http.SetCookie(w, &cookie)
+// adminChallenge is a row of the admin console's challenge list. Classic
+// challenges have a ChallengeID, package challenges a PackageName and
+// PackageChallenge.
+type adminChallenge struct {
+	ChallengeID      int
+	PackageName      string
+	PackageChallenge string
+	Label            string
+	Status           string // models.ChallengeAvailable or models.ChallengeComingSoon
+	Missing          bool   // A package challenge in the learning path that has no directory yet
+}
+
+// adminPackage groups the challenges of a package in the admin console
+type adminPackage struct {
+	Label      string
+	Challenges []adminChallenge
+}
+
+// AdminPage renders the admin console, which loads everything else from
+// the admin API. Visitors who are not signed in are sent to sign in, and
+// signed-in users who are not admins get 403 Forbidden.
+func (h *WebHandler) AdminPage(w http.ResponseWriter, r *http.Request) {
+	id := requestIdentity(r)
+	if !id.Authenticated {
+		http.Redirect(w, r, "/login?next=/admin", http.StatusFound)
+		return
+	}
+	if !id.Admin {
+		http.Error(w, "Only admins can see the admin console", http.StatusForbidden)
+		return
+	}
+
+	tmpl, err := template.New("").Funcs(utils.GetTemplateFuncs()).ParseFS(h.content, "templates/base.html", "templates/admin.html")
+	if err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Failed to parse template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	challenges := h.challengeService.GetChallenges()
+	ids := make([]int, 0, len(challenges))
+	for id := range challenges {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	classic := make([]adminChallenge, 0, len(ids))
+	for _, id := range ids {
+		status := challenges[id].Status
+		if status == "" {
+			status = models.ChallengeAvailable
+		}
+		classic = append(classic, adminChallenge{
+			ChallengeID: id,
+			Label:       fmt.Sprintf("%d. %s", id, challenges[id].Title),
+			Status:      status,
+		})
+	}
+
+	var packages []adminPackage
+	for name, pkg := range h.packageService.GetPackages() {
+		onDisk, _ := h.packageService.GetPackageChallenges(name)
+		group := adminPackage{Label: pkg.DisplayName}
+		for _, challengeID := range pkg.LearningPath {
+			challenge := adminChallenge{
+				PackageName:      name,
+				PackageChallenge: challengeID,
+				Label:            challengeID,
+				Status:           models.ChallengeAvailable,
+				Missing:          onDisk[challengeID] == nil,
+			}
+			if info := pkg.ChallengeDetails[challengeID]; info != nil {
+				if info.Title != "" {
+					challenge.Label = info.Title
+				}
+				challenge.Status = info.Status
+			}
+			group.Challenges = append(group.Challenges, challenge)
+		}
+		if len(group.Challenges) > 0 {
+			packages = append(packages, group)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Label < packages[j].Label })
+
+	data := struct {
+		Username string
+		Classic  []adminChallenge
+		Packages []adminPackage
+	}{
+		Username: id.Username,
+		Classic:  classic,
+		Packages: packages,
+	}
+
+	err = tmpl.ExecuteTemplate(w, "base", data)
+	if err != nil {
+		log.Printf("Template execution error: %v", err)
+		// Don't call http.Error here since headers may already be sent during template execution
+	}
 }
 
 // PackageDetailPage renders the package detail page
@@ -376,8 +717,8 @@ func (h *WebHandler) PackageDetailPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the username from cookie if available
-	username := h.getUsernameFromCookie(r)
+	// Get the requesting user if known
+	username := requestUsername(r)
 
 	// Check which package challenges the user has attempted
 	packageAttempts := make(map[string]bool)
@@ -407,8 +748,12 @@ func (h *WebHandler) PackageDetailPage(w http.ResponseWriter, r *http.Request) {
 		submissionCounts[challenge.ID] = h.countPackageChallengeSubmissions(packageName, challenge.ID)
 	}
 
-	// Create actual leaderboard using submission data
-	leaderboard := h.createPackageLeaderboard(packageName, challenges)
+	// Rank the package's learners from its challenges' scoreboards
+	completions, err := h.packageService.LoadPackageCompletions(packageName)
+	if err != nil {
+		log.Printf("Failed to load the scoreboards of package %s: %v", packageName, err)
+	}
+	leaderboard := services.RankPackage(completions)
 
 	data := struct {
 		Package          *models.Package
@@ -416,7 +761,7 @@ func (h *WebHandler) PackageDetailPage(w http.ResponseWriter, r *http.Request) {
 		Username         string
 		UserProgress     interface{}
 		TotalChallenges  int
-		Leaderboard      []models.PackageScoreboardEntry
+		Leaderboard      []models.PackageLeaderboardUser
 		PackageAttempts  map[string]bool
 		SubmissionCounts map[string]int
 	}{
@@ -472,25 +817,25 @@ func (h *WebHandler) PackageChallengePage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get username from cookie first
-	username := h.getUsernameFromCookie(r)
+	// Get the requesting user if known
+	username := requestUsername(r)
 
-	// If no username from cookie, try to get it from Git config
-	if username == "" {
+	// In local mode, fall back to the Git config
+	if username == "" && requestIdentity(r).Local {
 		gitInfo := utils.GetGitUsername()
 		if gitInfo.Username != "" {
 			username = gitInfo.Username
 			// Set the cookie for future requests
-			h.setUsernameCookie(w, username)
+			setUsernameCookie(w, r, username)
 		}
 	}
 
 	// Check if user has attempted this challenge
 	hasAttempted := false
-	existingSolution := ""
+	var existingFiles models.SourceFiles
 	if username != "" {
 		hasAttempted = h.hasUserAttemptedPackageChallenge(username, packageName, challengeID)
-		existingSolution = h.getUserPackageChallengeSolution(username, packageName, challengeID)
+		existingFiles = h.getUserPackageChallengeFiles(username, packageName, challengeID)
 	}
 
 	data := struct {
@@ -500,13 +845,15 @@ func (h *WebHandler) PackageChallengePage(w http.ResponseWriter, r *http.Request
 		SubmissionCount  int
 		HasAttempted     bool
 		ExistingSolution string
+		ExistingFiles    models.SourceFiles // Set when the existing solution has several files
 	}{
 		Package:          pkg,
 		Challenge:        challenge,
 		Username:         username,
 		SubmissionCount:  0,
 		HasAttempted:     hasAttempted,
-		ExistingSolution: existingSolution,
+		ExistingSolution: existingFiles[services.MainFileOf(existingFiles)],
+		ExistingFiles:    multipleFiles(existingFiles),
 	}
 
 	err = tmpl.ExecuteTemplate(w, "base", data)
@@ -516,44 +863,29 @@ func (h *WebHandler) PackageChallengePage(w http.ResponseWriter, r *http.Request
 	}
 }
 
-// hasUserAttemptedPackageChallenge checks if a user has attempted a package challenge
-func (h *WebHandler) hasUserAttemptedPackageChallenge(username, packageName, challengeID string) bool {
-	// Check if submission file exists in ../packages/{packageName}/{challengeID}/submissions/{username}/solution.go
-	submissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution.go")
-	if _, err := os.Stat(submissionPath); err == nil {
-		return true
-	}
-
-	// Try alternative path in case of different file naming
-	altSubmissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution-template.go")
-	if _, err := os.Stat(altSubmissionPath); err == nil {
-		return true
+// multipleFiles returns a file tree if it has more than one file, and nil
+// otherwise, so templates only set up file tabs for multi-file solutions
+func multipleFiles(files models.SourceFiles) models.SourceFiles {
+	if len(files) > 1 {
+		return files
 	}
+	return nil
+}
 
-	return false
+// hasUserAttemptedPackageChallenge checks if a user has attempted a package
+// challenge: any Go file in ../packages/{packageName}/{challengeID}/submissions/{username}
+func (h *WebHandler) hasUserAttemptedPackageChallenge(username, packageName, challengeID string) bool {
+	_, found := services.LatestSubmissionTime(filepath.Join("..", "packages", packageName, challengeID, "submissions", username))
+	return found
 }
 
-// getUserPackageChallengeSolution retrieves a user's existing solution for a package challenge
-func (h *WebHandler) getUserPackageChallengeSolution(username, packageName, challengeID string) string {
+// getUserPackageChallengeFiles retrieves the files of a user's existing
+// solution for a package challenge, or nil if the user has none
+func (h *WebHandler) getUserPackageChallengeFiles(username, packageName, challengeID string) models.SourceFiles {
 	if username == "" {
-		return ""
-	}
-
-	// Try solution.go first
-	submissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution.go")
-	content, err := ioutil.ReadFile(submissionPath)
-	if err == nil {
-		return string(content)
-	}
-
-	// Try solution-template.go as fallback
-	altSubmissionPath := filepath.Join("..", "packages", packageName, challengeID, "submissions", username, "solution-template.go")
-	content, err = ioutil.ReadFile(altSubmissionPath)
-	if err == nil {
-		return string(content)
+		return nil
 	}
-
-	return ""
+	return services.ReadSubmissionFiles(filepath.Join("..", "packages", packageName, challengeID, "submissions", username))
 }
 
 // countPackageChallengeSubmissions counts the number of submissions for a package challenge
@@ -574,14 +906,8 @@ func (h *WebHandler) countPackageChallengeSubmissions(packageName, challengeID s
 	count := 0
 	for _, entry := range entries {
 		if entry.IsDir() {
-			// Check if this user directory has a solution file
-			userDir := filepath.Join(submissionsDir, entry.Name())
-			solutionPath := filepath.Join(userDir, "solution.go")
-			altSolutionPath := filepath.Join(userDir, "solution-template.go")
-
-			if _, err := os.Stat(solutionPath); err == nil {
-				count++
-			} else if _, err := os.Stat(altSolutionPath); err == nil {
+			// Check if this user directory has any solution files
+			if _, found := services.LatestSubmissionTime(filepath.Join(submissionsDir, entry.Name())); found {
 				count++
 			}
 		}
@@ -589,89 +915,3 @@ func (h *WebHandler) countPackageChallengeSubmissions(packageName, challengeID s
 
 	return count
 }
-
-// createPackageLeaderboard creates a leaderboard for package challenges similar to classic challenges
-func (h *WebHandler) createPackageLeaderboard(packageName string, challenges []*models.PackageChallenge) []models.PackageScoreboardEntry {
-	var leaderboard []models.PackageScoreboardEntry
-	userStats := make(map[string]*userPackageStats)
-
-	// Collect submission data for each challenge
-	for _, challenge := range challenges {
-		submissionsDir := filepath.Join("..", "packages", packageName, challenge.ID, "submissions")
-
-		// Check if submissions directory exists
-		if _, err := os.Stat(submissionsDir); os.IsNotExist(err) {
-			continue
-		}
-
-		// Read the submissions directory
-		entries, err := ioutil.ReadDir(submissionsDir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				username := entry.Name()
-				userDir := filepath.Join(submissionsDir, username)
-				solutionPath := filepath.Join(userDir, "solution.go")
-
-				// Check if user has a solution file
-				if stat, err := os.Stat(solutionPath); err == nil {
-					if userStats[username] == nil {
-						userStats[username] = &userPackageStats{
-							username:            username,
-							completedCount:      0,
-							lastSubmission:      stat.ModTime(),
-							challengesCompleted: make(map[string]bool),
-						}
-					}
-
-					// Only count if not already counted
-					if !userStats[username].challengesCompleted[challenge.ID] {
-						userStats[username].completedCount++
-						userStats[username].challengesCompleted[challenge.ID] = true
-
-						// Update last submission time if this is more recent
-						if stat.ModTime().After(userStats[username].lastSubmission) {
-							userStats[username].lastSubmission = stat.ModTime()
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Convert to leaderboard entries and sort
-	for username, stats := range userStats {
-		if stats.completedCount > 0 {
-			entry := models.PackageScoreboardEntry{
-				Username:    username,
-				PackageName: packageName,
-				ChallengeID: "", // Not specific to one challenge
-				SubmittedAt: stats.lastSubmission,
-				TestsPassed: stats.completedCount,
-				TestsTotal:  len(challenges),
-			}
-			leaderboard = append(leaderboard, entry)
-		}
-	}
-
-	// Sort by completed count (descending), then by submission time (ascending for earliest)
-	sort.Slice(leaderboard, func(i, j int) bool {
-		if leaderboard[i].TestsPassed != leaderboard[j].TestsPassed {
-			return leaderboard[i].TestsPassed > leaderboard[j].TestsPassed
-		}
-		return leaderboard[i].SubmittedAt.Before(leaderboard[j].SubmittedAt)
-	})
-
-	return leaderboard
-}
-
-// userPackageStats helper struct for collecting user statistics
-type userPackageStats struct {
-	username            string
-	completedCount      int
-	lastSubmission      time.Time
-	challengesCompleted map[string]bool
-}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// User is a local account. Its username is the GitHub login that names the
+// user's submissions and scoreboard rows. Users who only sign in with an
+// identity provider have no password hash.
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`              // bcrypt hash of the password
+	Role         Role      `json:"role,omitempty"` // Empty for users without a site role
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Role is what a user may do on the whole site
+type Role string
+
+// RoleAdmin users manage challenges, scoreboard entries and other users'
+// roles from the admin console
+const RoleAdmin Role = "admin"
+
+// Session is a signed-in browser, kept on the server. The browser holds its
+// ID in a signed cookie.
+type Session struct {
+	ID        string
+	UserID    int64
+	Username  string
+	Role      Role
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// HiddenEntry hides a user's scoreboard entries from the scoreboards and
+// leaderboards, without removing their SCOREBOARD.md rows
+type HiddenEntry struct {
+	Username    string    `json:"username"`
+	ChallengeID int       `json:"challengeId"` // Zero hides the user from every challenge
+	Reason      string    `json:"reason"`
+	HiddenBy    string    `json:"hiddenBy"`
+	HiddenAt    time.Time `json:"hiddenAt"`
+}
+
+// ChallengeStatusOverride is a status admins set on a classic or package
+// challenge, in place of the one it has on disk. Classic challenges have a
+// ChallengeID, package challenges a PackageName and PackageChallenge.
+type ChallengeStatusOverride struct {
+	ChallengeID      int       `json:"challengeId,omitempty"`
+	PackageName      string    `json:"packageName,omitempty"`
+	PackageChallenge string    `json:"packageChallenge,omitempty"`
+	Status           string    `json:"status"` // ChallengeAvailable or ChallengeComingSoon
+	SetBy            string    `json:"setBy"`
+	SetAt            time.Time `json:"setAt"`
+}
+
+// AuditAction is the kind of change an admin made
+type AuditAction string
+
+const (
+	AuditSetRole         AuditAction = "set_role"
+	AuditHide            AuditAction = "hide"
+	AuditUnhide          AuditAction = "unhide"
+	AuditDisqualify      AuditAction = "disqualify"
+	AuditChallengeStatus AuditAction = "challenge_status"
+	AuditRejudge         AuditAction = "rejudge"
+)
+
+// AuditEntry records one admin action
+type AuditEntry struct {
+	ID        int64       `json:"id"`
+	Actor     string      `json:"actor"`
+	Action    AuditAction `json:"action"`
+	Target    string      `json:"target"` // What the action changed, e.g. "alice" or "challenge-5"
+	Detail    string      `json:"detail,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// AuditPage is one page of the audit log, newest first, with the number of
+// entries in the whole log
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+}
+
+// RejudgeState is how far a rejudge got
+type RejudgeState string
+
+const (
+	RejudgeRunning   RejudgeState = "running"
+	RejudgeFinished  RejudgeState = "finished"
+	RejudgeCancelled RejudgeState = "cancelled" // An admin stopped it before every submission ran
+)
+
+// Rejudge runs the submissions of a classic challenge, or of a user, again
+// and records the new test counts of those that pass on the scoreboards.
+// Exactly one of ChallengeID and Username is set.
+type Rejudge struct {
+	ID          int64           `json:"id"`
+	ChallengeID int             `json:"challengeId,omitempty"`
+	Username    string          `json:"username,omitempty"`
+	State       RejudgeState    `json:"state"`
+	Total       int             `json:"total"` // Submissions to run
+	Results     []RejudgeResult `json:"results"`
+	StartedBy   string          `json:"startedBy"`
+	StartedAt   time.Time       `json:"startedAt"`
+	FinishedAt  time.Time       `json:"finishedAt,omitzero"`
+}
+
+// RejudgeResult is the new result of one rejudged submission
+type RejudgeResult struct {
+	Username    string `json:"username"`
+	ChallengeID int    `json:"challengeId"`
+	Passed      bool   `json:"passed"`
+	TestsPassed int    `json:"testsPassed"`
+	TestsTotal  int    `json:"testsTotal"`
+	Error       string `json:"error,omitempty"` // Why the result could not be recorded
+}
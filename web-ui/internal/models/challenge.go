@@ -6,32 +6,138 @@ import (
 
 // Challenge represents a coding challenge
 type Challenge struct {
-	ID                int    `json:"id"`
-	Title             string `json:"title"`
-	Description       string `json:"description"`
-	Difficulty        string `json:"difficulty"`
-	Template          string `json:"template"`
-	TestFile          string `json:"testFile"`
-	LearningMaterials string `json:"learningMaterials"`
-	Hints             string `json:"hints"`
+	ID                int             `json:"id"`
+	Title             string          `json:"title"`
+	Description       string          `json:"description"`
+	Difficulty        string          `json:"difficulty"`
+	Points            int             `json:"points"`           // Worth of the challenge in difficulty-weighted rankings
+	Tags              []string        `json:"tags,omitempty"`   // Topics of the challenge, which decide its skill categories
+	Status            string          `json:"status,omitempty"` // ChallengeComingSoon when admins closed it, else empty
+	Template          string          `json:"template"`
+	TestFile          string          `json:"testFile"`
+	LearningMaterials string          `json:"learningMaterials"`
+	Hints             string          `json:"hints"`
+	Limits            ExecutionLimits `json:"limits"`
+	Stages            StageConfig     `json:"stages"`
+	Benchmark         BenchmarkConfig `json:"benchmark"`
+	HasBenchmarks     bool            `json:"hasBenchmarks"` // The test file defines Benchmark functions
+	Fuzz              FuzzConfig      `json:"fuzz"`
+	Dir               string          `json:"-"` // Challenge directory, for its go.mod and go.sum
 }
 
+// Challenge statuses, as ChallengeInfo.Status has them. Challenges that are
+// coming soon are listed, but cannot be run or submitted.
+const (
+	ChallengeAvailable  = "available"
+	ChallengeComingSoon = "coming-soon"
+)
+
+// SourceFiles is a submission's file tree: slash-separated paths relative
+// to the challenge's package directory, mapped to their contents
+type SourceFiles map[string]string
+
 // Submission represents a user's submitted solution
 type Submission struct {
-	Username    string    `json:"username"`
-	ChallengeID int       `json:"challengeId"`
-	Code        string    `json:"code"`
-	SubmittedAt time.Time `json:"submittedAt"`
-	Passed      bool      `json:"passed"`
-	TestOutput  string    `json:"testOutput"`
-	ExecutionMs int64     `json:"executionMs"`
+	Username     string          `json:"username"`
+	ChallengeID  int             `json:"challengeId"`
+	Code         string          `json:"code"`            // The main file; the only file of single-file submissions
+	Files        SourceFiles     `json:"files,omitempty"` // Every file of a multi-file submission, including the main file
+	SubmittedAt  time.Time       `json:"submittedAt"`
+	Passed       bool            `json:"passed"`
+	TestOutput   string          `json:"testOutput"`
+	ExecutionMs  int64           `json:"executionMs"`
+	TestsPassed  int             `json:"testsPassed"`
+	TestsTotal   int             `json:"testsTotal"`
+	Tests        []*TestResult   `json:"tests"`
+	Coverage     *CoverageReport `json:"coverage,omitempty"`
+	FuzzFailures []FuzzFailure   `json:"fuzzFailures,omitempty"` // Failing inputs the fuzz stage found
 }
 
 // ScoreboardEntry represents an entry in the scoreboard
 type ScoreboardEntry struct {
 	Username    string    `json:"username"`
 	ChallengeID int       `json:"challengeId"`
-	SubmittedAt time.Time `json:"submittedAt"`
+	SubmittedAt time.Time `json:"submittedAt,omitzero"` // Zero when the submission time is not known
+	PassedTests int       `json:"passedTests"`
+	TotalTests  int       `json:"totalTests"`
+	Coverage    float64   `json:"coverage,omitempty"` // Statement coverage percentage, when the submission was run here
+}
+
+// LeaderboardUser represents a user in the main leaderboard
+type LeaderboardUser struct {
+	Username            string       `json:"username"`
+	CompletedCount      int          `json:"completedCount"`
+	CompletionRate      float64      `json:"completionRate"`
+	CompletedChallenges map[int]bool `json:"completedChallenges"`
+	Achievement         string       `json:"achievement"`
+	Rank                int          `json:"rank"`
+	CompletedAt         time.Time    `json:"completedAt,omitzero"` // When the last of the completed challenges was first solved, if known
+	CurrentStreak       int          `json:"currentStreak"`        // Consecutive days with a first solve, up to today or yesterday
+	LongestStreak       int          `json:"longestStreak"`
+	Score               int          `json:"score"`             // Points under the leaderboard's ranking; the completed count by default
+	Penalty             int          `json:"penalty,omitempty"` // Minutes of ICPC time penalty, lower ranks higher
+}
+
+// LeaderboardWeights are what a completed classic and a completed package
+// challenge are worth on the global leaderboard
+type LeaderboardWeights struct {
+	Classic float64 `json:"classic"`
+	Package float64 `json:"package"`
+}
+
+// GlobalLeaderboardUser is a user's rank by classic and package challenges
+// together
+type GlobalLeaderboardUser struct {
+	Username         string         `json:"username"`
+	Score            float64        `json:"score"` // Completed challenges of each kind times its weight
+	ClassicCompleted int            `json:"classicCompleted"`
+	PackageCompleted int            `json:"packageCompleted"`
+	Packages         map[string]int `json:"packages"` // Completed challenges per package
+	Rank             int            `json:"rank"`
+}
+
+// RecentSolve is a user's first solve of a challenge
+type RecentSolve struct {
+	Username       string    `json:"username"`
+	ChallengeID    int       `json:"challengeId"`
+	ChallengeTitle string    `json:"challengeTitle"`
+	SolvedAt       time.Time `json:"solvedAt"`
+}
+
+// Climber is a user who moved up the main leaderboard over a period
+type Climber struct {
+	Username string `json:"username"`
+	Solved   int    `json:"solved"` // Challenges first solved during the period
+	From     int    `json:"from"`   // Rank at the start of the period, 0 when unranked
+	To       int    `json:"to"`
+	Climbed  int    `json:"climbed"` // Places gained; unranked users start below the last ranked one
+}
+
+// RankChange is a user's move in the main leaderboard
+type RankChange struct {
+	Username string `json:"username"`
+	From     int    `json:"from"` // 0 when the user was not ranked before
+	To       int    `json:"to"`
+}
+
+// ScoreboardUpdate is pushed to connected browsers when a passing
+// submission changes a challenge's scoreboard
+type ScoreboardUpdate struct {
+	ID          int               `json:"id"` // Increases with every update since the server started
+	ChallengeID int               `json:"challengeId"`
+	Entry       ScoreboardEntry   `json:"entry"`
+	RankChanges []RankChange      `json:"rankChanges"`
+	Leaderboard []LeaderboardUser `json:"leaderboard"` // The main leaderboard after the update
+}
+
+// ShieldsBadge is a shields.io endpoint badge, see
+// https://shields.io/badges/endpoint-badge
+type ShieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	Style         string `json:"style,omitempty"`
 }
 
 // UserAttemptedChallenges tracks attempted challenges by username
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// Cohort is a group of users, such as a team onboarding together, with its
+// own assigned challenges and leaderboard. Only its members see it.
+type Cohort struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	CreatedBy   string             `json:"createdBy"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	Members     []CohortMember     `json:"members"`     // Admins first, then by username
+	Assignments []CohortAssignment `json:"assignments"` // By due date, undated last
+}
+
+// CohortRole is what a member may do in a cohort
+type CohortRole string
+
+const (
+	// CohortAdmin members invite and remove members and assign challenges
+	CohortAdmin CohortRole = "admin"
+	// CohortLearner members work on the assignments
+	CohortLearner CohortRole = "member"
+)
+
+// CohortMember is a user invited to a cohort. Invited users see the cohort
+// and count as members once they join.
+type CohortMember struct {
+	Username  string     `json:"username"`
+	Role      CohortRole `json:"role"`
+	Joined    bool       `json:"joined"`
+	InvitedBy string     `json:"invitedBy"`
+	InvitedAt time.Time  `json:"invitedAt"`
+	JoinedAt  time.Time  `json:"joinedAt,omitzero"`
+}
+
+// CohortAssignment is a classic or package challenge assigned to a cohort.
+// Classic challenges have a ChallengeID, package challenges a PackageName
+// and PackageChallenge.
+type CohortAssignment struct {
+	ID               int64     `json:"id"`
+	ChallengeID      int       `json:"challengeId,omitempty"`
+	PackageName      string    `json:"packageName,omitempty"`
+	PackageChallenge string    `json:"packageChallenge,omitempty"`
+	Title            string    `json:"title"`
+	DueAt            time.Time `json:"dueAt,omitzero"` // No due date when zero
+	AssignedBy       string    `json:"assignedBy"`
+	AssignedAt       time.Time `json:"assignedAt"`
+}
+
+// AssignmentState is where a member is with an assignment
+type AssignmentState string
+
+const (
+	AssignmentCompleted  AssignmentState = "completed"   // Every test passed, by the due date if it is known when
+	AssignmentLate       AssignmentState = "late"        // Every test passed, after the due date
+	AssignmentInProgress AssignmentState = "in_progress" // On the scoreboard without passing, or run without a scoreboard entry
+	AssignmentOverdue    AssignmentState = "overdue"     // Not completed, and the due date has passed
+	AssignmentPending    AssignmentState = "pending"     // Not started, and not due yet
+)
+
+// AssignmentProgress is a member's progress on one assignment
+type AssignmentProgress struct {
+	State       AssignmentState `json:"state"`
+	CompletedAt time.Time       `json:"completedAt,omitzero"` // When first solved, if known
+	TestsPassed int             `json:"testsPassed,omitempty"`
+	TestsTotal  int             `json:"testsTotal,omitempty"`
+}
+
+// CohortMemberProgress is a member's row of a cohort's leaderboard and
+// progress matrix
+type CohortMemberProgress struct {
+	Username    string                       `json:"username"`
+	Completed   int                          `json:"completed"` // Assignments completed, late or not
+	Late        int                          `json:"late"`
+	Overdue     int                          `json:"overdue"`
+	Assignments map[int64]AssignmentProgress `json:"assignments"` // Assignment ID -> progress
+	Packages    map[string]*PackageProgress  `json:"packages"`    // Progress in each package the cohort is assigned challenges of
+	Rank        int                          `json:"rank"`
+}
+
+// CohortProgress is a cohort's leaderboard and progress matrix: its joined
+// members, ranked, with their progress on every assignment
+type CohortProgress struct {
+	Assignments []CohortAssignment     `json:"assignments"`
+	Members     []CohortMemberProgress `json:"members"`
+}
@@ -0,0 +1,156 @@
+package models
+
+// ExecutionLimits bounds the resources a single test run may consume.
+// Zero values fall back to the execution service defaults.
+type ExecutionLimits struct {
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"` // Wall-clock limit for the whole run
+	CPUSeconds     int `json:"cpu_seconds,omitempty"`     // CPU time limit per process
+	MemoryMB       int `json:"memory_mb,omitempty"`       // Address space limit per process
+}
+
+// Test statuses reported in TestResult.Status
+const (
+	TestStatusPass    = "pass"
+	TestStatusFail    = "fail"
+	TestStatusSkip    = "skip"
+	TestStatusRunning = "run" // Started but never finished (e.g. the run was killed)
+)
+
+// TestResult is the outcome of a single test or subtest, built from
+// `go test -json` events
+type TestResult struct {
+	Name           string        `json:"name"`                     // Full name, e.g. "TestSum/Zero_values"
+	Status         string        `json:"status"`                   // One of the TestStatus constants
+	ElapsedMs      int64         `json:"elapsedMs"`                // Time reported by the testing package
+	Output         string        `json:"output"`                   // Output captured while the test ran
+	FailureMessage string        `json:"failureMessage,omitempty"` // t.Error/t.Fatal messages and panics
+	Subtests       []*TestResult `json:"subtests,omitempty"`
+}
+
+// TestSummary counts test results the same way the scoreboard workflow
+// does: every test and subtest that passed or failed counts once
+type TestSummary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Total   int `json:"total"` // Passed + Failed
+}
+
+// Run stages, in the order they execute
+const (
+	StageGofmt = "gofmt" // Source is formatted as gofmt would
+	StageVet   = "vet"   // go vet reports nothing
+	StageTest  = "test"  // The challenge's tests pass
+	StageRace  = "race"  // The tests pass under the race detector
+	StageLint  = "lint"  // The analyzer suite reports nothing
+	StageFuzz  = "fuzz"  // Fuzzing the challenge's Fuzz targets finds no failing input (submissions only)
+)
+
+// Stage statuses reported in StageResult.Status
+const (
+	StageStatusPass  = "pass"
+	StageStatusFail  = "fail"
+	StageStatusSkip  = "skip"  // Not run, e.g. because an earlier stage was killed
+	StageStatusError = "error" // The stage could not run, e.g. the code does not compile
+)
+
+// StageConfig selects the stages run for a challenge. The test stage always
+// runs and is always required.
+type StageConfig struct {
+	Enabled  []string `json:"enabled,omitempty"`  // Extra stages to run; defaults to gofmt, vet, lint and fuzz
+	Required []string `json:"required,omitempty"` // Stages that must pass for the run to count as passed
+}
+
+// Diagnostic is a single finding reported by a stage
+type Diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Category string `json:"category,omitempty"` // Analyzer or check that reported it
+	Message  string `json:"message"`
+}
+
+// StageResult is the outcome of one stage of a run
+type StageResult struct {
+	Name        string       `json:"name"`     // One of the Stage constants
+	Status      string       `json:"status"`   // One of the StageStatus constants
+	Required    bool         `json:"required"` // Whether the run only passes if this stage passes
+	ElapsedMs   int64        `json:"elapsedMs"`
+	Output      string       `json:"output,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// CoverageBlock is a run of statements in the submitted file and how many
+// times the tests executed it
+type CoverageBlock struct {
+	StartLine int `json:"startLine"`
+	StartCol  int `json:"startCol"`
+	EndLine   int `json:"endLine"`
+	EndCol    int `json:"endCol"`
+	NumStmt   int `json:"numStmt"`
+	Count     int `json:"count"` // 0 means the tests never reached the block
+}
+
+// CoverageReport is the statement coverage of the submitted file under the
+// challenge's tests
+type CoverageReport struct {
+	File       string           `json:"file"`
+	Statements int              `json:"statements"`
+	Covered    int              `json:"covered"`
+	Percent    float64          `json:"percent"` // 0-100
+	Blocks     []CoverageBlock  `json:"blocks"`
+	Files      []CoverageReport `json:"files,omitempty"` // Per-file coverage of multi-file submissions
+}
+
+// BenchmarkConfig controls benchmark runs for a challenge
+type BenchmarkConfig struct {
+	Pattern   string `json:"pattern,omitempty"`   // -bench regexp; defaults to "." (all benchmarks)
+	Count     int    `json:"count,omitempty"`     // Runs per benchmark; defaults to 6
+	Benchtime string `json:"benchtime,omitempty"` // Duration or iterations per run, e.g. "100ms" or "500x"
+}
+
+// BenchmarkResult is the outcome of one benchmark across all of its runs
+type BenchmarkResult struct {
+	Name    string            `json:"name"` // Full name without the GOMAXPROCS suffix, e.g. "BenchmarkSort/100"
+	Metrics []BenchmarkMetric `json:"metrics"`
+}
+
+// BenchmarkMetric summarizes one unit of a benchmark, like benchstat does:
+// the median of the runs with a 95% confidence interval
+type BenchmarkMetric struct {
+	Unit     string               `json:"unit"` // "ns/op", "B/op", "allocs/op" or a custom metric
+	Median   float64              `json:"median"`
+	Range    string               `json:"range"` // Confidence interval as ± percent, e.g. "3%"
+	Samples  int                  `json:"samples"`
+	Baseline *BenchmarkComparison `json:"baseline,omitempty"` // Set when the challenge has a baseline for this benchmark
+}
+
+// BenchmarkComparison compares a metric with the challenge's baseline using
+// a Mann-Whitney U test, like benchstat does
+type BenchmarkComparison struct {
+	Median  float64 `json:"median"` // Baseline median
+	Range   string  `json:"range"`
+	Samples int     `json:"samples"`
+	Delta   string  `json:"delta"`   // Change from the baseline, e.g. "-12.34%", or "~" when not significant
+	P       float64 `json:"p"`       // p-value of the difference
+	Speedup float64 `json:"speedup"` // Baseline median divided by the new median; above 1 is better
+	Verdict string  `json:"verdict"` // "improvement", "regression" or "unchanged"
+}
+
+// FuzzConfig controls the fuzz stage for a challenge
+type FuzzConfig struct {
+	Time         string `json:"time,omitempty"`         // Fuzzing time per target; defaults to "10s"
+	MinimizeTime string `json:"minimizeTime,omitempty"` // Time spent minimizing a failing input; defaults to "10s"
+}
+
+// FuzzFailure is a failing input found by the fuzz stage. Input is the
+// corpus file the go command wrote; placed at File in the package directory,
+// it runs as part of a plain `go test`.
+type FuzzFailure struct {
+	Target  string `json:"target"`  // Fuzz function, e.g. "FuzzReverseString"
+	Name    string `json:"name"`    // Corpus entry name, e.g. "582528ddfad69eb5"
+	File    string `json:"file"`    // e.g. "testdata/fuzz/FuzzReverseString/582528ddfad69eb5"
+	Input   string `json:"input"`   // Corpus file contents: "go test fuzz v1" and one Go literal per argument
+	Message string `json:"message"` // Failure output of the run that found the input
+	Replay  string `json:"replay"`  // Command that replays the input, e.g. "go test -run=FuzzReverseString/582528ddfad69eb5"
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// SubmissionRecord is one run or submit kept in the submission history.
+// Listings leave out the code, output and per-test results; fetching a
+// single record returns everything.
+type SubmissionRecord struct {
+	ID               int64         `json:"id"`
+	Username         string        `json:"username,omitempty"`         // Empty for runs without a username cookie
+	ChallengeID      int           `json:"challengeId,omitempty"`      // Set for classic challenges
+	PackageName      string        `json:"packageName,omitempty"`      // Set for package challenges
+	PackageChallenge string        `json:"packageChallenge,omitempty"` // e.g. "challenge-1-basic-routing"
+	Mode             string        `json:"mode"`                       // test, submit or benchmark
+	SubmittedAt      time.Time     `json:"submittedAt"`
+	Passed           bool          `json:"passed"`
+	Killed           string        `json:"killed,omitempty"` // Resource limit that stopped the run
+	TestsPassed      int           `json:"testsPassed"`
+	TestsTotal       int           `json:"testsTotal"`
+	Coverage         float64       `json:"coverage,omitempty"` // Statement coverage percentage
+	ExecutionMs      int64         `json:"executionMs"`
+	GoVersion        string        `json:"goVersion"` // Toolchain that ran the code, e.g. "go1.25.0"
+	Sandbox          string        `json:"sandbox"`
+	CodeHash         string        `json:"codeHash"`        // Identifies the submitted files, see services.FilesHash
+	Code             string        `json:"code,omitempty"`  // The main file
+	Files            SourceFiles   `json:"files,omitempty"` // Every file of a multi-file submission
+	Output           string        `json:"output,omitempty"`
+	Tests            []*TestResult `json:"tests,omitempty"`
+}
+
+// Attempt is one saved version of a user's solution: a commit that touched
+// their submission directory, or the files on disk when they differ from
+// the last commit
+type Attempt struct {
+	ID           string      `json:"id"`     // Commit hash, or AttemptWorking
+	Number       int         `json:"number"` // 1 for the first attempt
+	Author       string      `json:"author,omitempty"`
+	Message      string      `json:"message,omitempty"`
+	Time         time.Time   `json:"time"`
+	Files        SourceFiles `json:"-"`
+	Tested       bool        `json:"tested"`                 // A run of exactly these files is in the submission history
+	SubmissionID int64       `json:"submissionId,omitempty"` // The latest such run
+	Passed       bool        `json:"passed"`
+	TestsPassed  int         `json:"testsPassed"`
+	TestsTotal   int         `json:"testsTotal"`
+}
+
+// AttemptWorking is the ID of the attempt made of uncommitted files
+const AttemptWorking = "working"
+
+// AttemptDiff compares two attempts
+type AttemptDiff struct {
+	From    Attempt      `json:"from"`
+	To      Attempt      `json:"to"`
+	Files   []FileDiff   `json:"files"`   // Changed files, in path order
+	Unified string       `json:"unified"` // The same changes as a unified diff
+	Tests   []TestChange `json:"tests"`   // Tests whose status differs, when both attempts were tested
+}
+
+// FileDiff is the change to one file between two attempts
+type FileDiff struct {
+	Path   string     `json:"path"`
+	Status string     `json:"status"` // added, deleted or modified
+	Hunks  []DiffHunk `json:"hunks"`
+}
+
+// DiffHunk is a run of changed lines with their context
+type DiffHunk struct {
+	Header string     `json:"header"` // e.g. "@@ -10,6 +10,8 @@ func Sum(a, b int) int {"
+	Lines  []DiffLine `json:"lines"`
+}
+
+// DiffLine is one line of a hunk. Line numbers are 0 on the side the line
+// is not on.
+type DiffLine struct {
+	Kind    string `json:"kind"` // context, add or delete
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"`
+	NewLine int    `json:"newLine,omitempty"`
+}
+
+// TestChange is a test whose status differs between two attempts. A status
+// is empty when the test did not run in that attempt.
+type TestChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
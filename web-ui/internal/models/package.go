@@ -41,43 +41,53 @@ type ChallengeInfo struct {
 
 // ChallengeMetadata represents metadata that can be loaded from challenge directories
 type ChallengeMetadata struct {
-	Title               string   `json:"title"`
-	Description         string   `json:"description"`
-	ShortDescription    string   `json:"short_description"` // Brief description for cards
-	Difficulty          string   `json:"difficulty"`
-	EstimatedTime       string   `json:"estimated_time"`
-	LearningObjectives  []string `json:"learning_objectives"`
-	Prerequisites       []string `json:"prerequisites"`
-	Tags                []string `json:"tags"`
-	RealWorldConnection string   `json:"real_world_connection"`
-	Requirements        []string `json:"requirements"`
-	BonusPoints         []string `json:"bonus_points"`
-	Icon                string   `json:"icon,omitempty"`
-	Order               int      `json:"order"`
+	Title               string           `json:"title"`
+	Description         string           `json:"description"`
+	ShortDescription    string           `json:"short_description"` // Brief description for cards
+	Difficulty          string           `json:"difficulty"`
+	Points              int              `json:"points,omitempty"` // Overrides the difficulty's points in weighted rankings
+	EstimatedTime       string           `json:"estimated_time"`
+	LearningObjectives  []string         `json:"learning_objectives"`
+	Prerequisites       []string         `json:"prerequisites"`
+	Tags                []string         `json:"tags"`
+	RealWorldConnection string           `json:"real_world_connection"`
+	Requirements        []string         `json:"requirements"`
+	BonusPoints         []string         `json:"bonus_points"`
+	Icon                string           `json:"icon,omitempty"`
+	Order               int              `json:"order"`
+	Limits              *ExecutionLimits `json:"limits,omitempty"`    // Optional per-challenge execution limits
+	Stages              *StageConfig     `json:"stages,omitempty"`    // Optional extra run stages (race, lint, ...)
+	Benchmark           *BenchmarkConfig `json:"benchmark,omitempty"` // Optional benchmark run settings
+	Fuzz                *FuzzConfig      `json:"fuzz,omitempty"`      // Optional fuzz stage settings
 }
 
 // PackageChallenge represents a challenge specific to a package
 type PackageChallenge struct {
-	ID                  string   `json:"id"`           // e.g., "challenge-1-basic-routing"
-	PackageName         string   `json:"package_name"` // e.g., "gin"
-	Title               string   `json:"title"`
-	Description         string   `json:"description"`
-	ShortDescription    string   `json:"short_description"` // Brief description for cards
-	Difficulty          string   `json:"difficulty"`
-	LearningObjectives  []string `json:"learning_objectives"`
-	Template            string   `json:"template"`
-	TestFile            string   `json:"testFile"`
-	LearningMaterials   string   `json:"learningMaterials"`
-	Hints               string   `json:"hints"`
-	Requirements        []string `json:"requirements"`
-	BonusPoints         []string `json:"bonus_points"`
-	RealWorldConnection string   `json:"real_world_connection"`
-	EstimatedTime       string   `json:"estimated_time"`
-	Tags                []string `json:"tags"`
-	Prerequisites       []string `json:"prerequisites"`
-	Icon                string   `json:"icon,omitempty"`
-	Order               int      `json:"order"`
-	Status              string   `json:"status,omitempty"` // "available", "coming-soon", etc.
+	ID                  string          `json:"id"`           // e.g., "challenge-1-basic-routing"
+	PackageName         string          `json:"package_name"` // e.g., "gin"
+	Title               string          `json:"title"`
+	Description         string          `json:"description"`
+	ShortDescription    string          `json:"short_description"` // Brief description for cards
+	Difficulty          string          `json:"difficulty"`
+	LearningObjectives  []string        `json:"learning_objectives"`
+	Template            string          `json:"template"`
+	TestFile            string          `json:"testFile"`
+	LearningMaterials   string          `json:"learningMaterials"`
+	Hints               string          `json:"hints"`
+	Requirements        []string        `json:"requirements"`
+	BonusPoints         []string        `json:"bonus_points"`
+	RealWorldConnection string          `json:"real_world_connection"`
+	EstimatedTime       string          `json:"estimated_time"`
+	Tags                []string        `json:"tags"`
+	Prerequisites       []string        `json:"prerequisites"`
+	Icon                string          `json:"icon,omitempty"`
+	Order               int             `json:"order"`
+	Status              string          `json:"status,omitempty"` // "available", "coming-soon", etc.
+	Limits              ExecutionLimits `json:"limits"`
+	Stages              StageConfig     `json:"stages"`
+	Benchmark           BenchmarkConfig `json:"benchmark"`
+	Fuzz                FuzzConfig      `json:"fuzz"`
+	Dir                 string          `json:"-"` // Challenge directory, for its go.mod and go.sum
 }
 
 // PackageSubmission represents a user's submitted solution for a package challenge
@@ -118,6 +128,33 @@ type PackageScoreboardEntry struct {
 	TestsTotal  int       `json:"tests_total"`
 }
 
+// PackageLeaderboardUser is a user's rank among the learners of one package
+type PackageLeaderboardUser struct {
+	Username        string               `json:"username"`
+	CompletedCount  int                  `json:"completedCount"`
+	TotalChallenges int                  `json:"totalChallenges"`
+	TestsPassed     int                  `json:"testsPassed"` // Across the challenges the user has a score for
+	TestsTotal      int                  `json:"testsTotal"`
+	Challenges      map[string]TestScore `json:"challenges"` // The user's score on each challenge's scoreboard
+	Rank            int                  `json:"rank"`
+}
+
+// TestScore is how many of a challenge's tests a user's solution passed
+type TestScore struct {
+	Passed int `json:"passed"`
+	Total  int `json:"total"`
+}
+
+// PackageLearner is a user's rank by package challenges completed across
+// all packages
+type PackageLearner struct {
+	Username       string         `json:"username"`
+	CompletedCount int            `json:"completedCount"`
+	Packages       map[string]int `json:"packages"` // Completed challenges per package
+	Achievement    string         `json:"achievement"`
+	Rank           int            `json:"rank"`
+}
+
 // Type aliases for collections
 type PackageMap map[string]*Package
 type PackageChallengeMap map[string]map[string]*PackageChallenge // package -> challenge_id -> challenge
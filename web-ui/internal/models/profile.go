@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// SkillCategory is an area of Go a user's skills are broken down by
+type SkillCategory string
+
+const (
+	SkillConcurrency SkillCategory = "concurrency"
+	SkillWeb         SkillCategory = "web"
+	SkillAlgorithms  SkillCategory = "algorithms"
+	SkillDatabases   SkillCategory = "databases"
+)
+
+// SkillCategories are every skill category, in the order profiles show them
+var SkillCategories = []SkillCategory{SkillConcurrency, SkillWeb, SkillAlgorithms, SkillDatabases}
+
+// SkillProgress is how many of a skill category's classic and package
+// challenges a user completed
+type SkillProgress struct {
+	Category  SkillCategory `json:"category"`
+	Completed int           `json:"completed"`
+	Total     int           `json:"total"`
+}
+
+// ProfileChallenge is a user's status on one classic challenge
+type ProfileChallenge struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Difficulty string    `json:"difficulty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Attempted  bool      `json:"attempted"` // The user has a solution in the challenge's submissions
+	Score      int       `json:"score"`     // Percentage of tests the solution passed on the scoreboard
+	Completed  bool      `json:"completed"`
+	SolvedAt   time.Time `json:"solvedAt,omitzero"` // When first solved, if known
+}
+
+// UserProfile is everything known about one user's practice: their classic
+// challenges, main leaderboard standing, package progress, skills and
+// latest submissions
+type UserProfile struct {
+	Username          string             `json:"username"` // As the scoreboards spell it, when the user is on one
+	Rank              int                `json:"rank"`     // On the main leaderboard, 0 when unranked
+	Achievement       string             `json:"achievement"`
+	CompletedCount    int                `json:"completedCount"`
+	TotalChallenges   int                `json:"totalChallenges"`
+	CurrentStreak     int                `json:"currentStreak"`
+	LongestStreak     int                `json:"longestStreak"`
+	Challenges        []ProfileChallenge `json:"challenges"`        // Every classic challenge, by ID
+	PackageCompleted  int                `json:"packageCompleted"`  // Package challenges completed across all packages
+	Packages          []*PackageProgress `json:"packages"`          // Packages the user has worked on, by name
+	Skills            []SkillProgress    `json:"skills"`            // In SkillCategories order
+	RecentSubmissions []SubmissionRecord `json:"recentSubmissions"` // Latest submits, newest first, without code or output
+}
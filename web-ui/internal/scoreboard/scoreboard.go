@@ -0,0 +1,321 @@
+// Package scoreboard reads and writes the SCOREBOARD.md files of classic and
+// package challenges. Every consumer goes through it, so a table is parsed
+// the same way everywhere.
+package scoreboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the name of a challenge's scoreboard file
+const FileName = "SCOREBOARD.md"
+
+// Format is the layout of a scoreboard table
+type Format int
+
+// Table formats
+const (
+	FormatTests  Format = iota // | Username | Passed Tests | Total Tests |, written by the scoreboard workflows
+	FormatRanked               // | Rank | Username | Solution | Date Submitted |, the older layout
+)
+
+// Table headers and separators as the workflows write them
+const (
+	testsHeader     = "| Username   | Passed Tests | Total Tests |"
+	testsSeparator  = "|------------|--------------|-------------|"
+	rankedHeader    = "| Rank | Username | Solution | Date Submitted |"
+	rankedSeparator = "|------|----------|----------|----------------|"
+)
+
+// dateLayout is how FormatRanked writes submission dates
+const dateLayout = "2006-01-02"
+
+// dateLayouts are the submission date layouts FormatRanked tables use
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", dateLayout}
+
+// ScoreRow is one user's result in a scoreboard
+type ScoreRow struct {
+	Username    string    `json:"username"`
+	Passed      int       `json:"passed"`               // Zero in FormatRanked tables, which have no counts
+	Total       int       `json:"total"`                // Zero in FormatRanked tables
+	SubmittedAt time.Time `json:"submittedAt,omitzero"` // Only FormatRanked tables record it
+	Solution    string    `json:"solution,omitempty"`   // The Solution cell of FormatRanked tables
+}
+
+// Completed reports whether the row passed every test
+func (r ScoreRow) Completed() bool {
+	return r.Total > 0 && r.Passed == r.Total
+}
+
+// Board is a parsed scoreboard file. The text around the table is kept so
+// that writing a board back changes nothing but the table.
+type Board struct {
+	Header string // Everything before the table, usually the title
+	Format Format
+	Rows   []ScoreRow
+	Footer string // Everything after the table
+
+	table bool // The file had a table; a board without one writes none until it has rows
+}
+
+// New creates an empty board with the title the scoreboard workflows use
+func New(name string) *Board {
+	return &Board{
+		Header: "# Scoreboard for " + name + "\n",
+		Format: FormatTests,
+		table:  true,
+	}
+}
+
+// Load reads and parses a scoreboard file
+func Load(path string) (*Board, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content), nil
+}
+
+// Parse parses a scoreboard file. The table is the first run of lines
+// starting with "|" whose first line has a Username column; its columns
+// are found by name. Rows without a username or with counts that are not
+// numbers are skipped.
+func Parse(content []byte) *Board {
+	lines := strings.SplitAfter(string(content), "\n")
+	board := &Board{}
+
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "|") && columnIndex(cells(line), "username") >= 0 {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		board.Header = string(content)
+		return board
+	}
+
+	end := start
+	for end < len(lines) && strings.HasPrefix(lines[end], "|") {
+		end++
+	}
+	board.Header = strings.Join(lines[:start], "")
+	board.Footer = strings.Join(lines[end:], "")
+	board.table = true
+
+	header := cells(lines[start])
+	columns := map[string]int{}
+	for _, name := range []string{"rank", "username", "passed tests", "total tests", "solution", "date submitted"} {
+		columns[name] = columnIndex(header, name)
+	}
+	if columns["rank"] >= 0 {
+		board.Format = FormatRanked
+	}
+
+	for _, line := range lines[start+1 : end] {
+		row, ok := parseRow(cells(line), columns, board.Format)
+		if ok {
+			board.Rows = append(board.Rows, row)
+		}
+	}
+	return board
+}
+
+// parseRow reads a data row, reporting false for separators and rows that
+// do not hold a result
+func parseRow(values []string, columns map[string]int, format Format) (ScoreRow, bool) {
+	cell := func(name string) string {
+		if i := columns[name]; i >= 0 && i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+
+	row := ScoreRow{Username: cell("username")}
+	if row.Username == "" || strings.Trim(row.Username, "-: ") == "" {
+		return row, false
+	}
+
+	if format == FormatRanked {
+		row.Solution = cell("solution")
+		for _, layout := range dateLayouts {
+			if submittedAt, err := time.Parse(layout, cell("date submitted")); err == nil {
+				row.SubmittedAt = submittedAt
+				break
+			}
+		}
+		return row, true
+	}
+
+	passed, err1 := strconv.Atoi(cell("passed tests"))
+	total, err2 := strconv.Atoi(cell("total tests"))
+	if err1 != nil || err2 != nil {
+		return row, false
+	}
+	row.Passed, row.Total = passed, total
+	return row, true
+}
+
+// cells splits a table line into its trimmed cells
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	values := strings.Split(line, "|")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// columnIndex finds a column by name, ignoring case, or returns -1
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Find returns a user's row. Usernames match exactly, ignoring case as
+// GitHub does.
+func (b *Board) Find(username string) (ScoreRow, bool) {
+	for _, row := range b.Rows {
+		if strings.EqualFold(row.Username, username) {
+			return row, true
+		}
+	}
+	return ScoreRow{}, false
+}
+
+// Set records a user's row, replacing the row they already have. FormatTests
+// rows are kept in the order the workflows sort them: most passed tests
+// first, then by username.
+func (b *Board) Set(row ScoreRow) {
+	b.table = true
+	replaced := false
+	for i := range b.Rows {
+		if strings.EqualFold(b.Rows[i].Username, row.Username) {
+			b.Rows[i] = row
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		b.Rows = append(b.Rows, row)
+	}
+
+	if b.Format == FormatTests {
+		sort.SliceStable(b.Rows, func(i, j int) bool {
+			if b.Rows[i].Passed != b.Rows[j].Passed {
+				return b.Rows[i].Passed > b.Rows[j].Passed
+			}
+			return b.Rows[i].Username < b.Rows[j].Username
+		})
+	}
+}
+
+// Remove removes a user's row, matching the username ignoring case, and
+// reports whether the board had one. FormatRanked rows keep their order, so
+// the users below move up a rank.
+func (b *Board) Remove(username string) bool {
+	for i := range b.Rows {
+		if strings.EqualFold(b.Rows[i].Username, username) {
+			b.Rows = append(b.Rows[:i], b.Rows[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Title returns the text of the board's first heading
+func (b *Board) Title() string {
+	for _, line := range strings.Split(b.Header, "\n") {
+		if strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+	}
+	return ""
+}
+
+// Write writes the board in its format. The output always ends with a
+// newline.
+func (b *Board) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(b.Header)
+	if b.Header != "" && !strings.HasSuffix(b.Header, "\n") {
+		buf.WriteString("\n")
+	}
+
+	if b.table || len(b.Rows) > 0 {
+		if b.Format == FormatRanked {
+			buf.WriteString(rankedHeader + "\n" + rankedSeparator + "\n")
+		} else {
+			buf.WriteString(testsHeader + "\n" + testsSeparator + "\n")
+		}
+		for i, row := range b.Rows {
+			if b.Format == FormatRanked {
+				date := ""
+				if !row.SubmittedAt.IsZero() {
+					date = row.SubmittedAt.Format(dateLayout)
+				}
+				fmt.Fprintf(&buf, "| %d | %s | %s | %s |\n", i+1, row.Username, row.Solution, date)
+			} else {
+				fmt.Fprintf(&buf, "| %s | %d | %d |\n", row.Username, row.Passed, row.Total)
+			}
+		}
+	}
+
+	buf.WriteString(b.Footer)
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteString("\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Save writes the board to path atomically: it is written to a temporary
+// file in the same directory, which then replaces path, so readers never see
+// a partly written scoreboard
+func (b *Board) Save(path string) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // Fails harmlessly once renamed
+
+	if err := b.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Bytes returns the board as written by Write
+func (b *Board) Bytes() []byte {
+	var buf bytes.Buffer
+	b.Write(&buf)
+	return buf.Bytes()
+}
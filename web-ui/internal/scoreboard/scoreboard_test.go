@@ -0,0 +1,215 @@
+package scoreboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// parsed is what the golden JSON files record about a parsed board
+type parsed struct {
+	Title  string     `json:"title"`
+	Format Format     `json:"format"`
+	Rows   []ScoreRow `json:"rows"`
+}
+
+// TestGolden parses every scoreboard in testdata, most of them copies of
+// real SCOREBOARD.md files, and compares the parsed rows and the written
+// board with the golden files
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no scoreboards in testdata: %v", err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".md")
+		t.Run(name, func(t *testing.T) {
+			board, err := Load(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.MarshalIndent(parsed{Title: board.Title(), Format: board.Format, Rows: board.Rows}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join("testdata", "golden", name+".json"), append(got, '\n'))
+			checkGolden(t, filepath.Join("testdata", "golden", name+".md"), board.Bytes())
+		})
+	}
+}
+
+// checkGolden compares output with a golden file, or rewrites it with -update
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// TestRepositoryScoreboardsRoundTrip parses every SCOREBOARD.md in the
+// repository and checks that writing it back changes nothing, so the
+// parser understands every row the workflows have written
+func TestRepositoryScoreboardsRoundTrip(t *testing.T) {
+	root := filepath.Join("..", "..", "..")
+	var files []string
+	for _, pattern := range []string{"challenge-*/" + FileName, "packages/*/*/" + FileName} {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Skip("no scoreboards found; not running inside the repository")
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		board := Parse(content)
+		if got, want := board.Bytes(), normalize(content); !bytes.Equal(normalize(got), want) {
+			t.Errorf("%s changed when written back\ngot:\n%s\nwant:\n%s", file, got, want)
+		}
+		for _, row := range board.Rows {
+			if row.Passed > row.Total {
+				t.Errorf("%s: %s passed %d of %d tests", file, row.Username, row.Passed, row.Total)
+			}
+		}
+	}
+}
+
+// normalize drops trailing whitespace, which Write does not keep, and ends
+// the content with a newline as Write does
+func normalize(content []byte) []byte {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func TestFindMatchesWholeUsernames(t *testing.T) {
+	board, err := Load(filepath.Join("testdata", "lookalikes.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username string
+		found    bool
+		passed   int
+	}{
+		{"bob", true, 3},
+		{"bobby", true, 7},
+		{"BOB", true, 3},
+		{"bo", false, 0},
+		{"robert", true, 0},
+		{"not-a-number", false, 0},
+	}
+	for _, test := range tests {
+		row, found := board.Find(test.username)
+		if found != test.found || row.Passed != test.passed {
+			t.Errorf("Find(%q) = %+v, %v; want passed %d, %v", test.username, row, found, test.passed, test.found)
+		}
+	}
+}
+
+func TestNewBoardWritesWorkflowFormat(t *testing.T) {
+	board := New("challenge-5")
+	board.Rows = append(board.Rows, ScoreRow{Username: "alice", Passed: 4, Total: 4})
+
+	want := "# Scoreboard for challenge-5\n" +
+		"| Username   | Passed Tests | Total Tests |\n" +
+		"|------------|--------------|-------------|\n" +
+		"| alice | 4 | 4 |\n"
+	if got := string(board.Bytes()); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if !board.Rows[0].Completed() {
+		t.Error("4 of 4 passed tests should be completed")
+	}
+}
+
+func TestSetReplacesRowsInWorkflowOrder(t *testing.T) {
+	board := New("challenge-5")
+	board.Set(ScoreRow{Username: "carol", Passed: 2, Total: 4})
+	board.Set(ScoreRow{Username: "bob", Passed: 4, Total: 4})
+	board.Set(ScoreRow{Username: "alice", Passed: 4, Total: 4})
+	board.Set(ScoreRow{Username: "Carol", Passed: 4, Total: 4})
+
+	var got []string
+	for _, row := range board.Rows {
+		got = append(got, fmt.Sprintf("%s %d/%d", row.Username, row.Passed, row.Total))
+	}
+	want := []string{"Carol 4/4", "alice 4/4", "bob 4/4"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("rows = %v; want %v", got, want)
+	}
+}
+
+func TestRemoveKeepsTheOtherRows(t *testing.T) {
+	board := New("challenge-5")
+	board.Set(ScoreRow{Username: "alice", Passed: 4, Total: 4})
+	board.Set(ScoreRow{Username: "bob", Passed: 3, Total: 4})
+	board.Set(ScoreRow{Username: "carol", Passed: 2, Total: 4})
+
+	if !board.Remove("BOB") {
+		t.Error("Remove(BOB) = false; want true")
+	}
+	if board.Remove("bob") {
+		t.Error("Remove(bob) after removing it = true; want false")
+	}
+
+	var got []string
+	for _, row := range board.Rows {
+		got = append(got, row.Username)
+	}
+	if want := []string{"alice", "carol"}; strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("rows = %v; want %v", got, want)
+	}
+}
+
+func TestSaveReplacesTheFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	board := New("challenge-5")
+	board.Set(ScoreRow{Username: "alice", Passed: 4, Total: 4})
+	if err := board.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, board.Bytes()) {
+		t.Errorf("saved:\n%s\nwant:\n%s", content, board.Bytes())
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Save left %d files in the directory; want 1", len(entries))
+	}
+}
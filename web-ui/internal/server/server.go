@@ -23,6 +23,13 @@ type Server struct {
 	executionService  *services.ExecutionService
 	packageService    *services.PackageService
 	aiService         *services.AIService
+	executionQueue    *services.ExecutionQueue
+	submissionStore   services.SubmissionStore
+	attemptService    *services.AttemptService
+	authService       *services.AuthService
+	cohortService     *services.CohortService
+	profileService    *services.ProfileService
+	adminService      *services.AdminService
 }
 
 // NewServer creates a new server instance
@@ -34,6 +41,13 @@ func NewServer(
 	executionService *services.ExecutionService,
 	packageService *services.PackageService,
 	aiService *services.AIService,
+	executionQueue *services.ExecutionQueue,
+	submissionStore services.SubmissionStore,
+	attemptService *services.AttemptService,
+	authService *services.AuthService,
+	cohortService *services.CohortService,
+	profileService *services.ProfileService,
+	adminService *services.AdminService,
 ) *Server {
 	return &Server{
 		content:           content,
@@ -43,11 +57,19 @@ func NewServer(
 		executionService:  executionService,
 		packageService:    packageService,
 		aiService:         aiService,
+		executionQueue:    executionQueue,
+		submissionStore:   submissionStore,
+		attemptService:    attemptService,
+		authService:       authService,
+		cohortService:     cohortService,
+		profileService:    profileService,
+		adminService:      adminService,
 	}
 }
 
-// SetupRoutes configures all HTTP routes
-func (s *Server) SetupRoutes() *http.ServeMux {
+// SetupRoutes configures all HTTP routes behind the auth middleware, which
+// identifies the user of every request
+func (s *Server) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	// Setup static file handling
@@ -61,6 +83,10 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 		s.executionService,
 		s.packageService,
 		s.aiService,
+		s.executionQueue,
+		s.submissionStore,
+		s.attemptService,
+		s.profileService,
 	)
 
 	webHandler := handlers.NewWebHandler(
@@ -69,19 +95,58 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 		s.scoreboardService,
 		s.userService,
 		s.packageService,
+		s.attemptService,
+		s.profileService,
 	)
 
+	authHandler := handlers.NewAuthHandler(s.authService)
+	cohortHandler := handlers.NewCohortHandler(s.cohortService)
+	adminHandler := handlers.NewAdminHandler(s.adminService)
+
+	// Account routes
+	mux.HandleFunc("/api/auth/session", authHandler.GetSession)
+	mux.HandleFunc("/api/auth/register", authHandler.Register)
+	mux.HandleFunc("/api/auth/login", authHandler.Login)
+	mux.HandleFunc("/api/auth/logout", authHandler.Logout)
+	mux.HandleFunc("/auth/", authHandler.HandleExternal)
+
 	// API routes
 	mux.HandleFunc("/api/challenges", apiHandler.GetAllChallenges)
 	mux.HandleFunc("/api/challenges/", apiHandler.GetChallengeByID)
 	mux.HandleFunc("/api/submissions", apiHandler.HandleSubmissions)
+	mux.HandleFunc("/api/submissions/", apiHandler.GetSubmission)
+	mux.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
+		// /api/users/{username}/submissions -> submission history,
+		// /api/users/{username} -> profile
+		if strings.HasSuffix(r.URL.Path, "/submissions") {
+			apiHandler.GetUserSubmissions(w, r)
+		} else {
+			apiHandler.GetUserProfile(w, r)
+		}
+	})
 	mux.HandleFunc("/api/scoreboard/", apiHandler.GetScoreboard)
 	mux.HandleFunc("/api/run", apiHandler.RunCode)
+	mux.HandleFunc("/api/run/stream", apiHandler.StartRunStream)
+	mux.HandleFunc("/api/jobs/", apiHandler.HandleJob)
 	mux.HandleFunc("/api/save-to-filesystem", apiHandler.SaveSubmissionToFilesystem)
 	mux.HandleFunc("/api/refresh-attempts", apiHandler.RefreshUserAttempts)
 	mux.HandleFunc("/api/git-username", apiHandler.GetGitUsername)
 	mux.HandleFunc("/api/main-scoreboard-rank", apiHandler.GetMainScoreboardRank)
 	mux.HandleFunc("/api/main-leaderboard", apiHandler.GetMainLeaderboard)
+	mux.HandleFunc("/api/scoreboard-events", apiHandler.StreamScoreboardUpdates)
+	mux.HandleFunc("/api/recently-solved", apiHandler.GetRecentlySolved)
+	mux.HandleFunc("/api/fastest-climbers", apiHandler.GetFastestClimbers)
+	mux.HandleFunc("/api/global-leaderboard", apiHandler.GetGlobalLeaderboard)
+
+	// Cohort routes
+	mux.HandleFunc("/api/cohorts", cohortHandler.HandleCohorts)
+	mux.HandleFunc("/api/cohorts/", cohortHandler.HandleCohort)
+
+	// Admin console routes, for admins only
+	mux.HandleFunc("/api/admin/", adminHandler.HandleAdmin)
+
+	// Achievement badges, rendered from the scoreboards
+	mux.HandleFunc("/badges/", apiHandler.ServeBadge)
 
 	// Package challenge API routes
 	mux.HandleFunc("/api/packages/", apiHandler.HandlePackageChallenge)
@@ -131,8 +196,20 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 
 	// Web routes
 	mux.HandleFunc("/", webHandler.HomePage)
-	mux.HandleFunc("/challenge/", webHandler.ChallengePage)
+	mux.HandleFunc("/challenge/", func(w http.ResponseWriter, r *http.Request) {
+		// /challenge/1/attempts -> attempt timeline, /challenge/1 -> challenge page
+		if strings.HasSuffix(r.URL.Path, "/attempts") {
+			webHandler.AttemptsPage(w, r)
+		} else {
+			webHandler.ChallengePage(w, r)
+		}
+	})
 	mux.HandleFunc("/interview", webHandler.InterviewPage)
+	mux.HandleFunc("/login", webHandler.LoginPage)
+	mux.HandleFunc("/cohorts", webHandler.CohortsPage)
+	mux.HandleFunc("/cohorts/", webHandler.CohortPage)
+	mux.HandleFunc("/admin", webHandler.AdminPage)
+	mux.HandleFunc("/users/", webHandler.UserProfilePage)
 	mux.HandleFunc("/scoreboard", webHandler.ScoreboardPage)
 	mux.HandleFunc("/scoreboard/", webHandler.ScoreChallengeHandler)
 	mux.HandleFunc("/packages/", func(w http.ResponseWriter, r *http.Request) {
@@ -149,7 +226,7 @@ func (s *Server) SetupRoutes() *http.ServeMux {
 		}
 	})
 
-	return mux
+	return authHandler.Middleware(mux)
 }
 
 // setupStaticFiles configures static file serving
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"web-ui/internal/models"
+)
+
+// Account store errors
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// AccountStore keeps local accounts, the identity provider accounts linked
+// to them and their sessions
+type AccountStore interface {
+	// CreateUser creates an account, or returns ErrUsernameTaken when the
+	// username, ignoring case, has one
+	CreateUser(username, passwordHash string) (*models.User, error)
+	// UserByUsername returns the account with a username, ignoring case, or
+	// ErrUserNotFound
+	UserByUsername(username string) (*models.User, error)
+	// UserByIdentity returns the account an identity provider's account,
+	// named by the provider and its stable subject ID, is linked to, or
+	// ErrUserNotFound
+	UserByIdentity(provider, subject string) (*models.User, error)
+	// HasIdentity reports whether a user has an account of an identity
+	// provider linked
+	HasIdentity(userID int64, provider string) (bool, error)
+	// LinkIdentity links an identity provider's account to a user
+	LinkIdentity(userID int64, provider, subject string) error
+	// RenameUser changes a user's username, or returns ErrUsernameTaken
+	RenameUser(userID int64, username string) error
+	// SetRole changes the site role of the user with a username, ignoring
+	// case, or returns ErrUserNotFound
+	SetRole(username string, role models.Role) error
+	// UsersWithRole returns the users with a site role, by username
+	UsersWithRole(role models.Role) ([]models.User, error)
+	// CreateSession stores a session. Stores keep only a hash of session
+	// IDs, which sign in whoever holds them.
+	CreateSession(session *models.Session) error
+	// Session returns a session with its username, or ErrSessionNotFound.
+	// Expired sessions are returned too; callers check ExpiresAt.
+	Session(id string) (*models.Session, error)
+	// DeleteSession removes a session, if it exists
+	DeleteSession(id string) error
+	Close() error
+}
+
+// NewAccountStore opens the SQLite account database at ACCOUNTS_DB, or next
+// to the workspace caches by default
+func NewAccountStore(workspaces *WorkspaceManager) (AccountStore, error) {
+	path := os.Getenv("ACCOUNTS_DB")
+	if path == "" {
+		path = filepath.Join(workspaces.root, "accounts.db")
+	}
+	return NewSQLiteAccountStore(path)
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"web-ui/internal/models"
+)
+
+// accountMigrations create and upgrade the account schema, see
+// historyMigrations
+var accountMigrations = []string{
+	`CREATE TABLE users (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		username      TEXT    NOT NULL UNIQUE COLLATE NOCASE,
+		password_hash TEXT    NOT NULL,
+		created_at    INTEGER NOT NULL -- Unix milliseconds
+	);
+	CREATE TABLE sessions (
+		id         TEXT    PRIMARY KEY,
+		user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+	CREATE INDEX sessions_user ON sessions (user_id);
+	CREATE TABLE secrets (
+		name  TEXT PRIMARY KEY,
+		value BLOB NOT NULL
+	);`,
+	// Accounts of identity providers; their users have no password
+	`CREATE TABLE identities (
+		provider   TEXT    NOT NULL,
+		subject    TEXT    NOT NULL,
+		user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (provider, subject)
+	);
+	CREATE INDEX identities_user ON identities (user_id);`,
+	// Site roles, such as admin
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT '';`,
+	// Sessions are kept by the hash of their ID, and the signing secret
+	// moved out of the database; the sessions it signed end
+	`DROP TABLE secrets;
+	DELETE FROM sessions;
+	ALTER TABLE sessions RENAME COLUMN id TO id_hash;`,
+}
+
+// SQLiteAccountStore is an AccountStore in an embedded SQLite database
+type SQLiteAccountStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteAccountStore opens the database at path, creating it and its
+// directory if needed, and brings its schema up to date
+func NewSQLiteAccountStore(path string) (*SQLiteAccountStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create account directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	if err := migrateSQLite(db, accountMigrations); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+
+	// Sessions that expired are of no use; clear them out on startup
+	if _, err := db.Exec("DELETE FROM sessions WHERE expires_at <= ?", time.Now().UnixMilli()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to remove expired sessions: %v", err)
+	}
+	return &SQLiteAccountStore{db: db}, nil
+}
+
+// CreateUser creates an account, or returns ErrUsernameTaken
+func (s *SQLiteAccountStore) CreateUser(username, passwordHash string) (*models.User, error) {
+	user := &models.User{Username: username, PasswordHash: passwordHash, CreatedAt: time.Now()}
+	result, err := s.db.Exec("INSERT INTO users (username, password_hash, created_at) VALUES (?, ?, ?)",
+		user.Username, user.PasswordHash, user.CreatedAt.UnixMilli())
+	if uniqueViolation(err) {
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+
+	user.ID, err = result.LastInsertId()
+	return user, err
+}
+
+// UserByUsername returns the account with a username, or ErrUserNotFound
+func (s *SQLiteAccountStore) UserByUsername(username string) (*models.User, error) {
+	return scanUser(s.db.QueryRow("SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?", username))
+}
+
+// UserByIdentity returns the account an identity provider's account is
+// linked to, or ErrUserNotFound
+func (s *SQLiteAccountStore) UserByIdentity(provider, subject string) (*models.User, error) {
+	return scanUser(s.db.QueryRow(`SELECT u.id, u.username, u.password_hash, u.role, u.created_at
+		FROM identities i JOIN users u ON u.id = i.user_id WHERE i.provider = ? AND i.subject = ?`, provider, subject))
+}
+
+// HasIdentity reports whether a user has an account of an identity
+// provider linked
+func (s *SQLiteAccountStore) HasIdentity(userID int64, provider string) (bool, error) {
+	var linked bool
+	err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM identities WHERE user_id = ? AND provider = ?)", userID, provider).Scan(&linked)
+	return linked, err
+}
+
+// LinkIdentity links an identity provider's account to a user
+func (s *SQLiteAccountStore) LinkIdentity(userID int64, provider, subject string) error {
+	_, err := s.db.Exec("INSERT INTO identities (provider, subject, user_id, created_at) VALUES (?, ?, ?, ?)",
+		provider, subject, userID, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to link %s identity: %v", provider, err)
+	}
+	return nil
+}
+
+// RenameUser changes a user's username, or returns ErrUsernameTaken
+func (s *SQLiteAccountStore) RenameUser(userID int64, username string) error {
+	_, err := s.db.Exec("UPDATE users SET username = ? WHERE id = ?", username, userID)
+	if uniqueViolation(err) {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename user: %v", err)
+	}
+	return nil
+}
+
+// SetRole changes the site role of the user with a username, or returns
+// ErrUserNotFound
+func (s *SQLiteAccountStore) SetRole(username string, role models.Role) error {
+	result, err := s.db.Exec("UPDATE users SET role = ? WHERE username = ?", role, username)
+	return expectAffected(result, err, ErrUserNotFound)
+}
+
+// UsersWithRole returns the users with a site role, by username
+func (s *SQLiteAccountStore) UsersWithRole(role models.Role) ([]models.User, error) {
+	rows, err := s.db.Query("SELECT id, username, password_hash, role, created_at FROM users WHERE role = ? ORDER BY username", role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// CreateSession stores a session
+func (s *SQLiteAccountStore) CreateSession(session *models.Session) error {
+	_, err := s.db.Exec("INSERT INTO sessions (id_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		sessionHash(session.ID), session.UserID, session.CreatedAt.UnixMilli(), session.ExpiresAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+// Session returns a session with its username, or ErrSessionNotFound
+func (s *SQLiteAccountStore) Session(id string) (*models.Session, error) {
+	session := models.Session{ID: id}
+	var createdAt, expiresAt int64
+	err := s.db.QueryRow(`SELECT s.user_id, u.username, u.role, s.created_at, s.expires_at
+		FROM sessions s JOIN users u ON u.id = s.user_id WHERE s.id_hash = ?`, sessionHash(id)).
+		Scan(&session.UserID, &session.Username, &session.Role, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	session.CreatedAt = time.UnixMilli(createdAt)
+	session.ExpiresAt = time.UnixMilli(expiresAt)
+	return &session, nil
+}
+
+// DeleteSession removes a session, if it exists
+func (s *SQLiteAccountStore) DeleteSession(id string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE id_hash = ?", sessionHash(id))
+	return err
+}
+
+// sessionHash is what the database keeps of a session ID, so that a copy
+// of it holds no IDs that sign in
+func sessionHash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// scanUser reads a user row, of a QueryRow or of Query's rows, or returns
+// ErrUserNotFound
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
+	var user models.User
+	var createdAt int64
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.CreatedAt = time.UnixMilli(createdAt)
+	return &user, nil
+}
+
+// uniqueViolation reports whether an error is a UNIQUE or PRIMARY KEY
+// constraint failure
+func uniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// Close closes the database
+func (s *SQLiteAccountStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"web-ui/internal/models"
+)
+
+// MaxAdminReasonLength is the longest reason, in characters, an admin may
+// give for hiding or disqualifying an entry
+const MaxAdminReasonLength = 500
+
+// Audit log page sizes
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 200
+)
+
+// MaxKeptRejudges is how many of the latest rejudges are kept for the
+// console to show
+const MaxKeptRejudges = 20
+
+// Admin errors
+var (
+	ErrEntryNotFound          = errors.New("the user has no row on the challenge's scoreboard")
+	ErrEntryHidden            = errors.New("the entry is already hidden")
+	ErrHiddenEntryNotFound    = errors.New("the entry is not hidden")
+	ErrInvalidEntry           = fmt.Errorf("an entry needs a valid username, an existing classic challenge, and a reason of at most %d characters", MaxAdminReasonLength)
+	ErrInvalidChallengeStatus = fmt.Errorf("a status must be %q or %q, for an existing classic challenge, or an existing package and package challenge", models.ChallengeAvailable, models.ChallengeComingSoon)
+	ErrOwnRole                = errors.New("admins cannot change their own role")
+	ErrInvalidRejudge         = errors.New("a rejudge needs either an existing classic challenge or a valid username")
+	ErrRejudgeRunning         = errors.New("a rejudge is already running, try again once it finishes")
+	ErrRejudgeNotRunning      = errors.New("the rejudge is not running")
+)
+
+// AdminStore keeps what admins change outside the repository: hidden
+// scoreboard entries, challenge statuses and the audit log of every admin
+// action
+type AdminStore interface {
+	// HideEntry stores a hidden entry, or returns ErrEntryHidden when the
+	// user, ignoring case, has one for the challenge
+	HideEntry(entry models.HiddenEntry) error
+	// UnhideEntry removes a hidden entry, or returns ErrHiddenEntryNotFound
+	UnhideEntry(username string, challengeID int) error
+	// HiddenEntries returns the hidden entries, by username, then challenge
+	HiddenEntries() ([]models.HiddenEntry, error)
+	// SetChallengeStatus stores a challenge's status, replacing the one set
+	// before
+	SetChallengeStatus(override models.ChallengeStatusOverride) error
+	// ClearChallengeStatus removes the status set on a challenge, if any
+	ClearChallengeStatus(challengeID int, packageName, packageChallenge string) error
+	// ChallengeStatuses returns the statuses set on challenges
+	ChallengeStatuses() ([]models.ChallengeStatusOverride, error)
+	// AddAuditEntry appends an entry to the audit log and sets its ID
+	AddAuditEntry(entry *models.AuditEntry) error
+	// AuditLog returns a page of the audit log, newest first
+	AuditLog(limit, offset int) (*models.AuditPage, error)
+	Close() error
+}
+
+// NewAdminStore opens the SQLite admin database at ADMIN_DB, or next to
+// the workspace caches by default
+func NewAdminStore(workspaces *WorkspaceManager) (AdminStore, error) {
+	path := os.Getenv("ADMIN_DB")
+	if path == "" {
+		path = filepath.Join(workspaces.root, "admin.db")
+	}
+	return NewSQLiteAdminStore(path)
+}
+
+// AdminService carries out the admin console's actions: granting the admin
+// role, hiding and disqualifying scoreboard entries, closing challenges and
+// rejudging submissions. Every action is recorded in the audit log. Callers
+// check that the actor is an admin.
+type AdminService struct {
+	store             AdminStore
+	authService       *AuthService
+	challengeService  *ChallengeService
+	packageService    *PackageService
+	userService       *UserService
+	scoreboardService *ScoreboardService
+	executionQueue    *ExecutionQueue
+
+	mu            sync.Mutex
+	rejudges      []*models.Rejudge // The latest rejudges, oldest first
+	nextRejudge   int64
+	cancelRejudge context.CancelFunc // Stops the running rejudge, if any
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(store AdminStore, authService *AuthService, challengeService *ChallengeService, packageService *PackageService, userService *UserService, scoreboardService *ScoreboardService, executionQueue *ExecutionQueue) *AdminService {
+	return &AdminService{
+		store:             store,
+		authService:       authService,
+		challengeService:  challengeService,
+		packageService:    packageService,
+		userService:       userService,
+		scoreboardService: scoreboardService,
+		executionQueue:    executionQueue,
+		nextRejudge:       1,
+	}
+}
+
+// Load applies the hidden entries and challenge statuses admins set before
+// the server started. The challenges and scoreboards must be loaded first.
+func (a *AdminService) Load() error {
+	if err := a.applyHidden(); err != nil {
+		return err
+	}
+	overrides, err := a.store.ChallengeStatuses()
+	if err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		a.applyChallengeStatus(override)
+	}
+	return nil
+}
+
+// Admins returns the accounts with the admin role
+func (a *AdminService) Admins() ([]models.User, error) {
+	return a.authService.Admins()
+}
+
+// ConfiguredAdmins returns the usernames ADMIN_USERS makes admins, whose
+// role the console cannot change
+func (a *AdminService) ConfiguredAdmins() []string {
+	return a.authService.ConfiguredAdmins()
+}
+
+// SetRole changes the site role of another user's account
+func (a *AdminService) SetRole(actor, username string, role models.Role) error {
+	if strings.EqualFold(actor, username) {
+		return ErrOwnRole
+	}
+	if err := a.authService.SetRole(username, role); err != nil {
+		return err
+	}
+	detail := "no role"
+	if role != "" {
+		detail = string(role)
+	}
+	return a.audit(actor, models.AuditSetRole, username, detail)
+}
+
+// HiddenEntries returns the hidden scoreboard entries
+func (a *AdminService) HiddenEntries() ([]models.HiddenEntry, error) {
+	return a.store.HiddenEntries()
+}
+
+// Hide hides a user's entry for a challenge, or every entry of the user
+// when the challenge ID is zero, from the scoreboards and leaderboards
+func (a *AdminService) Hide(actor string, entry models.HiddenEntry) (*models.HiddenEntry, error) {
+	entry.Username, entry.Reason = strings.TrimSpace(entry.Username), strings.TrimSpace(entry.Reason)
+	if err := a.validateEntry(entry.Username, entry.ChallengeID, entry.Reason, true); err != nil {
+		return nil, err
+	}
+
+	entry.HiddenBy = actor
+	entry.HiddenAt = time.Now()
+	if err := a.store.HideEntry(entry); err != nil {
+		return nil, err
+	}
+	if err := a.applyHidden(); err != nil {
+		return nil, err
+	}
+	return &entry, a.audit(actor, models.AuditHide, entryTarget(entry.Username, entry.ChallengeID), entry.Reason)
+}
+
+// Unhide shows a hidden entry again
+func (a *AdminService) Unhide(actor, username string, challengeID int) error {
+	if err := a.store.UnhideEntry(username, challengeID); err != nil {
+		return err
+	}
+	if err := a.applyHidden(); err != nil {
+		return err
+	}
+	return a.audit(actor, models.AuditUnhide, entryTarget(username, challengeID), "")
+}
+
+// Disqualify removes a user's row from a challenge's SCOREBOARD.md. Unlike
+// a hidden entry it does not come back, unless the user submits again.
+func (a *AdminService) Disqualify(actor, username string, challengeID int, reason string) error {
+	username, reason = strings.TrimSpace(username), strings.TrimSpace(reason)
+	if err := a.validateEntry(username, challengeID, reason, false); err != nil {
+		return err
+	}
+	challenge, _ := a.challengeService.GetChallenge(challengeID)
+	if err := a.scoreboardService.RemoveEntry(challenge, username); err != nil {
+		return err
+	}
+	return a.audit(actor, models.AuditDisqualify, entryTarget(username, challengeID), reason)
+}
+
+// validateEntry checks the user, challenge and reason of an entry to hide
+// or disqualify; only hidden entries may name no challenge
+func (a *AdminService) validateEntry(username string, challengeID int, reason string, allChallenges bool) error {
+	if !ValidUsername(username) || utf8.RuneCountInString(reason) > MaxAdminReasonLength {
+		return ErrInvalidEntry
+	}
+	if challengeID == 0 && allChallenges {
+		return nil
+	}
+	if _, exists := a.challengeService.GetChallenge(challengeID); !exists {
+		return ErrInvalidEntry
+	}
+	return nil
+}
+
+// applyHidden hides the stored hidden entries from the scoreboards
+func (a *AdminService) applyHidden() error {
+	hidden, err := a.store.HiddenEntries()
+	if err != nil {
+		return err
+	}
+	a.scoreboardService.SetHidden(hidden)
+	return nil
+}
+
+// ChallengeStatuses returns the statuses admins set on challenges
+func (a *AdminService) ChallengeStatuses() ([]models.ChallengeStatusOverride, error) {
+	return a.store.ChallengeStatuses()
+}
+
+// SetChallengeStatus sets the status of a classic or package challenge.
+// Challenges that are coming soon are listed, but cannot be run or
+// submitted; making one available again restores its status on disk.
+func (a *AdminService) SetChallengeStatus(actor string, override models.ChallengeStatusOverride) (*models.ChallengeStatusOverride, error) {
+	if override.Status != models.ChallengeAvailable && override.Status != models.ChallengeComingSoon {
+		return nil, ErrInvalidChallengeStatus
+	}
+	classic := override.ChallengeID != 0
+	if classic == (override.PackageName != "" || override.PackageChallenge != "") {
+		return nil, ErrInvalidChallengeStatus
+	}
+	if classic {
+		if _, exists := a.challengeService.GetChallenge(override.ChallengeID); !exists {
+			return nil, ErrInvalidChallengeStatus
+		}
+	} else {
+		if _, err := a.packageService.GetPackage(override.PackageName); err != nil {
+			return nil, ErrInvalidChallengeStatus
+		}
+		challenges, err := a.packageService.GetPackageChallenges(override.PackageName)
+		if err != nil || challenges[override.PackageChallenge] == nil {
+			return nil, ErrInvalidChallengeStatus
+		}
+	}
+
+	override.SetBy = actor
+	override.SetAt = time.Now()
+	var err error
+	if override.Status == models.ChallengeAvailable {
+		err = a.store.ClearChallengeStatus(override.ChallengeID, override.PackageName, override.PackageChallenge)
+	} else {
+		err = a.store.SetChallengeStatus(override)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.applyChallengeStatus(override)
+	return &override, a.audit(actor, models.AuditChallengeStatus, challengeTarget(override), override.Status)
+}
+
+// applyChallengeStatus gives a challenge the status admins set. Available
+// is the status challenges have when none is set.
+func (a *AdminService) applyChallengeStatus(override models.ChallengeStatusOverride) {
+	status := override.Status
+	if status == models.ChallengeAvailable {
+		status = ""
+	}
+	if override.ChallengeID != 0 {
+		a.challengeService.SetStatus(override.ChallengeID, status)
+	} else {
+		a.packageService.SetChallengeStatus(override.PackageName, override.PackageChallenge, status)
+	}
+}
+
+// rejudgeTarget is a saved solution a rejudge runs
+type rejudgeTarget struct {
+	username  string
+	challenge *models.Challenge
+}
+
+// StartRejudge runs, in the background, the saved solutions of a classic
+// challenge, or of a user, again, and records their new test counts on the
+// scoreboards as the rejudge-challenge workflow does. Exactly one of the
+// challenge ID and the username is set. One rejudge runs at a time, until
+// it finishes or CancelRejudge stops it.
+func (a *AdminService) StartRejudge(actor string, challengeID int, username string) (*models.Rejudge, error) {
+	username = strings.TrimSpace(username)
+	if (challengeID != 0) == (username != "") {
+		return nil, ErrInvalidRejudge
+	}
+
+	var targets []rejudgeTarget
+	if challengeID != 0 {
+		challenge, exists := a.challengeService.GetChallenge(challengeID)
+		if !exists {
+			return nil, ErrInvalidRejudge
+		}
+		entries, _ := os.ReadDir(filepath.Join(challenge.Dir, "submissions"))
+		for _, entry := range entries {
+			if entry.IsDir() && ValidUsername(entry.Name()) {
+				targets = append(targets, rejudgeTarget{username: entry.Name(), challenge: challenge})
+			}
+		}
+	} else {
+		if !ValidUsername(username) {
+			return nil, ErrInvalidRejudge
+		}
+		challenges := a.challengeService.GetChallenges()
+		ids := make([]int, 0, len(challenges))
+		for id := range challenges {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			if a.userService.GetExistingSolutionFiles(username, id) != nil {
+				targets = append(targets, rejudgeTarget{username: username, challenge: challenges[id]})
+			}
+		}
+	}
+
+	a.mu.Lock()
+	for _, rejudge := range a.rejudges {
+		if rejudge.State == models.RejudgeRunning {
+			a.mu.Unlock()
+			return nil, ErrRejudgeRunning
+		}
+	}
+	rejudge := &models.Rejudge{
+		ID:          a.nextRejudge,
+		ChallengeID: challengeID,
+		Username:    username,
+		State:       models.RejudgeRunning,
+		Total:       len(targets),
+		Results:     []models.RejudgeResult{},
+		StartedBy:   actor,
+		StartedAt:   time.Now(),
+	}
+	a.nextRejudge++
+	a.rejudges = append(a.rejudges, rejudge)
+	if len(a.rejudges) > MaxKeptRejudges {
+		a.rejudges = a.rejudges[len(a.rejudges)-MaxKeptRejudges:]
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancelRejudge = cancel
+	started := copyRejudge(rejudge)
+	a.mu.Unlock()
+
+	target := rejudgeName(rejudge)
+	if err := a.audit(actor, models.AuditRejudge, target, fmt.Sprintf("started, %d submissions", len(targets))); err != nil {
+		log.Printf("Failed to audit rejudge of %s: %v", target, err)
+	}
+	go a.rejudge(ctx, rejudge, target, targets)
+	return &started, nil
+}
+
+// CancelRejudge stops a running rejudge: the solution it is running is
+// killed and the rest are skipped. The results recorded so far are kept.
+func (a *AdminService) CancelRejudge(actor string, id int64) error {
+	a.mu.Lock()
+	var rejudge *models.Rejudge
+	for _, r := range a.rejudges {
+		if r.ID == id && r.State == models.RejudgeRunning {
+			rejudge = r
+		}
+	}
+	if rejudge == nil {
+		a.mu.Unlock()
+		return ErrRejudgeNotRunning
+	}
+	a.cancelRejudge()
+	a.mu.Unlock()
+
+	return a.audit(actor, models.AuditRejudge, rejudgeName(rejudge), "cancelled")
+}
+
+// rejudgeName names what a rejudge runs in the audit log
+func rejudgeName(rejudge *models.Rejudge) string {
+	if rejudge.ChallengeID != 0 {
+		return fmt.Sprintf("challenge-%d", rejudge.ChallengeID)
+	}
+	return rejudge.Username
+}
+
+// rejudgeRetryInterval is how long a rejudge waits to submit again when the
+// execution queue is full or the solution's user has no free runs
+const rejudgeRetryInterval = 2 * time.Second
+
+// runRejudged runs a saved solution through the execution queue as a
+// submission of its user would, so that it counts against the queue's
+// limits. It returns false when ctx is cancelled first.
+func (a *AdminService) runRejudged(ctx context.Context, t rejudgeTarget, files models.SourceFiles) (ExecutionResult, bool) {
+	for {
+		job, err := a.executionQueue.Submit(t.username, ModeSubmit, files, t.challenge)
+		if err == nil {
+			result := job.Wait(ctx)
+			return result, ctx.Err() == nil
+		}
+		if !errors.Is(err, ErrQueueFull) && !errors.Is(err, ErrUserLimit) {
+			return ExecutionResult{Output: err.Error()}, true
+		}
+		select {
+		case <-ctx.Done():
+			return ExecutionResult{}, false
+		case <-time.After(rejudgeRetryInterval):
+		}
+	}
+}
+
+// rejudge runs a rejudge's solutions one after another and records each
+// result as it comes. As with submissions, only passing runs are recorded
+// on the scoreboards; a failing run, e.g. one whose vet, race or lint stage
+// failed, keeps the recorded result and is reported to the console.
+func (a *AdminService) rejudge(ctx context.Context, rejudge *models.Rejudge, target string, targets []rejudgeTarget) {
+	passing := 0
+	for _, t := range targets {
+		result := models.RejudgeResult{Username: t.username, ChallengeID: t.challenge.ID}
+		files := a.userService.GetExistingSolutionFiles(t.username, t.challenge.ID)
+		if files == nil {
+			result.Error = "no solution files"
+		} else if run, ok := a.runRejudged(ctx, t, files); !ok {
+			break
+		} else if run.Summary.Total == 0 {
+			// The tests did not run, e.g. the solution no longer compiles or
+			// the workspace could not be set up
+			result.Error = "no tests ran: " + firstLine(run.Output)
+		} else {
+			result.Passed = run.Passed
+			result.TestsPassed = run.Summary.Passed
+			result.TestsTotal = run.Summary.Total
+			if run.Passed {
+				submission := models.Submission{
+					Username:    t.username,
+					ChallengeID: t.challenge.ID,
+					SubmittedAt: time.Now(),
+					Passed:      run.Passed,
+					TestsPassed: run.Summary.Passed,
+					TestsTotal:  run.Summary.Total,
+					Coverage:    run.Coverage,
+				}
+				if _, err := a.scoreboardService.RecordSubmission(t.challenge, submission); err != nil {
+					result.Error = err.Error()
+				}
+			}
+		}
+		if result.Passed && result.Error == "" {
+			passing++
+		}
+
+		a.mu.Lock()
+		rejudge.Results = append(rejudge.Results, result)
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	rejudge.State = models.RejudgeFinished
+	if ctx.Err() != nil {
+		rejudge.State = models.RejudgeCancelled
+	}
+	rejudge.FinishedAt = time.Now()
+	a.cancelRejudge()
+	a.cancelRejudge = nil
+	a.mu.Unlock()
+	if rejudge.State == models.RejudgeCancelled {
+		return
+	}
+
+	detail := fmt.Sprintf("finished, %d of %d submissions passing", passing, len(targets))
+	if err := a.audit(rejudge.StartedBy, models.AuditRejudge, target, detail); err != nil {
+		log.Printf("Failed to audit rejudge of %s: %v", target, err)
+	}
+}
+
+// firstLine returns the first non-empty line of a run's output
+func firstLine(output string) string {
+	for line := range strings.Lines(output) {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return "no output"
+}
+
+// Rejudges returns the latest rejudges, newest first
+func (a *AdminService) Rejudges() []models.Rejudge {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rejudges := make([]models.Rejudge, 0, len(a.rejudges))
+	for i := len(a.rejudges) - 1; i >= 0; i-- {
+		rejudges = append(rejudges, copyRejudge(a.rejudges[i]))
+	}
+	return rejudges
+}
+
+// copyRejudge returns a copy of a rejudge that its run does not change;
+// a.mu must be held
+func copyRejudge(rejudge *models.Rejudge) models.Rejudge {
+	copied := *rejudge
+	copied.Results = append([]models.RejudgeResult{}, rejudge.Results...)
+	return copied
+}
+
+// AuditLog returns a page of the audit log, newest first. The limit is
+// DefaultAuditLimit when zero, and at most MaxAuditLimit.
+func (a *AdminService) AuditLog(limit, offset int) (*models.AuditPage, error) {
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	return a.store.AuditLog(min(limit, MaxAuditLimit), max(offset, 0))
+}
+
+// audit appends an admin action to the audit log
+func (a *AdminService) audit(actor string, action models.AuditAction, target, detail string) error {
+	return a.store.AddAuditEntry(&models.AuditEntry{
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	})
+}
+
+// entryTarget names a scoreboard entry in the audit log
+func entryTarget(username string, challengeID int) string {
+	if challengeID == 0 {
+		return username
+	}
+	return fmt.Sprintf("%s on challenge-%d", username, challengeID)
+}
+
+// challengeTarget names a classic or package challenge in the audit log
+func challengeTarget(override models.ChallengeStatusOverride) string {
+	if override.ChallengeID != 0 {
+		return fmt.Sprintf("challenge-%d", override.ChallengeID)
+	}
+	return override.PackageName + "/" + override.PackageChallenge
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// adminMigrations create and upgrade the admin schema, see
+// historyMigrations
+var adminMigrations = []string{
+	`CREATE TABLE hidden_entries (
+		username     TEXT    NOT NULL COLLATE NOCASE,
+		challenge_id INTEGER NOT NULL, -- 0 hides every entry of the user
+		reason       TEXT    NOT NULL DEFAULT '',
+		hidden_by    TEXT    NOT NULL,
+		hidden_at    INTEGER NOT NULL, -- Unix milliseconds
+		PRIMARY KEY (username, challenge_id)
+	);
+	CREATE TABLE challenge_statuses (
+		challenge_id      INTEGER NOT NULL DEFAULT 0,
+		package_name      TEXT    NOT NULL DEFAULT '',
+		package_challenge TEXT    NOT NULL DEFAULT '',
+		status            TEXT    NOT NULL,
+		set_by            TEXT    NOT NULL,
+		set_at            INTEGER NOT NULL,
+		PRIMARY KEY (challenge_id, package_name, package_challenge)
+	);
+	CREATE TABLE audit_log (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor      TEXT    NOT NULL,
+		action     TEXT    NOT NULL,
+		target     TEXT    NOT NULL,
+		detail     TEXT    NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);`,
+}
+
+// SQLiteAdminStore is an AdminStore in an embedded SQLite database
+type SQLiteAdminStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteAdminStore opens the database at path, creating it and its
+// directory if needed, and brings its schema up to date
+func NewSQLiteAdminStore(path string) (*SQLiteAdminStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create admin directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	if err := migrateSQLite(db, adminMigrations); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+	return &SQLiteAdminStore{db: db}, nil
+}
+
+// HideEntry stores a hidden entry, or returns ErrEntryHidden
+func (s *SQLiteAdminStore) HideEntry(entry models.HiddenEntry) error {
+	_, err := s.db.Exec("INSERT INTO hidden_entries (username, challenge_id, reason, hidden_by, hidden_at) VALUES (?, ?, ?, ?, ?)",
+		entry.Username, entry.ChallengeID, entry.Reason, entry.HiddenBy, entry.HiddenAt.UnixMilli())
+	if uniqueViolation(err) {
+		return ErrEntryHidden
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hide entry: %v", err)
+	}
+	return nil
+}
+
+// UnhideEntry removes a hidden entry, or returns ErrHiddenEntryNotFound
+func (s *SQLiteAdminStore) UnhideEntry(username string, challengeID int) error {
+	result, err := s.db.Exec("DELETE FROM hidden_entries WHERE username = ? AND challenge_id = ?", username, challengeID)
+	return expectAffected(result, err, ErrHiddenEntryNotFound)
+}
+
+// HiddenEntries returns the hidden entries, by username, then challenge
+func (s *SQLiteAdminStore) HiddenEntries() ([]models.HiddenEntry, error) {
+	rows, err := s.db.Query(`SELECT username, challenge_id, reason, hidden_by, hidden_at FROM hidden_entries
+		ORDER BY username, challenge_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.HiddenEntry{}
+	for rows.Next() {
+		var entry models.HiddenEntry
+		var hiddenAt int64
+		if err := rows.Scan(&entry.Username, &entry.ChallengeID, &entry.Reason, &entry.HiddenBy, &hiddenAt); err != nil {
+			return nil, err
+		}
+		entry.HiddenAt = time.UnixMilli(hiddenAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SetChallengeStatus stores a challenge's status, replacing the one set
+// before
+func (s *SQLiteAdminStore) SetChallengeStatus(override models.ChallengeStatusOverride) error {
+	_, err := s.db.Exec(`INSERT INTO challenge_statuses (challenge_id, package_name, package_challenge, status, set_by, set_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (challenge_id, package_name, package_challenge)
+		DO UPDATE SET status = excluded.status, set_by = excluded.set_by, set_at = excluded.set_at`,
+		override.ChallengeID, override.PackageName, override.PackageChallenge, override.Status,
+		override.SetBy, override.SetAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to set challenge status: %v", err)
+	}
+	return nil
+}
+
+// ClearChallengeStatus removes the status set on a challenge, if any
+func (s *SQLiteAdminStore) ClearChallengeStatus(challengeID int, packageName, packageChallenge string) error {
+	_, err := s.db.Exec("DELETE FROM challenge_statuses WHERE challenge_id = ? AND package_name = ? AND package_challenge = ?",
+		challengeID, packageName, packageChallenge)
+	return err
+}
+
+// ChallengeStatuses returns the statuses set on challenges, classic
+// challenges first
+func (s *SQLiteAdminStore) ChallengeStatuses() ([]models.ChallengeStatusOverride, error) {
+	rows, err := s.db.Query(`SELECT challenge_id, package_name, package_challenge, status, set_by, set_at
+		FROM challenge_statuses ORDER BY package_name, challenge_id, package_challenge`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []models.ChallengeStatusOverride{}
+	for rows.Next() {
+		var override models.ChallengeStatusOverride
+		var setAt int64
+		if err := rows.Scan(&override.ChallengeID, &override.PackageName, &override.PackageChallenge,
+			&override.Status, &override.SetBy, &setAt); err != nil {
+			return nil, err
+		}
+		override.SetAt = time.UnixMilli(setAt)
+		overrides = append(overrides, override)
+	}
+	return overrides, rows.Err()
+}
+
+// AddAuditEntry appends an entry to the audit log and sets its ID
+func (s *SQLiteAdminStore) AddAuditEntry(entry *models.AuditEntry) error {
+	result, err := s.db.Exec("INSERT INTO audit_log (actor, action, target, detail, created_at) VALUES (?, ?, ?, ?, ?)",
+		entry.Actor, entry.Action, entry.Target, entry.Detail, entry.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %v", err)
+	}
+	entry.ID, err = result.LastInsertId()
+	return err
+}
+
+// AuditLog returns a page of the audit log, newest first
+func (s *SQLiteAdminStore) AuditLog(limit, offset int) (*models.AuditPage, error) {
+	page := &models.AuditPage{Entries: []models.AuditEntry{}}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_log").Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT id, actor, action, target, detail, created_at FROM audit_log
+		ORDER BY id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		var createdAt int64
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Target, &entry.Detail, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt)
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteAdminStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+)
+
+// newTestAdminService returns an admin service over a fresh admin store and
+// a queue without workers, with challenge 1 and alice's saved solution to
+// it in a temporary directory
+func newTestAdminService(t *testing.T) (*AdminService, *ExecutionQueue, *models.Challenge) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	challenge := &models.Challenge{ID: 1, Dir: filepath.Join(dir, "challenge-1")}
+	solution := filepath.Join(challenge.Dir, "submissions", "alice", "solution-template.go")
+	if err := os.MkdirAll(filepath.Dir(solution), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(solution, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewSQLiteAdminStore(filepath.Join(dir, "admin.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	challenges := NewChallengeService()
+	challenges.challenges[challenge.ID] = challenge
+	queue := newTestQueue(DefaultQueueSize, 1)
+	admin := NewAdminService(store, nil, challenges, nil, NewUserService(), NewScoreboardService(), queue)
+	return admin, queue, challenge
+}
+
+// waitForRejudge waits for the latest rejudge to stop running
+func waitForRejudge(t *testing.T, admin *AdminService) models.Rejudge {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if rejudge := admin.Rejudges()[0]; rejudge.State != models.RejudgeRunning {
+			return rejudge
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the rejudge did not stop")
+	return models.Rejudge{}
+}
+
+func TestRejudgeRecordsPassingRuns(t *testing.T) {
+	admin, queue, challenge := newTestAdminService(t)
+
+	tests := []struct {
+		name   string
+		result ExecutionResult
+		row    bool // Whether the run is recorded on the scoreboard
+	}{
+		{"failing stage", ExecutionResult{Passed: false, Summary: models.TestSummary{Passed: 4, Total: 4}}, false},
+		{"failing tests", ExecutionResult{Passed: false, Summary: models.TestSummary{Passed: 3, Failed: 1, Total: 4}}, false},
+		{"no tests ran", ExecutionResult{Output: "build failed\n"}, false},
+		{"passing", ExecutionResult{Passed: true, Summary: models.TestSummary{Passed: 4, Total: 4}}, true},
+	}
+	for _, tt := range tests {
+		if _, err := admin.StartRejudge("root", challenge.ID, ""); err != nil {
+			t.Fatal(err)
+		}
+		job := takeJob(t, queue)
+		if job.User != "alice" || job.mode != ModeSubmit {
+			t.Errorf("%s: queued %s's run in mode %q, want alice's in mode submit", tt.name, job.User, job.mode)
+		}
+		queue.complete(job, tt.result)
+
+		rejudge := waitForRejudge(t, admin)
+		if rejudge.State != models.RejudgeFinished || len(rejudge.Results) != 1 {
+			t.Fatalf("%s: rejudge %+v", tt.name, rejudge)
+		}
+		if result := rejudge.Results[0]; result.Passed != tt.result.Passed {
+			t.Errorf("%s: result %+v, want passed %v", tt.name, result, tt.result.Passed)
+		}
+		recorded := false
+		if board, err := scoreboard.Load(scoreboardPath(challenge)); err == nil {
+			_, recorded = board.Find("alice")
+		} else if !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if recorded != tt.row {
+			t.Errorf("%s: recorded on the scoreboard %v, want %v", tt.name, recorded, tt.row)
+		}
+	}
+}
+
+func TestCancelRejudge(t *testing.T) {
+	admin, queue, challenge := newTestAdminService(t)
+
+	// Queued behind other jobs
+	rejudge, err := admin.StartRejudge("root", challenge.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := takeJob(t, queue)
+	queue.mu.Lock()
+	queue.pending = append(queue.pending, job) // Put back, as if no worker were free
+	queue.mu.Unlock()
+	if err := admin.CancelRejudge("root", rejudge.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitForRejudge(t, admin); got.State != models.RejudgeCancelled || len(got.Results) != 0 {
+		t.Errorf("cancelled rejudge %+v, want cancelled without results", got)
+	}
+	if result := job.Result(); result == nil || result.Killed != KillCancelled {
+		t.Errorf("the rejudge's job was not cancelled: %+v", result)
+	}
+	if err := admin.CancelRejudge("root", rejudge.ID); err != ErrRejudgeNotRunning {
+		t.Errorf("cancelling again: got %v, want ErrRejudgeNotRunning", err)
+	}
+
+	// Waiting for a free slot of alice's
+	queue.mu.Lock()
+	queue.userJobs["alice"] = 1
+	queue.mu.Unlock()
+	rejudge, err = admin.StartRejudge("root", challenge.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := admin.CancelRejudge("root", rejudge.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitForRejudge(t, admin); got.State != models.RejudgeCancelled {
+		t.Errorf("rejudge waiting for a slot: state %s, want cancelled", got.State)
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.pending) != 0 || queue.userJobs["alice"] != 1 {
+		t.Errorf("the rejudge went past alice's limit: %d queued, %d of alice's", len(queue.pending), queue.userJobs["alice"])
+	}
+}
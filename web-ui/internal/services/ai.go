@@ -257,8 +257,9 @@ type OpenAIError struct {
 	Type    string `json:"type"`
 }
 
-// ReviewCode performs AI-powered code review
-func (ai *AIService) ReviewCode(code string, challenge *models.Challenge, context string) (*AICodeReview, error) {
+// ReviewCode performs AI-powered code review. tests and coverage are the
+// results of running the code against the challenge tests and may be nil.
+func (ai *AIService) ReviewCode(code string, challenge *models.Challenge, context string, tests []*models.TestResult, coverage *models.CoverageReport) (*AICodeReview, error) {
 
 	if ai.config.APIKey == "" {
 		return &AICodeReview{
@@ -278,7 +279,7 @@ func (ai *AIService) ReviewCode(code string, challenge *models.Challenge, contex
 		}, nil
 	}
 
-	prompt := ai.buildCodeReviewPrompt(code, challenge, context)
+	prompt := ai.buildCodeReviewPrompt(code, challenge, context, tests, coverage)
 
 	response, err := ai.callLLMWithOpts(prompt, true /* expectJSON */)
 	if err != nil {
@@ -305,6 +306,11 @@ func (ai *AIService) ReviewCode(code string, challenge *models.Challenge, contex
 		return ai.createFallbackReview("Unexpected parsing error", response), nil
 	}
 
+	// Lead with the measured figure so it never depends on the model
+	if coverage != nil {
+		review.TestCoverage = strings.TrimSpace(fmt.Sprintf("%.1f%% of statements covered by the challenge tests. %s", coverage.Percent, review.TestCoverage))
+	}
+
 	return review, nil
 }
 
@@ -342,8 +348,8 @@ func (ai *AIService) GetCodeHint(code string, challenge *models.Challenge, hintL
 }
 
 // BuildCodeReviewPrompt exposes the prompt builder for debugging
-func (ai *AIService) BuildCodeReviewPrompt(code string, challenge *models.Challenge, context string) string {
-	return ai.buildCodeReviewPrompt(code, challenge, context)
+func (ai *AIService) BuildCodeReviewPrompt(code string, challenge *models.Challenge, context string, tests []*models.TestResult, coverage *models.CoverageReport) string {
+	return ai.buildCodeReviewPrompt(code, challenge, context, tests, coverage)
 }
 
 // CallLLMRaw calls the LLM and returns raw response for debugging
@@ -352,7 +358,7 @@ func (ai *AIService) CallLLMRaw(prompt string) (string, error) {
 }
 
 // buildCodeReviewPrompt creates the prompt for code review
-func (ai *AIService) buildCodeReviewPrompt(code string, challenge *models.Challenge, context string) string {
+func (ai *AIService) buildCodeReviewPrompt(code string, challenge *models.Challenge, context string, tests []*models.TestResult, coverage *models.CoverageReport) string {
 	return fmt.Sprintf(`You are a senior Go interviewer. Respond ONLY with a single JSON object. Do NOT include markdown or code fences. All numeric fields must be JSON numbers, not strings.
 
 SCHEMA:
@@ -390,12 +396,60 @@ SCHEMA:
 CHALLENGE: %s
 CONTEXT: %s
 
+TEST RESULTS (from running the hidden challenge tests):
+%s
+
+COVERAGE (statements of the code reached by the hidden challenge tests):
+%s
+
 CODE (Go):
 BEGIN_CODE
 %s
 END_CODE
 
-Focus on: (1) correctness and edge cases, (2) Go idioms, (3) performance, (4) readability, (5) interviewer follow-ups.`, challenge.Title, context, code)
+Focus on: (1) correctness and edge cases, (2) Go idioms, (3) performance, (4) readability, (5) interviewer follow-ups.
+Base "test_coverage" on the TEST RESULTS and COVERAGE above: mention which tests fail and why and which code the tests never reach (dead code, untested branches), and do not guess when results are given.`, challenge.Title, context, formatTestResultsForPrompt(tests), formatCoverageForPrompt(coverage), code)
+}
+
+// formatTestResultsForPrompt renders test results as one line per test,
+// followed by the failure message of each failing test
+func formatTestResultsForPrompt(tests []*models.TestResult) string {
+	if len(tests) == 0 {
+		return "Not available"
+	}
+
+	var b strings.Builder
+	var walk func(tests []*models.TestResult, depth int)
+	walk = func(tests []*models.TestResult, depth int) {
+		for _, test := range tests {
+			fmt.Fprintf(&b, "%s%s %s (%dms)\n", strings.Repeat("  ", depth), strings.ToUpper(test.Status), test.Name, test.ElapsedMs)
+			if test.FailureMessage != "" {
+				for _, line := range strings.Split(test.FailureMessage, "\n") {
+					fmt.Fprintf(&b, "%s  > %s\n", strings.Repeat("  ", depth), line)
+				}
+			}
+			walk(test.Subtests, depth+1)
+		}
+	}
+	walk(tests, 0)
+
+	summary := summarizeTests(tests)
+	fmt.Fprintf(&b, "Summary: %d/%d passed, %d skipped", summary.Passed, summary.Total, summary.Skipped)
+	return b.String()
+}
+
+// formatCoverageForPrompt summarizes coverage as a percentage and the lines
+// the tests never executed
+func formatCoverageForPrompt(coverage *models.CoverageReport) string {
+	if coverage == nil {
+		return "Not available"
+	}
+
+	text := fmt.Sprintf("%.1f%% of statements (%d/%d)", coverage.Percent, coverage.Covered, coverage.Statements)
+	if lines := UncoveredLines(coverage); len(lines) > 0 {
+		text += "\nLines never executed: " + formatLineRanges(lines)
+	}
+	return text
 }
 
 // buildQuestionPrompt creates the prompt for generating interview questions
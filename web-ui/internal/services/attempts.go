@@ -0,0 +1,374 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// maxAttempts bounds how far back the timeline reads the git log
+const maxAttempts = 100
+
+// Errors returned by the attempt service
+var (
+	ErrInvalidUsername = errors.New("invalid username")
+	ErrAttemptNotFound = errors.New("attempt not found")
+)
+
+// usernameRe matches the usernames submission directories may be named
+// after, so that a username can never leave the submissions directory
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// hunkHeaderRe captures the first old and new line numbers of a hunk
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// ValidUsername reports whether a username can name a submission directory
+func ValidUsername(username string) bool {
+	return usernameRe.MatchString(username)
+}
+
+// AttemptService builds the timeline of a user's attempts at a challenge
+// from the git history of their submission directory plus the files on
+// disk, and diffs attempts against each other
+type AttemptService struct {
+	userService *UserService
+	store       SubmissionStore
+}
+
+// NewAttemptService creates an attempt service that looks up the test
+// results of attempts in the submission history
+func NewAttemptService(userService *UserService, store SubmissionStore) *AttemptService {
+	return &AttemptService{
+		userService: userService,
+		store:       store,
+	}
+}
+
+// Attempts returns a user's attempts at a challenge, oldest first. The files
+// on disk are the last attempt when they differ from the last commit.
+func (as *AttemptService) Attempts(username string, challengeID int) ([]models.Attempt, error) {
+	if !ValidUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+
+	dir := as.userService.submissionDir(username, challengeID)
+	attempts, err := gitAttempts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if files := ReadSubmissionFiles(dir); files != nil {
+		last := len(attempts) - 1
+		if last < 0 || FilesHash(attempts[last].Files) != FilesHash(files) {
+			modTime, _ := LatestSubmissionTime(dir)
+			attempts = append(attempts, models.Attempt{
+				ID:      models.AttemptWorking,
+				Message: "Uncommitted changes",
+				Time:    modTime,
+				Files:   files,
+			})
+		}
+	}
+
+	for i := range attempts {
+		attempts[i].Number = i + 1
+		as.matchRun(&attempts[i], username, challengeID)
+	}
+	return attempts, nil
+}
+
+// Diff compares two of a user's attempts at a challenge. Commit IDs may be
+// abbreviated.
+func (as *AttemptService) Diff(username string, challengeID int, fromID, toID string) (*models.AttemptDiff, error) {
+	attempts, err := as.Attempts(username, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	from, ok := findAttempt(attempts, fromID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAttemptNotFound, fromID)
+	}
+	to, ok := findAttempt(attempts, toID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAttemptNotFound, toID)
+	}
+
+	unified, err := diffFiles(from.Files, to.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.AttemptDiff{
+		From:    from,
+		To:      to,
+		Files:   parseUnifiedDiff(unified),
+		Unified: unified,
+		Tests:   []models.TestChange{},
+	}
+	if from.Tested && to.Tested {
+		diff.Tests, err = as.testChanges(from.SubmissionID, to.SubmissionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// matchRun fills in an attempt's test results from the latest run of
+// exactly its files, if the submission history has one
+func (as *AttemptService) matchRun(attempt *models.Attempt, username string, challengeID int) {
+	page, err := as.store.List(SubmissionQuery{
+		Username:    username,
+		ChallengeID: challengeID,
+		CodeHash:    FilesHash(attempt.Files),
+		Limit:       MaxHistoryLimit,
+	})
+	if err != nil {
+		return
+	}
+	for _, record := range page.Submissions {
+		// Benchmark runs do not run the tests
+		if record.Mode == string(ModeBenchmark) {
+			continue
+		}
+		attempt.Tested = true
+		attempt.SubmissionID = record.ID
+		attempt.Passed = record.Passed
+		attempt.TestsPassed = record.TestsPassed
+		attempt.TestsTotal = record.TestsTotal
+		return
+	}
+}
+
+// testChanges lists the tests whose status differs between two runs
+func (as *AttemptService) testChanges(fromID, toID int64) ([]models.TestChange, error) {
+	from, err := as.store.Get(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := as.store.Get(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := make(map[string]string)
+	after := make(map[string]string)
+	testStatuses(from.Tests, before)
+	testStatuses(to.Tests, after)
+
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	changes := []models.TestChange{}
+	for name := range names {
+		if before[name] != after[name] {
+			changes = append(changes, models.TestChange{Name: name, From: before[name], To: after[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// testStatuses records the status of every test and subtest by name
+func testStatuses(tests []*models.TestResult, statuses map[string]string) {
+	for _, test := range tests {
+		statuses[test.Name] = test.Status
+		testStatuses(test.Subtests, statuses)
+	}
+}
+
+// findAttempt finds an attempt by ID or commit hash prefix
+func findAttempt(attempts []models.Attempt, id string) (models.Attempt, bool) {
+	if id == "" {
+		return models.Attempt{}, false
+	}
+	for _, attempt := range attempts {
+		if attempt.ID == id || (len(id) >= 7 && strings.HasPrefix(attempt.ID, id)) {
+			return attempt, true
+		}
+	}
+	return models.Attempt{}, false
+}
+
+// gitAttempts reads the commits that touched a submission directory, oldest
+// first, skipping those that left no Go files in it. Outside a git checkout
+// there are none.
+func gitAttempts(dir string) ([]models.Attempt, error) {
+	if _, err := gitOutput("rev-parse", "--git-dir"); err != nil {
+		return nil, nil
+	}
+
+	pathspec := filepath.ToSlash(dir)
+	history, err := gitOutput("log", "-n", strconv.Itoa(maxAttempts), "--format=%H%x1f%an%x1f%at%x1f%s%x1e", "--", pathspec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the git log of %s: %v", pathspec, err)
+	}
+
+	var attempts []models.Attempt
+	for _, entry := range strings.Split(history, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(entry), "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		files, err := gitFiles(fields[0], pathspec)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		seconds, _ := strconv.ParseInt(fields[2], 10, 64)
+		attempts = append(attempts, models.Attempt{
+			ID:      fields[0],
+			Author:  fields[1],
+			Time:    time.Unix(seconds, 0),
+			Message: fields[3],
+			Files:   files,
+		})
+	}
+
+	// The log lists the newest commit first
+	for i, j := 0, len(attempts)-1; i < j; i, j = i+1, j-1 {
+		attempts[i], attempts[j] = attempts[j], attempts[i]
+	}
+	return attempts, nil
+}
+
+// gitFiles reads a submission directory's Go files as of a commit, with the
+// same filtering as ReadSubmissionFiles
+func gitFiles(commit, dir string) (models.SourceFiles, error) {
+	listing, err := gitOutput("ls-tree", "-r", "-z", "--name-only", commit, "--", dir+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s at %s: %v", dir, commit, err)
+	}
+
+	files := make(models.SourceFiles)
+	for _, file := range strings.Split(listing, "\x00") {
+		name := strings.TrimPrefix(file, dir+"/")
+		if file == "" || name == file || validatePath(name) != nil || len(files) >= MaxSubmissionFiles {
+			continue
+		}
+		content, err := gitOutput("cat-file", "blob", commit+":"+gitRelativePath(file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at %s: %v", file, commit, err)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// gitRelativePath marks a path as relative to the working directory, as
+// git expects in <commit>:<path>
+func gitRelativePath(file string) string {
+	if strings.HasPrefix(file, "../") {
+		return file
+	}
+	return "./" + path.Clean(file)
+}
+
+// gitOutput runs git in the working directory and returns its output
+func gitOutput(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(output), err
+}
+
+// diffFiles returns a unified diff between two file trees, using git so no
+// diff algorithm needs to live here
+func diffFiles(from, to models.SourceFiles) (string, error) {
+	dir, err := ioutil.TempDir("", "attempt-diff-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	// The trees are written to a and b so that, without prefixes, the diff
+	// names files a/<path> and b/<path> like any other patch
+	for name, files := range map[string]models.SourceFiles{"a": from, "b": to} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			return "", err
+		}
+		if err := writeFiles(filepath.Join(dir, name), files); err != nil {
+			return "", err
+		}
+	}
+
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "--no-ext-diff", "--no-renames",
+		"--src-prefix=", "--dst-prefix=", "a", "b")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	// Exit status 1 means the trees differ
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to diff attempts: %v", err)
+	}
+	return string(output), nil
+}
+
+// parseUnifiedDiff splits a unified diff into files, hunks and numbered
+// lines
+func parseUnifiedDiff(diff string) []models.FileDiff {
+	files := []models.FileDiff{}
+	var file *models.FileDiff
+	var hunk *models.DiffHunk
+	oldLine, newLine := 0, 0
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, models.FileDiff{Status: "modified", Hunks: []models.DiffHunk{}})
+			file = &files[len(files)-1]
+			hunk = nil
+		case file == nil:
+			continue
+		case hunk == nil && strings.HasPrefix(line, "new file mode"):
+			file.Status = "added"
+		case hunk == nil && strings.HasPrefix(line, "deleted file mode"):
+			file.Status = "deleted"
+		case hunk == nil && strings.HasPrefix(line, "--- a/"):
+			file.Path = strings.TrimPrefix(line, "--- a/")
+		case hunk == nil && strings.HasPrefix(line, "+++ b/"):
+			file.Path = strings.TrimPrefix(line, "+++ b/")
+		case strings.HasPrefix(line, "@@"):
+			if match := hunkHeaderRe.FindStringSubmatch(line); match != nil {
+				oldLine, _ = strconv.Atoi(match[1])
+				newLine, _ = strconv.Atoi(match[2])
+			}
+			file.Hunks = append(file.Hunks, models.DiffHunk{Header: line})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		case hunk == nil:
+			continue
+		case strings.HasPrefix(line, "+"):
+			hunk.Lines = append(hunk.Lines, models.DiffLine{Kind: "add", Text: line[1:], NewLine: newLine})
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			hunk.Lines = append(hunk.Lines, models.DiffLine{Kind: "delete", Text: line[1:], OldLine: oldLine})
+			oldLine++
+		case strings.HasPrefix(line, " "):
+			hunk.Lines = append(hunk.Lines, models.DiffLine{Kind: "context", Text: line[1:], OldLine: oldLine, NewLine: newLine})
+			oldLine++
+			newLine++
+		}
+	}
+	return files
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// Versions of alice's solution to challenge 1
+const (
+	attemptFirst  = "package main\n\nfunc Sum(a, b int) int {\n\treturn a + a\n}\n"
+	attemptSecond = "package main\n\nfunc Sum(a, b int) int {\n\treturn add(a, b)\n}\n"
+	attemptHelper = "package main\n\nfunc add(a, b int) int { return a + b }\n"
+	attemptThird  = "package main\n\n// Sum adds two numbers\nfunc Sum(a, b int) int {\n\treturn add(a, b)\n}\n"
+)
+
+// newTestAttemptRepo creates a checkout where alice committed two attempts
+// at challenge 1 and changed the files again without committing, and moves
+// into its web-ui directory, where the server runs
+func newTestAttemptRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Alice")
+	t.Setenv("GIT_AUTHOR_EMAIL", "alice@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Alice")
+	t.Setenv("GIT_COMMITTER_EMAIL", "alice@example.com")
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, "challenge-1", "submissions", "alice", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("README.md", "My notes\n")
+	git("add", "-A")
+	git("commit", "-qm", "Start challenge 1")
+	write(MainFile, attemptFirst)
+	git("add", "-A")
+	git("commit", "-qm", "First try")
+	write(MainFile, attemptSecond)
+	write("add.go", attemptHelper)
+	git("add", "-A")
+	git("commit", "-qm", "Fix Sum")
+	write(MainFile, attemptThird)
+
+	if err := os.Mkdir(filepath.Join(repo, "web-ui"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(repo, "web-ui"))
+	return repo
+}
+
+func TestAttempts(t *testing.T) {
+	newTestAttemptRepo(t)
+	store, _ := newTestSubmissionStore(t)
+	as := NewAttemptService(NewUserService(), store)
+
+	// The first attempt failed a test the second one fixed
+	runs := []struct {
+		files models.SourceFiles
+		mode  RunMode
+		tests []*models.TestResult
+	}{
+		{SingleFile(attemptFirst), ModeTest, []*models.TestResult{{Name: "TestSum", Status: models.TestStatusFail, Subtests: []*models.TestResult{
+			{Name: "TestSum/Zero", Status: models.TestStatusPass},
+			{Name: "TestSum/Positive", Status: models.TestStatusFail},
+		}}}},
+		{models.SourceFiles{MainFile: attemptSecond, "add.go": attemptHelper}, ModeSubmit, []*models.TestResult{{Name: "TestSum", Status: models.TestStatusPass, Subtests: []*models.TestResult{
+			{Name: "TestSum/Zero", Status: models.TestStatusPass},
+			{Name: "TestSum/Positive", Status: models.TestStatusPass},
+			{Name: "TestSum/Negative", Status: models.TestStatusPass},
+		}}}},
+		// Benchmarks do not say whether the tests pass
+		{models.SourceFiles{MainFile: attemptThird, "add.go": attemptHelper}, ModeBenchmark, nil},
+	}
+	for i, run := range runs {
+		record := &models.SubmissionRecord{
+			Username:    "alice",
+			ChallengeID: 1,
+			Mode:        string(run.mode),
+			SubmittedAt: time.Now().Add(time.Duration(i) * time.Minute),
+			Passed:      run.mode == ModeSubmit,
+			TestsPassed: 1,
+			TestsTotal:  3,
+			CodeHash:    FilesHash(run.files),
+			Code:        run.files[MainFile],
+			Tests:       run.tests,
+		}
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attempts, err := as.Attempts("alice", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for i, attempt := range attempts {
+		messages = append(messages, attempt.Message)
+		if attempt.Number != i+1 {
+			t.Errorf("attempt %d is numbered %d", i+1, attempt.Number)
+		}
+	}
+	if want := []string{"First try", "Fix Sum", "Uncommitted changes"}; !slices.Equal(messages, want) {
+		t.Fatalf("attempts %q, want %q", messages, want)
+	}
+	if first := attempts[0]; first.Author != "Alice" || len(first.ID) != 40 || !first.Tested || first.Passed || len(first.Files) != 1 {
+		t.Errorf("first attempt %+v", first)
+	}
+	if second := attempts[1]; !second.Tested || !second.Passed || second.Files["add.go"] != attemptHelper {
+		t.Errorf("second attempt %+v", second)
+	}
+	if working := attempts[2]; working.ID != models.AttemptWorking || working.Tested || working.Files[MainFile] != attemptThird {
+		t.Errorf("working attempt %+v", working)
+	}
+
+	// Diffing the two committed attempts shows the files and tests it changed
+	diff, err := as.Diff("alice", 1, attempts[0].ID[:7], attempts[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	for _, file := range diff.Files {
+		changed = append(changed, file.Path+" "+file.Status)
+	}
+	if want := []string{"add.go added", "solution-template.go modified"}; !slices.Equal(changed, want) {
+		t.Errorf("changed files %q, want %q", changed, want)
+	}
+	wantTests := []models.TestChange{
+		{Name: "TestSum", From: models.TestStatusFail, To: models.TestStatusPass},
+		{Name: "TestSum/Negative", From: "", To: models.TestStatusPass},
+		{Name: "TestSum/Positive", From: models.TestStatusFail, To: models.TestStatusPass},
+	}
+	if !slices.Equal(diff.Tests, wantTests) {
+		t.Errorf("test changes %+v, want %+v", diff.Tests, wantTests)
+	}
+
+	// The uncommitted files were never tested
+	diff, err = as.Diff("alice", 1, attempts[1].ID, models.AttemptWorking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Files) != 1 || diff.Files[0].Path != MainFile || len(diff.Tests) != 0 || diff.Tests == nil {
+		t.Errorf("diff of the working files %+v", diff)
+	}
+
+	if _, err := as.Diff("alice", 1, attempts[0].ID, "abc"); !errors.Is(err, ErrAttemptNotFound) {
+		t.Errorf("Diff with a short unknown ID: %v, want ErrAttemptNotFound", err)
+	}
+	for _, username := range []string{"../alice", ".alice", "alice/..", ""} {
+		if _, err := as.Attempts(username, 1); !errors.Is(err, ErrInvalidUsername) {
+			t.Errorf("Attempts(%q): %v, want ErrInvalidUsername", username, err)
+		}
+	}
+	if attempts, err := as.Attempts("bob", 1); err != nil || len(attempts) != 0 {
+		t.Errorf("attempts of a user without submissions %+v, %v", attempts, err)
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/solution-template.go b/solution-template.go
+index 1111111..2222222 100644
+--- a/solution-template.go
++++ b/solution-template.go
+@@ -2,3 +2,4 @@ package main
+ import "math"
++// Sum adds two numbers
+ func Sum(a, b int) int {
+-	return a + a
++	return a + b
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 3333333..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+`
+	files := parseUnifiedDiff(diff)
+	if len(files) != 2 {
+		t.Fatalf("%d files, want 2", len(files))
+	}
+	if files[0].Path != MainFile || files[0].Status != "modified" || len(files[0].Hunks) != 1 {
+		t.Errorf("first file %+v", files[0])
+	}
+	if files[1].Path != "old.go" || files[1].Status != "deleted" {
+		t.Errorf("second file %+v", files[1])
+	}
+
+	want := []models.DiffLine{
+		{Kind: "context", Text: `import "math"`, OldLine: 2, NewLine: 2},
+		{Kind: "add", Text: "// Sum adds two numbers", NewLine: 3},
+		{Kind: "context", Text: "func Sum(a, b int) int {", OldLine: 3, NewLine: 4},
+		{Kind: "delete", Text: "\treturn a + a", OldLine: 4},
+		{Kind: "add", Text: "\treturn a + b", NewLine: 5},
+	}
+	if lines := files[0].Hunks[0].Lines; !slices.Equal(lines, want) {
+		t.Errorf("lines %+v, want %+v", lines, want)
+	}
+
+	if files := parseUnifiedDiff(""); files == nil || len(files) != 0 {
+		t.Errorf("an empty diff has files %+v", files)
+	}
+}
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+
+	"web-ui/internal/models"
+)
+
+// AuthMode is how the server tells who a request comes from
+type AuthMode string
+
+const (
+	// AuthLocal trusts the username the browser gives, for a server only its
+	// owner uses. Signed-in accounts take precedence.
+	AuthLocal AuthMode = "local"
+	// AuthAccounts only trusts signed-in sessions of local accounts, for a
+	// server a team shares
+	AuthAccounts AuthMode = "accounts"
+)
+
+// Password limits; bcrypt ignores everything past 72 bytes
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// SessionLifetime is how long a sign-in lasts
+const SessionLifetime = 30 * 24 * time.Hour
+
+// ExternalSignInLifetime is how long a user may take to sign in with an
+// identity provider
+const ExternalSignInLifetime = 10 * time.Minute
+
+// identityProviderTimeout bounds the requests made to an identity provider
+// when a user returns from it
+const identityProviderTimeout = 30 * time.Second
+
+// Authentication errors
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidPassword    = fmt.Errorf("password must be %d to %d bytes long", MinPasswordLength, MaxPasswordLength)
+	ErrInvalidSignIn      = errors.New("the sign-in expired or was not started in this browser")
+	ErrInvalidRole        = fmt.Errorf("role must be %q or empty", models.RoleAdmin)
+	ErrUsernameReserved   = errors.New("username is reserved for an admin")
+)
+
+// AuthService registers and signs in local accounts, signs in users of
+// identity providers, and signs the tokens that identify sessions and
+// protect against cross-site request forgery
+type AuthService struct {
+	store     AccountStore
+	providers []IdentityProvider
+	mode      AuthMode
+	defaulted bool // AUTH_MODE is unset
+	secret    []byte
+	dummyHash []byte           // Compared against for unknown users, so they take as long as wrong passwords
+	admins    map[int64]string // Accounts ADMIN_USERS makes admins, by ID, with the name it lists
+	reserved  map[string]bool  // Lowercased names ADMIN_USERS lists that no account had at startup
+}
+
+// NewAuthService creates an auth service in the mode AUTH_MODE names,
+// local by default. Tokens are signed with SESSION_SECRET, or else with a
+// secret generated once and kept in the file SESSION_SECRET_FILE names, see
+// sessionSecretFile. The accounts ADMIN_USERS lists, separated by commas,
+// are admins whatever their role, see configureAdmins.
+func NewAuthService(store AccountStore, providers []IdentityProvider) (*AuthService, error) {
+	mode := AuthMode(strings.ToLower(os.Getenv("AUTH_MODE")))
+	defaulted := mode == ""
+	if defaulted {
+		mode = AuthLocal
+	}
+	if mode != AuthLocal && mode != AuthAccounts {
+		return nil, fmt.Errorf("unknown AUTH_MODE %q: expected %q or %q", mode, AuthLocal, AuthAccounts)
+	}
+
+	secret := []byte(os.Getenv("SESSION_SECRET"))
+	if len(secret) == 0 {
+		path, err := sessionSecretFile()
+		if err != nil {
+			return nil, err
+		}
+		if secret, err = readOrCreateSecret(path); err != nil {
+			return nil, err
+		}
+	}
+
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &AuthService{store: store, providers: providers, mode: mode, defaulted: defaulted, secret: secret, dummyHash: dummyHash}
+	if err := a.configureAdmins(os.Getenv("ADMIN_USERS")); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// configureAdmins binds the usernames in a comma-separated list to the IDs
+// of the accounts that have them now, so that an account which takes one
+// of the names later, by registering or signing in, or after a rename,
+// does not become an admin. Names without an account are reserved instead:
+// nobody can create an account with them, and they make no one an admin.
+func (a *AuthService) configureAdmins(list string) error {
+	a.admins, a.reserved = make(map[int64]string), make(map[string]bool)
+	for _, username := range strings.Split(list, ",") {
+		if username = strings.TrimSpace(username); username == "" {
+			continue
+		}
+		user, err := a.store.UserByUsername(username)
+		switch {
+		case err == nil:
+			a.admins[user.ID] = user.Username
+		case errors.Is(err, ErrUserNotFound):
+			log.Printf("ADMIN_USERS lists %q, which has no account; the name is reserved, and ignored until its account exists", username)
+			a.reserved[strings.ToLower(username)] = true
+		default:
+			return fmt.Errorf("failed to look up admin %q: %v", username, err)
+		}
+	}
+	return nil
+}
+
+// reservedUsername reports whether ADMIN_USERS lists a username that no
+// account had at startup
+func (a *AuthService) reservedUsername(username string) bool {
+	return a.reserved[strings.ToLower(username)]
+}
+
+// MinSecretLength is the fewest bytes a secret file must hold
+const MinSecretLength = 16
+
+// sessionSecretFile returns the file the session secret is kept in:
+// SESSION_SECRET_FILE, or session.key in the user's configuration directory.
+// Unlike the workspace cache directory, neither is visible to sandboxed
+// code.
+func sessionSecretFile() (string, error) {
+	if path := os.Getenv("SESSION_SECRET_FILE"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find a place for the session secret, set SESSION_SECRET or SESSION_SECRET_FILE: %v", err)
+	}
+	return filepath.Join(configDir, "go-interview-practice", "session.key"), nil
+}
+
+// readOrCreateSecret reads the secret in a file only its owner may read,
+// generating it the first time
+func readOrCreateSecret(path string) ([]byte, error) {
+	for {
+		file, err := os.Open(path)
+		if err == nil {
+			defer file.Close()
+			info, err := file.Stat()
+			if err != nil {
+				return nil, err
+			}
+			if info.Mode().Perm()&0077 != 0 {
+				return nil, fmt.Errorf("%s may be read by other users, make it private with chmod 600", path)
+			}
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return nil, err
+			}
+			secret := bytes.TrimSpace(data)
+			if len(secret) < MinSecretLength {
+				return nil, fmt.Errorf("%s must hold a secret of at least %d bytes", path, MinSecretLength)
+			}
+			return secret, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create the directory of %s: %v", path, err)
+		}
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue // Another server created it first; read theirs
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %v", path, err)
+		}
+		_, err = file.WriteString(NewToken() + NewToken() + "\n")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+// Mode returns how the server identifies users
+func (a *AuthService) Mode() AuthMode {
+	return a.mode
+}
+
+// ListenWarning returns what to warn about when the server listens on addr,
+// or "" if nothing. In local mode anyone who can reach the server can
+// submit under any name, which is only safe on a loopback address or when
+// AUTH_MODE=local was chosen on purpose.
+func (a *AuthService) ListenWarning(addr string) string {
+	if a.mode != AuthLocal || !a.defaulted || isLoopbackAddr(addr) {
+		return ""
+	}
+	return fmt.Sprintf("AUTH_MODE is not set, so the server trusts the username a browser or request gives, "+
+		"and it listens on %s, where other machines can reach it. Anyone who can connect can submit and save "+
+		"solutions as any user. Set AUTH_MODE=accounts on a shared server, or AUTH_MODE=local to keep trusting usernames.", addr)
+}
+
+// isLoopbackAddr reports whether a listen address only accepts connections
+// from this machine. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Register creates an account with a bcrypt hash of its password. Names
+// reserved for admins are refused with ErrUsernameReserved.
+func (a *AuthService) Register(username, password string) (*models.User, error) {
+	if !ValidUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+	if a.reservedUsername(username) {
+		return nil, ErrUsernameReserved
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return a.store.CreateUser(username, string(hash))
+}
+
+// Authenticate returns the account a username and password sign in to, or
+// ErrInvalidCredentials. Accounts without a password, created by identity
+// providers, never match.
+func (a *AuthService) Authenticate(username, password string) (*models.User, error) {
+	user, err := a.store.UserByUsername(username)
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Providers returns the configured identity providers
+func (a *AuthService) Providers() []IdentityProvider {
+	return a.providers
+}
+
+// Provider returns the identity provider with a name
+func (a *AuthService) Provider(name string) (IdentityProvider, bool) {
+	for _, provider := range a.providers {
+		if provider.Name() == name {
+			return provider, true
+		}
+	}
+	return nil, false
+}
+
+// externalSignIn is what a browser keeps while its user signs in with an
+// identity provider
+type externalSignIn struct {
+	Provider string    `json:"provider"`
+	State    string    `json:"state"`
+	Nonce    string    `json:"nonce"`
+	Verifier string    `json:"verifier"`
+	Next     string    `json:"next"`
+	Expires  time.Time `json:"expires"`
+}
+
+// StartExternalSignIn begins signing in with an identity provider. It
+// returns the provider's URL to send the user to, and a signed token the
+// browser must keep until they return, binding the sign-in to it.
+func (a *AuthService) StartExternalSignIn(provider IdentityProvider, next string) (string, string, error) {
+	signIn := externalSignIn{
+		Provider: provider.Name(),
+		State:    NewToken(),
+		Nonce:    NewToken(),
+		Verifier: oauth2.GenerateVerifier(),
+		Next:     next,
+		Expires:  time.Now().Add(ExternalSignInLifetime),
+	}
+	data, err := json.Marshal(signIn)
+	if err != nil {
+		return "", "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	token := payload + "." + a.sign("external", payload)
+	return provider.AuthCodeURL(signIn.State, signIn.Nonce, signIn.Verifier), token, nil
+}
+
+// FinishExternalSignIn completes signing in when a user returns from an
+// identity provider with a code and the state sent with them. It returns
+// the user the provider's account maps to, and where to send them next.
+func (a *AuthService) FinishExternalSignIn(ctx context.Context, provider IdentityProvider, token, state, code string) (*models.User, string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(a.sign("external", payload))) {
+		return nil, "", ErrInvalidSignIn
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", ErrInvalidSignIn
+	}
+	var signIn externalSignIn
+	if err := json.Unmarshal(data, &signIn); err != nil {
+		return nil, "", ErrInvalidSignIn
+	}
+	if signIn.Provider != provider.Name() || !time.Now().Before(signIn.Expires) ||
+		!hmac.Equal([]byte(signIn.State), []byte(state)) {
+		return nil, "", ErrInvalidSignIn
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, identityProviderTimeout)
+	defer cancel()
+	identity, err := provider.Exchange(ctx, code, signIn.Nonce, signIn.Verifier)
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := a.externalUser(provider.Name(), identity)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, signIn.Next, nil
+}
+
+// maxUsernameSuffix bounds the numbered usernames tried for a provider's
+// account whose login is taken
+const maxUsernameSuffix = 100
+
+// externalUser returns the user an identity provider's account is linked
+// to, following renames of its login while the new login is free. An
+// account seen for the first time is linked to the user with its login
+// only if that user has no password and no account of the provider, i.e.
+// was created by another provider; otherwise it gets a user of its own,
+// named after its login with a number appended when the login is taken.
+// Local passwords and logins the provider gave to someone else before
+// thus never sign in to somebody else's user. Names reserved for admins
+// are treated as taken.
+func (a *AuthService) externalUser(provider string, identity *ExternalIdentity) (*models.User, error) {
+	if !ValidUsername(identity.Login) {
+		return nil, ErrInvalidUsername
+	}
+
+	user, err := a.store.UserByIdentity(provider, identity.Subject)
+	if err == nil {
+		if user.Username != identity.Login && !a.reservedUsername(identity.Login) {
+			err := a.store.RenameUser(user.ID, identity.Login)
+			switch {
+			case err == nil:
+				user.Username = identity.Login
+			case !errors.Is(err, ErrUsernameTaken):
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	if a.reservedUsername(identity.Login) {
+		user, err = a.createNumberedUser(identity.Login)
+	} else if user, err = a.store.UserByUsername(identity.Login); errors.Is(err, ErrUserNotFound) {
+		user, err = a.store.CreateUser(identity.Login, "")
+	} else if err == nil {
+		var linkable bool
+		if linkable, err = a.linkable(user, provider); err == nil && !linkable {
+			user, err = a.createNumberedUser(identity.Login)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := a.store.LinkIdentity(user.ID, provider, identity.Subject); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkable reports whether a provider's account may be linked to an
+// existing user of the same name: one another provider created
+func (a *AuthService) linkable(user *models.User, provider string) (bool, error) {
+	if user.PasswordHash != "" {
+		return false, nil
+	}
+	linked, err := a.store.HasIdentity(user.ID, provider)
+	return !linked, err
+}
+
+// createNumberedUser creates a passwordless user named after a login that
+// is taken, as login-2, login-3 and so on
+func (a *AuthService) createNumberedUser(login string) (*models.User, error) {
+	for n := 2; n <= maxUsernameSuffix; n++ {
+		username := fmt.Sprintf("%s-%d", login, n)
+		if !ValidUsername(username) {
+			break
+		}
+		if a.reservedUsername(username) {
+			continue
+		}
+		user, err := a.store.CreateUser(username, "")
+		if !errors.Is(err, ErrUsernameTaken) {
+			return user, err
+		}
+	}
+	return nil, ErrUsernameTaken
+}
+
+// CreateSession signs a user in and returns the session's signed token
+func (a *AuthService) CreateSession(user *models.User) (string, *models.Session, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:        NewToken(),
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionLifetime),
+	}
+	if err := a.store.CreateSession(session); err != nil {
+		return "", nil, err
+	}
+	return session.ID + "." + a.sign("session", session.ID), session, nil
+}
+
+// Session returns the session a signed token identifies, or
+// ErrSessionNotFound when the token is forged, unknown or expired
+func (a *AuthService) Session(token string) (*models.Session, error) {
+	id, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(a.sign("session", id))) {
+		return nil, ErrSessionNotFound
+	}
+	session, err := a.store.Session(id)
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		a.store.DeleteSession(id)
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// EndSession signs out the session a signed token identifies
+func (a *AuthService) EndSession(token string) error {
+	session, err := a.Session(token)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.store.DeleteSession(session.ID)
+}
+
+// Admin reports whether the account with an ID and role is an admin: it
+// has the admin role, or ADMIN_USERS listed its name at startup
+func (a *AuthService) Admin(userID int64, role models.Role) bool {
+	_, configured := a.admins[userID]
+	return role == models.RoleAdmin || configured
+}
+
+// ConfiguredAdmins returns the usernames of the accounts ADMIN_USERS makes
+// admins as they were at startup, sorted
+func (a *AuthService) ConfiguredAdmins() []string {
+	admins := make([]string, 0, len(a.admins))
+	for _, username := range a.admins {
+		admins = append(admins, username)
+	}
+	sort.Strings(admins)
+	return admins
+}
+
+// Admins returns the accounts with the admin role
+func (a *AuthService) Admins() ([]models.User, error) {
+	return a.store.UsersWithRole(models.RoleAdmin)
+}
+
+// SetRole changes the site role of the account with a username, or
+// returns ErrUserNotFound. Sessions pick up the new role on their next
+// request.
+func (a *AuthService) SetRole(username string, role models.Role) error {
+	if role != "" && role != models.RoleAdmin {
+		return ErrInvalidRole
+	}
+	return a.store.SetRole(username, role)
+}
+
+// CSRFToken returns the token that requests from a browser must carry,
+// derived from a random nonce the browser keeps in a cookie. Another site
+// can make the browser send the cookie but cannot read it to derive the
+// token.
+func (a *AuthService) CSRFToken(nonce string) string {
+	return a.sign("csrf", nonce)
+}
+
+// ValidCSRFToken reports whether a request's token matches its nonce
+func (a *AuthService) ValidCSRFToken(nonce, token string) bool {
+	return nonce != "" && hmac.Equal([]byte(token), []byte(a.CSRFToken(nonce)))
+}
+
+// sign returns the HMAC of a value for one purpose, so that a signature
+// made for one purpose is not valid for another
+func (a *AuthService) sign(purpose, value string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(purpose + "\x00" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewToken returns a random token for session IDs and nonces
+func NewToken() string {
+	return rand.Text()
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// newTestAuthService returns an auth service in accounts mode over a fresh
+// account store, signing with a fixed secret
+func newTestAuthService(t *testing.T, providers ...IdentityProvider) (*AuthService, *SQLiteAccountStore) {
+	t.Helper()
+	t.Setenv("AUTH_MODE", string(AuthAccounts))
+	t.Setenv("SESSION_SECRET", "test-session-secret")
+	t.Setenv("ADMIN_USERS", "")
+
+	store, err := NewSQLiteAccountStore(filepath.Join(t.TempDir(), "accounts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	auth, err := NewAuthService(store, providers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth, store
+}
+
+func TestSessionTokens(t *testing.T) {
+	auth, store := newTestAuthService(t)
+	user, err := auth.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := auth.CreateSession(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := auth.Session(token)
+	if err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	if session.Username != "alice" {
+		t.Errorf("session of %q, want alice", session.Username)
+	}
+
+	id, signature, _ := strings.Cut(token, ".")
+	otherSecret := *auth
+	otherSecret.secret = []byte("another secret")
+	changed := "A"
+	if strings.HasSuffix(signature, changed) {
+		changed = "B"
+	}
+	tampered := map[string]string{
+		"no signature":          id,
+		"empty signature":       id + ".",
+		"changed signature":     id + "." + signature[:len(signature)-1] + changed,
+		"signature of another":  NewToken() + "." + signature,
+		"unknown id":            NewToken() + "." + auth.sign("session", NewToken()),
+		"signed for csrf":       id + "." + auth.sign("csrf", id),
+		"signed by another key": id + "." + otherSecret.sign("session", id),
+	}
+	for name, token := range tampered {
+		if _, err := auth.Session(token); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("%s: got %v, want ErrSessionNotFound", name, err)
+		}
+	}
+
+	// The database only holds a hash of the ID
+	var stored string
+	if err := store.db.QueryRow("SELECT id_hash FROM sessions").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == id || strings.Contains(stored, id) {
+		t.Errorf("the session ID is stored as is: %q", stored)
+	}
+
+	if err := auth.EndSession(token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Session(token); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ended session: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	auth, store := newTestAuthService(t)
+	user, err := auth.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &models.Session{
+		ID:        NewToken(),
+		UserID:    user.ID,
+		CreatedAt: time.Now().Add(-SessionLifetime - time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	if err := store.CreateSession(session); err != nil {
+		t.Fatal(err)
+	}
+	token := session.ID + "." + auth.sign("session", session.ID)
+
+	if _, err := auth.Session(token); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expired session: got %v, want ErrSessionNotFound", err)
+	}
+	// Expired sessions are removed when they are seen
+	if _, err := store.Session(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expired session still stored: %v", err)
+	}
+}
+
+func TestSessionSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "session.key")
+
+	first, err := readOrCreateSecret(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("secret file mode %o, want 600", perm)
+	}
+	if len(first) < MinSecretLength {
+		t.Errorf("secret of %d bytes, want at least %d", len(first), MinSecretLength)
+	}
+
+	second, err := readOrCreateSecret(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Error("the secret changed between starts")
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOrCreateSecret(path); err == nil {
+		t.Error("a secret file other users may read was accepted")
+	}
+
+	short := filepath.Join(t.TempDir(), "short.key")
+	if err := os.WriteFile(short, []byte("short\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOrCreateSecret(short); err == nil {
+		t.Error("a short secret was accepted")
+	}
+}
+
+func TestConfiguredAdmins(t *testing.T) {
+	auth, _ := newTestAuthService(t)
+	stub := newStubGitHub(t)
+	alice, err := auth.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.configureAdmins(" Alice, root,"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !auth.Admin(alice.ID, "") {
+		t.Error("alice, listed with an account, is not an admin")
+	}
+	if got := auth.ConfiguredAdmins(); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("configured admins %q, want [alice]", got)
+	}
+
+	// A listed name without an account can be neither registered nor
+	// taken by signing in
+	for _, username := range []string{"root", "ROOT"} {
+		if _, err := auth.Register(username, "correct horse"); !errors.Is(err, ErrUsernameReserved) {
+			t.Errorf("register %s: got %v, want ErrUsernameReserved", username, err)
+		}
+	}
+	id, username := signIn(t, auth, stub, 1, "root")
+	if username != "root-2" || auth.Admin(id, "") {
+		t.Errorf("GitHub user root got %q, admin %v; want a user named root-2 who is not an admin", username, auth.Admin(id, ""))
+	}
+	if _, username := signIn(t, auth, stub, 2, "someone"); username != "someone" {
+		t.Fatalf("GitHub user someone got %q", username)
+	}
+	if _, username := signIn(t, auth, stub, 2, "root"); username != "someone" {
+		t.Errorf("rename to a reserved name got %q, want someone", username)
+	}
+
+	// Admin follows the account, not its name
+	if err := auth.store.RenameUser(alice.ID, "alice-renamed"); err != nil {
+		t.Fatal(err)
+	}
+	impostor, err := auth.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Admin(impostor.ID, "") || !auth.Admin(alice.ID, "") {
+		t.Error("admin moved with the name alice to a new account")
+	}
+	if !auth.Admin(impostor.ID, models.RoleAdmin) {
+		t.Error("an account with the admin role is not an admin")
+	}
+}
+
+func TestListenWarning(t *testing.T) {
+	store, err := NewSQLiteAccountStore(filepath.Join(t.TempDir(), "accounts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	t.Setenv("SESSION_SECRET", "test-session-secret")
+	t.Setenv("ADMIN_USERS", "")
+
+	tests := []struct {
+		mode string // AUTH_MODE
+		addr string
+		warn bool
+	}{
+		{"", ":8080", true},
+		{"", "0.0.0.0:8080", true},
+		{"", "192.0.2.1:8080", true},
+		{"", "[::]:8080", true},
+		{"", "localhost:8080", false},
+		{"", "127.0.0.1:8080", false},
+		{"", "[::1]:8080", false},
+		{"local", ":8080", false},
+		{"accounts", ":8080", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("AUTH_MODE", tt.mode)
+		auth, err := NewAuthService(store, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if warning := auth.ListenWarning(tt.addr); (warning != "") != tt.warn {
+			t.Errorf("AUTH_MODE=%q on %s: warning %q, want one: %v", tt.mode, tt.addr, warning, tt.warn)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"web-ui/internal/models"
+)
+
+// ErrInvalidBadgeTheme is returned for a badge theme that does not exist
+var ErrInvalidBadgeTheme = errors.New("invalid badge theme")
+
+// BadgeLabel is the label of the shields.io badge
+const BadgeLabel = "Go Interview Practice"
+
+// BadgeStats are the numbers a user's achievement badges show
+type BadgeStats struct {
+	Username        string
+	Solved          int // Completed classic challenges
+	TotalChallenges int
+	Packages        map[string]int // Completed challenges per package
+}
+
+// BadgeStatsFor collects a user's badge numbers from the main leaderboard
+// and the package scoreboards. Usernames match case-insensitively, as on
+// GitHub, and the badges show the scoreboards' casing. It reports false
+// when the user has not completed any challenge.
+func BadgeStatsFor(username string, leaderboard []models.LeaderboardUser, totalChallenges int, packages []PackageCompletions) (BadgeStats, bool) {
+	stats := BadgeStats{TotalChallenges: totalChallenges, Packages: make(map[string]int)}
+	for _, user := range leaderboard {
+		if strings.EqualFold(user.Username, username) {
+			stats.Username = user.Username
+			stats.Solved = user.CompletedCount
+			break
+		}
+	}
+	for _, completions := range packages {
+		for name, completed := range completions.Completed {
+			if len(completed) > 0 && strings.EqualFold(name, username) {
+				if stats.Username == "" {
+					stats.Username = name
+				}
+				stats.Packages[completions.Package] += len(completed)
+			}
+		}
+	}
+	return stats, stats.Username != ""
+}
+
+// Achievement returns the main leaderboard achievement the badges show
+func (s BadgeStats) Achievement() AchievementTier {
+	return MainAchievement(s.Solved)
+}
+
+// CompletionRate returns the percentage of classic challenges completed
+func (s BadgeStats) CompletionRate() float64 {
+	if s.TotalChallenges == 0 {
+		return 0
+	}
+	return float64(s.Solved) / float64(s.TotalChallenges) * 100
+}
+
+// PackageChallenges returns the number of package challenges completed
+func (s BadgeStats) PackageChallenges() int {
+	total := 0
+	for _, completed := range s.Packages {
+		total += completed
+	}
+	return total
+}
+
+// badgePalette colors a badge by achievement
+type badgePalette struct {
+	Primary, Secondary, Accent string
+}
+
+// badgePalettes are keyed by main achievement name
+var badgePalettes = map[string]badgePalette{
+	"Master":       {"#FFD700", "#FFA500", "#FF8C00"},
+	"Expert":       {"#4A90E2", "#357ABD", "#2E5F87"},
+	"Advanced":     {"#FF8C42", "#FF6B1A", "#E55A00"},
+	"Intermediate": {"#9B6CEF", "#7E4FD6", "#6238B0"},
+	"Beginner":     {"#97CA00", "#7BA428", "#5F7E1F"},
+}
+
+// badgeTheme colors the parts of a badge that do not depend on the
+// achievement
+type badgeTheme struct {
+	BackgroundTop, BackgroundBottom string
+	Text, Muted, Stats, Track       string
+}
+
+// badgeThemes are the values of the badge theme parameter
+var badgeThemes = map[string]badgeTheme{
+	"light": {"#f8f9fa", "#e9ecef", "#212529", "#6c757d", "#495057", "#dee2e6"},
+	"dark":  {"#22272e", "#1c2128", "#e6edf3", "#8b949e", "#c9d1d9", "#373e47"},
+}
+
+// DefaultBadgeTheme is used when no theme is asked for
+const DefaultBadgeTheme = "light"
+
+// badgeData is what the badge templates render
+type badgeData struct {
+	BadgeStats
+	Tier             AchievementTier
+	Rate             string
+	Palette          badgePalette
+	Theme            badgeTheme
+	CardProgress     int // Width of the card's progress bar, out of 140
+	CompactProgress  int // Width of the compact badge's progress bar, out of 100
+	PackageCount     int
+	PackageSolved    int
+	PackagesNoun     string
+	PackageChallenge string
+}
+
+// newBadgeData prepares a user's numbers for the badge templates
+func newBadgeData(stats BadgeStats, theme string) (badgeData, error) {
+	if theme == "" {
+		theme = DefaultBadgeTheme
+	}
+	colors, ok := badgeThemes[theme]
+	if !ok {
+		return badgeData{}, ErrInvalidBadgeTheme
+	}
+
+	tier := stats.Achievement()
+	data := badgeData{
+		BadgeStats:       stats,
+		Tier:             tier,
+		Rate:             fmt.Sprintf("%.1f", stats.CompletionRate()),
+		Palette:          badgePalettes[tier.Name],
+		Theme:            colors,
+		PackageCount:     len(stats.Packages),
+		PackageSolved:    stats.PackageChallenges(),
+		PackagesNoun:     "packages",
+		PackageChallenge: "package challenges",
+	}
+	if stats.TotalChallenges > 0 {
+		data.CardProgress = stats.Solved * 140 / stats.TotalChallenges
+		data.CompactProgress = stats.Solved * 100 / stats.TotalChallenges
+	}
+	if data.PackageCount == 1 {
+		data.PackagesNoun = "package"
+	}
+	if data.PackageSolved == 1 {
+		data.PackageChallenge = "package challenge"
+	}
+	return data, nil
+}
+
+// badgeFont is the font stack of the badges
+const badgeFont = `font-family="-apple-system,BlinkMacSystemFont,Segoe UI,sans-serif"`
+
+var cardBadgeTemplate = template.Must(template.New("card").Parse(`<svg width="350" height="120" viewBox="0 0 350 120" xmlns="http://www.w3.org/2000/svg" role="img" aria-label="{{.Username | html}}: {{.Tier.Name}} ({{.Solved}}/{{.TotalChallenges}})">
+  <title>@{{.Username | html}}: {{.Tier.Emoji}} {{.Tier.Name}} ({{.Solved}}/{{.TotalChallenges}})</title>
+  <defs>
+    <linearGradient id="cardGradient" x1="0%" y1="0%" x2="100%" y2="100%">
+      <stop offset="0%" stop-color="{{.Theme.BackgroundTop}}"/>
+      <stop offset="100%" stop-color="{{.Theme.BackgroundBottom}}"/>
+    </linearGradient>
+    <linearGradient id="headerGradient" x1="0%" y1="0%" x2="100%" y2="0%">
+      <stop offset="0%" stop-color="{{.Palette.Primary}}"/>
+      <stop offset="100%" stop-color="{{.Palette.Secondary}}"/>
+    </linearGradient>
+    <linearGradient id="progressGradient" x1="0%" y1="0%" x2="100%" y2="0%">
+      <stop offset="0%" stop-color="{{.Palette.Accent}}"/>
+      <stop offset="100%" stop-color="{{.Palette.Primary}}"/>
+    </linearGradient>
+  </defs>
+
+  <rect width="350" height="120" fill="url(#cardGradient)" rx="12"/>
+  <rect width="350" height="35" fill="url(#headerGradient)" rx="12"/>
+  <rect y="12" width="350" height="23" fill="url(#headerGradient)"/>
+
+  <text x="15" y="15" {{.Font}} font-size="10" font-weight="600" fill="white" opacity="0.9">GO INTERVIEW PRACTICE</text>
+  <text x="15" y="27" {{.Font}} font-size="8" fill="white" opacity="0.8">github.com/RezaSi/go-interview-practice</text>
+  <text x="320" y="22" {{.Font}} font-size="16" text-anchor="middle" fill="white">{{.Tier.Emoji}}</text>
+
+  <text x="15" y="58" {{.Font}} font-size="14" font-weight="700" fill="{{.Theme.Text}}">@{{.Username | html}}</text>
+  <text x="15" y="75" {{.Font}} font-size="12" font-weight="600" fill="{{.Palette.Primary}}">{{.Tier.Emoji}} {{.Tier.Name}} Developer</text>
+
+  <text x="15" y="95" {{.Font}} font-size="10" font-weight="500" fill="{{.Theme.Muted}}">Classic Challenges</text>
+  <rect x="15" y="100" width="140" height="6" fill="{{.Theme.Track}}" rx="3"/>
+  <rect x="15" y="100" width="{{.CardProgress}}" height="6" fill="url(#progressGradient)" rx="3"/>
+  <text x="160" y="106" {{.Font}} font-size="9" font-weight="600" fill="{{.Theme.Stats}}">{{.Solved}}/{{.TotalChallenges}} ({{.Rate}}%)</text>
+{{if .PackageCount}}
+  <text x="190" y="58" {{.Font}} font-size="10" font-weight="500" fill="{{.Theme.Muted}}">Package Challenges</text>
+  <text x="190" y="72" {{.Font}} font-size="12" font-weight="600" fill="{{.Palette.Secondary}}">{{.PackageSolved}} across {{.PackageCount}} {{.PackagesNoun}}</text>
+  <circle cx="195" cy="82" r="3" fill="{{.Palette.Primary}}" opacity="0.8"/>
+  <circle cx="205" cy="82" r="3" fill="{{.Palette.Secondary}}" opacity="0.8"/>
+  <circle cx="215" cy="82" r="3" fill="{{.Palette.Accent}}" opacity="0.8"/>
+{{- else}}
+  <text x="190" y="65" {{.Font}} font-size="10" font-weight="500" fill="{{.Theme.Muted}}">Ready for</text>
+  <text x="190" y="78" {{.Font}} font-size="12" font-weight="600" fill="{{.Palette.Secondary}}">Package Challenges!</text>
+{{- end}}
+{{if eq .Tier.Name "Master"}}
+  <circle cx="320" cy="85" r="8" fill="{{.Palette.Primary}}" opacity="0.2"/>
+  <text x="320" y="89" {{.Font}} font-size="10" text-anchor="middle" fill="{{.Palette.Primary}}" font-weight="700">★</text>
+{{- end}}
+</svg>
+`))
+
+var compactBadgeTemplate = template.Must(template.New("compact").Parse(`<svg width="400" height="60" viewBox="0 0 400 60" xmlns="http://www.w3.org/2000/svg" role="img" aria-label="{{.Username | html}}: {{.Tier.Name}} ({{.Solved}}/{{.TotalChallenges}})">
+  <title>@{{.Username | html}}: {{.Tier.Emoji}} {{.Tier.Name}} ({{.Solved}}/{{.TotalChallenges}})</title>
+  <defs>
+    <linearGradient id="compactGradient" x1="0%" y1="0%" x2="100%" y2="0%">
+      <stop offset="0%" stop-color="{{.Palette.Primary}}"/>
+      <stop offset="100%" stop-color="{{.Palette.Secondary}}"/>
+    </linearGradient>
+    <linearGradient id="compactBg" x1="0%" y1="0%" x2="0%" y2="100%">
+      <stop offset="0%" stop-color="{{.Theme.BackgroundTop}}"/>
+      <stop offset="100%" stop-color="{{.Theme.BackgroundBottom}}"/>
+    </linearGradient>
+  </defs>
+
+  <rect width="400" height="60" fill="url(#compactBg)" rx="8"/>
+  <rect width="6" height="60" fill="url(#compactGradient)" rx="3"/>
+
+  <text x="20" y="20" {{.Font}} font-size="12" font-weight="700" fill="{{.Theme.Text}}">🐹 Go Interview Practice</text>
+  <text x="20" y="38" {{.Font}} font-size="11" fill="{{.Theme.Muted}}">@{{.Username | html}}</text>
+  <text x="20" y="52" {{.Font}} font-size="10" font-weight="600" fill="{{.Palette.Primary}}">{{.Tier.Emoji}} {{.Tier.Name}} Developer</text>
+
+  <text x="220" y="20" {{.Font}} font-size="10" font-weight="500" fill="{{.Theme.Stats}}">Classic Progress</text>
+  <rect x="220" y="25" width="100" height="4" fill="{{.Theme.Track}}" rx="2"/>
+  <rect x="220" y="25" width="{{.CompactProgress}}" height="4" fill="url(#compactGradient)" rx="2"/>
+  <text x="220" y="42" {{.Font}} font-size="10" font-weight="600" fill="{{.Theme.Stats}}">{{.Solved}}/{{.TotalChallenges}} ({{.Rate}}%)</text>
+{{- if .PackageCount}}
+  <text x="220" y="54" {{.Font}} font-size="9" fill="{{.Palette.Secondary}}">📦 {{.PackageSolved}} {{.PackageChallenge}}</text>
+{{- end}}
+
+  <circle cx="365" cy="30" r="12" fill="{{.Palette.Primary}}" opacity="0.15"/>
+  <text x="365" y="34" {{.Font}} font-size="12" text-anchor="middle" fill="{{.Palette.Primary}}">{{.Tier.Emoji}}</text>
+</svg>
+`))
+
+// Font returns the font attribute of the badges' text
+func (badgeData) Font() string {
+	return badgeFont
+}
+
+// RenderCardBadge renders a user's full-size achievement card as SVG
+func RenderCardBadge(stats BadgeStats, theme string) ([]byte, error) {
+	return renderBadge(cardBadgeTemplate, stats, theme)
+}
+
+// RenderCompactBadge renders a user's compact horizontal badge as SVG
+func RenderCompactBadge(stats BadgeStats, theme string) ([]byte, error) {
+	return renderBadge(compactBadgeTemplate, stats, theme)
+}
+
+// renderBadge executes a badge template for a user
+func renderBadge(tmpl *template.Template, stats BadgeStats, theme string) ([]byte, error) {
+	data, err := newBadgeData(stats, theme)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ShieldsBadgeFor returns a user's shields.io endpoint badge, colored like
+// their SVG badges
+func ShieldsBadgeFor(stats BadgeStats) models.ShieldsBadge {
+	tier := stats.Achievement()
+	return models.ShieldsBadge{
+		SchemaVersion: 1,
+		Label:         BadgeLabel,
+		Message:       fmt.Sprintf("%s (%d/%d)", tier, stats.Solved, stats.TotalChallenges),
+		Color:         strings.TrimPrefix(badgePalettes[tier.Name].Primary, "#"),
+		Style:         "for-the-badge",
+	}
+}
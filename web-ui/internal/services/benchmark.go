@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchmath"
+
+	"web-ui/internal/models"
+)
+
+// benchmarkBaselineFile holds a challenge's reference benchmark results in
+// the standard Go benchmark format, as written by the same command the
+// benchmark mode runs
+const benchmarkBaselineFile = "benchmark-baseline.txt"
+
+// Defaults for challenges whose metadata does not configure benchmarks.
+// benchstat needs at least 6 samples for a 95% confidence interval.
+const (
+	DefaultBenchmarkCount = 6
+	DefaultBenchmarkTime  = "100ms"
+)
+
+// benchmarkConfidence is the confidence level of the reported ranges
+const benchmarkConfidence = 0.95
+
+// Benchmark verdicts
+const (
+	VerdictImprovement = "improvement"
+	VerdictRegression  = "regression"
+	VerdictUnchanged   = "unchanged"
+)
+
+// benchmarkFuncRe matches a benchmark function declaration
+var benchmarkFuncRe = regexp.MustCompile(`(?m)^func Benchmark\w*\(`)
+
+// HasBenchmarks reports whether a test file declares any benchmarks
+func HasBenchmarks(testFile string) bool {
+	return benchmarkFuncRe.MatchString(testFile)
+}
+
+// ResolveBenchmarkConfig fills in the defaults for a challenge's benchmark
+// settings
+func ResolveBenchmarkConfig(config models.BenchmarkConfig) models.BenchmarkConfig {
+	if config.Pattern == "" {
+		config.Pattern = "."
+	}
+	if config.Count <= 0 {
+		config.Count = DefaultBenchmarkCount
+	}
+	if config.Benchtime == "" {
+		config.Benchtime = DefaultBenchmarkTime
+	}
+	return config
+}
+
+// Benchmark runs the challenge's benchmarks against the provided files and
+// compares the results with the challenge's baseline. Tests are not run;
+// Passed reports whether every benchmark completed.
+func (es *ExecutionService) Benchmark(ctx context.Context, files models.SourceFiles, challenge *models.Challenge, emit func(ExecutionEvent)) ExecutionResult {
+	if emit == nil {
+		emit = func(ExecutionEvent) {}
+	}
+
+	start := time.Now()
+	limits := ResolveLimits(challenge.Limits)
+	config := ResolveBenchmarkConfig(challenge.Benchmark)
+	emit(ExecutionEvent{Type: EventPhase, Phase: PhaseSetup, Message: "Preparing workspace"})
+
+	tempDir, failure := es.prepareWorkspace(ctx, files, challenge, start, limits, emit)
+	if failure != nil {
+		return *failure
+	}
+	defer os.RemoveAll(tempDir)
+
+	emit(ExecutionEvent{Type: EventPhase, Phase: PhaseBenchmark, Message: "Running benchmarks"})
+	sandboxResult := es.sandbox.Run(ctx, SandboxCommand{
+		Dir:    tempDir,
+		Args:   benchmarkArgs(config, limits),
+		Env:    es.testEnv(),
+		Limits: limits,
+		Stream: newOutputEventWriter(emit),
+	})
+
+	outputStr := string(sandboxResult.Output)
+	result := ExecutionResult{
+		Output:      outputStr,
+		ExecutionMs: time.Since(start).Milliseconds(),
+		Killed:      sandboxResult.Killed,
+		Sandbox:     es.sandbox.Name(),
+		Limits:      limits,
+	}
+
+	switch {
+	case sandboxResult.Err != nil:
+		result.Output = fmt.Sprintf("Failed to run benchmarks: %v\n%s", sandboxResult.Err, outputStr)
+		return result
+	case sandboxResult.Killed != KillNone:
+		result.Output = fmt.Sprintf("%s\n\n%s", outputStr, DescribeKill(sandboxResult.Killed, limits))
+		return result
+	}
+	result.Passed = sandboxResult.ExitCode == 0
+
+	samples, err := parseBenchmarks(bytes.NewReader(sandboxResult.Output), "benchmark")
+	if err != nil {
+		result.Passed = false
+		result.Output = fmt.Sprintf("%s\n\nFailed to parse benchmark output: %v", outputStr, err)
+		return result
+	}
+
+	baseline, err := loadBenchmarkBaseline(challenge.Dir)
+	if err != nil {
+		result.Output = fmt.Sprintf("%s\n\nBaseline not used: %v", outputStr, err)
+	}
+
+	result.Benchmarks = compareBenchmarks(samples, baseline)
+	return result
+}
+
+// benchmarkArgs builds the go test command for a benchmark run. -run=^$
+// skips the tests so only benchmarks are timed.
+func benchmarkArgs(config models.BenchmarkConfig, limits models.ExecutionLimits) []string {
+	return []string{"go", "test", "-run=^$",
+		"-bench=" + config.Pattern,
+		"-benchmem",
+		fmt.Sprintf("-count=%d", config.Count),
+		"-benchtime=" + config.Benchtime,
+		fmt.Sprintf("-timeout=%ds", limits.TimeoutSeconds),
+	}
+}
+
+// benchmarkSamples holds every measured value of a run, keyed by benchmark
+// name and then by unit, with benchmark names in the order they first ran
+type benchmarkSamples struct {
+	names  []string
+	units  map[string][]string
+	values map[string]map[string][]float64
+}
+
+// parseBenchmarks reads results in the Go benchmark format. Names lose their
+// GOMAXPROCS suffix so that runs on machines with different core counts
+// still line up, and values keep the units the benchmark reported.
+func parseBenchmarks(r io.Reader, fileName string) (*benchmarkSamples, error) {
+	samples := &benchmarkSamples{
+		units:  make(map[string][]string),
+		values: make(map[string]map[string][]float64),
+	}
+
+	reader := benchfmt.NewReader(r, fileName)
+	for reader.Scan() {
+		record, ok := reader.Result().(*benchfmt.Result)
+		if !ok {
+			continue // Configuration lines and syntax errors in non-benchmark output
+		}
+
+		name := benchmarkName(record)
+		if _, seen := samples.values[name]; !seen {
+			samples.names = append(samples.names, name)
+			samples.values[name] = make(map[string][]float64)
+		}
+		for _, value := range record.Values {
+			unit, measured := value.OrigUnit, value.OrigValue
+			if unit == "" {
+				unit, measured = value.Unit, value.Value
+			}
+			if _, seen := samples.values[name][unit]; !seen {
+				samples.units[name] = append(samples.units[name], unit)
+			}
+			samples.values[name][unit] = append(samples.values[name][unit], measured)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// benchmarkName returns a result's full name without its GOMAXPROCS suffix
+func benchmarkName(record *benchfmt.Result) string {
+	base, parts := record.Name.Parts()
+	name := "Benchmark" + string(base)
+	for _, part := range parts {
+		if !bytes.HasPrefix(part, []byte("-")) {
+			name += string(part)
+		}
+	}
+	return name
+}
+
+// loadBenchmarkBaseline reads a challenge's baseline results. A challenge
+// without a baseline file has no baseline, which is not an error.
+func loadBenchmarkBaseline(challengeDir string) (*benchmarkSamples, error) {
+	if challengeDir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(challengeDir, benchmarkBaselineFile)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseBenchmarks(file, path)
+}
+
+// compareBenchmarks summarizes every measured metric and compares it with the
+// baseline when the baseline has the same benchmark and unit. Like
+// benchstat, it makes no assumptions about the distribution of the samples.
+func compareBenchmarks(samples, baseline *benchmarkSamples) []models.BenchmarkResult {
+	thresholds := &benchmath.DefaultThresholds
+	results := make([]models.BenchmarkResult, 0, len(samples.names))
+
+	for _, name := range samples.names {
+		result := models.BenchmarkResult{Name: name}
+		for _, unit := range samples.units[name] {
+			sample := benchmath.NewSample(samples.values[name][unit], thresholds)
+			summary := benchmath.AssumeNothing.Summary(sample, benchmarkConfidence)
+			metric := models.BenchmarkMetric{
+				Unit:    unit,
+				Median:  summary.Center,
+				Range:   summary.PctRangeString(),
+				Samples: len(sample.Values),
+			}
+
+			if baseline != nil {
+				if values, ok := baseline.values[name][unit]; ok {
+					metric.Baseline = compareWithBaseline(benchmath.NewSample(values, thresholds), sample)
+				}
+			}
+			result.Metrics = append(result.Metrics, metric)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// compareWithBaseline compares a sample with its baseline. Every standard
+// benchmark unit is a cost, so a lower value is an improvement.
+func compareWithBaseline(old, new *benchmath.Sample) *models.BenchmarkComparison {
+	oldSummary := benchmath.AssumeNothing.Summary(old, benchmarkConfidence)
+	newSummary := benchmath.AssumeNothing.Summary(new, benchmarkConfidence)
+	comparison := benchmath.AssumeNothing.Compare(old, new)
+
+	result := &models.BenchmarkComparison{
+		Median:  oldSummary.Center,
+		Range:   oldSummary.PctRangeString(),
+		Samples: len(old.Values),
+		Delta:   comparison.FormatDelta(oldSummary.Center, newSummary.Center),
+		P:       comparison.P,
+		Verdict: VerdictUnchanged,
+	}
+	if newSummary.Center != 0 {
+		result.Speedup = oldSummary.Center / newSummary.Center
+	} else if oldSummary.Center == 0 {
+		result.Speedup = 1
+	}
+	if math.IsNaN(comparison.P) || comparison.P > comparison.Alpha {
+		return result
+	}
+	switch {
+	case newSummary.Center < oldSummary.Center:
+		result.Verdict = VerdictImprovement
+	case newSummary.Center > oldSummary.Center:
+		result.Verdict = VerdictRegression
+	}
+	return result
+}
+
+// outputEventWriter reports plain command output as output events, one per
+// complete line
+type outputEventWriter struct {
+	emit    func(ExecutionEvent)
+	pending []byte
+}
+
+// newOutputEventWriter creates a writer that reports output lines to emit
+func newOutputEventWriter(emit func(ExecutionEvent)) *outputEventWriter {
+	return &outputEventWriter{emit: emit}
+}
+
+// Write splits p into lines and emits an event for each complete line
+func (w *outputEventWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(ExecutionEvent{Type: EventOutput, Output: string(w.pending[:i+1])})
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+func TestParseBenchmarks(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "benchmark", "run.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	samples, err := parseBenchmarks(file, "run.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Names lose the GOMAXPROCS suffix and keep the order they first ran in
+	if want := []string{"BenchmarkSort/100", "BenchmarkSearch", "BenchmarkInsert"}; !slices.Equal(samples.names, want) {
+		t.Errorf("benchmarks %q, want %q", samples.names, want)
+	}
+	if want := []string{"ns/op", "B/op", "allocs/op"}; !slices.Equal(samples.units["BenchmarkSort/100"], want) {
+		t.Errorf("units %q, want %q", samples.units["BenchmarkSort/100"], want)
+	}
+	if want := []string{"ns/op"}; !slices.Equal(samples.units["BenchmarkInsert"], want) {
+		t.Errorf("units without -benchmem %q, want %q", samples.units["BenchmarkInsert"], want)
+	}
+	if values := samples.values["BenchmarkSort/100"]["ns/op"]; len(values) != 6 || values[0] != 505 {
+		t.Errorf("ns/op samples %v, want 6 starting at 505", values)
+	}
+}
+
+func TestCompareBenchmarks(t *testing.T) {
+	baseline, err := loadBenchmarkBaseline(filepath.Join("testdata", "benchmark"))
+	if err != nil || baseline == nil {
+		t.Fatalf("baseline %v, %v", baseline, err)
+	}
+	output, err := os.ReadFile(filepath.Join("testdata", "benchmark", "run.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := parseBenchmarks(strings.NewReader(string(output)), "run.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := compareBenchmarks(samples, baseline)
+	metrics := make(map[string]models.BenchmarkMetric)
+	for _, result := range results {
+		for _, metric := range result.Metrics {
+			metrics[result.Name+" "+metric.Unit] = metric
+		}
+	}
+
+	tests := []struct {
+		metric  string
+		verdict string // Empty when there is no baseline to compare with
+		speedup float64
+	}{
+		{"BenchmarkSort/100 ns/op", VerdictImprovement, 2},
+		{"BenchmarkSort/100 B/op", VerdictUnchanged, 1},
+		{"BenchmarkSearch ns/op", VerdictRegression, 0.5},
+		{"BenchmarkSearch allocs/op", VerdictRegression, 0},
+		{"BenchmarkInsert ns/op", "", 0},
+	}
+	for _, tt := range tests {
+		metric, ok := metrics[tt.metric]
+		if !ok {
+			t.Errorf("no %s metric", tt.metric)
+			continue
+		}
+		if metric.Samples != 6 || metric.Range == "" {
+			t.Errorf("%s: %d samples, range %q", tt.metric, metric.Samples, metric.Range)
+		}
+		if tt.verdict == "" {
+			if metric.Baseline != nil {
+				t.Errorf("%s: compared with %+v, want no baseline", tt.metric, metric.Baseline)
+			}
+			continue
+		}
+		if metric.Baseline == nil {
+			t.Errorf("%s: not compared with the baseline", tt.metric)
+			continue
+		}
+		if metric.Baseline.Verdict != tt.verdict {
+			t.Errorf("%s: verdict %s (p=%v), want %s", tt.metric, metric.Baseline.Verdict, metric.Baseline.P, tt.verdict)
+		}
+		if speedup := metric.Baseline.Speedup; speedup < tt.speedup*0.95 || speedup > tt.speedup*1.05 {
+			t.Errorf("%s: speedup %v, want about %v", tt.metric, speedup, tt.speedup)
+		}
+	}
+
+	// Without a baseline every metric is only summarized
+	for _, result := range compareBenchmarks(samples, nil) {
+		for _, metric := range result.Metrics {
+			if metric.Baseline != nil {
+				t.Errorf("%s %s compared without a baseline", result.Name, metric.Unit)
+			}
+		}
+	}
+}
+
+func TestLoadBenchmarkBaselineMissing(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		if baseline, err := loadBenchmarkBaseline(dir); baseline != nil || err != nil {
+			t.Errorf("loadBenchmarkBaseline(%q) = %v, %v, want no baseline", dir, baseline, err)
+		}
+	}
+}
+
+func TestBenchmarkArgs(t *testing.T) {
+	config := ResolveBenchmarkConfig(models.BenchmarkConfig{Pattern: "Sort"})
+	if config.Count != DefaultBenchmarkCount || config.Benchtime != DefaultBenchmarkTime {
+		t.Errorf("resolved config %+v, want the defaults", config)
+	}
+	args := benchmarkArgs(config, models.ExecutionLimits{TimeoutSeconds: 30})
+	want := []string{"go", "test", "-run=^$", "-bench=Sort", "-benchmem", "-count=6", "-benchtime=100ms", "-timeout=30s"}
+	if !slices.Equal(args, want) {
+		t.Errorf("args %q, want %q", args, want)
+	}
+
+	if !HasBenchmarks("package main\n\nfunc BenchmarkSort(b *testing.B) {}\n") {
+		t.Error("a benchmark was not found")
+	}
+	if HasBenchmarks("package main\n\n// func BenchmarkSort(b *testing.B) {}\nfunc TestSort(t *testing.T) {}\n") {
+		t.Error("a commented-out benchmark was found")
+	}
+}
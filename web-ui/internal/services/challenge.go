@@ -1,20 +1,24 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"maps"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"web-ui/internal/models"
 )
 
 // ChallengeService handles challenge-related operations
 type ChallengeService struct {
-	challenges models.ChallengeMap
+	mu         sync.RWMutex
+	challenges models.ChallengeMap // Replaced, never changed, once loaded, as callers keep it
 }
 
 // NewChallengeService creates a new challenge service
@@ -26,6 +30,9 @@ func NewChallengeService() *ChallengeService {
 
 // LoadChallenges loads all challenges from the filesystem
 func (cs *ChallengeService) LoadChallenges() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
 	// Find challenge directories (challenge-1, challenge-2, etc.)
 	challengeDirs, err := filepath.Glob("../challenge-*")
 	if err != nil {
@@ -70,9 +77,6 @@ func (cs *ChallengeService) loadSingleChallenge(id int, dir string) (*models.Cha
 	// Extract title from README (first heading)
 	title := cs.extractTitle(string(readmeContent), id)
 
-	// Determine difficulty level
-	difficulty := cs.determineDifficulty(id)
-
 	// Read solution template
 	templatePath := filepath.Join(dir, "solution-template.go")
 	templateContent, err := ioutil.ReadFile(templatePath)
@@ -101,21 +105,80 @@ func (cs *ChallengeService) loadSingleChallenge(id int, dir string) (*models.Cha
 		hintsContent = hintsFileContent
 	}
 
+	// Read metadata: the difficulty and tags, and optional execution
+	// limits, run stages, benchmarks, etc.
+	var difficulty string
+	var points int
+	var tags []string
+	var limits models.ExecutionLimits
+	var stages models.StageConfig
+	var benchmark models.BenchmarkConfig
+	var fuzz models.FuzzConfig
+	if metadata := cs.loadChallengeMetadata(dir); metadata != nil {
+		difficulty = metadata.Difficulty
+		points = metadata.Points
+		tags = metadata.Tags
+		if metadata.Limits != nil {
+			limits = *metadata.Limits
+		}
+		if metadata.Stages != nil {
+			stages = *metadata.Stages
+		}
+		if metadata.Benchmark != nil {
+			benchmark = *metadata.Benchmark
+		}
+		if metadata.Fuzz != nil {
+			fuzz = *metadata.Fuzz
+		}
+	}
+
+	if _, known := difficultyPoints[difficulty]; !known {
+		log.Printf("Warning: Challenge %d has no known difficulty in metadata.json (%q), using %s", id, difficulty, DefaultDifficulty)
+		difficulty = DefaultDifficulty
+	}
+	if points <= 0 {
+		points = difficultyPoints[difficulty]
+	}
+
 	// Create challenge
 	challenge := &models.Challenge{
 		ID:                id,
 		Title:             title,
 		Description:       cs.filterWebUIDescription(string(readmeContent)),
 		Difficulty:        difficulty,
+		Points:            points,
+		Tags:              tags,
 		Template:          string(templateContent),
 		TestFile:          string(testContent),
 		LearningMaterials: string(learningContent),
 		Hints:             string(hintsContent),
+		Limits:            limits,
+		Stages:            stages,
+		Benchmark:         benchmark,
+		HasBenchmarks:     HasBenchmarks(string(testContent)),
+		Fuzz:              fuzz,
+		Dir:               dir,
 	}
 
 	return challenge, nil
 }
 
+// loadChallengeMetadata loads the optional metadata.json from a challenge directory
+func (cs *ChallengeService) loadChallengeMetadata(dir string) *models.ChallengeMetadata {
+	metadataBytes, err := ioutil.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil
+	}
+
+	var metadata models.ChallengeMetadata
+	if err := json.Unmarshal(metadataBytes, &metadata); err != nil {
+		log.Printf("Warning: Could not parse metadata.json in %s: %v", dir, err)
+		return nil
+	}
+
+	return &metadata
+}
+
 // extractTitle extracts the title from README content
 func (cs *ChallengeService) extractTitle(readmeContent string, id int) string {
 	titleRe := regexp.MustCompile(`#\s+(.+)`)
@@ -131,18 +194,6 @@ func (cs *ChallengeService) extractTitle(readmeContent string, id int) string {
 	return fmt.Sprintf("Challenge %d", id)
 }
 
-// determineDifficulty determines the difficulty level based on challenge ID
-func (cs *ChallengeService) determineDifficulty(id int) string {
-	switch {
-	case id <= 3 || id == 6 || id == 18 || id == 21 || id == 22:
-		return "Beginner"
-	case id == 4 || id == 5 || id == 7 || id == 10 || id == 13 || id == 14 || id == 16 || id == 17 || id == 19 || id == 20 || id == 23 || id == 27 || id == 30:
-		return "Intermediate"
-	default:
-		return "Advanced"
-	}
-}
-
 // filterWebUIDescription removes manual instructions that are not relevant for web-ui users
 func (cs *ChallengeService) filterWebUIDescription(content string) string {
 	lines := strings.Split(content, "\n")
@@ -181,11 +232,36 @@ func (cs *ChallengeService) filterWebUIDescription(content string) string {
 
 // GetChallenges returns all challenges
 func (cs *ChallengeService) GetChallenges() models.ChallengeMap {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 	return cs.challenges
 }
 
 // GetChallenge returns a specific challenge by ID
 func (cs *ChallengeService) GetChallenge(id int) (*models.Challenge, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 	challenge, exists := cs.challenges[id]
 	return challenge, exists
 }
+
+// SetStatus sets a challenge's status, models.ChallengeComingSoon or empty,
+// and reports whether the challenge exists. The challenge and the map are
+// copied with the new status rather than changed, so the ones callers hold
+// stay as they were.
+func (cs *ChallengeService) SetStatus(id int, status string) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	challenge, exists := cs.challenges[id]
+	if !exists {
+		return false
+	}
+	updated := *challenge
+	updated.Status = status
+
+	challenges := maps.Clone(cs.challenges)
+	challenges[id] = &updated
+	cs.challenges = challenges
+	return true
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"web-ui/internal/models"
+)
+
+// Cohort name and description limits, in characters
+const (
+	MaxCohortNameLength        = 100
+	MaxCohortDescriptionLength = 1000
+)
+
+// Cohort errors
+var (
+	ErrCohortNotFound       = errors.New("cohort not found")
+	ErrCohortForbidden      = errors.New("only the cohort's admins can do that")
+	ErrInvalidCohort        = fmt.Errorf("a cohort needs a name of at most %d characters, and a description of at most %d", MaxCohortNameLength, MaxCohortDescriptionLength)
+	ErrInvalidCohortRole    = fmt.Errorf("role must be %q or %q", models.CohortAdmin, models.CohortLearner)
+	ErrCohortMemberExists   = errors.New("the user is already a member or invited")
+	ErrCohortMemberNotFound = errors.New("the user is not a member of the cohort")
+	ErrLastCohortAdmin      = errors.New("a cohort must keep at least one admin")
+	ErrInvalidAssignment    = errors.New("an assignment needs an existing classic challenge, or an existing package and package challenge")
+	ErrAssignmentExists     = errors.New("the challenge is already assigned to the cohort")
+	ErrAssignmentNotFound   = errors.New("assignment not found")
+)
+
+// CohortStore keeps cohorts, their members and their assignments
+type CohortStore interface {
+	// CreateCohort stores a cohort and sets its ID. Its creator becomes its
+	// first admin.
+	CreateCohort(cohort *models.Cohort) error
+	// Cohort returns a cohort with its members and assignments, or
+	// ErrCohortNotFound
+	Cohort(id int64) (*models.Cohort, error)
+	// UserCohorts returns the cohorts a user, ignoring case, is a member of
+	// or invited to, newest first
+	UserCohorts(username string) ([]models.Cohort, error)
+	// DeleteCohort removes a cohort with its members and assignments
+	DeleteCohort(id int64) error
+	// AddMember invites a user, or returns ErrCohortMemberExists
+	AddMember(cohortID int64, member models.CohortMember) error
+	// JoinCohort accepts a user's invitation, or returns
+	// ErrCohortMemberNotFound
+	JoinCohort(cohortID int64, username string) error
+	// RemoveMember removes a member or invitation, or returns
+	// ErrCohortMemberNotFound
+	RemoveMember(cohortID int64, username string) error
+	// AddAssignment assigns a challenge and sets the assignment's ID, or
+	// returns ErrAssignmentExists
+	AddAssignment(cohortID int64, assignment *models.CohortAssignment) error
+	// RemoveAssignment removes an assignment, or returns
+	// ErrAssignmentNotFound
+	RemoveAssignment(cohortID, assignmentID int64) error
+	Close() error
+}
+
+// NewCohortStore opens the SQLite cohort database at COHORTS_DB, or next to
+// the workspace caches by default
+func NewCohortStore(workspaces *WorkspaceManager) (CohortStore, error) {
+	path := os.Getenv("COHORTS_DB")
+	if path == "" {
+		path = filepath.Join(workspaces.root, "cohorts.db")
+	}
+	return NewSQLiteCohortStore(path)
+}
+
+// CohortService lets users run cohorts: groups with their own assigned
+// challenges, leaderboard and progress matrix. Only a cohort's members and
+// invited users see it, and only its admins change it.
+type CohortService struct {
+	store             CohortStore
+	challengeService  *ChallengeService
+	packageService    *PackageService
+	scoreboardService *ScoreboardService
+	submissionStore   SubmissionStore
+}
+
+// NewCohortService creates a new cohort service
+func NewCohortService(store CohortStore, challengeService *ChallengeService, packageService *PackageService, scoreboardService *ScoreboardService, submissionStore SubmissionStore) *CohortService {
+	return &CohortService{
+		store:             store,
+		challengeService:  challengeService,
+		packageService:    packageService,
+		scoreboardService: scoreboardService,
+		submissionStore:   submissionStore,
+	}
+}
+
+// Create creates a cohort with its creator as its admin. Callers check
+// that the creator is a site admin.
+func (c *CohortService) Create(creator, name, description string) (*models.Cohort, error) {
+	name, description = strings.TrimSpace(name), strings.TrimSpace(description)
+	if name == "" || utf8.RuneCountInString(name) > MaxCohortNameLength ||
+		utf8.RuneCountInString(description) > MaxCohortDescriptionLength {
+		return nil, ErrInvalidCohort
+	}
+
+	cohort := &models.Cohort{
+		Name:        name,
+		Description: description,
+		CreatedBy:   creator,
+		CreatedAt:   time.Now(),
+		Assignments: []models.CohortAssignment{},
+	}
+	if err := c.store.CreateCohort(cohort); err != nil {
+		return nil, err
+	}
+	return cohort, nil
+}
+
+// Cohorts returns the cohorts a user is a member of or invited to
+func (c *CohortService) Cohorts(username string) ([]models.Cohort, error) {
+	cohorts, err := c.store.UserCohorts(username)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cohorts {
+		c.describeAssignments(cohorts[i].Assignments)
+	}
+	return cohorts, nil
+}
+
+// Cohort returns a cohort a user is a member of or invited to. Other users
+// get ErrCohortNotFound, so they cannot tell which cohorts exist.
+func (c *CohortService) Cohort(username string, id int64) (*models.Cohort, error) {
+	cohort, err := c.store.Cohort(id)
+	if err != nil {
+		return nil, err
+	}
+	if cohortMember(cohort, username) == nil {
+		return nil, ErrCohortNotFound
+	}
+	c.describeAssignments(cohort.Assignments)
+	return cohort, nil
+}
+
+// Delete removes a cohort. Only its admins may.
+func (c *CohortService) Delete(actor string, id int64) error {
+	if _, err := c.adminCohort(actor, id); err != nil {
+		return err
+	}
+	return c.store.DeleteCohort(id)
+}
+
+// Invite invites a user to a cohort in a role. Only its admins may.
+func (c *CohortService) Invite(actor string, id int64, username string, role models.CohortRole) (*models.CohortMember, error) {
+	if _, err := c.adminCohort(actor, id); err != nil {
+		return nil, err
+	}
+	if !ValidUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+	if role == "" {
+		role = models.CohortLearner
+	}
+	if role != models.CohortAdmin && role != models.CohortLearner {
+		return nil, ErrInvalidCohortRole
+	}
+
+	member := models.CohortMember{Username: username, Role: role, InvitedBy: actor, InvitedAt: time.Now()}
+	if err := c.store.AddMember(id, member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// Join accepts a user's invitation to a cohort
+func (c *CohortService) Join(username string, id int64) error {
+	if _, err := c.Cohort(username, id); err != nil {
+		return err
+	}
+	return c.store.JoinCohort(id, username)
+}
+
+// RemoveMember removes a member or invitation from a cohort. Admins may
+// remove anyone, and members themselves, but the last admin stays.
+func (c *CohortService) RemoveMember(actor string, id int64, username string) error {
+	cohort, err := c.Cohort(actor, id)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actor, username) && !isCohortAdmin(cohort, actor) {
+		return ErrCohortForbidden
+	}
+	member := cohortMember(cohort, username)
+	if member == nil {
+		return ErrCohortMemberNotFound
+	}
+
+	if member.Role == models.CohortAdmin && member.Joined {
+		admins := 0
+		for _, other := range cohort.Members {
+			if other.Role == models.CohortAdmin && other.Joined {
+				admins++
+			}
+		}
+		if admins == 1 {
+			return ErrLastCohortAdmin
+		}
+	}
+	return c.store.RemoveMember(id, member.Username)
+}
+
+// Assign assigns a classic or package challenge to a cohort, with an
+// optional due date. Only its admins may.
+func (c *CohortService) Assign(actor string, id int64, assignment models.CohortAssignment) (*models.CohortAssignment, error) {
+	if _, err := c.adminCohort(actor, id); err != nil {
+		return nil, err
+	}
+
+	classic := assignment.ChallengeID != 0
+	if classic == (assignment.PackageName != "" || assignment.PackageChallenge != "") {
+		return nil, ErrInvalidAssignment
+	}
+	if classic {
+		if _, exists := c.challengeService.GetChallenge(assignment.ChallengeID); !exists {
+			return nil, ErrInvalidAssignment
+		}
+	} else {
+		if _, err := c.packageService.GetPackage(assignment.PackageName); err != nil {
+			return nil, ErrInvalidAssignment
+		}
+		challenges, err := c.packageService.GetPackageChallenges(assignment.PackageName)
+		if err != nil || challenges[assignment.PackageChallenge] == nil {
+			return nil, ErrInvalidAssignment
+		}
+	}
+
+	assignment.ID = 0
+	assignment.AssignedBy = actor
+	assignment.AssignedAt = time.Now()
+	if err := c.store.AddAssignment(id, &assignment); err != nil {
+		return nil, err
+	}
+	assignments := []models.CohortAssignment{assignment}
+	c.describeAssignments(assignments)
+	return &assignments[0], nil
+}
+
+// Unassign removes an assignment from a cohort. Only its admins may.
+func (c *CohortService) Unassign(actor string, id, assignmentID int64) error {
+	if _, err := c.adminCohort(actor, id); err != nil {
+		return err
+	}
+	return c.store.RemoveAssignment(id, assignmentID)
+}
+
+// Progress returns a cohort's leaderboard and progress matrix. Its joined
+// members are ranked by assignments completed, then by fewest completed
+// late, then by fewest overdue, then by username.
+//
+// Classic challenges count as completed from the main leaderboard, and
+// their first solve times decide whether they were late; they are started
+// once the member has a scoreboard row or a run in the submission history.
+// Package challenges count as completed from their scoreboards, which do
+// not record when, so they are never late.
+func (c *CohortService) Progress(username string, id int64) (*models.CohortProgress, error) {
+	cohort, err := c.Cohort(username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	classic := make(map[string]models.LeaderboardUser)
+	for _, user := range c.scoreboardService.MainLeaderboard() {
+		classic[strings.ToLower(user.Username)] = user
+	}
+	solveTimes := c.scoreboardService.SolveTimes()
+
+	// Classic scoreboard rows by challenge, then by lowercased username
+	classicRows := make(map[int]map[string]models.ScoreboardEntry)
+	for _, assignment := range cohort.Assignments {
+		if assignment.ChallengeID == 0 || classicRows[assignment.ChallengeID] != nil {
+			continue
+		}
+		entries, _ := c.scoreboardService.GetScoreboard(assignment.ChallengeID)
+		rows := make(map[string]models.ScoreboardEntry, len(entries))
+		for _, entry := range entries {
+			rows[strings.ToLower(entry.Username)] = entry
+		}
+		classicRows[assignment.ChallengeID] = rows
+	}
+
+	packages := make(map[string]PackageCompletions)
+	for _, assignment := range cohort.Assignments {
+		if assignment.PackageName == "" {
+			continue
+		}
+		if _, loaded := packages[assignment.PackageName]; loaded {
+			continue
+		}
+		completions, err := c.packageService.LoadPackageCompletions(assignment.PackageName)
+		if err != nil {
+			return nil, err
+		}
+		packages[assignment.PackageName] = foldPackageCompletions(completions)
+	}
+
+	now := time.Now()
+	progress := &models.CohortProgress{Assignments: cohort.Assignments, Members: []models.CohortMemberProgress{}}
+	for _, member := range cohort.Members {
+		if !member.Joined {
+			continue
+		}
+		key := strings.ToLower(member.Username)
+		row := models.CohortMemberProgress{
+			Username:    member.Username,
+			Assignments: make(map[int64]models.AssignmentProgress),
+			Packages:    make(map[string]*models.PackageProgress),
+		}
+
+		for _, assignment := range cohort.Assignments {
+			var completed, started bool
+			var status models.AssignmentProgress
+			if assignment.ChallengeID != 0 {
+				completed = classic[key].CompletedChallenges[assignment.ChallengeID]
+				if completed {
+					status.CompletedAt = solveTimes.Get(member.Username, assignment.ChallengeID)
+				}
+				var entry models.ScoreboardEntry
+				if entry, started = classicRows[assignment.ChallengeID][key]; started {
+					status.TestsPassed, status.TestsTotal = entry.PassedTests, entry.TotalTests
+				} else if !completed {
+					if started, err = c.attempted(member.Username, assignment.ChallengeID); err != nil {
+						return nil, err
+					}
+				}
+			} else {
+				completions := packages[assignment.PackageName]
+				completed = completions.Completed[key][assignment.PackageChallenge]
+				var score models.TestScore
+				score, started = completions.Scores[key][assignment.PackageChallenge]
+				status.TestsPassed, status.TestsTotal = score.Passed, score.Total
+			}
+
+			status.State = assignmentState(assignment.DueAt, completed, status.CompletedAt, started, now)
+			switch status.State {
+			case models.AssignmentCompleted:
+				row.Completed++
+			case models.AssignmentLate:
+				row.Completed++
+				row.Late++
+			case models.AssignmentOverdue:
+				row.Overdue++
+			}
+			row.Assignments[assignment.ID] = status
+		}
+
+		for name, completions := range packages {
+			row.Packages[name] = packageProgress(member.Username, completions)
+		}
+		progress.Members = append(progress.Members, row)
+	}
+
+	sort.Slice(progress.Members, func(i, j int) bool {
+		a, b := progress.Members[i], progress.Members[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		if a.Late != b.Late {
+			return a.Late < b.Late
+		}
+		if a.Overdue != b.Overdue {
+			return a.Overdue < b.Overdue
+		}
+		return strings.ToLower(a.Username) < strings.ToLower(b.Username)
+	})
+	for i := range progress.Members {
+		progress.Members[i].Rank = i + 1
+	}
+	return progress, nil
+}
+
+// attempted reports whether the submission history has a run or submit of
+// a user's for a classic challenge
+func (c *CohortService) attempted(username string, challengeID int) (bool, error) {
+	page, err := c.submissionStore.List(SubmissionQuery{Username: username, ChallengeID: challengeID, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	return page.Total > 0, nil
+}
+
+// adminCohort returns a cohort if a user is one of its joined admins,
+// ErrCohortForbidden if they are only a member, and ErrCohortNotFound
+// otherwise
+func (c *CohortService) adminCohort(username string, id int64) (*models.Cohort, error) {
+	cohort, err := c.Cohort(username, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isCohortAdmin(cohort, username) {
+		return nil, ErrCohortForbidden
+	}
+	return cohort, nil
+}
+
+// describeAssignments fills in the titles of assignments
+func (c *CohortService) describeAssignments(assignments []models.CohortAssignment) {
+	for i := range assignments {
+		assignment := &assignments[i]
+		if assignment.ChallengeID != 0 {
+			if challenge, exists := c.challengeService.GetChallenge(assignment.ChallengeID); exists {
+				assignment.Title = challenge.Title
+			} else {
+				assignment.Title = fmt.Sprintf("Challenge %d", assignment.ChallengeID)
+			}
+			continue
+		}
+		assignment.Title = assignment.PackageName + "/" + assignment.PackageChallenge
+		if pkg, err := c.packageService.GetPackage(assignment.PackageName); err == nil {
+			if info := pkg.ChallengeDetails[assignment.PackageChallenge]; info != nil && info.Title != "" {
+				assignment.Title = pkg.DisplayName + ": " + info.Title
+			}
+		}
+	}
+}
+
+// cohortMember returns a user's membership or invitation, ignoring case, or
+// nil
+func cohortMember(cohort *models.Cohort, username string) *models.CohortMember {
+	if username == "" {
+		return nil
+	}
+	for i := range cohort.Members {
+		if strings.EqualFold(cohort.Members[i].Username, username) {
+			return &cohort.Members[i]
+		}
+	}
+	return nil
+}
+
+// isCohortAdmin reports whether a user is one of a cohort's joined admins
+func isCohortAdmin(cohort *models.Cohort, username string) bool {
+	member := cohortMember(cohort, username)
+	return member != nil && member.Joined && member.Role == models.CohortAdmin
+}
+
+// assignmentState returns where a member is with an assignment. Completions
+// whose time is not known are on time.
+func assignmentState(dueAt time.Time, completed bool, completedAt time.Time, started bool, now time.Time) models.AssignmentState {
+	switch {
+	case completed && !dueAt.IsZero() && completedAt.After(dueAt):
+		return models.AssignmentLate
+	case completed:
+		return models.AssignmentCompleted
+	case !dueAt.IsZero() && now.After(dueAt):
+		return models.AssignmentOverdue
+	case started:
+		return models.AssignmentInProgress
+	default:
+		return models.AssignmentPending
+	}
+}
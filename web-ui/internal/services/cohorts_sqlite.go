@@ -0,0 +1,313 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// cohortMigrations create and upgrade the cohort schema, see
+// historyMigrations
+var cohortMigrations = []string{
+	`CREATE TABLE cohorts (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		name        TEXT    NOT NULL,
+		description TEXT    NOT NULL DEFAULT '',
+		created_by  TEXT    NOT NULL,
+		created_at  INTEGER NOT NULL -- Unix milliseconds
+	);
+	CREATE TABLE cohort_members (
+		cohort_id  INTEGER NOT NULL REFERENCES cohorts (id) ON DELETE CASCADE,
+		username   TEXT    NOT NULL COLLATE NOCASE,
+		role       TEXT    NOT NULL,
+		joined_at  INTEGER, -- NULL until the invitation is accepted
+		invited_by TEXT    NOT NULL,
+		invited_at INTEGER NOT NULL,
+		PRIMARY KEY (cohort_id, username)
+	);
+	CREATE INDEX cohort_members_user ON cohort_members (username);
+	CREATE TABLE cohort_assignments (
+		id                INTEGER PRIMARY KEY AUTOINCREMENT,
+		cohort_id         INTEGER NOT NULL REFERENCES cohorts (id) ON DELETE CASCADE,
+		challenge_id      INTEGER NOT NULL DEFAULT 0,
+		package_name      TEXT    NOT NULL DEFAULT '',
+		package_challenge TEXT    NOT NULL DEFAULT '',
+		due_at            INTEGER, -- NULL when there is no due date
+		assigned_by       TEXT    NOT NULL,
+		assigned_at       INTEGER NOT NULL,
+		UNIQUE (cohort_id, challenge_id, package_name, package_challenge)
+	);`,
+}
+
+// SQLiteCohortStore is a CohortStore in an embedded SQLite database
+type SQLiteCohortStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteCohortStore opens the database at path, creating it and its
+// directory if needed, and brings its schema up to date
+func NewSQLiteCohortStore(path string) (*SQLiteCohortStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cohort directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	if err := migrateSQLite(db, cohortMigrations); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+	return &SQLiteCohortStore{db: db}, nil
+}
+
+// CreateCohort stores a cohort with its creator as its first admin
+func (s *SQLiteCohortStore) CreateCohort(cohort *models.Cohort) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO cohorts (name, description, created_by, created_at) VALUES (?, ?, ?, ?)",
+		cohort.Name, cohort.Description, cohort.CreatedBy, cohort.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create cohort: %v", err)
+	}
+	if cohort.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	creator := models.CohortMember{
+		Username:  cohort.CreatedBy,
+		Role:      models.CohortAdmin,
+		Joined:    true,
+		InvitedBy: cohort.CreatedBy,
+		InvitedAt: cohort.CreatedAt,
+		JoinedAt:  cohort.CreatedAt,
+	}
+	if err := insertCohortMember(tx, cohort.ID, creator); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	cohort.Members = []models.CohortMember{creator}
+	return nil
+}
+
+// Cohort returns a cohort with its members and assignments, or
+// ErrCohortNotFound
+func (s *SQLiteCohortStore) Cohort(id int64) (*models.Cohort, error) {
+	var cohort models.Cohort
+	var createdAt int64
+	err := s.db.QueryRow("SELECT id, name, description, created_by, created_at FROM cohorts WHERE id = ?", id).
+		Scan(&cohort.ID, &cohort.Name, &cohort.Description, &cohort.CreatedBy, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCohortNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	cohort.CreatedAt = time.UnixMilli(createdAt)
+
+	if cohort.Members, err = s.members(id); err != nil {
+		return nil, err
+	}
+	if cohort.Assignments, err = s.assignments(id); err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+// UserCohorts returns the cohorts a user is a member of or invited to,
+// newest first
+func (s *SQLiteCohortStore) UserCohorts(username string) ([]models.Cohort, error) {
+	rows, err := s.db.Query(`SELECT c.id FROM cohorts c JOIN cohort_members m ON m.cohort_id = c.id
+		WHERE m.username = ? ORDER BY c.created_at DESC, c.id DESC`, username)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cohorts := []models.Cohort{}
+	for _, id := range ids {
+		cohort, err := s.Cohort(id)
+		if errors.Is(err, ErrCohortNotFound) {
+			continue // Deleted since
+		}
+		if err != nil {
+			return nil, err
+		}
+		cohorts = append(cohorts, *cohort)
+	}
+	return cohorts, nil
+}
+
+// DeleteCohort removes a cohort with its members and assignments
+func (s *SQLiteCohortStore) DeleteCohort(id int64) error {
+	_, err := s.db.Exec("DELETE FROM cohorts WHERE id = ?", id)
+	return err
+}
+
+// AddMember invites a user, or returns ErrCohortMemberExists
+func (s *SQLiteCohortStore) AddMember(cohortID int64, member models.CohortMember) error {
+	return insertCohortMember(s.db, cohortID, member)
+}
+
+// JoinCohort accepts a user's invitation, or returns ErrCohortMemberNotFound
+func (s *SQLiteCohortStore) JoinCohort(cohortID int64, username string) error {
+	result, err := s.db.Exec("UPDATE cohort_members SET joined_at = COALESCE(joined_at, ?) WHERE cohort_id = ? AND username = ?",
+		time.Now().UnixMilli(), cohortID, username)
+	return expectAffected(result, err, ErrCohortMemberNotFound)
+}
+
+// RemoveMember removes a member or invitation, or returns
+// ErrCohortMemberNotFound
+func (s *SQLiteCohortStore) RemoveMember(cohortID int64, username string) error {
+	result, err := s.db.Exec("DELETE FROM cohort_members WHERE cohort_id = ? AND username = ?", cohortID, username)
+	return expectAffected(result, err, ErrCohortMemberNotFound)
+}
+
+// AddAssignment assigns a challenge and sets the assignment's ID, or returns
+// ErrAssignmentExists
+func (s *SQLiteCohortStore) AddAssignment(cohortID int64, assignment *models.CohortAssignment) error {
+	var dueAt any
+	if !assignment.DueAt.IsZero() {
+		dueAt = assignment.DueAt.UnixMilli()
+	}
+	result, err := s.db.Exec(`INSERT INTO cohort_assignments
+		(cohort_id, challenge_id, package_name, package_challenge, due_at, assigned_by, assigned_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		cohortID, assignment.ChallengeID, assignment.PackageName, assignment.PackageChallenge, dueAt,
+		assignment.AssignedBy, assignment.AssignedAt.UnixMilli())
+	if uniqueViolation(err) {
+		return ErrAssignmentExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add assignment: %v", err)
+	}
+	assignment.ID, err = result.LastInsertId()
+	return err
+}
+
+// RemoveAssignment removes an assignment, or returns ErrAssignmentNotFound
+func (s *SQLiteCohortStore) RemoveAssignment(cohortID, assignmentID int64) error {
+	result, err := s.db.Exec("DELETE FROM cohort_assignments WHERE cohort_id = ? AND id = ?", cohortID, assignmentID)
+	return expectAffected(result, err, ErrAssignmentNotFound)
+}
+
+// Close closes the database
+func (s *SQLiteCohortStore) Close() error {
+	return s.db.Close()
+}
+
+// members returns a cohort's members, admins first, then by username
+func (s *SQLiteCohortStore) members(cohortID int64) ([]models.CohortMember, error) {
+	rows, err := s.db.Query(`SELECT username, role, joined_at, invited_by, invited_at FROM cohort_members
+		WHERE cohort_id = ? ORDER BY role = ? DESC, username`, cohortID, models.CohortAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.CohortMember{}
+	for rows.Next() {
+		var member models.CohortMember
+		var joinedAt sql.NullInt64
+		var invitedAt int64
+		if err := rows.Scan(&member.Username, &member.Role, &joinedAt, &member.InvitedBy, &invitedAt); err != nil {
+			return nil, err
+		}
+		member.InvitedAt = time.UnixMilli(invitedAt)
+		if joinedAt.Valid {
+			member.Joined = true
+			member.JoinedAt = time.UnixMilli(joinedAt.Int64)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// assignments returns a cohort's assignments by due date, undated last
+func (s *SQLiteCohortStore) assignments(cohortID int64) ([]models.CohortAssignment, error) {
+	rows, err := s.db.Query(`SELECT id, challenge_id, package_name, package_challenge, due_at, assigned_by, assigned_at
+		FROM cohort_assignments WHERE cohort_id = ? ORDER BY due_at IS NULL, due_at, id`, cohortID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []models.CohortAssignment{}
+	for rows.Next() {
+		var assignment models.CohortAssignment
+		var dueAt sql.NullInt64
+		var assignedAt int64
+		if err := rows.Scan(&assignment.ID, &assignment.ChallengeID, &assignment.PackageName, &assignment.PackageChallenge,
+			&dueAt, &assignment.AssignedBy, &assignedAt); err != nil {
+			return nil, err
+		}
+		assignment.AssignedAt = time.UnixMilli(assignedAt)
+		if dueAt.Valid {
+			assignment.DueAt = time.UnixMilli(dueAt.Int64)
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// execer is a database or a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertCohortMember stores a member, or returns ErrCohortMemberExists
+func insertCohortMember(db execer, cohortID int64, member models.CohortMember) error {
+	var joinedAt any
+	if member.Joined {
+		joinedAt = member.JoinedAt.UnixMilli()
+	}
+	_, err := db.Exec(`INSERT INTO cohort_members (cohort_id, username, role, joined_at, invited_by, invited_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		cohortID, member.Username, member.Role, joinedAt, member.InvitedBy, member.InvitedAt.UnixMilli())
+	if uniqueViolation(err) {
+		return ErrCohortMemberExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add cohort member: %v", err)
+	}
+	return nil
+}
+
+// expectAffected returns notFound when a statement changed no row
+func expectAffected(result sql.Result, err error, notFound error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
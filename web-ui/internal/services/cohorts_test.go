@@ -0,0 +1,81 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+func TestCohortProgressClassicStates(t *testing.T) {
+	ss, challenge := newTestScoreboardService(t, `# Scoreboard for challenge-2
+| Username   | Passed Tests | Total Tests |
+|------------|--------------|-------------|
+| alice | 5 | 5 |
+| Bob | 2 | 5 |
+`)
+	challenges := NewChallengeService()
+	challenges.challenges[challenge.ID] = challenge
+
+	dir := t.TempDir()
+	cohortStore, err := NewSQLiteCohortStore(filepath.Join(dir, "cohorts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cohortStore.Close() })
+	history, err := NewSQLiteSubmissionStore(filepath.Join(dir, "submissions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { history.Close() })
+	cohorts := NewCohortService(cohortStore, challenges, nil, ss, history)
+
+	cohort, err := cohorts.Create("root", "Onboarding", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, username := range []string{"alice", "bob", "carol", "dave"} {
+		if _, err := cohorts.Invite("root", cohort.ID, username, models.CohortLearner); err != nil {
+			t.Fatal(err)
+		}
+		if err := cohorts.Join(username, cohort.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assignment, err := cohorts.Assign("root", cohort.ID, models.CohortAssignment{ChallengeID: challenge.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Carol ran the tests without submitting
+	record := &models.SubmissionRecord{Username: "carol", ChallengeID: challenge.ID, Mode: string(ModeTest), SubmittedAt: time.Now(), TestsPassed: 1, TestsTotal: 5}
+	if err := history.Save(record); err != nil {
+		t.Fatal(err)
+	}
+
+	progress, err := cohorts.Progress("root", cohort.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]models.AssignmentState{
+		"root":  models.AssignmentPending,
+		"alice": models.AssignmentCompleted,
+		"bob":   models.AssignmentInProgress,
+		"carol": models.AssignmentInProgress,
+		"dave":  models.AssignmentPending,
+	}
+	for _, member := range progress.Members {
+		if got := member.Assignments[assignment.ID].State; got != want[member.Username] {
+			t.Errorf("%s is %s, want %s", member.Username, got, want[member.Username])
+		}
+		if member.Username == "bob" {
+			if status := member.Assignments[assignment.ID]; status.TestsPassed != 2 || status.TestsTotal != 5 {
+				t.Errorf("bob passed %d of %d tests, want 2 of 5", status.TestsPassed, status.TestsTotal)
+			}
+		}
+	}
+	if len(progress.Members) != len(want) {
+		t.Errorf("%d members in the progress matrix, want %d", len(progress.Members), len(want))
+	}
+}
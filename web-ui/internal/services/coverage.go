@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+
+	"web-ui/internal/models"
+)
+
+// The coverage profile is written to a workspace subdirectory the sandbox
+// keeps after the run. The leading dot keeps the go command out of it.
+const (
+	coverageDir  = ".output"
+	coverageFile = "coverage.out"
+)
+
+// readCoverage parses the coverage profile a run left in its workspace and
+// returns the coverage of the submitted files, or nil if the profile is
+// missing (e.g. the code did not compile), is not a regular file, or
+// mentions none of them. The report's blocks are those of the submission's
+// main file and its totals cover every submitted file; a multi-file
+// submission also gets a report per file.
+func readCoverage(workDir string, files models.SourceFiles) *models.CoverageReport {
+	profile, err := ReadOutputFile(workDir, filepath.Join(coverageDir, coverageFile))
+	if err != nil {
+		return nil
+	}
+
+	profiles, err := cover.ParseProfilesFromReader(bytes.NewReader(profile))
+	if err != nil {
+		return nil
+	}
+
+	reports := make(map[string]*models.CoverageReport)
+	for _, profile := range profiles {
+		// Profiles name files by import path, e.g. "challenge20/solution-template.go"
+		name, ok := submittedPath(files, profile.FileName)
+		if !ok {
+			continue
+		}
+
+		report := &models.CoverageReport{File: name, Blocks: []models.CoverageBlock{}}
+		for _, block := range profile.Blocks {
+			report.Blocks = append(report.Blocks, models.CoverageBlock{
+				StartLine: block.StartLine,
+				StartCol:  block.StartCol,
+				EndLine:   block.EndLine,
+				EndCol:    block.EndCol,
+				NumStmt:   block.NumStmt,
+				Count:     block.Count,
+			})
+			report.Statements += block.NumStmt
+			if block.Count > 0 {
+				report.Covered += block.NumStmt
+			}
+		}
+		report.Percent = coveragePercent(report.Covered, report.Statements)
+		reports[name] = report
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	main := MainFileOf(files)
+	total := &models.CoverageReport{File: main, Blocks: []models.CoverageBlock{}}
+	if report, ok := reports[main]; ok {
+		total.Blocks = report.Blocks
+	}
+	for _, name := range SortedPaths(files) {
+		report, ok := reports[name]
+		if !ok {
+			continue
+		}
+		total.Statements += report.Statements
+		total.Covered += report.Covered
+		if len(files) > 1 {
+			total.Files = append(total.Files, *report)
+		}
+	}
+	total.Percent = coveragePercent(total.Covered, total.Statements)
+	return total
+}
+
+// coveragePercent returns the share of covered statements, 0-100
+func coveragePercent(covered, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(statements)
+}
+
+// UncoveredLines returns the lines of statements the tests never reached,
+// in order
+func UncoveredLines(report *models.CoverageReport) []int {
+	if report == nil {
+		return nil
+	}
+
+	covered := make(map[int]bool)
+	uncovered := make(map[int]bool)
+	for _, block := range report.Blocks {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			if block.Count > 0 {
+				covered[line] = true
+			} else {
+				uncovered[line] = true
+			}
+		}
+	}
+
+	var lines []int
+	for line := range uncovered {
+		if !covered[line] {
+			lines = append(lines, line)
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// formatLineRanges renders sorted line numbers as "3, 7-9, 12"
+func formatLineRanges(lines []int) string {
+	var ranges []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprint(lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+// testCoverageProfile covers the main file partly and a second submitted
+// file fully; the challenge's tests are in it too, as with -coverpkg
+const testCoverageProfile = `mode: set
+challenge20/solution-template.go:5.24,7.2 1 1
+challenge20/solution-template.go:9.30,10.14 1 1
+challenge20/solution-template.go:10.14,12.3 2 0
+challenge20/solution-template.go:13.2,13.10 1 1
+challenge20/internal/store/store.go:3.20,5.2 2 1
+challenge20/solution-template_test.go:5.30,8.2 3 1
+`
+
+// writeCoverageProfile writes a profile where a run leaves it and returns
+// the workspace
+func writeCoverageProfile(t *testing.T, profile string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, coverageDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, coverageDir, coverageFile), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReadCoverage(t *testing.T) {
+	dir := writeCoverageProfile(t, testCoverageProfile)
+
+	single := readCoverage(dir, SingleFile("package main\n"))
+	if single == nil {
+		t.Fatal("no coverage for a single file")
+	}
+	if single.File != MainFile || single.Statements != 5 || single.Covered != 3 || len(single.Blocks) != 4 || single.Files != nil {
+		t.Errorf("single file coverage %+v", single)
+	}
+	if single.Percent != 60 {
+		t.Errorf("single file coverage is %v%%, want 60%%", single.Percent)
+	}
+
+	multi := readCoverage(dir, models.SourceFiles{MainFile: "", "internal/store/store.go": ""})
+	if multi == nil {
+		t.Fatal("no coverage for a multi-file submission")
+	}
+	if multi.Statements != 7 || multi.Covered != 5 || len(multi.Blocks) != 4 {
+		t.Errorf("multi-file coverage %+v, want 5 of 7 statements and the main file's blocks", multi)
+	}
+	var files []string
+	for _, report := range multi.Files {
+		files = append(files, report.File)
+	}
+	if want := []string{"internal/store/store.go", MainFile}; !slices.Equal(files, want) {
+		t.Errorf("per-file reports for %q, want %q", files, want)
+	}
+
+	if report := readCoverage(dir, models.SourceFiles{"other.go": ""}); report != nil {
+		t.Errorf("coverage %+v for a file the profile does not mention", report)
+	}
+	if report := readCoverage(t.TempDir(), SingleFile("package main\n")); report != nil {
+		t.Errorf("coverage %+v without a profile", report)
+	}
+	if report := readCoverage(writeCoverageProfile(t, "not a profile"), SingleFile("package main\n")); report != nil {
+		t.Errorf("coverage %+v from a malformed profile", report)
+	}
+}
+
+// TestReadCoverageIgnoresSymlinks is a run that replaced its profile with a
+// symlink to a file of the host
+func TestReadCoverageIgnoresSymlinks(t *testing.T) {
+	hostProfile := filepath.Join(writeCoverageProfile(t, testCoverageProfile), coverageDir, coverageFile)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, coverageDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(hostProfile, filepath.Join(dir, coverageDir, coverageFile)); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if report := readCoverage(dir, SingleFile("package main\n")); report != nil {
+		t.Errorf("coverage %+v read through a symlink", report)
+	}
+}
+
+func TestUncoveredLines(t *testing.T) {
+	report := readCoverage(writeCoverageProfile(t, testCoverageProfile), SingleFile("package main\n"))
+	lines := UncoveredLines(report)
+	if want := []int{11, 12}; !slices.Equal(lines, want) {
+		t.Errorf("uncovered lines %v, want %v", lines, want)
+	}
+	if lines := UncoveredLines(nil); lines != nil {
+		t.Errorf("uncovered lines %v without a report", lines)
+	}
+
+	tests := []struct {
+		lines []int
+		want  string
+	}{
+		{nil, ""},
+		{[]int{3}, "3"},
+		{[]int{3, 7, 8, 9, 12}, "3, 7-9, 12"},
+		{[]int{1, 2}, "1-2"},
+	}
+	for _, tt := range tests {
+		if got := formatLineRanges(tt.lines); got != tt.want {
+			t.Errorf("formatLineRanges(%v) = %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
@@ -1,285 +1,468 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"web-ui/internal/models"
 )
 
 // ExecutionService handles code execution and testing
-type ExecutionService struct{}
+type ExecutionService struct {
+	sandbox    Sandbox
+	workspaces *WorkspaceManager
+	fuzz       *FuzzStore
 
-// NewExecutionService creates a new execution service
-func NewExecutionService() *ExecutionService {
-	return &ExecutionService{}
+	goVersion     string
+	goVersionOnce sync.Once
+}
+
+// NewExecutionService creates a new execution service using the sandbox
+// selected by the EXECUTION_SANDBOX environment variable and the shared
+// caches of the workspace manager, which also holds the saved fuzz corpora
+func NewExecutionService(workspaces *WorkspaceManager) *ExecutionService {
+	sandbox := NewSandbox()
+	log.Printf("Code execution sandbox: %s", sandbox.Name())
+
+	return &ExecutionService{
+		sandbox:    sandbox,
+		workspaces: workspaces,
+		fuzz:       NewFuzzStore(filepath.Join(workspaces.root, "fuzz")),
+	}
+}
+
+// GoVersion returns the version of the toolchain that runs submissions,
+// e.g. "go1.25.0"
+func (es *ExecutionService) GoVersion() string {
+	es.goVersionOnce.Do(func() {
+		es.goVersion = goEnv("GOVERSION")
+	})
+	return es.goVersion
 }
 
 // ExecutionResult represents the result of code execution
 type ExecutionResult struct {
-	Passed      bool   `json:"passed"`
-	Output      string `json:"output"`
-	ExecutionMs int64  `json:"executionMs"`
+	Passed       bool                     `json:"passed"`
+	Output       string                   `json:"output"`
+	ExecutionMs  int64                    `json:"executionMs"`
+	Killed       KillReason               `json:"killed,omitempty"`       // Set when a resource limit stopped the run
+	Sandbox      string                   `json:"sandbox"`                // Sandbox implementation used for the run
+	Limits       models.ExecutionLimits   `json:"limits"`                 // Limits that applied to the run
+	Tests        []*models.TestResult     `json:"tests"`                  // Per-test results, subtests nested
+	Summary      models.TestSummary       `json:"summary"`                // Pass/fail counts over all tests
+	ImportError  *ImportError             `json:"importError,omitempty"`  // Set when the code imports a package the challenge does not allow
+	Stages       []models.StageResult     `json:"stages"`                 // Result of every stage that was planned, in run order
+	Coverage     *models.CoverageReport   `json:"coverage,omitempty"`     // Statement coverage of the submitted file, when the tests ran
+	Benchmarks   []models.BenchmarkResult `json:"benchmarks,omitempty"`   // Benchmark results and baseline comparisons in benchmark mode
+	FuzzFailures []models.FuzzFailure     `json:"fuzzFailures,omitempty"` // Failing inputs found by the fuzz stage
+}
+
+// RunMode selects what a run does with the submitted code
+type RunMode string
+
+// Run modes
+const (
+	ModeTest      RunMode = "test"      // Run the tests and the challenge's stages
+	ModeSubmit    RunMode = "submit"    // Like ModeTest, then fuzz the challenge's Fuzz targets
+	ModeBenchmark RunMode = "benchmark" // Run the benchmarks and compare them with the baseline
+)
+
+// ParseRunMode validates a run mode from a request. An empty mode is ModeTest.
+func ParseRunMode(mode string) (RunMode, error) {
+	switch RunMode(mode) {
+	case "", ModeTest:
+		return ModeTest, nil
+	case ModeSubmit, ModeBenchmark:
+		return RunMode(mode), nil
+	}
+	return "", fmt.Errorf("unknown run mode %q: expected %q, %q or %q", mode, ModeTest, ModeSubmit, ModeBenchmark)
+}
+
+// Execution event types sent to streaming clients
+const (
+	EventQueued     = "queued"      // The run is waiting for a worker; carries the queue position
+	EventPhase      = "phase"       // The run moved to a new phase (setup, dependencies, build, test, benchmark)
+	EventBuild      = "build"       // Compiler output
+	EventTestStart  = "test-start"  // A test or subtest started
+	EventTestFinish = "test-finish" // A test or subtest passed, failed or was skipped
+	EventOutput     = "output"      // A line of output, attributed to a test when possible
+	EventStage      = "stage"       // A stage (gofmt, vet, test, race, lint) finished; carries its StageResult
+	EventResult     = "result"      // The run finished; carries the full ExecutionResult
+)
+
+// Run phases reported in phase events. Stages other than test use their
+// stage name as the phase.
+const (
+	PhaseSetup        = "setup"
+	PhaseDependencies = "dependencies"
+	PhaseBuild        = "build"
+	PhaseTest         = "test"
+	PhaseBenchmark    = "benchmark"
+)
+
+// ExecutionEvent is a progress update emitted while code runs
+type ExecutionEvent struct {
+	Type      string              `json:"type"`                // One of the Event constants
+	Phase     string              `json:"phase,omitempty"`     // Set on phase events
+	Message   string              `json:"message,omitempty"`   // Human-readable phase description
+	Position  int                 `json:"position,omitempty"`  // 1-based queue position on queued events
+	Test      string              `json:"test,omitempty"`      // Test the event belongs to, if any
+	Status    string              `json:"status,omitempty"`    // Test status on test-finish events
+	ElapsedMs int64               `json:"elapsedMs,omitempty"` // Test duration on test-finish events
+	Output    string              `json:"output,omitempty"`    // Output text on build and output events
+	Stage     *models.StageResult `json:"stage,omitempty"`     // Set on stage events
+	Result    *ExecutionResult    `json:"result,omitempty"`    // Set on the final result event
 }
 
 // RunCode executes the provided code against a challenge's tests
 func (es *ExecutionService) RunCode(code string, challenge *models.Challenge) ExecutionResult {
-	start := time.Now()
+	return es.Run(context.Background(), SingleFile(code), challenge, nil)
+}
 
-	// Create temporary directory for execution
-	tempDir, err := ioutil.TempDir("", "challenge-exec")
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to create temporary directory: %v", err),
-		}
+// Execute runs the provided files in the given mode on behalf of user, whose
+// saved failing fuzz inputs are replayed with the tests
+func (es *ExecutionService) Execute(ctx context.Context, mode RunMode, user string, files models.SourceFiles, challenge *models.Challenge, emit func(ExecutionEvent)) ExecutionResult {
+	if mode == ModeBenchmark {
+		return es.Benchmark(ctx, files, challenge, emit)
 	}
-	defer os.RemoveAll(tempDir)
+	return es.runTests(ctx, user, files, challenge, mode == ModeSubmit, emit)
+}
 
-	// Write the submitted code to temporary file
-	codePath := filepath.Join(tempDir, "solution-template.go")
-	err = ioutil.WriteFile(codePath, []byte(code), 0644)
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to write code file: %v", err),
-		}
+// Run executes the provided files against a challenge's tests, reporting
+// progress to emit as it happens. Cancelling ctx kills the test process.
+// emit may be nil when the caller only needs the final result.
+func (es *ExecutionService) Run(ctx context.Context, files models.SourceFiles, challenge *models.Challenge, emit func(ExecutionEvent)) ExecutionResult {
+	return es.runTests(ctx, "", files, challenge, false, emit)
+}
+
+// runTests runs the tests and stages. When user is set, the failing fuzz
+// inputs saved for them are replayed as part of the tests; when fuzz is set,
+// the fuzz stage runs after the other stages.
+func (es *ExecutionService) runTests(ctx context.Context, user string, files models.SourceFiles, challenge *models.Challenge, fuzz bool, emit func(ExecutionEvent)) ExecutionResult {
+	if emit == nil {
+		emit = func(ExecutionEvent) {}
 	}
 
-	// Write the test file to temporary directory
-	testPath := filepath.Join(tempDir, "solution_test.go")
-	err = ioutil.WriteFile(testPath, []byte(challenge.TestFile), 0644)
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to write test file: %v", err),
-		}
+	start := time.Now()
+	limits := ResolveLimits(challenge.Limits)
+	emit(ExecutionEvent{Type: EventPhase, Phase: PhaseSetup, Message: "Preparing workspace"})
+
+	tempDir, failure := es.prepareWorkspace(ctx, files, challenge, start, limits, emit)
+	if failure != nil {
+		return *failure
 	}
+	defer os.RemoveAll(tempDir)
 
-	// Initialize Go module
-	err = es.initGoModule(tempDir, challenge.ID)
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to initialize Go module: %v", err),
+	if user != "" {
+		if _, err := es.fuzz.Install(challenge.Dir, user, tempDir); err != nil {
+			log.Printf("Warning: could not install saved fuzz inputs: %v", err)
 		}
 	}
 
-	// Automatically detect and install dependencies based on imports
-	err = es.installDependencies(tempDir, code, challenge.ID)
-	if err != nil {
-		return ExecutionResult{
-			Passed: false,
-			Output: fmt.Sprintf("Failed to install dependencies: %v", err),
-		}
+	plan := ResolveStages(challenge.Stages)
+	var stages []models.StageResult
+
+	if plan.Enabled(models.StageGofmt) {
+		stages = append(stages, es.runStage(emit, plan, models.StageGofmt, func() models.StageResult {
+			return checkFormatting(files)
+		}))
+	}
+	if plan.Enabled(models.StageVet) {
+		stages = append(stages, es.runStage(emit, plan, models.StageVet, func() models.StageResult {
+			return es.runVet(ctx, tempDir, files, limits)
+		}))
 	}
 
-	// Run tests
-	cmd := exec.Command("go", "test", "-v")
-	cmd.Dir = tempDir
+	// Run tests inside the sandbox. Dependencies are already in the shared
+	// module cache, so the test run itself never needs network access.
+	testStart := time.Now()
+	emit(ExecutionEvent{Type: EventPhase, Phase: PhaseBuild, Message: "Compiling"})
+	if err := os.Mkdir(filepath.Join(tempDir, coverageDir), 0755); err != nil {
+		return es.setupFailure(ctx, start, limits, fmt.Sprintf("Failed to create coverage directory: %v", err))
+	}
+	sandboxResult := es.sandbox.Run(ctx, SandboxCommand{
+		Dir: tempDir,
+		Args: []string{"go", "test", "-json", fmt.Sprintf("-timeout=%ds", limits.TimeoutSeconds),
+			"-coverpkg=./...", "-coverprofile=" + coverageDir + "/" + coverageFile},
+		Env:       es.testEnv(),
+		Limits:    limits,
+		Stream:    newTestEventWriter(emit),
+		OutputDir: coverageDir,
+	})
 
-	output, err := cmd.CombinedOutput()
 	executionTime := time.Since(start).Milliseconds()
-	outputStr := string(output)
+	report := parseTestJSON(sandboxResult.Output)
+	outputStr := report.Output
 
 	result := ExecutionResult{
 		Output:      outputStr,
 		ExecutionMs: executionTime,
+		Killed:      sandboxResult.Killed,
+		Sandbox:     es.sandbox.Name(),
+		Limits:      limits,
+		Tests:       report.Tests,
+		Summary:     report.Summary,
+		Coverage:    readCoverage(tempDir, files),
 	}
 
-	if err == nil {
-		result.Passed = true
-	} else {
-		// Check if tests ran but failed (this is the key logic!)
-		if _, ok := err.(*exec.ExitError); ok {
-			// Test ran but failed - this means tests executed but some failed
-			result.Passed = false // Tests failed, so Passed = false
-		} else {
-			// Command couldn't be run - this is a real error
-			result.Passed = false
-			result.Output = fmt.Sprintf("Failed to run tests: %v\n%s", err, outputStr)
-		}
+	switch {
+	case sandboxResult.Err != nil:
+		// Command couldn't be run - this is a real error
+		result.Passed = false
+		result.Output = fmt.Sprintf("Failed to run tests: %v\n%s", sandboxResult.Err, outputStr)
+	case sandboxResult.Killed != KillNone:
+		// A resource limit or the user stopped the run before the tests finished
+		result.Passed = false
+		result.Output = fmt.Sprintf("%s\n\n%s", outputStr, DescribeKill(sandboxResult.Killed, limits))
+	default:
+		// Tests ran; a non-zero exit code means some of them failed
+		result.Passed = sandboxResult.ExitCode == 0
 	}
 
-	return result
-}
-
-// initGoModule initializes a Go module in the temporary directory
-func (es *ExecutionService) initGoModule(tempDir string, challengeID int) error {
-	// Initialize go.mod
-	cmd := exec.Command("go", "mod", "init", fmt.Sprintf("challenge-%d", challengeID))
-	cmd.Dir = tempDir
-	return cmd.Run()
-}
-
-// installDependencies installs dependencies for the given challenge
-func (es *ExecutionService) installDependencies(tempDir string, code string, challengeID int) error {
-	// Detect imports from the code
-	requiredPackages := es.detectRequiredPackages(code, challengeID)
-
-	if len(requiredPackages) == 0 {
-		return nil // No external dependencies needed
+	testStage := models.StageResult{
+		Name:      models.StageTest,
+		Status:    models.StageStatusFail,
+		Required:  true,
+		ElapsedMs: time.Since(testStart).Milliseconds(),
+	}
+	if result.Passed {
+		testStage.Status = models.StageStatusPass
+	} else if sandboxResult.Err != nil {
+		testStage.Status = models.StageStatusError
+	}
+	emit(ExecutionEvent{Type: EventStage, Stage: &testStage})
+	stages = append(stages, testStage)
+
+	// The race detector runs the tests again, so it only runs once they pass
+	if plan.Enabled(models.StageRace) {
+		switch {
+		case result.Killed != KillNone:
+			stages = append(stages, skippedStage(plan, models.StageRace, "Skipped because the run was stopped"))
+		case !result.Passed:
+			stages = append(stages, skippedStage(plan, models.StageRace, "Skipped because the tests did not pass"))
+		default:
+			stages = append(stages, es.runStage(emit, plan, models.StageRace, func() models.StageResult {
+				stage, killed := es.runRace(ctx, tempDir, files, limits)
+				if killed == KillCancelled {
+					result.Passed = false
+					result.Killed = KillCancelled
+					result.Output = fmt.Sprintf("%s\n\n%s", result.Output, DescribeKill(KillCancelled, limits))
+				}
+				return stage
+			}))
+		}
 	}
 
-	// Install each required package
-	for _, pkg := range requiredPackages {
-		fmt.Printf("Installing dependency: %s\n", pkg)
-		cmd := exec.Command("go", "get", pkg)
-		cmd.Dir = tempDir
-
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("failed to install package %s: %v\nOutput: %s", pkg, err, string(output))
+	if plan.Enabled(models.StageLint) {
+		if result.Killed != KillNone {
+			stages = append(stages, skippedStage(plan, models.StageLint, "Skipped because the run was stopped"))
+		} else {
+			stages = append(stages, es.runStage(emit, plan, models.StageLint, func() models.StageResult {
+				return es.runLint(ctx, tempDir, files)
+			}))
 		}
 	}
 
-	// Run go mod tidy to clean up dependencies
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = tempDir
-	tidyCmd.Run() // Ignore errors for tidy
+	// Fuzzing takes its whole time budget, so it only runs on submissions
+	// whose tests pass
+	targets := FuzzTargets(challenge.TestFile)
+	if fuzz && plan.Enabled(models.StageFuzz) && len(targets) > 0 {
+		switch {
+		case result.Killed != KillNone:
+			stages = append(stages, skippedStage(plan, models.StageFuzz, "Skipped because the run was stopped"))
+		case !result.Passed:
+			stages = append(stages, skippedStage(plan, models.StageFuzz, "Skipped because the tests did not pass"))
+		default:
+			stages = append(stages, es.runStage(emit, plan, models.StageFuzz, func() models.StageResult {
+				stage, failures, killed := es.runFuzz(ctx, tempDir, targets, ResolveFuzzConfig(challenge.Fuzz), limits)
+				if killed == KillCancelled {
+					result.Passed = false
+					result.Killed = KillCancelled
+					result.Output = fmt.Sprintf("%s\n\n%s", result.Output, DescribeKill(KillCancelled, limits))
+				}
+				if len(failures) > 0 {
+					result.FuzzFailures = failures
+					if user != "" {
+						if err := es.fuzz.Save(challenge.Dir, user, failures); err != nil {
+							log.Printf("Warning: could not save fuzz inputs: %v", err)
+						}
+					}
+				}
+				return stage
+			}))
+		}
+	}
 
-	return nil
+	result.Stages = stages
+	result.Passed = result.Passed && requiredStagesPassed(stages)
+	result.ExecutionMs = time.Since(start).Milliseconds()
+	return result
 }
 
-// detectRequiredPackages analyzes the code to detect required external packages
-func (es *ExecutionService) detectRequiredPackages(code string, challengeID int) []string {
-	packages := make(map[string]bool)
-
-	// Common package mappings for known dependencies
-	knownPackages := map[string][]string{
-		"github.com/mattn/go-sqlite3": {"github.com/mattn/go-sqlite3"},
-		"database/sql":                {"github.com/mattn/go-sqlite3"}, // SQLite driver needed for database/sql
-		"google.golang.org/grpc":      {"google.golang.org/grpc", "google.golang.org/grpc/codes", "google.golang.org/grpc/status"},
-		"github.com/google/uuid":      {"github.com/google/uuid"},
-		"github.com/gorilla/mux":      {"github.com/gorilla/mux"},
-		"github.com/gin-gonic/gin":    {"github.com/gin-gonic/gin"},
-		"github.com/stretchr/testify": {"github.com/stretchr/testify"},
-		"go.mongodb.org/mongo-driver": {"go.mongodb.org/mongo-driver/mongo", "go.mongodb.org/mongo-driver/bson"},
-		"github.com/redis/go-redis":   {"github.com/redis/go-redis/v9"},
-		"gorm.io/gorm":                {"gorm.io/gorm", "gorm.io/driver/sqlite"},
-	}
-
-	// Challenge-specific package requirements
-	challengePackages := map[int][]string{
-		13: {"github.com/mattn/go-sqlite3"},                                                             // SQL Database Operations
-		14: {"google.golang.org/grpc", "google.golang.org/grpc/codes", "google.golang.org/grpc/status"}, // gRPC
-		9:  {"github.com/google/uuid"},                                                                  // RESTful API with UUID
-	}
-
-	// Add challenge-specific packages
-	if challengePkgs, exists := challengePackages[challengeID]; exists {
-		for _, pkg := range challengePkgs {
-			packages[pkg] = true
+// prepareWorkspace creates a temporary workspace with the submitted code,
+// the challenge's tests and its resolved module, and checks the code's
+// imports. It returns the workspace directory, which the caller removes, or
+// the result to report when the run cannot go ahead.
+func (es *ExecutionService) prepareWorkspace(ctx context.Context, files models.SourceFiles, challenge *models.Challenge, start time.Time, limits models.ExecutionLimits, emit func(ExecutionEvent)) (string, *ExecutionResult) {
+	// Create temporary directory for execution
+	tempDir, err := ioutil.TempDir("", "challenge-exec")
+	if err != nil {
+		return "", &ExecutionResult{
+			Passed: false,
+			Output: fmt.Sprintf("Failed to create temporary directory: %v", err),
 		}
 	}
+	failed := func(result ExecutionResult) (string, *ExecutionResult) {
+		os.RemoveAll(tempDir)
+		return "", &result
+	}
 
-	// Scan code for import statements
-	lines := strings.Split(code, "\n")
-	inImportBlock := false
+	// Write the submitted files into the temporary directory
+	err = writeFiles(tempDir, files)
+	if err != nil {
+		return failed(ExecutionResult{
+			Passed: false,
+			Output: fmt.Sprintf("Failed to write code files: %v", err),
+		})
+	}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	// Write the test file to temporary directory
+	testPath := filepath.Join(tempDir, "solution_test.go")
+	err = ioutil.WriteFile(testPath, []byte(challenge.TestFile), 0644)
+	if err != nil {
+		return failed(ExecutionResult{
+			Passed: false,
+			Output: fmt.Sprintf("Failed to write test file: %v", err),
+		})
+	}
 
-		// Handle import blocks
-		if strings.HasPrefix(line, "import (") {
-			inImportBlock = true
-			continue
-		}
-		if inImportBlock && line == ")" {
-			inImportBlock = false
-			continue
+	// Start from the challenge's warmed module when there is one, so the
+	// run needs no network access at all; otherwise use its own go.mod
+	warm, err := es.workspaces.Prepare(challenge.Dir, tempDir)
+	if err != nil {
+		return failed(es.setupFailure(ctx, start, limits, fmt.Sprintf("Failed to prepare workspace: %v", err)))
+	}
+	if !warm {
+		err = es.copyModuleFiles(ctx, challenge, tempDir)
+		if err != nil {
+			return failed(es.setupFailure(ctx, start, limits, fmt.Sprintf("Failed to initialize Go module: %v", err)))
 		}
+	}
 
-		// Handle single import statements
-		if strings.HasPrefix(line, "import ") || inImportBlock {
-			// Extract import path
-			importPath := es.extractImportPath(line)
-			if importPath != "" {
-				// Check if this import requires external packages
-				if deps, exists := knownPackages[importPath]; exists {
-					for _, dep := range deps {
-						packages[dep] = true
-					}
-				} else if es.isExternalPackage(importPath) {
-					packages[importPath] = true
-				}
-			}
+	// Only packages the challenge's module provides may be imported
+	module, err := readModuleInfo(ctx, filepath.Join(tempDir, "go.mod"))
+	if err != nil {
+		return failed(es.setupFailure(ctx, start, limits, err.Error()))
+	}
+	for _, name := range SortedPaths(files) {
+		if importErr := checkImports(name, files[name], module); importErr != nil {
+			result := es.setupFailure(ctx, start, limits, importErr.Message)
+			result.ImportError = importErr
+			return failed(result)
 		}
 	}
 
-	// Convert map to slice
-	result := make([]string, 0, len(packages))
-	for pkg := range packages {
-		result = append(result, pkg)
+	if !warm {
+		// Download what the code and tests need into the shared cache
+		emit(ExecutionEvent{Type: EventPhase, Phase: PhaseDependencies, Message: "Resolving dependencies"})
+		err = es.downloadDependencies(ctx, tempDir)
+		if err != nil {
+			return failed(es.setupFailure(ctx, start, limits, fmt.Sprintf("Failed to install dependencies: %v", err)))
+		}
 	}
 
-	return result
+	return tempDir, nil
 }
 
-// extractImportPath extracts the import path from an import line
-func (es *ExecutionService) extractImportPath(line string) string {
-	// Remove 'import' keyword
-	line = strings.TrimPrefix(line, "import")
-	line = strings.TrimSpace(line)
-
-	// Remove quotes and comments
-	if strings.Contains(line, "\"") {
-		parts := strings.Split(line, "\"")
-		if len(parts) >= 2 {
-			return parts[1]
-		}
+// setupFailure builds the result for a run that failed before the tests
+// started, reporting a cancellation instead of the error it caused
+func (es *ExecutionService) setupFailure(ctx context.Context, start time.Time, limits models.ExecutionLimits, message string) ExecutionResult {
+	result := ExecutionResult{
+		Passed:      false,
+		Output:      message,
+		ExecutionMs: time.Since(start).Milliseconds(),
+		Sandbox:     es.sandbox.Name(),
+		Limits:      limits,
 	}
+	if ctx.Err() != nil {
+		result.Killed = KillCancelled
+		result.Output = DescribeKill(KillCancelled, limits)
+	}
+	return result
+}
 
-	return ""
+// testEnv returns the environment for sandboxed test runs: offline module
+// resolution against the shared module cache. Checksums of cached modules
+// were verified when they were downloaded, so the checksum database is off.
+func (es *ExecutionService) testEnv() []string {
+	env := overrideEnv(os.Environ(), es.workspaces.CacheEnv())
+	return overrideEnv(env, map[string]string{
+		"GOFLAGS": "-mod=mod",
+		"GOPROXY": "off",
+		"GOSUMDB": "off",
+	})
 }
 
-// isExternalPackage determines if an import path is an external package
-func (es *ExecutionService) isExternalPackage(importPath string) bool {
-	// Standard library packages (don't need go get)
-	standardLibs := map[string]bool{
-		"fmt": true, "strings": true, "strconv": true, "time": true,
-		"os": true, "io": true, "bufio": true, "bytes": true,
-		"encoding/json": true, "encoding/xml": true, "encoding/csv": true,
-		"net/http": true, "net/url": true, "net": true,
-		"database/sql": true, "context": true, "sync": true,
-		"regexp": true, "sort": true, "math": true, "crypto": true,
-		"log": true, "errors": true, "flag": true, "path": true,
-		"path/filepath": true, "testing": true, "reflect": true,
-	}
+// resolveEnv returns the environment for dependency resolution on the host,
+// which downloads into the shared caches
+func (es *ExecutionService) resolveEnv() []string {
+	return overrideEnv(os.Environ(), es.workspaces.CacheEnv())
+}
 
-	// Check if it's a standard library package
-	if standardLibs[importPath] {
-		return false
+// copyModuleFiles copies the challenge's go.mod and go.sum into the
+// workspace, or initializes a standard-library-only module for challenges
+// without one
+func (es *ExecutionService) copyModuleFiles(ctx context.Context, challenge *models.Challenge, tempDir string) error {
+	goMod, err := ioutil.ReadFile(filepath.Join(challenge.Dir, "go.mod"))
+	if err != nil {
+		cmd := exec.CommandContext(ctx, "go", "mod", "init", fmt.Sprintf("challenge-%d", challenge.ID))
+		cmd.Dir = tempDir
+		cmd.Env = es.resolveEnv()
+		return cmd.Run()
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "go.mod"), goMod, 0644); err != nil {
+		return err
 	}
 
-	// Check if it starts with standard library prefixes
-	standardPrefixes := []string{
-		"crypto/", "encoding/", "net/", "os/", "path/",
-		"text/", "html/", "image/", "go/", "runtime/",
-		"unicode/", "mime/", "archive/", "compress/",
-		"container/", "debug/", "index/", "internal/",
+	if goSum, err := ioutil.ReadFile(filepath.Join(challenge.Dir, "go.sum")); err == nil {
+		return ioutil.WriteFile(filepath.Join(tempDir, "go.sum"), goSum, 0644)
 	}
+	return nil
+}
+
+// downloadDependencies resolves every package the code and tests import
+// against the challenge's module, downloading missing modules into the
+// shared cache and recording their checksums in go.sum
+func (es *ExecutionService) downloadDependencies(ctx context.Context, tempDir string) error {
+	cmd := exec.CommandContext(ctx, "go", "list", "-mod=mod", "-deps", "-test", "./...")
+	cmd.Dir = tempDir
+	cmd.Env = es.resolveEnv()
 
-	for _, prefix := range standardPrefixes {
-		if strings.HasPrefix(importPath, prefix) {
-			return false
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// Compile errors are reported by the test run; only fail on
+		// problems reaching or verifying modules
+		if strings.Contains(string(output), "go: ") {
+			return fmt.Errorf("%v\nOutput: %s", err, string(output))
 		}
 	}
-
-	// If it contains a dot, it's likely an external package
-	return strings.Contains(importPath, ".")
+	return nil
 }
 
 // SaveSubmissionRequest represents a request to save a submission to filesystem
 type SaveSubmissionRequest struct {
-	Username    string `json:"username"`
-	ChallengeID int    `json:"challengeId"`
-	Code        string `json:"code"`
+	Username    string             `json:"username"`
+	ChallengeID int                `json:"challengeId"`
+	Code        string             `json:"code"`
+	Files       models.SourceFiles `json:"files"` // Set instead of code for multi-file submissions
 }
 
 // SaveSubmissionResponse represents the response from saving a submission
@@ -292,6 +475,11 @@ type SaveSubmissionResponse struct {
 
 // SaveSubmissionToFilesystem saves a user's submission to the filesystem
 func (es *ExecutionService) SaveSubmissionToFilesystem(request SaveSubmissionRequest) SaveSubmissionResponse {
+	files, err := SubmissionFiles(request.Code, request.Files)
+	if err != nil {
+		return SaveSubmissionResponse{Success: false, Message: err.Error()}
+	}
+
 	// Get working directory for correct relative paths
 	workDir, _ := os.Getwd()
 
@@ -315,9 +503,7 @@ func (es *ExecutionService) SaveSubmissionToFilesystem(request SaveSubmissionReq
 			continue
 		}
 
-		solutionFile := filepath.Join(dirPath, "solution-template.go")
-		err = ioutil.WriteFile(solutionFile, []byte(request.Code), 0644)
-		if err != nil {
+		if err := SaveFiles(dirPath, files); err != nil {
 			continue
 		}
 
@@ -333,14 +519,16 @@ func (es *ExecutionService) SaveSubmissionToFilesystem(request SaveSubmissionReq
 		}
 	}
 
-	// Return success response with git commands
+	// Return success response with git commands. The whole directory is
+	// added so files removed since an earlier save are removed in git too.
+	relativePath := filepath.Join(fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username) + string(filepath.Separator)
 	return SaveSubmissionResponse{
 		Success:  true,
 		Message:  "Solution saved to filesystem",
-		FilePath: filepath.Join(submissionDir, "solution-template.go"),
+		FilePath: filepath.Join(submissionDir, filepath.FromSlash(MainFileOf(files))),
 		GitCommands: []string{
 			"cd " + filepath.Join(workDir, ".."),
-			fmt.Sprintf("git add %s", filepath.Join(fmt.Sprintf("challenge-%d", request.ChallengeID), "submissions", request.Username, "solution-template.go")),
+			fmt.Sprintf("git add %s", relativePath),
 			fmt.Sprintf("git commit -m \"Add solution for Challenge %d\"", request.ChallengeID),
 			"git push origin main",
 		},
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// MainFile is the file a single-file submission is run as. Package
+// challenges save it as PackageMainFile instead.
+const (
+	MainFile        = "solution-template.go"
+	PackageMainFile = "solution.go"
+)
+
+// Limits on a multi-file submission
+const (
+	MaxSubmissionFiles = 20
+	MaxSubmissionBytes = 512 * 1024 // Total size of all files
+)
+
+// SingleFile is the file tree of a single-file submission
+func SingleFile(code string) models.SourceFiles {
+	return models.SourceFiles{MainFile: code}
+}
+
+// SubmissionFiles returns the file tree of a request that sends either a
+// single file as code or a tree as files, after checking the tree
+func SubmissionFiles(code string, files models.SourceFiles) (models.SourceFiles, error) {
+	if len(files) == 0 {
+		files = SingleFile(code)
+	}
+	if err := ValidateFiles(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ValidateFiles checks a submission's file tree: at most MaxSubmissionFiles
+// Go files and MaxSubmissionBytes in total, at clean relative paths, with at
+// least one in the package directory itself. Test files, hidden files and
+// testdata are refused so the challenge's tests stay the only tests.
+func ValidateFiles(files models.SourceFiles) error {
+	if len(files) == 0 {
+		return fmt.Errorf("the submission has no files")
+	}
+	if len(files) > MaxSubmissionFiles {
+		return fmt.Errorf("the submission has %d files; at most %d are allowed", len(files), MaxSubmissionFiles)
+	}
+
+	total := 0
+	rootFile := false
+	for name, content := range files {
+		if err := validatePath(name); err != nil {
+			return err
+		}
+		total += len(content)
+		if !strings.Contains(name, "/") {
+			rootFile = true
+		}
+	}
+	if total > MaxSubmissionBytes {
+		return fmt.Errorf("the submission is %d bytes; at most %d are allowed", total, MaxSubmissionBytes)
+	}
+	if !rootFile {
+		return fmt.Errorf("the submission needs at least one file in the challenge's package directory")
+	}
+	return nil
+}
+
+// validatePath checks one path of a submission's file tree
+func validatePath(name string) error {
+	switch {
+	case name == "" || path.Clean(name) != name || path.IsAbs(name) || strings.Contains(name, `\`):
+		return fmt.Errorf("%q is not a clean relative path", name)
+	case !strings.HasSuffix(name, ".go"):
+		return fmt.Errorf("%q is not a Go file; only .go files can be submitted", name)
+	case strings.HasSuffix(name, "_test.go"):
+		return fmt.Errorf("%q is a test file; the challenge's own tests are used", name)
+	}
+	for _, element := range strings.Split(name, "/") {
+		if element == ".." || strings.HasPrefix(element, ".") || strings.HasPrefix(element, "_") ||
+			element == "testdata" || element == "vendor" {
+			return fmt.Errorf("%q contains %q, which the go command ignores or treats specially", name, element)
+		}
+	}
+	return nil
+}
+
+// SortedPaths returns a file tree's paths in order
+func SortedPaths(files models.SourceFiles) []string {
+	paths := make([]string, 0, len(files))
+	for name := range files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// FilesHash identifies a file tree by its paths and contents, so the same
+// code can be recognised wherever it was saved or run
+func FilesHash(files models.SourceFiles) string {
+	hash := sha256.New()
+	for _, name := range SortedPaths(files) {
+		fmt.Fprintf(hash, "%s\x00%d\x00%s", name, len(files[name]), files[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// MainFileOf returns the file of a tree that stands for the whole
+// submission where only one file fits, like the AI review or coverage
+// shading: MainFile or PackageMainFile when present, otherwise the first
+// file in the package directory
+func MainFileOf(files models.SourceFiles) string {
+	for _, name := range []string{MainFile, PackageMainFile} {
+		if _, ok := files[name]; ok {
+			return name
+		}
+	}
+	for _, name := range SortedPaths(files) {
+		if !strings.Contains(name, "/") {
+			return name
+		}
+	}
+	return ""
+}
+
+// submittedPath maps a file name reported by a tool, which may be absolute,
+// relative or qualified by import path, to the submitted file it names.
+// The longest matching path wins. It reports false for files that were not
+// submitted, like the challenge's tests.
+func submittedPath(files models.SourceFiles, reported string) (string, bool) {
+	reported = filepath.ToSlash(reported)
+	best := ""
+	for name := range files {
+		if (reported == name || strings.HasSuffix(reported, "/"+name)) && len(name) > len(best) {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+// writeFiles writes a file tree into a directory
+func writeFiles(dir string, files models.SourceFiles) error {
+	for _, name := range SortedPaths(files) {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, []byte(files[name]), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveFiles writes a submission's file tree into its directory, removing
+// the Go files an earlier save left there that the tree no longer has, and
+// the subdirectories that leaves empty
+func SaveFiles(dir string, files models.SourceFiles) error {
+	for name := range ReadSubmissionFiles(dir) {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+		// Remove fails on the first directory that is not empty
+		for parent := filepath.Dir(target); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	return writeFiles(dir, files)
+}
+
+// ReadSubmissionFiles reads the Go files saved in a submission directory,
+// skipping tests and anything ValidateFiles would refuse. It returns nil if
+// the directory holds no Go files.
+func ReadSubmissionFiles(dir string) models.SourceFiles {
+	files := make(models.SourceFiles)
+	filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || len(files) >= MaxSubmissionFiles {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil
+		}
+		name := filepath.ToSlash(relative)
+		if validatePath(name) != nil {
+			return nil
+		}
+		content, err := ioutil.ReadFile(file)
+		if err == nil {
+			files[name] = string(content)
+		}
+		return nil
+	})
+	if len(files) == 0 {
+		return nil
+	}
+	return files
+}
+
+// LatestSubmissionTime returns when a submission directory's newest Go file
+// was written, and false if it holds no Go files
+func LatestSubmissionTime(dir string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return nil
+		}
+		found = true
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, found
+}
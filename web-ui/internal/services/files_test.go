@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"solution-template.go", true},
+		{"internal/store/store.go", true},
+		{"cmd/tool/main.go", true},
+		{"", false},
+		{"../solution-template.go", false},
+		{"internal/../../escape.go", false},
+		{"internal/../store.go", false},
+		{"./solution-template.go", false},
+		{"internal//store.go", false},
+		{"/etc/passwd.go", false},
+		{"/tmp/solution-template.go", false},
+		{`internal\store.go`, false},
+		{"go.mod", false},
+		{"go.sum", false},
+		{"internal/go.mod", false},
+		{"go.work", false},
+		{"README.md", false},
+		{"solution-template_test.go", false},
+		{"internal/store/store_test.go", false},
+		{".hidden.go", false},
+		{"_ignored.go", false},
+		{"internal/.git/hook.go", false},
+		{"testdata/fixture.go", false},
+		{"vendor/example.com/lib/lib.go", false},
+	}
+	for _, tt := range tests {
+		if err := validatePath(tt.name); (err == nil) != tt.valid {
+			t.Errorf("validatePath(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateFiles(t *testing.T) {
+	tooMany := models.SourceFiles{MainFile: "package main\n"}
+	for i := 1; i < MaxSubmissionFiles+1; i++ {
+		tooMany[fmt.Sprintf("file%d.go", i)] = "package main\n"
+	}
+
+	tests := []struct {
+		name  string
+		files models.SourceFiles
+		valid bool
+	}{
+		{"single file", SingleFile("package main\n"), true},
+		{"package tree", models.SourceFiles{MainFile: "package main\n", "internal/store/store.go": "package store\n"}, true},
+		{"empty", models.SourceFiles{}, false},
+		{"go.mod override", models.SourceFiles{MainFile: "package main\n", "go.mod": "module evil\n"}, false},
+		{"nested go.mod", models.SourceFiles{MainFile: "package main\n", "internal/go.mod": "module evil\n"}, false},
+		{"parent directory", models.SourceFiles{MainFile: "package main\n", "../main_test.go": "package main\n"}, false},
+		{"absolute path", models.SourceFiles{MainFile: "package main\n", "/tmp/escape.go": "package main\n"}, false},
+		{"test file override", models.SourceFiles{MainFile: "package main\n", "solution-template_test.go": "package main\n"}, false},
+		{"only subdirectories", models.SourceFiles{"internal/store/store.go": "package store\n"}, false},
+		{"too many files", tooMany, false},
+		{"too large", models.SourceFiles{MainFile: strings.Repeat("/", MaxSubmissionBytes+1)}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateFiles(tt.files); (err == nil) != tt.valid {
+			t.Errorf("%s: ValidateFiles = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestSubmittedPath(t *testing.T) {
+	files := models.SourceFiles{MainFile: "", "store.go": "", "internal/store/store.go": ""}
+	tests := []struct {
+		reported string
+		want     string
+	}{
+		{"solution-template.go", MainFile},
+		{"/tmp/sandbox-root/work/solution-template.go", MainFile},
+		{"./store.go", "store.go"},
+		{"/tmp/sandbox-root/work/internal/store/store.go", "internal/store/store.go"},
+		{"challenge14/internal/store/store.go", "internal/store/store.go"},
+		{"/tmp/sandbox-root/work/solution-template_test.go", ""},
+		{"/usr/local/go/src/runtime/proc.go", ""},
+	}
+	for _, tt := range tests {
+		got, ok := submittedPath(files, tt.reported)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("submittedPath(%q) = %q, %v, want %q", tt.reported, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"web-ui/internal/models"
+)
+
+// Defaults for challenges whose metadata does not configure fuzzing
+const (
+	DefaultFuzzTime         = "10s"
+	DefaultFuzzMinimizeTime = "10s"
+)
+
+// fuzzParallel is how many fuzzing workers a target gets; the execution
+// queue already runs several jobs side by side
+const fuzzParallel = 2
+
+// fuzzCorpusDir is where the go command reads a package's seed corpus and
+// writes the failing inputs it finds, relative to the package directory
+const fuzzCorpusDir = "testdata/fuzz"
+
+// fuzzFuncRe matches a fuzz target declaration and captures its name
+var fuzzFuncRe = regexp.MustCompile(`(?m)^func (Fuzz\w*)\(\w+ \*testing\.F\)`)
+
+// FuzzTargets returns the names of the fuzz targets a test file declares
+func FuzzTargets(testFile string) []string {
+	var targets []string
+	for _, match := range fuzzFuncRe.FindAllStringSubmatch(testFile, -1) {
+		targets = append(targets, match[1])
+	}
+	return targets
+}
+
+// ResolveFuzzConfig fills in the defaults for a challenge's fuzz settings
+func ResolveFuzzConfig(config models.FuzzConfig) models.FuzzConfig {
+	if config.Time == "" {
+		config.Time = DefaultFuzzTime
+	}
+	if config.MinimizeTime == "" {
+		config.MinimizeTime = DefaultFuzzMinimizeTime
+	}
+	return config
+}
+
+// FuzzStore keeps the failing inputs the fuzz stage found for each user and
+// challenge. Every later run by the same user gets them as seed corpus, so
+// `go test` replays them until the code handles them.
+type FuzzStore struct {
+	root string
+	mu   sync.Mutex
+}
+
+// NewFuzzStore creates a store that keeps its corpora under root
+func NewFuzzStore(root string) *FuzzStore {
+	return &FuzzStore{root: root}
+}
+
+// dir returns the corpus directory of a user's runs of a challenge. Users
+// are hashed since they may be client addresses.
+func (s *FuzzStore) dir(challengeDir, user string) string {
+	sum := sha256.Sum256([]byte(user))
+	return filepath.Join(s.root, templateName(challengeDir), hex.EncodeToString(sum[:8]))
+}
+
+// Save records failing inputs for a user's runs of a challenge
+func (s *FuzzStore) Save(challengeDir, user string, failures []models.FuzzFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, failure := range failures {
+		dir := filepath.Join(s.dir(challengeDir, user), failure.Target)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, failure.Name), []byte(failure.Input), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Install copies a user's saved failing inputs for a challenge into a
+// workspace's corpus directory and returns how many it copied
+func (s *FuzzStore) Install(challengeDir, user, workDir string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source := s.dir(challengeDir, user)
+	targets, err := ioutil.ReadDir(source)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	installed := 0
+	for _, target := range targets {
+		if !target.IsDir() {
+			continue
+		}
+		entries, err := ioutil.ReadDir(filepath.Join(source, target.Name()))
+		if err != nil {
+			return installed, err
+		}
+		dir := filepath.Join(workDir, fuzzCorpusDir, target.Name())
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return installed, err
+		}
+		for _, entry := range entries {
+			content, err := ioutil.ReadFile(filepath.Join(source, target.Name(), entry.Name()))
+			if err != nil {
+				return installed, err
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, entry.Name()), content, 0644); err != nil {
+				return installed, err
+			}
+			installed++
+		}
+	}
+	return installed, nil
+}
+
+// runFuzz fuzzes each target in turn for the configured time. The corpus
+// directory is the sandbox's writable output, so the failing inputs the go
+// command writes there can be read back. It stops at the first target that
+// fails, since later targets would only repeat the same bug.
+func (es *ExecutionService) runFuzz(ctx context.Context, tempDir string, targets []string, config models.FuzzConfig, limits models.ExecutionLimits) (models.StageResult, []models.FuzzFailure, KillReason) {
+	if err := os.MkdirAll(filepath.Join(tempDir, fuzzCorpusDir), 0755); err != nil {
+		return models.StageResult{Status: models.StageStatusError, Output: err.Error()}, nil, KillNone
+	}
+
+	var fuzzed []string
+	for _, target := range targets {
+		existing := make(map[string]bool)
+		for _, name := range corpusEntries(tempDir, target) {
+			existing[name] = true
+		}
+		sandboxResult := es.sandbox.Run(ctx, SandboxCommand{
+			Dir: tempDir,
+			Args: []string{"go", "test", "-run=^$",
+				"-fuzz=^" + target + "$",
+				"-fuzztime=" + config.Time,
+				"-fuzzminimizetime=" + config.MinimizeTime,
+				fmt.Sprintf("-parallel=%d", fuzzParallel),
+				fmt.Sprintf("-timeout=%ds", limits.TimeoutSeconds),
+			},
+			Env:       es.testEnv(),
+			Limits:    limits,
+			OutputDir: "testdata",
+		})
+		output := string(sandboxResult.Output)
+
+		switch {
+		case sandboxResult.Err != nil:
+			return models.StageResult{Status: models.StageStatusError, Output: fmt.Sprintf("Failed to run %s: %v", target, sandboxResult.Err)}, nil, KillNone
+		case sandboxResult.Killed != KillNone:
+			return models.StageResult{
+				Status: models.StageStatusError,
+				Output: fmt.Sprintf("%s\n\n%s", output, DescribeKill(sandboxResult.Killed, limits)),
+			}, nil, sandboxResult.Killed
+		case sandboxResult.ExitCode == 0:
+			fuzzed = append(fuzzed, target)
+			continue
+		}
+
+		var failures []models.FuzzFailure
+		for _, name := range corpusEntries(tempDir, target) {
+			if existing[name] {
+				continue
+			}
+			file := fuzzCorpusDir + "/" + target + "/" + name
+			input, err := ReadOutputFile(tempDir, filepath.FromSlash(file))
+			if err != nil {
+				continue
+			}
+			failures = append(failures, models.FuzzFailure{
+				Target:  target,
+				Name:    name,
+				File:    file,
+				Input:   string(input),
+				Message: fuzzFailureMessage(output),
+				Replay:  "go test -run=" + target + "/" + name,
+			})
+		}
+		if len(failures) == 0 {
+			// The fuzzer failed without finding an input, e.g. on a build error
+			return models.StageResult{Status: models.StageStatusError, Output: output}, nil, KillNone
+		}
+
+		var report strings.Builder
+		for _, failure := range failures {
+			fmt.Fprintf(&report, "%s failed on input %s:\n%s\n\n%s\nReplay it with: %s\n",
+				failure.Target, failure.Name, failure.Message, failure.Input, failure.Replay)
+		}
+		return models.StageResult{Status: models.StageStatusFail, Output: strings.TrimSpace(report.String())}, failures, KillNone
+	}
+
+	return models.StageResult{
+		Status: models.StageStatusPass,
+		Output: fmt.Sprintf("No failing inputs found in %s of fuzzing per target: %s", config.Time, strings.Join(fuzzed, ", ")),
+	}, nil, KillNone
+}
+
+// corpusEntries lists the corpus files of a fuzz target in a workspace,
+// sorted by name. The run can write anything there, so only regular files
+// count.
+func corpusEntries(tempDir, target string) []string {
+	entries, err := ReadOutputDir(tempDir, filepath.Join(fuzzCorpusDir, target))
+	if err != nil {
+		return nil
+	}
+	return entries
+}
+
+// fuzzFailureMessage extracts what the failing target reported from the go
+// command's output: the lines between its "--- FAIL" header and the note
+// about where the input was written
+func fuzzFailureMessage(output string) string {
+	start := strings.Index(output, "--- FAIL:")
+	if start < 0 {
+		return strings.TrimSpace(output)
+	}
+	body := output[start:]
+	if end := strings.Index(body, "Failing input written to"); end >= 0 {
+		body = body[:end]
+	}
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--- FAIL:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
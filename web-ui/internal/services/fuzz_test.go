@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+func TestFuzzTargets(t *testing.T) {
+	testFile := `package main
+
+func TestReverse(t *testing.T) {}
+
+func FuzzReverse(f *testing.F) {}
+
+// func FuzzCommented(f *testing.F) {}
+
+func FuzzPalindrome(fuzz *testing.F) {}
+
+func FuzzHelper(t *testing.T) {}
+`
+	if targets, want := FuzzTargets(testFile), []string{"FuzzReverse", "FuzzPalindrome"}; !slices.Equal(targets, want) {
+		t.Errorf("targets %q, want %q", targets, want)
+	}
+	if targets := FuzzTargets("package main\n"); targets != nil {
+		t.Errorf("targets %q in a file without any", targets)
+	}
+}
+
+func TestFuzzFailureMessage(t *testing.T) {
+	output := `fuzz: elapsed: 0s, gathering baseline coverage: 0/3 completed
+fuzz: elapsed: 0s, gathering baseline coverage: 3/3 completed, now fuzzing with 2 workers
+fuzz: minimizing 38-byte failing input file
+fuzz: elapsed: 1s, minimizing
+--- FAIL: FuzzReverse (0.41s)
+    --- FAIL: FuzzReverse (0.00s)
+        reverse_test.go:20: Reverse("\x91") = "\ufffd", which has length 3, expected 1
+
+    Failing input written to testdata/fuzz/FuzzReverse/582528ddfad69eb5
+    To re-run:
+    go test -run=FuzzReverse/582528ddfad69eb5
+FAIL
+exit status 1
+FAIL	challenge2	0.421s
+`
+	want := `reverse_test.go:20: Reverse("\x91") = "\ufffd", which has length 3, expected 1`
+	if got := fuzzFailureMessage(output); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := fuzzFailureMessage("  build failed\n"); got != "build failed" {
+		t.Errorf("output without a failing test became %q", got)
+	}
+}
+
+func TestFuzzStore(t *testing.T) {
+	store := NewFuzzStore(t.TempDir())
+	challengeDir := filepath.Join(t.TempDir(), "challenge-2")
+	failures := []models.FuzzFailure{
+		{Target: "FuzzReverse", Name: "582528ddfad69eb5", Input: "go test fuzz v1\nstring(\"\\x91\")\n"},
+		{Target: "FuzzReverse", Name: "7a1b3c", Input: "go test fuzz v1\nstring(\"\\xff\")\n"},
+		{Target: "FuzzPalindrome", Name: "00aa", Input: "go test fuzz v1\nstring(\"Aa\")\n"},
+	}
+	if err := store.Save(challengeDir, "user:alice", failures); err != nil {
+		t.Fatal(err)
+	}
+
+	workDir := t.TempDir()
+	installed, err := store.Install(challengeDir, "user:alice", workDir)
+	if err != nil || installed != len(failures) {
+		t.Fatalf("Install = %d, %v, want %d inputs", installed, err, len(failures))
+	}
+	for _, failure := range failures {
+		input, err := os.ReadFile(filepath.Join(workDir, fuzzCorpusDir, failure.Target, failure.Name))
+		if err != nil || string(input) != failure.Input {
+			t.Errorf("%s/%s installed as %q, %v", failure.Target, failure.Name, input, err)
+		}
+	}
+
+	// Inputs belong to the user and challenge whose runs found them
+	for _, other := range []struct{ challengeDir, user string }{
+		{challengeDir, "user:bob"},
+		{filepath.Join(t.TempDir(), "challenge-2"), "user:alice"},
+	} {
+		if installed, err := store.Install(other.challengeDir, other.user, t.TempDir()); installed != 0 || err != nil {
+			t.Errorf("Install for %s in %s = %d, %v, want nothing", other.user, other.challengeDir, installed, err)
+		}
+	}
+}
+
+// corpusSandbox stands in for a fuzzing run: it calls write with the
+// workspace and then fails
+type corpusSandbox struct {
+	write func(dir string)
+}
+
+func (s corpusSandbox) Name() string { return "corpus" }
+
+func (s corpusSandbox) Run(ctx context.Context, cmd SandboxCommand) SandboxResult {
+	s.write(cmd.Dir)
+	return SandboxResult{
+		Output:   []byte("--- FAIL: FuzzReverse (0.10s)\n    --- FAIL: FuzzReverse (0.00s)\n        reverse_test.go:20: wrong\n"),
+		ExitCode: 1,
+	}
+}
+
+// TestRunFuzzReadsOnlyRegularFiles is a run that leaves links to files of
+// the host in its corpus, hoping they are reported as failing inputs
+func TestRunFuzzReadsOnlyRegularFiles(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "session.key")
+	if err := os.WriteFile(secret, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sandbox := corpusSandbox{write: func(dir string) {
+		corpus := filepath.Join(dir, fuzzCorpusDir, "FuzzReverse")
+		if err := os.MkdirAll(corpus, 0755); err != nil {
+			t.Error(err)
+			return
+		}
+		os.WriteFile(filepath.Join(corpus, "582528ddfad69eb5"), []byte("go test fuzz v1\nstring(\"\\x91\")\n"), 0644)
+		os.Symlink(secret, filepath.Join(corpus, "1a2b3c"))
+		os.Symlink(filepath.Dir(secret), filepath.Join(corpus, "keys"))
+	}}
+	es := &ExecutionService{sandbox: sandbox, workspaces: NewWorkspaceManager()}
+
+	stage, failures, killed := es.runFuzz(context.Background(), t.TempDir(), []string{"FuzzReverse"}, ResolveFuzzConfig(models.FuzzConfig{}), ResolveLimits(models.ExecutionLimits{}))
+	if stage.Status != models.StageStatusFail || killed != KillNone {
+		t.Fatalf("stage %+v, killed %q, want a failure", stage, killed)
+	}
+	if len(failures) != 1 || failures[0].Name != "582528ddfad69eb5" || failures[0].Message != "reverse_test.go:20: wrong" {
+		t.Errorf("failures %+v, want only the regular corpus file", failures)
+	}
+	if strings.Contains(stage.Output, "secret") {
+		t.Errorf("the stage output contains a file of the host: %q", stage.Output)
+	}
+
+	// A corpus directory that is itself a link is not read at all
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, fuzzCorpusDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(secret), filepath.Join(workDir, fuzzCorpusDir, "FuzzReverse")); err != nil {
+		t.Fatal(err)
+	}
+	if entries := corpusEntries(workDir, "FuzzReverse"); entries != nil {
+		t.Errorf("corpus entries %q through a linked directory", entries)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"web-ui/internal/models"
+)
+
+// DefaultLeaderboardWeights count classic and package challenges the same
+var DefaultLeaderboardWeights = models.LeaderboardWeights{Classic: 1, Package: 1}
+
+// ErrInvalidLeaderboardWeights is returned for negative weights, or when
+// both are zero
+var ErrInvalidLeaderboardWeights = errors.New("invalid weights: they must not be negative, and one must be positive")
+
+// ValidateLeaderboardWeights checks that weights can rank users
+func ValidateLeaderboardWeights(weights models.LeaderboardWeights) error {
+	for _, weight := range []float64{weights.Classic, weights.Package} {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return ErrInvalidLeaderboardWeights
+		}
+	}
+	if weights.Classic == 0 && weights.Package == 0 {
+		return ErrInvalidLeaderboardWeights
+	}
+	return nil
+}
+
+// RankGlobal ranks users by their completed classic challenges, from the
+// main leaderboard, and package challenges, each times its weight. Ties go
+// to whoever completed more challenges, then by username. Usernames are
+// matched ignoring case, keeping the main leaderboard's spelling, and users
+// who score nothing are left out.
+func RankGlobal(main []models.LeaderboardUser, packages []PackageCompletions, weights models.LeaderboardWeights) []models.GlobalLeaderboardUser {
+	users := make(map[string]*models.GlobalLeaderboardUser)
+	user := func(username string) *models.GlobalLeaderboardUser {
+		key := strings.ToLower(username)
+		if users[key] == nil {
+			users[key] = &models.GlobalLeaderboardUser{Username: username, Packages: make(map[string]int)}
+		}
+		return users[key]
+	}
+
+	for _, classic := range main {
+		user(classic.Username).ClassicCompleted = classic.CompletedCount
+	}
+	for _, completions := range packages {
+		for username, completed := range completions.Completed {
+			if len(completed) == 0 {
+				continue
+			}
+			learner := user(username)
+			learner.PackageCompleted += len(completed)
+			learner.Packages[completions.Package] += len(completed)
+		}
+	}
+
+	leaderboard := make([]models.GlobalLeaderboardUser, 0, len(users))
+	for _, user := range users {
+		user.Score = weights.Classic*float64(user.ClassicCompleted) + weights.Package*float64(user.PackageCompleted)
+		if user.Score > 0 {
+			leaderboard = append(leaderboard, *user)
+		}
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Score != leaderboard[j].Score {
+			return leaderboard[i].Score > leaderboard[j].Score
+		}
+		completedI := leaderboard[i].ClassicCompleted + leaderboard[i].PackageCompleted
+		completedJ := leaderboard[j].ClassicCompleted + leaderboard[j].PackageCompleted
+		if completedI != completedJ {
+			return completedI > completedJ
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	return leaderboard
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"web-ui/internal/models"
+)
+
+// Page sizes for submission history queries
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// ErrSubmissionNotFound is returned when a history record does not exist
+var ErrSubmissionNotFound = errors.New("submission not found")
+
+// SubmissionStore keeps the history of every run and submit
+type SubmissionStore interface {
+	// Save stores a record and sets its ID
+	Save(record *models.SubmissionRecord) error
+	// List returns a page of records matching the query, newest first,
+	// without their code, output and per-test results
+	List(query SubmissionQuery) (SubmissionPage, error)
+	// Get returns a complete record, or ErrSubmissionNotFound
+	Get(id int64) (*models.SubmissionRecord, error)
+	// FirstSolves returns when each user first passed a submit of each
+	// classic challenge
+	FirstSolves() (SolveTimes, error)
+	// SolveAttempts returns how each user got to their first passing submit
+	// of each classic challenge
+	SolveAttempts() (SolveAttempts, error)
+	Close() error
+}
+
+// SubmissionQuery filters and pages the submission history. Zero fields do
+// not filter.
+type SubmissionQuery struct {
+	Username         string
+	ChallengeID      int
+	PackageName      string
+	PackageChallenge string
+	Mode             string
+	CodeHash         string // Runs of exactly these files, see FilesHash
+	Passed           *bool
+	Limit            int // DefaultHistoryLimit when zero, at most MaxHistoryLimit
+	Offset           int
+}
+
+// SubmissionPage is one page of a history query
+type SubmissionPage struct {
+	Submissions []models.SubmissionRecord `json:"submissions"`
+	Total       int                       `json:"total"` // Records matching the query across all pages
+	Limit       int                       `json:"limit"`
+	Offset      int                       `json:"offset"`
+}
+
+// normalize applies the default and maximum page size
+func (q SubmissionQuery) normalize() SubmissionQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	if q.Limit > MaxHistoryLimit {
+		q.Limit = MaxHistoryLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return q
+}
+
+// NewSubmissionStore opens the SQLite submission history at SUBMISSIONS_DB,
+// or next to the workspace caches by default
+func NewSubmissionStore(workspaces *WorkspaceManager) (SubmissionStore, error) {
+	path := os.Getenv("SUBMISSIONS_DB")
+	if path == "" {
+		path = filepath.Join(workspaces.root, "submissions.db")
+	}
+	return NewSQLiteSubmissionStore(path)
+}
+
+// RecordResult copies the outcome of a run into a history record
+func RecordResult(record *models.SubmissionRecord, result ExecutionResult) {
+	record.Passed = result.Passed
+	record.Killed = string(result.Killed)
+	record.TestsPassed = result.Summary.Passed
+	record.TestsTotal = result.Summary.Total
+	record.ExecutionMs = result.ExecutionMs
+	record.Sandbox = result.Sandbox
+	record.Output = result.Output
+	record.Tests = result.Tests
+	if result.Coverage != nil {
+		record.Coverage = result.Coverage.Percent
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"web-ui/internal/models"
+)
+
+// historyMigrations create and upgrade the history schema. The database's
+// user_version is the number of migrations applied; append new ones, never
+// edit applied ones.
+var historyMigrations = []string{
+	`CREATE TABLE submissions (
+		id                INTEGER PRIMARY KEY AUTOINCREMENT,
+		username          TEXT    NOT NULL DEFAULT '',
+		challenge_id      INTEGER NOT NULL DEFAULT 0,
+		package_name      TEXT    NOT NULL DEFAULT '',
+		package_challenge TEXT    NOT NULL DEFAULT '',
+		mode              TEXT    NOT NULL,
+		submitted_at      INTEGER NOT NULL, -- Unix milliseconds
+		passed            INTEGER NOT NULL,
+		killed            TEXT    NOT NULL DEFAULT '',
+		tests_passed      INTEGER NOT NULL,
+		tests_total       INTEGER NOT NULL,
+		coverage          REAL    NOT NULL DEFAULT 0,
+		execution_ms      INTEGER NOT NULL,
+		go_version        TEXT    NOT NULL DEFAULT '',
+		sandbox           TEXT    NOT NULL DEFAULT '',
+		code              TEXT    NOT NULL,
+		files             TEXT, -- JSON object, only for multi-file submissions
+		output            TEXT    NOT NULL,
+		tests             TEXT    -- JSON array of test results
+	);
+	CREATE INDEX submissions_user ON submissions (username, submitted_at);
+	CREATE INDEX submissions_challenge ON submissions (challenge_id, submitted_at);
+	CREATE INDEX submissions_package ON submissions (package_name, package_challenge, submitted_at);`,
+	// Filled in for earlier records by backfillCodeHashes
+	`ALTER TABLE submissions ADD COLUMN code_hash TEXT NOT NULL DEFAULT '';
+	CREATE INDEX submissions_code ON submissions (code_hash);`,
+}
+
+// historySummaryColumns are the columns of a listed record
+const historySummaryColumns = `id, username, challenge_id, package_name, package_challenge, mode,
+	submitted_at, passed, killed, tests_passed, tests_total, coverage, execution_ms, go_version, sandbox, code_hash`
+
+// SQLiteSubmissionStore is a SubmissionStore in an embedded SQLite database
+type SQLiteSubmissionStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSubmissionStore opens the database at path, creating it and its
+// directory if needed, and brings its schema up to date
+func NewSQLiteSubmissionStore(path string) (*SQLiteSubmissionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	// WAL lets the history pages read while a worker saves a run
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+
+	store := &SQLiteSubmissionStore{db: db}
+	if err := migrateSQLite(db, historyMigrations); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+	if err := store.backfillCodeHashes(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// migrateSQLite applies the migrations a database has not seen yet. Its
+// user_version is the number of migrations applied.
+func migrateSQLite(db *sql.DB, migrations []string) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than this server supports (%d)", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		// PRAGMA does not take bind parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillCodeHashes sets the code hash of records saved before it was
+// stored, from their code and files
+func (s *SQLiteSubmissionStore) backfillCodeHashes() error {
+	rows, err := s.db.Query("SELECT id, code, files FROM submissions WHERE code_hash = ''")
+	if err != nil {
+		return err
+	}
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var code string
+		var files sql.NullString
+		if err := rows.Scan(&id, &code, &files); err != nil {
+			rows.Close()
+			return err
+		}
+		tree := SingleFile(code)
+		if files.Valid {
+			json.Unmarshal([]byte(files.String), &tree)
+		}
+		hashes[id] = FilesHash(tree)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, hash := range hashes {
+		if _, err := s.db.Exec("UPDATE submissions SET code_hash = ? WHERE id = ?", hash, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save stores a record and sets its ID
+func (s *SQLiteSubmissionStore) Save(record *models.SubmissionRecord) error {
+	var files, tests []byte
+	var err error
+	if len(record.Files) > 1 {
+		if files, err = json.Marshal(record.Files); err != nil {
+			return err
+		}
+	}
+	if tests, err = json.Marshal(record.Tests); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(`INSERT INTO submissions (
+		username, challenge_id, package_name, package_challenge, mode, submitted_at, passed, killed,
+		tests_passed, tests_total, coverage, execution_ms, go_version, sandbox, code_hash, code, files, output, tests
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Username, record.ChallengeID, record.PackageName, record.PackageChallenge, record.Mode,
+		record.SubmittedAt.UnixMilli(), record.Passed, record.Killed, record.TestsPassed, record.TestsTotal,
+		record.Coverage, record.ExecutionMs, record.GoVersion, record.Sandbox, record.CodeHash, record.Code,
+		nullableText(files), record.Output, string(tests))
+	if err != nil {
+		return fmt.Errorf("failed to save submission: %v", err)
+	}
+
+	record.ID, err = result.LastInsertId()
+	return err
+}
+
+// List returns a page of records matching the query, newest first
+func (s *SQLiteSubmissionStore) List(query SubmissionQuery) (SubmissionPage, error) {
+	query = query.normalize()
+	page := SubmissionPage{
+		Submissions: []models.SubmissionRecord{},
+		Limit:       query.Limit,
+		Offset:      query.Offset,
+	}
+
+	where, args := historyFilter(query)
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM submissions"+where, args...).Scan(&page.Total); err != nil {
+		return page, fmt.Errorf("failed to count submissions: %v", err)
+	}
+
+	rows, err := s.db.Query("SELECT "+historySummaryColumns+" FROM submissions"+where+
+		" ORDER BY submitted_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return page, fmt.Errorf("failed to list submissions: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record models.SubmissionRecord
+		if err := scanSummary(rows, &record); err != nil {
+			return page, err
+		}
+		page.Submissions = append(page.Submissions, record)
+	}
+	return page, rows.Err()
+}
+
+// Get returns a complete record, or ErrSubmissionNotFound
+func (s *SQLiteSubmissionStore) Get(id int64) (*models.SubmissionRecord, error) {
+	row := s.db.QueryRow("SELECT "+historySummaryColumns+", code, files, output, tests FROM submissions WHERE id = ?", id)
+
+	var record models.SubmissionRecord
+	var files, tests sql.NullString
+	err := scanSummary(row, &record, &record.Code, &files, &record.Output, &tests)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if files.Valid {
+		if err := json.Unmarshal([]byte(files.String), &record.Files); err != nil {
+			return nil, fmt.Errorf("invalid files of submission %d: %v", id, err)
+		}
+	}
+	if tests.Valid {
+		if err := json.Unmarshal([]byte(tests.String), &record.Tests); err != nil {
+			return nil, fmt.Errorf("invalid tests of submission %d: %v", id, err)
+		}
+	}
+	return &record, nil
+}
+
+// Close closes the database
+func (s *SQLiteSubmissionStore) Close() error {
+	return s.db.Close()
+}
+
+// FirstSolves returns when each user first passed a submit of each classic
+// challenge
+func (s *SQLiteSubmissionStore) FirstSolves() (SolveTimes, error) {
+	rows, err := s.db.Query(`SELECT username, challenge_id, MIN(submitted_at) FROM submissions
+		WHERE mode = ? AND passed = 1 AND challenge_id > 0 AND username != ''
+		GROUP BY username, challenge_id`, string(ModeSubmit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query first solves: %v", err)
+	}
+	defer rows.Close()
+
+	times := make(SolveTimes)
+	for rows.Next() {
+		var username string
+		var challengeID int
+		var submittedAt int64
+		if err := rows.Scan(&username, &challengeID, &submittedAt); err != nil {
+			return nil, err
+		}
+		times.Add(username, challengeID, time.UnixMilli(submittedAt))
+	}
+	return times, rows.Err()
+}
+
+// SolveAttempts returns, for each classic challenge a user passed a submit
+// of, their first submit, their first passing submit and how many submits
+// failed in between. Cancelled submits do not count as failures.
+func (s *SQLiteSubmissionStore) SolveAttempts() (SolveAttempts, error) {
+	// Usernames ignore case, as on the scoreboards
+	rows, err := s.db.Query(`SELECT f.username, s.challenge_id, MIN(s.submitted_at), f.first_pass,
+			SUM(s.passed = 0 AND s.killed != ? AND s.submitted_at < f.first_pass)
+		FROM submissions s JOIN (
+			SELECT LOWER(username) AS username, challenge_id, MIN(submitted_at) AS first_pass FROM submissions
+			WHERE mode = ? AND passed = 1 AND challenge_id > 0 AND username != ''
+			GROUP BY LOWER(username), challenge_id
+		) f ON LOWER(s.username) = f.username AND s.challenge_id = f.challenge_id
+		WHERE s.mode = ?
+		GROUP BY f.username, s.challenge_id`, string(KillCancelled), string(ModeSubmit), string(ModeSubmit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query solve attempts: %v", err)
+	}
+	defer rows.Close()
+
+	attempts := make(SolveAttempts)
+	for rows.Next() {
+		var username string
+		var challengeID int
+		var firstTry, firstPass int64
+		var failures int
+		if err := rows.Scan(&username, &challengeID, &firstTry, &firstPass, &failures); err != nil {
+			return nil, err
+		}
+		attempts.Add(username, challengeID, SolveAttempt{
+			FirstTry:  time.UnixMilli(firstTry),
+			FirstPass: time.UnixMilli(firstPass),
+			Failures:  failures,
+		})
+	}
+	return attempts, rows.Err()
+}
+
+// historyFilter builds the WHERE clause of a query
+func historyFilter(query SubmissionQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if query.Username != "" {
+		add("username = ?", query.Username)
+	}
+	if query.ChallengeID != 0 {
+		add("challenge_id = ?", query.ChallengeID)
+	}
+	if query.PackageName != "" {
+		add("package_name = ?", query.PackageName)
+	}
+	if query.PackageChallenge != "" {
+		add("package_challenge = ?", query.PackageChallenge)
+	}
+	if query.Mode != "" {
+		add("mode = ?", query.Mode)
+	}
+	if query.CodeHash != "" {
+		add("code_hash = ?", query.CodeHash)
+	}
+	if query.Passed != nil {
+		add("passed = ?", *query.Passed)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanSummary scans the summary columns of a row into record, followed by
+// any extra destinations
+func scanSummary(row interface{ Scan(...interface{}) error }, record *models.SubmissionRecord, extra ...interface{}) error {
+	var submittedAt int64
+	dest := append([]interface{}{
+		&record.ID, &record.Username, &record.ChallengeID, &record.PackageName, &record.PackageChallenge,
+		&record.Mode, &submittedAt, &record.Passed, &record.Killed, &record.TestsPassed, &record.TestsTotal,
+		&record.Coverage, &record.ExecutionMs, &record.GoVersion, &record.Sandbox, &record.CodeHash,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	record.SubmittedAt = time.UnixMilli(submittedAt)
+	return nil
+}
+
+// nullableText stores empty JSON as NULL
+func nullableText(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// newTestSubmissionStore opens a history database in a temporary directory
+func newTestSubmissionStore(t *testing.T) (*SQLiteSubmissionStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history", "submissions.db")
+	store, err := NewSQLiteSubmissionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func TestSubmissionStoreSaveAndGet(t *testing.T) {
+	store, _ := newTestSubmissionStore(t)
+	files := models.SourceFiles{MainFile: "package main\n", "store/store.go": "package store\n"}
+	record := &models.SubmissionRecord{
+		Username:    "alice",
+		ChallengeID: 2,
+		Mode:        string(ModeSubmit),
+		SubmittedAt: time.UnixMilli(1760000000123),
+		Passed:      true,
+		TestsPassed: 2,
+		TestsTotal:  2,
+		Coverage:    87.5,
+		ExecutionMs: 1234,
+		GoVersion:   "go1.25.0",
+		Sandbox:     "namespace",
+		CodeHash:    FilesHash(files),
+		Code:        files[MainFile],
+		Files:       files,
+		Output:      "=== RUN   TestSum\n--- PASS: TestSum (0.00s)\n",
+		Tests: []*models.TestResult{{
+			Name:     "TestSum",
+			Status:   models.TestStatusPass,
+			Subtests: []*models.TestResult{{Name: "TestSum/Zero", Status: models.TestStatusPass}},
+		}},
+	}
+	if err := store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	if record.ID == 0 {
+		t.Fatal("Save did not set the ID")
+	}
+
+	got, err := store.Get(record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.SubmittedAt.Equal(record.SubmittedAt) || got.Username != "alice" || got.ChallengeID != 2 || !got.Passed ||
+		got.Coverage != 87.5 || got.ExecutionMs != 1234 || got.GoVersion != "go1.25.0" || got.CodeHash != record.CodeHash {
+		t.Errorf("got %+v, want %+v", got, record)
+	}
+	if got.Code != record.Code || got.Output != record.Output || len(got.Files) != 2 || got.Files["store/store.go"] != "package store\n" {
+		t.Errorf("code %q, files %q, output %q", got.Code, got.Files, got.Output)
+	}
+	if len(got.Tests) != 1 || len(got.Tests[0].Subtests) != 1 || got.Tests[0].Subtests[0].Name != "TestSum/Zero" {
+		t.Errorf("tests %+v", got.Tests)
+	}
+
+	// A single-file submission keeps only its code
+	single := &models.SubmissionRecord{Mode: string(ModeTest), SubmittedAt: time.Now(), Code: "package main\n", Files: SingleFile("package main\n")}
+	if err := store.Save(single); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(single.ID); err != nil || got.Files != nil || got.Code != "package main\n" {
+		t.Errorf("single file submission %+v, %v", got, err)
+	}
+
+	if _, err := store.Get(record.ID + 100); !errors.Is(err, ErrSubmissionNotFound) {
+		t.Errorf("Get of a missing record: %v, want ErrSubmissionNotFound", err)
+	}
+}
+
+func TestSubmissionStoreList(t *testing.T) {
+	store, _ := newTestSubmissionStore(t)
+	start := time.Now().Add(-time.Hour)
+	records := []models.SubmissionRecord{
+		{Username: "alice", ChallengeID: 1, Mode: string(ModeTest)},
+		{Username: "alice", ChallengeID: 1, Mode: string(ModeSubmit), Passed: true},
+		{Username: "bob", ChallengeID: 1, Mode: string(ModeSubmit)},
+		{Username: "alice", ChallengeID: 2, Mode: string(ModeTest)},
+		{Username: "bob", PackageName: "gin", PackageChallenge: "challenge-1-basic-routing", Mode: string(ModeSubmit), Passed: true},
+	}
+	for i := range records {
+		records[i].SubmittedAt = start.Add(time.Duration(i) * time.Minute)
+		records[i].Code = "package main\n"
+		records[i].Output = "output"
+		if err := store.Save(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	passed, failed := true, false
+	tests := []struct {
+		name  string
+		query SubmissionQuery
+		want  []int // Indexes into records, newest first
+	}{
+		{"everything", SubmissionQuery{}, []int{4, 3, 2, 1, 0}},
+		{"user", SubmissionQuery{Username: "alice"}, []int{3, 1, 0}},
+		{"challenge", SubmissionQuery{ChallengeID: 1}, []int{2, 1, 0}},
+		{"user and challenge", SubmissionQuery{Username: "alice", ChallengeID: 1}, []int{1, 0}},
+		{"package", SubmissionQuery{PackageName: "gin", PackageChallenge: "challenge-1-basic-routing"}, []int{4}},
+		{"mode", SubmissionQuery{Mode: string(ModeSubmit)}, []int{4, 2, 1}},
+		{"passed", SubmissionQuery{Passed: &passed}, []int{4, 1}},
+		{"failed", SubmissionQuery{Passed: &failed, ChallengeID: 1}, []int{2, 0}},
+		{"nobody", SubmissionQuery{Username: "carol"}, nil},
+	}
+	for _, tt := range tests {
+		page, err := store.List(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if page.Total != len(tt.want) || len(page.Submissions) != len(tt.want) {
+			t.Errorf("%s: %d of %d records, want %d", tt.name, len(page.Submissions), page.Total, len(tt.want))
+			continue
+		}
+		for i, index := range tt.want {
+			got := page.Submissions[i]
+			if got.ID != records[index].ID {
+				t.Errorf("%s: record %d is %d, want %d", tt.name, i, got.ID, records[index].ID)
+			}
+			if got.Code != "" || got.Output != "" {
+				t.Errorf("%s: listed record %d has its code or output", tt.name, got.ID)
+			}
+		}
+	}
+
+	// Pages keep the total of the whole query
+	page, err := store.List(SubmissionQuery{Limit: 2, Offset: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || page.Limit != 2 || page.Offset != 3 || len(page.Submissions) != 2 || page.Submissions[0].ID != records[1].ID {
+		t.Errorf("page %+v, want records %d and %d of 5", page, records[1].ID, records[0].ID)
+	}
+	if page, err := store.List(SubmissionQuery{Limit: 1000, Offset: -1}); err != nil || page.Limit != MaxHistoryLimit || page.Offset != 0 {
+		t.Errorf("page limit %d, offset %d, %v, want %d and 0", page.Limit, page.Offset, err, MaxHistoryLimit)
+	}
+	if page, err := store.List(SubmissionQuery{Offset: 10}); err != nil || page.Submissions == nil || page.Total != 5 {
+		t.Errorf("page past the end %+v, %v, want an empty list", page, err)
+	}
+}
+
+func TestSubmissionStoreReopen(t *testing.T) {
+	store, path := newTestSubmissionStore(t)
+	record := &models.SubmissionRecord{Username: "alice", ChallengeID: 1, Mode: string(ModeTest), SubmittedAt: time.Now(), Code: "package main\n"}
+	if err := store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	// Records saved before code hashes were stored get one when the
+	// database is opened
+	if _, err := store.db.Exec("UPDATE submissions SET code_hash = ''"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reopened, err := NewSQLiteSubmissionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	got, err := reopened.Get(record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != record.Code || got.CodeHash != FilesHash(SingleFile(record.Code)) {
+		t.Errorf("after reopening: code %q, hash %q", got.Code, got.CodeHash)
+	}
+
+	// A database from a newer server is refused
+	if _, err := reopened.db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+	if _, err := NewSQLiteSubmissionStore(path); err == nil {
+		t.Error("opened a database with a newer schema")
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// ExternalIdentity is a user as an identity provider verified them
+type ExternalIdentity struct {
+	Subject string // The provider's stable ID of the account, unchanged by renames
+	Login   string // The username the account maps to
+	Email   string // Empty when the provider does not share it
+}
+
+// IdentityProvider signs users in with an account elsewhere through the
+// OAuth2 authorization code flow
+type IdentityProvider interface {
+	// Name identifies the provider in URLs and linked identities
+	Name() string
+	// DisplayName is shown on the sign-in button
+	DisplayName() string
+	// AuthCodeURL returns the provider's page that asks the user to sign
+	// in. state, nonce and the PKCE verifier are checked when they return.
+	AuthCodeURL(state, nonce, verifier string) string
+	// Exchange trades the code the user returned with for their verified
+	// identity
+	Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalIdentity, error)
+}
+
+// NewIdentityProviders creates the identity providers the environment
+// configures: GitHub when GITHUB_CLIENT_ID is set and OIDC when OIDC_ISSUER
+// is. Providers redirect back to PUBLIC_URL, by default
+// http://localhost:8080.
+func NewIdentityProviders(ctx context.Context) ([]IdentityProvider, error) {
+	var providers []IdentityProvider
+
+	if clientID := os.Getenv("GITHUB_CLIENT_ID"); clientID != "" {
+		providers = append(providers, NewGitHubProvider(GitHubConfig{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+			RedirectURL:  callbackURL("github"),
+			AuthURL:      os.Getenv("GITHUB_AUTH_URL"),
+			TokenURL:     os.Getenv("GITHUB_TOKEN_URL"),
+			APIURL:       os.Getenv("GITHUB_API_URL"),
+		}))
+	}
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		provider, err := NewOIDCProvider(ctx, OIDCConfig{
+			Issuer:        issuer,
+			ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:   callbackURL("oidc"),
+			DisplayName:   os.Getenv("OIDC_NAME"),
+			UsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// callbackURL returns where a provider sends users back to
+func callbackURL(provider string) string {
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
+	return publicURL + "/auth/" + provider + "/callback"
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// stubGitHub is a local stand-in for GitHub's OAuth and user endpoints. A
+// code signs in the GitHub user registered for it.
+type stubGitHub struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	users  map[string]stubGitHubUser // By code, then by access token
+}
+
+// stubGitHubUser is a GitHub user as the /user endpoint returns them
+type stubGitHubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+func newStubGitHub(t *testing.T) *stubGitHub {
+	t.Helper()
+	stub := &stubGitHub{users: make(map[string]stubGitHubUser)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code_verifier") == "" {
+			http.Error(w, "missing PKCE verifier", http.StatusBadRequest)
+			return
+		}
+		stub.mu.Lock()
+		_, ok := stub.users[r.Form.Get("code")]
+		stub.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"bad_verification_code"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token-" + r.Form.Get("code"), "token_type": "bearer"})
+	})
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer token-")
+		stub.mu.Lock()
+		user, ok := stub.users[code]
+		stub.mu.Unlock()
+		if !ok {
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(user)
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// provider returns a GitHub provider pointed at the stub
+func (s *stubGitHub) provider() *GitHubProvider {
+	return NewGitHubProvider(GitHubConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/github/callback",
+		AuthURL:      s.server.URL + "/login/oauth/authorize",
+		TokenURL:     s.server.URL + "/login/oauth/access_token",
+		APIURL:       s.server.URL,
+	})
+}
+
+// code returns a code that signs in a GitHub user
+func (s *stubGitHub) code(id int64, login string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := NewToken()
+	s.users[code] = stubGitHubUser{ID: id, Login: login}
+	return code
+}
+
+// signIn goes through the whole sign-in with the stub as a GitHub user
+func signIn(t *testing.T, auth *AuthService, stub *stubGitHub, id int64, login string) (int64, string) {
+	t.Helper()
+	provider := stub.provider()
+	authURL, token, err := auth.StartExternalSignIn(provider, "/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, next, err := auth.FinishExternalSignIn(context.Background(), provider, token, parsed.Query().Get("state"), stub.code(id, login))
+	if err != nil {
+		t.Fatalf("sign in as %s (%d): %v", login, id, err)
+	}
+	if next != "/next" {
+		t.Errorf("next is %q, want /next", next)
+	}
+	return user.ID, user.Username
+}
+
+func TestExternalSignInState(t *testing.T) {
+	stub := newStubGitHub(t)
+	auth, _ := newTestAuthService(t)
+	provider := stub.provider()
+
+	authURL, token, err := auth.StartExternalSignIn(provider, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(authURL)
+	state := parsed.Query().Get("state")
+	if state == "" || parsed.Query().Get("code_challenge") == "" {
+		t.Fatalf("authorization URL without state or PKCE challenge: %s", authURL)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	otherToken := func() string {
+		_, token, err := auth.StartExternalSignIn(provider, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}()
+	tests := map[string]struct{ token, state string }{
+		"state mismatch":       {token, NewToken()},
+		"no state":             {token, ""},
+		"another sign-in":      {otherToken, state},
+		"tampered payload":     {payload + "x." + signature, state},
+		"unsigned":             {payload, state},
+		"no sign-in in cookie": {"", state},
+	}
+	for name, tt := range tests {
+		_, _, err := auth.FinishExternalSignIn(context.Background(), provider, tt.token, tt.state, stub.code(1, "octocat"))
+		if !errors.Is(err, ErrInvalidSignIn) {
+			t.Errorf("%s: got %v, want ErrInvalidSignIn", name, err)
+		}
+	}
+
+	// A sign-in started with one provider does not finish with another
+	other := &fakeProvider{name: "oidc"}
+	if _, _, err := auth.FinishExternalSignIn(context.Background(), other, token, state, "code"); !errors.Is(err, ErrInvalidSignIn) {
+		t.Errorf("other provider: got %v, want ErrInvalidSignIn", err)
+	}
+
+	// The stub rejects codes it did not hand out
+	if _, _, err := auth.FinishExternalSignIn(context.Background(), provider, token, state, "unknown-code"); err == nil {
+		t.Error("an unknown code signed in")
+	}
+}
+
+func TestExternalSignInUnknownSubject(t *testing.T) {
+	stub := newStubGitHub(t)
+	auth, _ := newTestAuthService(t)
+
+	id, username := signIn(t, auth, stub, 1, "octocat")
+	if username != "octocat" {
+		t.Errorf("new user %q, want octocat", username)
+	}
+
+	// The subject is linked: signing in again finds the same user, and a
+	// new login renames them
+	if again, _ := signIn(t, auth, stub, 1, "octocat"); again != id {
+		t.Errorf("second sign-in got user %d, want %d", again, id)
+	}
+	renamedID, renamed := signIn(t, auth, stub, 1, "octocat-renamed")
+	if renamedID != id || renamed != "octocat-renamed" {
+		t.Errorf("renamed sign-in got %d %q, want %d octocat-renamed", renamedID, renamed, id)
+	}
+}
+
+func TestExternalSignInUsernameCollision(t *testing.T) {
+	stub := newStubGitHub(t)
+	auth, _ := newTestAuthService(t)
+
+	// Someone registers a GitHub login locally before its owner signs in
+	squatter, err := auth.Register("octocat", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerID, owner := signIn(t, auth, stub, 1, "octocat")
+	if ownerID == squatter.ID || owner != "octocat-2" {
+		t.Errorf("GitHub user got %d %q, want a user of their own named octocat-2", ownerID, owner)
+	}
+
+	// A login GitHub gave to a new owner does not sign in to the old
+	// owner's user
+	recycledID, recycled := signIn(t, auth, stub, 2, "octocat-2")
+	if recycledID == ownerID || recycled != "octocat-2-2" {
+		t.Errorf("new owner of the login got %d %q, want a user of their own named octocat-2-2", recycledID, recycled)
+	}
+
+	// Renaming to a taken login keeps the current username
+	if id, username := signIn(t, auth, stub, 1, "octocat"); id != ownerID || username != "octocat-2" {
+		t.Errorf("rename to a taken login got %d %q, want %d octocat-2", id, username, ownerID)
+	}
+}
+
+func TestExternalSignInLinksAcrossProviders(t *testing.T) {
+	stub := newStubGitHub(t)
+	oidc := &fakeProvider{name: "oidc"}
+	auth, _ := newTestAuthService(t, oidc)
+
+	// A user created by single sign-on, without a password, is the same
+	// person as the GitHub account with their login
+	oidc.identity = &ExternalIdentity{Subject: "sso-1", Login: "octocat"}
+	ssoUser, err := auth.externalUser(oidc.Name(), oidc.identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := signIn(t, auth, stub, 1, "octocat"); id != ssoUser.ID {
+		t.Errorf("GitHub sign-in got user %d, want the single sign-on user %d", id, ssoUser.ID)
+	}
+
+	// A second single sign-on account with the same login is not
+	oidc.identity = &ExternalIdentity{Subject: "sso-2", Login: "octocat"}
+	other, err := auth.externalUser(oidc.Name(), oidc.identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == ssoUser.ID {
+		t.Error("a second account of the provider was linked to the same user")
+	}
+}
+
+// fakeProvider is an identity provider that signs in a fixed identity
+type fakeProvider struct {
+	name     string
+	identity *ExternalIdentity
+}
+
+func (p *fakeProvider) Name() string        { return p.name }
+func (p *fakeProvider) DisplayName() string { return p.name }
+
+func (p *fakeProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return "http://provider.invalid/authorize?state=" + url.QueryEscape(state)
+}
+
+func (p *fakeProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*ExternalIdentity, error) {
+	return p.identity, nil
+}
+
+// stubOIDC is a local OpenID provider: discovery, keys and a token
+// endpoint that issues signed ID tokens with the claims registered for a
+// code
+type stubOIDC struct {
+	server *httptest.Server
+	signer jose.Signer
+	mu     sync.Mutex
+	claims map[string]map[string]any // By code
+}
+
+func newStubOIDC(t *testing.T) *stubOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &stubOIDC{signer: signer, claims: make(map[string]map[string]any)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                stub.server.URL,
+			"authorization_endpoint":                stub.server.URL + "/authorize",
+			"token_endpoint":                        stub.server.URL + "/token",
+			"jwks_uri":                              stub.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		stub.mu.Lock()
+		claims, ok := stub.claims[r.Form.Get("code")]
+		stub.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		payload, _ := json.Marshal(claims)
+		signed, err := stub.signer.Sign(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		idToken, _ := signed.CompactSerialize()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token", "token_type": "bearer", "id_token": idToken})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// provider returns an OIDC provider discovered from the stub
+func (s *stubOIDC) provider(t *testing.T) *OIDCProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		Issuer:      s.server.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost:8080/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// code returns a code for an ID token with a subject, a username and a
+// nonce, valid for the stub's client
+func (s *stubOIDC) code(subject, username, nonce string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := NewToken()
+	now := time.Now()
+	s.claims[code] = map[string]any{
+		"iss":                s.server.URL,
+		"aud":                "client",
+		"sub":                subject,
+		"preferred_username": username,
+		"nonce":              nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+	return code
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	stub := newStubOIDC(t)
+	auth, _ := newTestAuthService(t)
+	provider := stub.provider(t)
+
+	// signIn signs in through the stub; a wrong nonce stands for an ID
+	// token issued for another sign-in
+	signIn := func(subject, username string, wrongNonce bool) (string, error) {
+		authURL, token, err := auth.StartExternalSignIn(provider, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _ := url.Parse(authURL)
+		nonce := parsed.Query().Get("nonce")
+		if wrongNonce {
+			nonce = NewToken()
+		}
+		user, _, err := auth.FinishExternalSignIn(context.Background(), provider, token, parsed.Query().Get("state"), stub.code(subject, username, nonce))
+		if err != nil {
+			return "", err
+		}
+		return user.Username, nil
+	}
+
+	if username, err := signIn("sso-1", "alice", false); err != nil || username != "alice" {
+		t.Errorf("single sign-on user %q, %v; want alice", username, err)
+	}
+
+	if _, err := signIn("sso-2", "bob", true); err == nil {
+		t.Error("an ID token with another sign-in's nonce was accepted")
+	}
+
+	// A username claim naming an existing account does not sign in to it
+	if _, err := auth.Register("carol", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if username, err := signIn("sso-3", "carol", false); err != nil || username != "carol-2" {
+		t.Errorf("claim of an existing account got %q, %v; want a user of its own named carol-2", username, err)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModuleInfo is the part of a go.mod that decides which imports a
+// submission may use
+type ModuleInfo struct {
+	Path     string   // Module path of the challenge, e.g. "challenge14"
+	Requires []string // Required module paths, direct and indirect
+}
+
+// ImportError reports an import the challenge's module does not provide
+type ImportError struct {
+	File    string `json:"file"` // Submitted file with the import
+	Import  string `json:"import"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the import error
+func (e *ImportError) Error() string {
+	return e.Message
+}
+
+// readModuleInfo reads a go.mod with `go mod edit -json`, which handles
+// every form of require block the go command accepts
+func readModuleInfo(ctx context.Context, goModPath string) (*ModuleInfo, error) {
+	output, err := exec.CommandContext(ctx, "go", "mod", "edit", "-json", goModPath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", goModPath, err)
+	}
+
+	var goMod struct {
+		Module struct {
+			Path string
+		}
+		Require []struct {
+			Path string
+		}
+	}
+	if err := json.Unmarshal(output, &goMod); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", goModPath, err)
+	}
+
+	info := &ModuleInfo{Path: goMod.Module.Path}
+	for _, require := range goMod.Require {
+		info.Requires = append(info.Requires, require.Path)
+	}
+	sort.Strings(info.Requires)
+	return info, nil
+}
+
+// Allows reports whether an import path is provided by the standard library,
+// the challenge module itself or one of the modules it requires
+func (m *ModuleInfo) Allows(importPath string) bool {
+	if isStandardLibraryImport(importPath) {
+		return true
+	}
+	for _, modulePath := range append([]string{m.Path}, m.Requires...) {
+		if importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// describe lists what a module provides, for error messages
+func (m *ModuleInfo) describe() string {
+	if len(m.Requires) == 0 {
+		return "only the standard library"
+	}
+	return "the standard library and " + strings.Join(m.Requires, ", ")
+}
+
+// isStandardLibraryImport uses the go command's own rule: standard library
+// paths never contain a dot in their first element. "C" (cgo) is included.
+func isStandardLibraryImport(importPath string) bool {
+	first := importPath
+	if i := strings.Index(importPath, "/"); i >= 0 {
+		first = importPath[:i]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// checkImports parses the submitted file's imports, including aliased, dot
+// and blank imports, and returns an ImportError for the first one the module
+// does not allow. Files whose import section does not parse are left for
+// the compiler to report.
+func checkImports(filename, code string, module *ModuleInfo) *ImportError {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || module.Allows(importPath) {
+			continue
+		}
+
+		position := fset.Position(spec.Path.Pos())
+		return &ImportError{
+			File:   filename,
+			Import: importPath,
+			Line:   position.Line,
+			Column: position.Column,
+			Message: fmt.Sprintf("%s: import %q is not allowed: this challenge's module provides %s",
+				position, importPath, module.describe()),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModuleInfoAllows(t *testing.T) {
+	module := &ModuleInfo{
+		Path:     "challenge14",
+		Requires: []string{"github.com/spf13/cobra", "golang.org/x/sync"},
+	}
+	tests := []struct {
+		importPath string
+		allowed    bool
+	}{
+		{"fmt", true},
+		{"net/http", true},
+		{"C", true},
+		{"challenge14", true},
+		{"challenge14/internal/store", true},
+		{"github.com/spf13/cobra", true},
+		{"github.com/spf13/cobra/doc", true},
+		{"golang.org/x/sync/errgroup", true},
+		{"github.com/spf13/cobra-extra", false},
+		{"github.com/spf13/viper", false},
+		{"golang.org/x/net/context", false},
+		{"example.com/challenge14", false},
+		{"gopkg.in/yaml.v3", false},
+	}
+	for _, tt := range tests {
+		if got := module.Allows(tt.importPath); got != tt.allowed {
+			t.Errorf("Allows(%q) = %v, want %v", tt.importPath, got, tt.allowed)
+		}
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	module := &ModuleInfo{Path: "challenge14", Requires: []string{"github.com/spf13/cobra"}}
+	tests := []struct {
+		name   string
+		code   string
+		denied string // Import reported, or "" if all are allowed
+		line   int
+	}{
+		{"standard library", "package main\n\nimport \"fmt\"\n", "", 0},
+		{"required module", "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/spf13/cobra\"\n)\n", "", 0},
+		{"own package", "package main\n\nimport \"challenge14/internal/store\"\n", "", 0},
+		{"single", "package main\n\nimport \"github.com/spf13/viper\"\n", "github.com/spf13/viper", 3},
+		{"grouped", "package main\n\nimport (\n\t\"fmt\"\n\t\"gopkg.in/yaml.v3\"\n)\n", "gopkg.in/yaml.v3", 5},
+		{"aliased", "package main\n\nimport y \"gopkg.in/yaml.v3\"\n", "gopkg.in/yaml.v3", 3},
+		{"dot", "package main\n\nimport . \"gopkg.in/yaml.v3\"\n", "gopkg.in/yaml.v3", 3},
+		{"blank", "package main\n\nimport _ \"github.com/lib/pq\"\n", "github.com/lib/pq", 3},
+		{"first of several", "package main\n\nimport (\n\t\"github.com/lib/pq\"\n\t\"gopkg.in/yaml.v3\"\n)\n", "github.com/lib/pq", 4},
+		{"syntax error", "package main\n\nimport (\n", "", 0},
+	}
+	for _, tt := range tests {
+		err := checkImports(MainFile, tt.code, module)
+		if tt.denied == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: import %q was allowed", tt.name, tt.denied)
+			continue
+		}
+		if err.Import != tt.denied || err.File != MainFile || err.Line != tt.line {
+			t.Errorf("%s: got %s:%d import %q, want %s:%d import %q", tt.name, err.File, err.Line, err.Import, MainFile, tt.line, tt.denied)
+		}
+		if !strings.Contains(err.Message, "github.com/spf13/cobra") {
+			t.Errorf("%s: the message %q does not say what the module provides", tt.name, err.Message)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/deepequalerrors"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/reflectvaluecompare"
+	"golang.org/x/tools/go/analysis/passes/sortslice"
+	"golang.org/x/tools/go/analysis/passes/unusedwrite"
+	"golang.org/x/tools/go/packages"
+
+	"web-ui/internal/models"
+)
+
+// lintAnalyzers complement go vet with checks it does not run. None of them
+// use facts, so dependencies are only type-checked, never analyzed.
+var lintAnalyzers = []*analysis.Analyzer{
+	nilness.Analyzer,
+	unusedwrite.Analyzer,
+	deepequalerrors.Analyzer,
+	sortslice.Analyzer,
+	reflectvaluecompare.Analyzer,
+}
+
+// runLint runs the analyzer suite in-process over the submitted files. The
+// packages are loaded from source and type-checked, never built or run, so
+// only cgo code (whose preprocessing runs the C toolchain) is refused.
+func (es *ExecutionService) runLint(ctx context.Context, tempDir string, files models.SourceFiles) (result models.StageResult) {
+	for filename, code := range files {
+		if importsCgo(filename, code) {
+			return models.StageResult{Status: models.StageStatusSkip, Output: "The analyzers do not run on cgo code"}
+		}
+	}
+
+	// A bug in an analyzer must not take the server down with it
+	defer func() {
+		if r := recover(); r != nil {
+			result = models.StageResult{Status: models.StageStatusError, Output: fmt.Sprintf("analyzer panicked: %v", r)}
+		}
+	}()
+
+	pkgs, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    packages.LoadAllSyntax,
+		Dir:     tempDir,
+		Env:     es.testEnv(),
+	}, "./...")
+	if err != nil {
+		return models.StageResult{Status: models.StageStatusError, Output: err.Error()}
+	}
+
+	// Analyzers need well-typed code; compile errors are the test stage's to report
+	var loadErrors []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, pkgErr := range pkg.Errors {
+			loadErrors = append(loadErrors, pkgErr.Error())
+		}
+	})
+	if len(loadErrors) > 0 {
+		return models.StageResult{Status: models.StageStatusError, Output: strings.Join(loadErrors, "\n")}
+	}
+
+	graph, err := checker.Analyze(lintAnalyzers, pkgs, nil)
+	if err != nil {
+		return models.StageResult{Status: models.StageStatusError, Output: err.Error()}
+	}
+
+	var diagnostics []models.Diagnostic
+	for _, action := range graph.Roots {
+		if action.Err != nil {
+			return models.StageResult{Status: models.StageStatusError, Output: action.Err.Error()}
+		}
+		for _, finding := range action.Diagnostics {
+			position := action.Package.Fset.Position(finding.Pos)
+			filename, ok := submittedPath(files, position.Filename)
+			if !ok {
+				continue
+			}
+			diagnostics = append(diagnostics, models.Diagnostic{
+				File:     filename,
+				Line:     position.Line,
+				Column:   position.Column,
+				Category: action.Analyzer.Name,
+				Message:  finding.Message,
+			})
+		}
+	}
+
+	if len(diagnostics) == 0 {
+		return models.StageResult{Status: models.StageStatusPass}
+	}
+	sortDiagnostics(diagnostics)
+	return models.StageResult{
+		Status:      models.StageStatusFail,
+		Output:      formatDiagnostics(diagnostics),
+		Diagnostics: diagnostics,
+	}
+}
+
+// importsCgo reports whether a file imports "C"
+func importsCgo(filename, code string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, code, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain lets the test binary act as the sandbox init process, which
+// NamespaceSandbox starts by re-executing the running binary
+func TestMain(m *testing.M) {
+	MaybeRunSandboxChild()
+	os.Exit(m.Run())
+}
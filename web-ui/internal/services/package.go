@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"web-ui/internal/models"
@@ -16,6 +17,9 @@ import (
 type PackageService struct {
 	httpClient   *http.Client
 	packagesPath string
+
+	mu       sync.RWMutex
+	statuses map[string]string // "{package}/{challenge}" -> status admins set in place of the one on disk
 }
 
 func NewPackageService() *PackageService {
@@ -24,9 +28,34 @@ func NewPackageService() *PackageService {
 			Timeout: 30 * time.Second,
 		},
 		packagesPath: "../packages", // Relative to web-ui directory
+		statuses:     make(map[string]string),
 	}
 }
 
+// SetChallengeStatus sets the status a package challenge has in place of
+// the one on disk, such as models.ChallengeComingSoon; an empty status
+// restores the one on disk
+func (s *PackageService) SetChallengeStatus(packageName, challengeID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == "" {
+		delete(s.statuses, packageName+"/"+challengeID)
+	} else {
+		s.statuses[packageName+"/"+challengeID] = status
+	}
+}
+
+// challengeStatus returns the status admins set on a package challenge, or
+// onDisk when they set none
+func (s *PackageService) challengeStatus(packageName, challengeID, onDisk string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if status, ok := s.statuses[packageName+"/"+challengeID]; ok {
+		return status
+	}
+	return onDisk
+}
+
 type PackageMetadata struct {
 	Name             string   `json:"name"`
 	DisplayName      string   `json:"display_name"`
@@ -179,6 +208,10 @@ func (s *PackageService) loadChallengeDetails(packagePath string, learningPath [
 		}
 	}
 
+	packageName := filepath.Base(packagePath)
+	for challengeID, info := range challengeDetails {
+		info.Status = s.challengeStatus(packageName, challengeID, info.Status)
+	}
 	return challengeDetails
 }
 
@@ -354,8 +387,24 @@ func (s *PackageService) loadChallenge(challengePath, challengeName string) *mod
 	// Determine difficulty - try to load from metadata first, then infer from challenge name
 	difficulty := "Beginner" // default fallback
 
-	// Try to load metadata.json for difficulty
+	// Try to load metadata.json for difficulty, execution limits, run stages and benchmark settings
 	metadata := s.loadChallengeMetadata(challengePath)
+	var limits models.ExecutionLimits
+	if metadata != nil && metadata.Limits != nil {
+		limits = *metadata.Limits
+	}
+	var stages models.StageConfig
+	if metadata != nil && metadata.Stages != nil {
+		stages = *metadata.Stages
+	}
+	var benchmark models.BenchmarkConfig
+	if metadata != nil && metadata.Benchmark != nil {
+		benchmark = *metadata.Benchmark
+	}
+	var fuzz models.FuzzConfig
+	if metadata != nil && metadata.Fuzz != nil {
+		fuzz = *metadata.Fuzz
+	}
 	if metadata != nil && metadata.Difficulty != "" {
 		difficulty = metadata.Difficulty
 	} else {
@@ -377,6 +426,12 @@ func (s *PackageService) loadChallenge(challengePath, challengeName string) *mod
 		TestFile:          testFile,
 		Hints:             hints,
 		LearningMaterials: learningMaterials, // Use learning.md for learning materials tab
+		Limits:            limits,
+		Stages:            stages,
+		Benchmark:         benchmark,
+		Fuzz:              fuzz,
+		Status:            s.challengeStatus(filepath.Base(filepath.Dir(challengePath)), challengeName, models.ChallengeAvailable),
+		Dir:               challengePath,
 	}
 }
 
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+)
+
+// PackageCompletions is who completed which challenges of a package,
+// according to the challenges' SCOREBOARD.md files
+type PackageCompletions struct {
+	Package    string
+	Challenges []string                               // Every challenge directory of the package, sorted
+	Completed  map[string]map[string]bool             // username -> challenges with every test passed
+	Scores     map[string]map[string]models.TestScore // username -> challenges on whose scoreboard they are, passed or not
+}
+
+// LoadCompletions reads the scoreboards of every package challenge. Every
+// package directory is listed, sorted by name, including packages nobody
+// has completed a challenge of.
+func (s *PackageService) LoadCompletions() ([]PackageCompletions, error) {
+	entries, err := os.ReadDir(s.packagesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []PackageCompletions
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		completions, err := s.LoadPackageCompletions(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, completions)
+	}
+	return packages, nil
+}
+
+// LoadPackageCompletions reads the scoreboards of one package's challenges
+func (s *PackageService) LoadPackageCompletions(packageName string) (PackageCompletions, error) {
+	completions := PackageCompletions{
+		Package:   packageName,
+		Completed: make(map[string]map[string]bool),
+		Scores:    make(map[string]map[string]models.TestScore),
+	}
+
+	packagePath := filepath.Join(s.packagesPath, packageName)
+	challenges, err := os.ReadDir(packagePath)
+	if err != nil {
+		return completions, err
+	}
+	for _, challenge := range challenges {
+		if !challenge.IsDir() || !strings.HasPrefix(challenge.Name(), "challenge-") {
+			continue
+		}
+		completions.Challenges = append(completions.Challenges, challenge.Name())
+
+		board, err := scoreboard.Load(filepath.Join(packagePath, challenge.Name(), scoreboard.FileName))
+		if err != nil {
+			continue
+		}
+		for _, row := range board.Rows {
+			if completions.Scores[row.Username] == nil {
+				completions.Scores[row.Username] = make(map[string]models.TestScore)
+			}
+			completions.Scores[row.Username][challenge.Name()] = models.TestScore{Passed: row.Passed, Total: row.Total}
+
+			if !row.Completed() {
+				continue
+			}
+			if completions.Completed[row.Username] == nil {
+				completions.Completed[row.Username] = make(map[string]bool)
+			}
+			completions.Completed[row.Username][challenge.Name()] = true
+		}
+	}
+	return completions, nil
+}
+
+// RankPackage ranks everyone on a package's scoreboards by completed
+// challenges, then by tests passed, then by username. Users who completed
+// none of the challenges rank last.
+func RankPackage(completions PackageCompletions) []models.PackageLeaderboardUser {
+	var leaderboard []models.PackageLeaderboardUser
+	for username, scores := range completions.Scores {
+		user := models.PackageLeaderboardUser{
+			Username:        username,
+			CompletedCount:  len(completions.Completed[username]),
+			TotalChallenges: len(completions.Challenges),
+			Challenges:      scores,
+		}
+		for _, score := range scores {
+			user.TestsPassed += score.Passed
+			user.TestsTotal += score.Total
+		}
+		leaderboard = append(leaderboard, user)
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].CompletedCount != leaderboard[j].CompletedCount {
+			return leaderboard[i].CompletedCount > leaderboard[j].CompletedCount
+		}
+		if leaderboard[i].TestsPassed != leaderboard[j].TestsPassed {
+			return leaderboard[i].TestsPassed > leaderboard[j].TestsPassed
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	return leaderboard
+}
+
+// RankPackageLearners ranks users by the package challenges they completed
+// across all packages, then by username
+func RankPackageLearners(packages []PackageCompletions) []models.PackageLearner {
+	learners := make(map[string]*models.PackageLearner)
+	for _, completions := range packages {
+		for username, completed := range completions.Completed {
+			if len(completed) == 0 {
+				continue
+			}
+			learner := learners[username]
+			if learner == nil {
+				learner = &models.PackageLearner{Username: username, Packages: make(map[string]int)}
+				learners[username] = learner
+			}
+			learner.CompletedCount += len(completed)
+			learner.Packages[completions.Package] = len(completed)
+		}
+	}
+
+	leaderboard := make([]models.PackageLearner, 0, len(learners))
+	for _, learner := range learners {
+		learner.Achievement = PackageAchievement(learner.CompletedCount).String()
+		leaderboard = append(leaderboard, *learner)
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].CompletedCount != leaderboard[j].CompletedCount {
+			return leaderboard[i].CompletedCount > leaderboard[j].CompletedCount
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	return leaderboard
+}
+
+// foldPackageCompletions returns package completions keyed by lowercased
+// username, so users match their scoreboard rows ignoring case
+func foldPackageCompletions(completions PackageCompletions) PackageCompletions {
+	folded := PackageCompletions{
+		Package:    completions.Package,
+		Challenges: completions.Challenges,
+		Completed:  make(map[string]map[string]bool),
+		Scores:     make(map[string]map[string]models.TestScore),
+	}
+	for username, completed := range completions.Completed {
+		key := strings.ToLower(username)
+		if folded.Completed[key] == nil {
+			folded.Completed[key] = make(map[string]bool)
+		}
+		for challenge := range completed {
+			folded.Completed[key][challenge] = true
+		}
+	}
+	for username, scores := range completions.Scores {
+		key := strings.ToLower(username)
+		if folded.Scores[key] == nil {
+			folded.Scores[key] = make(map[string]models.TestScore)
+		}
+		for challenge, score := range scores {
+			folded.Scores[key][challenge] = score
+		}
+	}
+	return folded
+}
+
+// packageProgress returns a user's progress through a package, from
+// completions folded by foldPackageCompletions: the challenges they
+// completed, in order, the first one they have started but not completed,
+// and their package achievement
+func packageProgress(username string, completions PackageCompletions) *models.PackageProgress {
+	key := strings.ToLower(username)
+	progress := &models.PackageProgress{
+		Username:            username,
+		PackageName:         completions.Package,
+		CompletedChallenges: []string{},
+		Achievements:        []string{},
+	}
+	for _, challenge := range completions.Challenges {
+		if completions.Completed[key][challenge] {
+			progress.CompletedChallenges = append(progress.CompletedChallenges, challenge)
+		} else if _, started := completions.Scores[key][challenge]; started && progress.InProgress == "" {
+			progress.InProgress = challenge
+		}
+	}
+	progress.Score = len(progress.CompletedChallenges)
+	if progress.Score > 0 {
+		progress.Achievements = append(progress.Achievements, PackageAchievement(progress.Score).String())
+	}
+	return progress
+}
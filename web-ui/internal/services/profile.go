@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// ErrProfileNotFound is returned for users with no attempt, scoreboard
+// entry or submission
+var ErrProfileNotFound = errors.New("no practice found for this user")
+
+// RecentProfileSubmissions is how many of the latest submits a profile lists
+const RecentProfileSubmissions = 10
+
+// maxActivityGap is the longest pause between two package runs that still
+// counts as time spent on the package
+const maxActivityGap = 30 * time.Minute
+
+// skillTags maps the tags of challenges, and the categories of packages,
+// to the skill categories they count towards
+var skillTags = map[string]models.SkillCategory{
+	"concurrency":         models.SkillConcurrency,
+	"goroutines":          models.SkillConcurrency,
+	"channels":            models.SkillConcurrency,
+	"mutex":               models.SkillConcurrency,
+	"context":             models.SkillConcurrency,
+	"pipelines":           models.SkillConcurrency,
+	"rate-limiting":       models.SkillConcurrency,
+	"web":                 models.SkillWeb,
+	"http":                models.SkillWeb,
+	"http-methods":        models.SkillWeb,
+	"rest-api":            models.SkillWeb,
+	"routing":             models.SkillWeb,
+	"middleware":          models.SkillWeb,
+	"grpc":                models.SkillWeb,
+	"microservices":       models.SkillWeb,
+	"oauth2":              models.SkillWeb,
+	"jwt":                 models.SkillWeb,
+	"cors":                models.SkillWeb,
+	"algorithms":          models.SkillAlgorithms,
+	"searching":           models.SkillAlgorithms,
+	"sorting":             models.SkillAlgorithms,
+	"graphs":              models.SkillAlgorithms,
+	"greedy":              models.SkillAlgorithms,
+	"dynamic-programming": models.SkillAlgorithms,
+	"pattern-matching":    models.SkillAlgorithms,
+	"data-structures":     models.SkillAlgorithms,
+	"databases":           models.SkillDatabases,
+	"database":            models.SkillDatabases,
+	"sql":                 models.SkillDatabases,
+	"raw-sql":             models.SkillDatabases,
+	"sqlite":              models.SkillDatabases,
+	"orm":                 models.SkillDatabases,
+	"crud":                models.SkillDatabases,
+	"migrations":          models.SkillDatabases,
+}
+
+// SkillCategoriesOf returns the skill categories tags count towards, in
+// models.SkillCategories order
+func SkillCategoriesOf(tags ...string) []models.SkillCategory {
+	matched := make(map[models.SkillCategory]bool)
+	for _, tag := range tags {
+		if category, ok := skillTags[strings.ToLower(tag)]; ok {
+			matched[category] = true
+		}
+	}
+	var categories []models.SkillCategory
+	for _, category := range models.SkillCategories {
+		if matched[category] {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// ProfileService builds user profiles from the attempts on disk, the
+// scoreboards and the submission history
+type ProfileService struct {
+	challengeService  *ChallengeService
+	userService       *UserService
+	packageService    *PackageService
+	scoreboardService *ScoreboardService
+	store             SubmissionStore
+}
+
+// NewProfileService creates a new profile service
+func NewProfileService(challengeService *ChallengeService, userService *UserService, packageService *PackageService, scoreboardService *ScoreboardService, store SubmissionStore) *ProfileService {
+	return &ProfileService{
+		challengeService:  challengeService,
+		userService:       userService,
+		packageService:    packageService,
+		scoreboardService: scoreboardService,
+		store:             store,
+	}
+}
+
+// Profile returns a user's profile, or ErrProfileNotFound. Usernames match
+// the scoreboards ignoring case, as on GitHub.
+func (p *ProfileService) Profile(username string) (*models.UserProfile, error) {
+	challenges := p.challengeService.GetChallenges()
+	solveTimes := p.scoreboardService.SolveTimes()
+
+	profile := &models.UserProfile{
+		Username:          username,
+		TotalChallenges:   len(challenges),
+		Challenges:        []models.ProfileChallenge{},
+		Packages:          []*models.PackageProgress{},
+		RecentSubmissions: []models.SubmissionRecord{},
+	}
+	var standing models.LeaderboardUser
+	for _, user := range p.scoreboardService.MainLeaderboard() {
+		if strings.EqualFold(user.Username, username) {
+			standing = user
+			profile.Username = user.Username
+			profile.Rank = user.Rank
+			profile.Achievement = user.Achievement
+			profile.CompletedCount = user.CompletedCount
+			profile.CurrentStreak = user.CurrentStreak
+			profile.LongestStreak = user.LongestStreak
+			break
+		}
+	}
+	// Submission directories and the history use the scoreboards' casing
+	attempts := p.userService.GetUserAttempts(profile.Username, challenges)
+
+	skills := make(map[models.SkillCategory]*models.SkillProgress)
+	for _, category := range models.SkillCategories {
+		skills[category] = &models.SkillProgress{Category: category}
+	}
+	countSkills := func(completed bool, tags ...string) {
+		for _, category := range SkillCategoriesOf(tags...) {
+			skills[category].Total++
+			if completed {
+				skills[category].Completed++
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(challenges))
+	for id := range challenges {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		challenge := challenges[id]
+		completed := standing.CompletedChallenges[id]
+		status := models.ProfileChallenge{
+			ID:         id,
+			Title:      challenge.Title,
+			Difficulty: challenge.Difficulty,
+			Tags:       challenge.Tags,
+			Attempted:  attempts.AttemptedIDs[id],
+			Score:      attempts.Scores[id],
+			Completed:  completed,
+		}
+		if completed {
+			status.SolvedAt = solveTimes.Get(profile.Username, id)
+		}
+		profile.Challenges = append(profile.Challenges, status)
+		countSkills(completed, challenge.Tags...)
+	}
+
+	packages, err := p.packageService.LoadCompletions()
+	if err != nil {
+		return nil, err
+	}
+	for _, completions := range packages {
+		progress, err := p.packageProgress(profile.Username, foldPackageCompletions(completions))
+		if err != nil {
+			return nil, err
+		}
+
+		var category string
+		details := make(map[string]*models.ChallengeInfo)
+		if pkg, err := p.packageService.GetPackage(completions.Package); err == nil {
+			category, details = pkg.Category, pkg.ChallengeDetails
+		}
+		completed := make(map[string]bool)
+		for _, challenge := range progress.CompletedChallenges {
+			completed[challenge] = true
+		}
+		for _, challenge := range completions.Challenges {
+			tags := []string{category}
+			if info := details[challenge]; info != nil {
+				tags = append(tags, info.Tags...)
+			}
+			countSkills(completed[challenge], tags...)
+		}
+
+		if progress.Score > 0 || progress.InProgress != "" || !progress.LastActivity.IsZero() {
+			progress.Username = profile.Username
+			profile.PackageCompleted += progress.Score
+			profile.Packages = append(profile.Packages, progress)
+		}
+	}
+
+	for _, category := range models.SkillCategories {
+		profile.Skills = append(profile.Skills, *skills[category])
+	}
+
+	recent, err := p.store.List(SubmissionQuery{Username: profile.Username, Mode: string(ModeSubmit), Limit: RecentProfileSubmissions})
+	if err != nil {
+		return nil, err
+	}
+	profile.RecentSubmissions = recent.Submissions
+
+	if profile.Rank == 0 && len(attempts.AttemptedIDs) == 0 && len(profile.Packages) == 0 && recent.Total == 0 {
+		return nil, ErrProfileNotFound
+	}
+	return profile, nil
+}
+
+// packageProgress returns a user's progress through a package: what the
+// package's scoreboards say, with when the user started and last ran one of
+// its challenges and the time they spent on it, from the submission
+// history. A challenge the user has run but is on no scoreboard of counts
+// as in progress.
+func (p *ProfileService) packageProgress(username string, completions PackageCompletions) (*models.PackageProgress, error) {
+	progress := packageProgress(username, completions)
+
+	var runs []time.Time
+	started := make(map[string]bool)
+	for offset := 0; ; {
+		page, err := p.store.List(SubmissionQuery{
+			Username:    username,
+			PackageName: completions.Package,
+			Limit:       MaxHistoryLimit,
+			Offset:      offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page.Submissions {
+			runs = append(runs, record.SubmittedAt)
+			started[record.PackageChallenge] = true
+		}
+		offset += len(page.Submissions)
+		if len(page.Submissions) == 0 || offset >= page.Total {
+			break
+		}
+	}
+	if len(runs) == 0 {
+		return progress, nil
+	}
+
+	// The history lists newest first
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Before(runs[j]) })
+	progress.StartedAt = runs[0]
+	progress.LastActivity = runs[len(runs)-1]
+	for i := 1; i < len(runs); i++ {
+		progress.TotalTime += min(runs[i].Sub(runs[i-1]), maxActivityGap)
+	}
+
+	if progress.InProgress == "" {
+		completed := make(map[string]bool)
+		for _, challenge := range progress.CompletedChallenges {
+			completed[challenge] = true
+		}
+		for _, challenge := range completions.Challenges {
+			if started[challenge] && !completed[challenge] {
+				progress.InProgress = challenge
+				break
+			}
+		}
+	}
+	return progress, nil
+}
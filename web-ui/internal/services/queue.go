@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// finishedJobRetention is how long a finished job's events stay available
+// for clients that reconnect or poll late
+const finishedJobRetention = 5 * time.Minute
+
+// Default queue settings, overridable with EXECUTION_WORKERS,
+// EXECUTION_QUEUE_SIZE and EXECUTION_USER_LIMIT
+const (
+	DefaultQueueSize = 20
+	DefaultUserLimit = 2
+)
+
+// Errors returned by ExecutionQueue.Submit when a job cannot be accepted
+var (
+	ErrQueueFull = errors.New("the execution queue is full, please try again shortly")
+	ErrUserLimit = errors.New("you already have the maximum number of runs in progress")
+)
+
+// Job states reported by JobStatus.State
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobFinished = "finished"
+)
+
+// ExecutionJob is a queued or running code run. It records every event so
+// that a client connecting late still receives the full history.
+type ExecutionJob struct {
+	ID        string
+	User      string // Who submitted the job, for per-user limits and access to it
+	CreatedAt time.Time
+
+	queue     *ExecutionQueue
+	mode      RunMode
+	files     models.SourceFiles
+	challenge *models.Challenge
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{} // Closed when the job finishes
+
+	mu        sync.Mutex
+	state     string
+	position  int // Last queue position reported to clients
+	startedAt time.Time
+	events    []ExecutionEvent
+	changed   chan struct{} // Closed and replaced whenever events are added
+	result    *ExecutionResult
+}
+
+// JobStatus is a snapshot of a job for status polling
+type JobStatus struct {
+	ID        string           `json:"id"`
+	State     string           `json:"state"`              // queued, running or finished
+	Position  int              `json:"position,omitempty"` // 1-based queue position while queued
+	CreatedAt time.Time        `json:"createdAt"`
+	StartedAt *time.Time       `json:"startedAt,omitempty"`
+	Result    *ExecutionResult `json:"result,omitempty"` // Set once the job has finished
+}
+
+// publish records an event and wakes up waiting readers
+func (j *ExecutionJob) publish(event ExecutionEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.appendLocked(event)
+}
+
+// finish records the final result and publishes it as the last event
+func (j *ExecutionJob) finish(result ExecutionResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Set together with the event so readers never see a finished job
+	// without its result event
+	j.state = JobFinished
+	j.result = &result
+	j.appendLocked(ExecutionEvent{Type: EventResult, Result: &result})
+	close(j.done)
+}
+
+// appendLocked adds an event; j.mu must be held
+func (j *ExecutionJob) appendLocked(event ExecutionEvent) {
+	j.events = append(j.events, event)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// EventsSince returns the events after the first n, whether the job has
+// finished, and a channel that is closed when more events arrive
+func (j *ExecutionJob) EventsSince(n int) ([]ExecutionEvent, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if n > len(j.events) {
+		n = len(j.events)
+	}
+	events := append([]ExecutionEvent(nil), j.events[n:]...)
+	return events, j.result != nil, j.changed
+}
+
+// Result returns the final result, or nil while the job is still queued or
+// running
+func (j *ExecutionJob) Result() *ExecutionResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result
+}
+
+// Status returns a snapshot of the job's state
+func (j *ExecutionJob) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := JobStatus{
+		ID:        j.ID,
+		State:     j.state,
+		CreatedAt: j.CreatedAt,
+		Result:    j.result,
+	}
+	if j.state == JobQueued {
+		status.Position = j.position
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	return status
+}
+
+// Wait blocks until the job finishes and returns its result. If ctx is
+// cancelled first (e.g. the HTTP client went away) the job is cancelled.
+func (j *ExecutionJob) Wait(ctx context.Context) ExecutionResult {
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.Cancel()
+		<-j.done
+	}
+	return *j.Result()
+}
+
+// Cancel removes the job from the queue, or kills the test process if it is
+// already running
+func (j *ExecutionJob) Cancel() {
+	j.queue.cancel(j)
+}
+
+// ExecutionQueue runs code on a fixed pool of workers so that a burst of
+// requests cannot start an unbounded number of `go test` processes
+type ExecutionQueue struct {
+	executionService *ExecutionService
+	workers          int
+	maxQueued        int
+	userLimit        int
+
+	mu       sync.Mutex
+	ready    *sync.Cond // Signalled when a job is added to pending
+	pending  []*ExecutionJob
+	jobs     map[string]*ExecutionJob
+	userJobs map[string]int // Queued and running jobs per user
+}
+
+// NewExecutionQueue creates a queue and starts its workers. The worker count
+// defaults to half the CPUs, since each `go test` is itself parallel.
+func NewExecutionQueue(executionService *ExecutionService) *ExecutionQueue {
+	q := &ExecutionQueue{
+		executionService: executionService,
+		workers:          envInt("EXECUTION_WORKERS", max(1, runtime.NumCPU()/2)),
+		maxQueued:        envInt("EXECUTION_QUEUE_SIZE", DefaultQueueSize),
+		userLimit:        envInt("EXECUTION_USER_LIMIT", DefaultUserLimit),
+		jobs:             make(map[string]*ExecutionJob),
+		userJobs:         make(map[string]int),
+	}
+	q.ready = sync.NewCond(&q.mu)
+
+	for i := 0; i < q.workers; i++ {
+		go q.worker()
+	}
+	log.Printf("Execution queue: %d workers, %d queued jobs max, %d jobs per user", q.workers, q.maxQueued, q.userLimit)
+
+	return q
+}
+
+// Submit queues a submission's files to run against a challenge in the given mode. It fails with ErrQueueFull
+// or ErrUserLimit when the job cannot be accepted.
+func (q *ExecutionQueue) Submit(user string, mode RunMode, files models.SourceFiles, challenge *models.Challenge) (*ExecutionJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) >= q.maxQueued {
+		return nil, ErrQueueFull
+	}
+	if q.userJobs[user] >= q.userLimit {
+		return nil, ErrUserLimit
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &ExecutionJob{
+		ID:        newJobID(),
+		User:      user,
+		CreatedAt: time.Now(),
+		queue:     q,
+		mode:      mode,
+		files:     files,
+		challenge: challenge,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		state:     JobQueued,
+		changed:   make(chan struct{}),
+	}
+
+	q.jobs[job.ID] = job
+	q.userJobs[user]++
+	q.pending = append(q.pending, job)
+	q.reportPositionsLocked()
+	q.ready.Signal()
+
+	return job, nil
+}
+
+// GetJob returns a queued, running or recently finished job by ID
+func (q *ExecutionQueue) GetJob(id string) (*ExecutionJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, exists := q.jobs[id]
+	return job, exists
+}
+
+// worker runs queued jobs one at a time
+func (q *ExecutionQueue) worker() {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 {
+			q.ready.Wait()
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.reportPositionsLocked()
+		q.mu.Unlock()
+
+		job.mu.Lock()
+		job.state = JobRunning
+		job.startedAt = time.Now()
+		job.mu.Unlock()
+
+		result := q.executionService.Execute(job.ctx, job.mode, job.User, job.files, job.challenge, job.publish)
+		job.cancel()
+		q.complete(job, result)
+	}
+}
+
+// cancel removes a queued job or stops a running one
+func (q *ExecutionQueue) cancel(job *ExecutionJob) {
+	q.mu.Lock()
+	removed := false
+	for i, pending := range q.pending {
+		if pending == job {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if removed {
+		q.reportPositionsLocked()
+	}
+	q.mu.Unlock()
+
+	job.cancel()
+	if removed {
+		// Never started, so no worker will finish it
+		q.complete(job, ExecutionResult{
+			Output: DescribeKill(KillCancelled, job.challenge.Limits),
+			Killed: KillCancelled,
+			Limits: ResolveLimits(job.challenge.Limits),
+		})
+	}
+}
+
+// complete finishes a job and releases its user's slot
+func (q *ExecutionQueue) complete(job *ExecutionJob, result ExecutionResult) {
+	job.finish(result)
+
+	q.mu.Lock()
+	q.userJobs[job.User]--
+	if q.userJobs[job.User] <= 0 {
+		delete(q.userJobs, job.User)
+	}
+	q.mu.Unlock()
+
+	// Forget the job once late clients have had a chance to read it
+	time.AfterFunc(finishedJobRetention, func() {
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+	})
+}
+
+// reportPositionsLocked tells queued jobs whose position changed where they
+// now are in the queue; q.mu must be held
+func (q *ExecutionQueue) reportPositionsLocked() {
+	for i, job := range q.pending {
+		position := i + 1
+
+		job.mu.Lock()
+		if job.position != position {
+			job.position = position
+			job.appendLocked(ExecutionEvent{
+				Type:     EventQueued,
+				Position: position,
+				Message:  "Waiting for a free worker",
+			})
+		}
+		job.mu.Unlock()
+	}
+}
+
+// newJobID returns a random identifier for a job
+func newJobID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
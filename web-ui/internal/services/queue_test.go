@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// newTestQueue returns an execution queue without workers, so that tests
+// decide when and how its jobs finish
+func newTestQueue(maxQueued, userLimit int) *ExecutionQueue {
+	q := &ExecutionQueue{
+		maxQueued: maxQueued,
+		userLimit: userLimit,
+		jobs:      make(map[string]*ExecutionJob),
+		userJobs:  make(map[string]int),
+	}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+// takeJob waits for a job to be queued and takes it off the queue, as a
+// worker would
+func takeJob(t *testing.T, q *ExecutionQueue) *ExecutionJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			job := q.pending[0]
+			q.pending = q.pending[1:]
+			q.reportPositionsLocked()
+			q.mu.Unlock()
+			return job
+		}
+		q.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no job was queued")
+	return nil
+}
+
+func TestExecutionQueueLimits(t *testing.T) {
+	challenge := &models.Challenge{ID: 1}
+	tests := []struct {
+		name      string
+		maxQueued int
+		userLimit int
+		users     []string // Submitters in order
+		want      []error
+	}{
+		{"within the limits", 4, 2, []string{"alice", "alice", "bob"}, []error{nil, nil, nil}},
+		{"per-user limit", 4, 2, []string{"alice", "alice", "alice", "bob"}, []error{nil, nil, ErrUserLimit, nil}},
+		{"queue full", 2, 2, []string{"alice", "bob", "carol"}, []error{nil, nil, ErrQueueFull}},
+		{"queue full before the user limit", 1, 1, []string{"alice", "alice"}, []error{nil, ErrQueueFull}},
+	}
+	for _, tt := range tests {
+		q := newTestQueue(tt.maxQueued, tt.userLimit)
+		for i, user := range tt.users {
+			if _, err := q.Submit(user, ModeTest, SingleFile("package main\n"), challenge); !errors.Is(err, tt.want[i]) {
+				t.Errorf("%s: submission %d by %s returned %v, want %v", tt.name, i+1, user, err, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestExecutionQueueRunningJobsCount(t *testing.T) {
+	challenge := &models.Challenge{ID: 1}
+	q := newTestQueue(4, 1)
+	job, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A running job still holds its user's slot, but no longer a queue place
+	takeJob(t, q)
+	if _, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge); !errors.Is(err, ErrUserLimit) {
+		t.Errorf("second run while the first is running returned %v, want ErrUserLimit", err)
+	}
+
+	q.complete(job, ExecutionResult{})
+	if _, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge); err != nil {
+		t.Errorf("run after the first finished returned %v", err)
+	}
+}
+
+func TestExecutionQueueCancel(t *testing.T) {
+	challenge := &models.Challenge{ID: 1}
+	q := newTestQueue(4, 2)
+	first, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := second.Status(); status.Position != 2 {
+		t.Errorf("second job is at position %d, want 2", status.Position)
+	}
+
+	// Cancelling a queued job finishes it and moves the rest of the queue up
+	first.Cancel()
+	result := first.Wait(context.Background())
+	if result.Killed != KillCancelled {
+		t.Errorf("cancelled job was killed for %q, want %q", result.Killed, KillCancelled)
+	}
+	if first.ctx.Err() == nil {
+		t.Error("cancelled job's context is still live")
+	}
+	if status := second.Status(); status.Position != 1 {
+		t.Errorf("second job is at position %d after the first was cancelled, want 1", status.Position)
+	}
+	if _, err := q.Submit("alice", ModeTest, SingleFile("package main\n"), challenge); err != nil {
+		t.Errorf("cancelling did not release the user's slot: %v", err)
+	}
+
+	// Cancelling a running job only stops it; the worker finishes it
+	running := takeJob(t, q)
+	if running != second {
+		t.Fatal("the queue did not keep its order")
+	}
+	running.Cancel()
+	if running.ctx.Err() == nil {
+		t.Error("running job's context was not cancelled")
+	}
+	select {
+	case <-running.done:
+		t.Error("running job finished before its worker completed it")
+	default:
+	}
+
+	// Giving up on waiting cancels the job
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	third := takeJob(t, q)
+	go func() {
+		<-third.ctx.Done()
+		q.complete(third, ExecutionResult{Killed: KillCancelled})
+	}()
+	if result := third.Wait(ctx); result.Killed != KillCancelled {
+		t.Errorf("abandoned job was killed for %q, want %q", result.Killed, KillCancelled)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// DefaultDifficulty is the difficulty of challenges whose metadata does not
+// give a known one
+const DefaultDifficulty = "Intermediate"
+
+// difficultyPoints are what a challenge of each difficulty is worth in the
+// weighted ranking, unless its metadata sets its points
+var difficultyPoints = map[string]int{
+	"Beginner":     1,
+	"Intermediate": 2,
+	"Advanced":     3,
+}
+
+// icpcFailurePenalty is the penalty, in minutes, for each failed submit
+// before a challenge's first passing one, as in ICPC contests
+const icpcFailurePenalty = 20
+
+// Ranking strategy names, as the main leaderboard's ranking parameter takes
+// them
+const (
+	RankingCount    = "count"
+	RankingWeighted = "weighted"
+	RankingICPC     = "icpc"
+)
+
+// DefaultRanking is the ranking the main leaderboard uses unless asked for
+// another
+const DefaultRanking = RankingCount
+
+// ErrUnknownRanking is returned for a ranking strategy name that does not
+// exist
+var ErrUnknownRanking = errors.New("unknown ranking: expected count, weighted or icpc")
+
+// Score is a user's standing under a ranking strategy. Users with more
+// points rank higher, and among equal points the one with less penalty.
+type Score struct {
+	Points  int
+	Penalty int
+}
+
+// RankingStrategy scores users for the main leaderboard from the classic
+// challenges they completed
+type RankingStrategy interface {
+	// Name returns the strategy's name, such as RankingCount
+	Name() string
+	// Score scores a user from their completed challenges and when they
+	// first solved them, which is zero if not known
+	Score(username string, completed map[int]time.Time) Score
+}
+
+// NewRankingStrategy returns the strategy with a name, or ErrUnknownRanking.
+// The weighted ranking takes its points from challenges; the ICPC ranking
+// reads its penalties from store.
+func NewRankingStrategy(name string, challenges models.ChallengeMap, store SubmissionStore) (RankingStrategy, error) {
+	switch name {
+	case RankingCount:
+		return CountRanking{}, nil
+	case RankingWeighted:
+		return WeightedRanking{Challenges: challenges}, nil
+	case RankingICPC:
+		attempts, err := store.SolveAttempts()
+		if err != nil {
+			return nil, err
+		}
+		return ICPCRanking{Attempts: attempts}, nil
+	}
+	return nil, ErrUnknownRanking
+}
+
+// CountRanking scores a point for each completed challenge
+type CountRanking struct{}
+
+// Name returns RankingCount
+func (CountRanking) Name() string { return RankingCount }
+
+// Score returns the number of completed challenges
+func (CountRanking) Score(username string, completed map[int]time.Time) Score {
+	return Score{Points: len(completed)}
+}
+
+// WeightedRanking scores each completed challenge by its points, which
+// come from its difficulty or its metadata
+type WeightedRanking struct {
+	Challenges models.ChallengeMap
+}
+
+// Name returns RankingWeighted
+func (WeightedRanking) Name() string { return RankingWeighted }
+
+// Score returns the sum of the completed challenges' points. Challenges
+// that no longer exist score nothing.
+func (r WeightedRanking) Score(username string, completed map[int]time.Time) Score {
+	var score Score
+	for id := range completed {
+		if challenge, ok := r.Challenges[id]; ok {
+			score.Points += challenge.Points
+		}
+	}
+	return score
+}
+
+// ICPCRanking scores a point for each completed challenge, with a penalty
+// in minutes: for each challenge, the time from the first submit to the
+// first passing one, plus icpcFailurePenalty for every failed submit in
+// between. Challenges without submission history, such as solutions only
+// committed to the repository, carry no penalty.
+type ICPCRanking struct {
+	Attempts SolveAttempts
+}
+
+// Name returns RankingICPC
+func (ICPCRanking) Name() string { return RankingICPC }
+
+// Score returns the number of completed challenges and their total penalty
+func (r ICPCRanking) Score(username string, completed map[int]time.Time) Score {
+	score := Score{Points: len(completed)}
+	for id := range completed {
+		if attempt, ok := r.Attempts.Get(username, id); ok {
+			score.Penalty += attempt.Penalty()
+		}
+	}
+	return score
+}
+
+// SolveAttempt is how a user got to their first passing submit of a
+// challenge
+type SolveAttempt struct {
+	FirstTry  time.Time // The first submit
+	FirstPass time.Time // The first passing submit
+	Failures  int       // Failed submits before FirstPass, not counting cancelled ones
+}
+
+// Penalty returns the attempt's ICPC penalty in minutes
+func (a SolveAttempt) Penalty() int {
+	return int(a.FirstPass.Sub(a.FirstTry)/time.Minute) + a.Failures*icpcFailurePenalty
+}
+
+// SolveAttempts records users' solve attempts of classic challenges.
+// Usernames are compared ignoring case.
+type SolveAttempts map[string]map[int]SolveAttempt
+
+// Add records an attempt, keeping the one that passed first when a user
+// already has one for the challenge
+func (a SolveAttempts) Add(username string, challengeID int, attempt SolveAttempt) {
+	key := strings.ToLower(username)
+	if a[key] == nil {
+		a[key] = make(map[int]SolveAttempt)
+	}
+	if existing, ok := a[key][challengeID]; !ok || attempt.FirstPass.Before(existing.FirstPass) {
+		a[key][challengeID] = attempt
+	}
+}
+
+// Get returns a user's attempt of a challenge, if recorded
+func (a SolveAttempts) Get(username string, challengeID int) (SolveAttempt, bool) {
+	attempt, ok := a[strings.ToLower(username)][challengeID]
+	return attempt, ok
+}
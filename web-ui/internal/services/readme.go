@@ -0,0 +1,315 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"web-ui/internal/models"
+)
+
+// README sections the scoreboard command maintains. Each runs from its
+// heading to the end of its marker line.
+const (
+	ClassicLeaderboardHeading = "## 🏆 Top 10 Leaderboard"
+	ClassicLeaderboardMarker  = "<!-- END_CLASSIC_LEADERBOARD -->"
+	PackageLeaderboardHeading = "## 🚀 Package Challenges Leaderboard"
+	PackageLeaderboardMarker  = "<!-- END_PACKAGE_LEADERBOARD -->"
+)
+
+// keyFeaturesHeading follows the leaderboards in the README
+const keyFeaturesHeading = "## Key Features"
+
+// README table sizes
+const (
+	readmeTopUsers        = 10
+	readmePackageTopUsers = 5
+	readmeProgressLength  = 10
+)
+
+// RenderClassicLeaderboard renders the README's classic leaderboard section
+// from the main leaderboard. challengeIDs are every challenge, in the order
+// of the progress indicators.
+func RenderClassicLeaderboard(leaderboard []models.LeaderboardUser, challengeIDs []int) string {
+	total := len(challengeIDs)
+	lines := []string{
+		ClassicLeaderboardHeading,
+		"",
+		"Our most accomplished Go developers, ranked by number of challenges completed:",
+		"",
+		"> **Note**: The data below is automatically updated by GitHub Actions when challenge scoreboards change.",
+		"",
+	}
+
+	if len(leaderboard) > 0 {
+		lines = append(lines,
+			"| 🏅 | Developer | Solved | Rate | Achievement | Progress |",
+			"|:---:|:---:|:---:|:---:|:---:|:---|",
+		)
+
+		// The indicators are split into two rows, the first one longer
+		half := total/2 + total%2
+		for _, user := range leaderboard[:min(len(leaderboard), readmeTopUsers)] {
+			var first, second strings.Builder
+			for i, id := range challengeIDs {
+				row := &first
+				if i >= half {
+					row = &second
+				}
+				if user.CompletedChallenges[id] {
+					row.WriteString("✅")
+				} else {
+					row.WriteString("⬜")
+				}
+			}
+
+			lines = append(lines, fmt.Sprintf("| %s | %s | **%d**/%d | **%.1f%%** | %s | %s<br/>%s |",
+				rankBadge(user.Rank), profileCell(user.Username), user.CompletedCount, total,
+				user.CompletionRate, MainAchievement(user.CompletedCount).Name, first.String(), second.String()))
+		}
+
+		lines = append(lines,
+			"",
+			`<div align="center">`,
+			"",
+			"✅ Completed • ⬜ Not Completed",
+			"",
+			fmt.Sprintf("*All %d challenges shown in two rows*", total),
+			"",
+			"</div>",
+		)
+	} else {
+		lines = append(lines, "No completed challenges yet. Be the first to solve a challenge!", "")
+	}
+
+	mostSolved := "0 by N/A"
+	if len(leaderboard) > 0 {
+		mostSolved = fmt.Sprintf("%d by %s", leaderboard[0].CompletedCount, leaderboard[0].Username)
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("*Updated automatically based on %d available challenges*", total),
+		"",
+		"### Challenge Progress Overview",
+		"",
+		fmt.Sprintf("- **Total Challenges Available**: %d", total),
+		fmt.Sprintf("- **Active Developers**: %d", len(leaderboard)),
+		"- **Most Challenges Solved**: "+mostSolved,
+		"",
+		ClassicLeaderboardMarker,
+		"",
+	)
+	return strings.Join(lines, "\n")
+}
+
+// RenderPackageLeaderboard renders the README's package leaderboard section:
+// the top learners across all packages and a table per package
+func RenderPackageLeaderboard(packages []PackageCompletions) string {
+	lines := []string{
+		PackageLeaderboardHeading,
+		"",
+		"Master Go packages through hands-on challenges! Each package offers a structured learning path with real-world scenarios.",
+		"",
+		"> **Note**: The data below is automatically updated by GitHub Actions when package challenge scoreboards change.",
+		"",
+	}
+
+	learners := RankPackageLearners(packages)
+	if len(learners) > 0 {
+		lines = append(lines,
+			"| 🏅 | Developer | Total Solved | Packages | Achievement | Challenge Distribution |",
+			"|:---:|:---:|:---:|:---:|:---:|:---|",
+		)
+		for _, learner := range learners[:min(len(learners), readmeTopUsers)] {
+			names := make([]string, 0, len(learner.Packages))
+			for name := range learner.Packages {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			breakdown := make([]string, len(names))
+			for i, name := range names {
+				breakdown[i] = fmt.Sprintf("**%s**: %d", name, learner.Packages[name])
+			}
+
+			plural := "s"
+			if len(names) == 1 {
+				plural = ""
+			}
+			lines = append(lines, fmt.Sprintf("| %s | %s | **%d** | **%d** pkg%s | %s | %s |",
+				rankBadge(learner.Rank), profileCell(learner.Username), learner.CompletedCount,
+				len(names), plural, learner.Achievement, strings.Join(breakdown, " • ")))
+		}
+		lines = append(lines,
+			"",
+			`<div align="center">`,
+			"",
+			"🚀 **Package Challenges** - Learn Go packages through practical, real-world scenarios",
+			"",
+			"</div>",
+		)
+	} else {
+		lines = append(lines, "No completed package challenges yet. Be the first to solve a package challenge!", "")
+	}
+
+	lines = append(lines, "", "### 📦 Per-Package Progress", "")
+	totalChallenges := 0
+	names := make([]string, len(packages))
+	for i, completions := range packages {
+		totalChallenges += len(completions.Challenges)
+		names[i] = completions.Package
+
+		// Only learners who completed a challenge are listed; they rank first
+		leaderboard := RankPackage(completions)
+		for len(leaderboard) > 0 && leaderboard[len(leaderboard)-1].CompletedCount == 0 {
+			leaderboard = leaderboard[:len(leaderboard)-1]
+		}
+		if len(leaderboard) == 0 {
+			continue
+		}
+		lines = append(lines,
+			fmt.Sprintf("#### %s Package", titleCase(completions.Package)),
+			"",
+			"| Rank | Developer | Completed | Progress |",
+			"|:---:|:---:|:---:|:---|",
+		)
+		for _, user := range leaderboard[:min(len(leaderboard), readmePackageTopUsers)] {
+			lines = append(lines, fmt.Sprintf("| %s | **[%s](https://github.com/%s)** | %d/%d | %s |",
+				rankBadge(user.Rank), user.Username, user.Username, user.CompletedCount, user.TotalChallenges,
+				progressBar(user.CompletedCount, user.TotalChallenges)))
+		}
+		lines = append(lines, "")
+	}
+
+	lines = append(lines,
+		"### 📊 Package Challenge Statistics",
+		"",
+		fmt.Sprintf("- **Total Package Challenges Available**: %d", totalChallenges),
+		fmt.Sprintf("- **Active Package Learners**: %d", len(learners)),
+		fmt.Sprintf("- **Available Packages**: %d (%s)", len(packages), strings.Join(names, ", ")),
+		"",
+	)
+	if len(learners) > 0 {
+		lines = append(lines, fmt.Sprintf("- **Most Package Challenges Solved**: %d by %s", learners[0].CompletedCount, learners[0].Username), "")
+	}
+	lines = append(lines, PackageLeaderboardMarker, "")
+	return strings.Join(lines, "\n")
+}
+
+// rankBadge returns a medal for the top three ranks and the number otherwise
+func rankBadge(rank int) string {
+	switch rank {
+	case 1:
+		return "🥇"
+	case 2:
+		return "🥈"
+	case 3:
+		return "🥉"
+	}
+	return fmt.Sprint(rank)
+}
+
+// profileCell shows a user's GitHub avatar above a link to their profile
+func profileCell(username string) string {
+	return fmt.Sprintf(`<img src="https://github.com/%s.png" width="24" height="24" style="border-radius: 50%%;"><br/>**[%s](https://github.com/%s)**`,
+		username, username, username)
+}
+
+// progressBar draws completed out of total as squares and a percentage
+func progressBar(completed, total int) string {
+	if total == 0 {
+		return strings.Repeat("⬜", readmeProgressLength)
+	}
+	progress := float64(completed) / float64(total)
+	filled := int(progress * readmeProgressLength)
+	return fmt.Sprintf("%s%s %.0f%%", strings.Repeat("🟩", filled), strings.Repeat("⬜", readmeProgressLength-filled), progress*100)
+}
+
+// titleCase capitalizes the first letter of every word, where any
+// character that is not a letter separates words
+func titleCase(s string) string {
+	var b strings.Builder
+	previousLetter := false
+	for _, r := range s {
+		if previousLetter {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		previousLetter = unicode.IsLetter(r)
+	}
+	return b.String()
+}
+
+// UpdateReadme replaces the leaderboard sections of the README with the
+// given ones. A section runs from its heading through its marker line, or
+// to the next section when the marker is missing. A missing classic section
+// is inserted before the package section, and a missing package section
+// after the classic one; either falls back to before "Key Features".
+func UpdateReadme(content, classic, packages string) (string, error) {
+	content, err := replaceSection(content, ClassicLeaderboardHeading, ClassicLeaderboardMarker, classic,
+		[]string{PackageLeaderboardHeading, keyFeaturesHeading, "## Getting Started"},
+		func(content string) int { return strings.Index(content, PackageLeaderboardHeading) })
+	if err != nil {
+		return content, err
+	}
+
+	return replaceSection(content, PackageLeaderboardHeading, PackageLeaderboardMarker, packages,
+		[]string{keyFeaturesHeading, "## Getting Started", "## Challenge Categories"},
+		func(content string) int {
+			end := strings.Index(content, ClassicLeaderboardMarker)
+			if end < 0 {
+				return -1
+			}
+			if newline := strings.Index(content[end:], "\n"); newline >= 0 {
+				return end + newline + 1
+			}
+			return len(content)
+		})
+}
+
+// StaleReadmeSections returns the names of the leaderboard sections the
+// README does not hold exactly as rendered, for reporting drift
+func StaleReadmeSections(content, classic, packages string) []string {
+	var stale []string
+	if !strings.Contains(content, classic) {
+		stale = append(stale, "classic leaderboard")
+	}
+	if !strings.Contains(content, packages) {
+		stale = append(stale, "package leaderboard")
+	}
+	return stale
+}
+
+// replaceSection replaces one README section. next are the headings that
+// may follow a section without a marker; insertAt finds where a missing
+// section goes, or returns -1 to fall back to before "Key Features".
+func replaceSection(content, heading, marker, section string, next []string, insertAt func(string) int) (string, error) {
+	start := strings.Index(content, heading)
+	if start < 0 {
+		at := insertAt(content)
+		if at < 0 {
+			at = strings.Index(content, keyFeaturesHeading)
+		}
+		if at < 0 {
+			return content, fmt.Errorf("could not find where to insert %q", heading)
+		}
+		return content[:at] + section + "\n" + content[at:], nil
+	}
+
+	end := len(content)
+	if markerAt := strings.Index(content, marker); markerAt >= 0 {
+		end = markerAt + len(marker)
+		if newline := strings.Index(content[end:], "\n"); newline >= 0 {
+			end += newline + 1
+		}
+	} else {
+		for _, following := range next {
+			if at := strings.Index(content[start+len(heading):], following); at >= 0 {
+				end = start + len(heading) + at
+				break
+			}
+		}
+	}
+	return content[:start] + section + content[end:], nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/readme/golden")
+
+// testLeaderboard is a main leaderboard over challenges 1 to 5
+func testLeaderboard() ([]models.LeaderboardUser, []int) {
+	completed := func(ids ...int) map[int]bool {
+		challenges := make(map[int]bool)
+		for _, id := range ids {
+			challenges[id] = true
+		}
+		return challenges
+	}
+	return []models.LeaderboardUser{
+		{Username: "alice", Rank: 1, CompletedCount: 4, CompletionRate: 80, CompletedChallenges: completed(1, 2, 3, 5)},
+		{Username: "bob", Rank: 2, CompletedCount: 2, CompletionRate: 40, CompletedChallenges: completed(1, 4)},
+		{Username: "carol", Rank: 2, CompletedCount: 2, CompletionRate: 40, CompletedChallenges: completed(2, 3)},
+		{Username: "dave", Rank: 4, CompletedCount: 1, CompletionRate: 20, CompletedChallenges: completed(5)},
+	}, []int{1, 2, 3, 4, 5}
+}
+
+// testPackages are the completions of two packages, one nobody completed a
+// challenge of
+func testPackages() []PackageCompletions {
+	return []PackageCompletions{
+		{
+			Package:    "cobra",
+			Challenges: []string{"challenge-1-basic-cli", "challenge-2-flags", "challenge-3-subcommands"},
+			Completed: map[string]map[string]bool{
+				"alice": {"challenge-1-basic-cli": true, "challenge-2-flags": true},
+				"bob":   {"challenge-1-basic-cli": true},
+			},
+			Scores: map[string]map[string]models.TestScore{
+				"alice": {"challenge-1-basic-cli": {Passed: 5, Total: 5}, "challenge-2-flags": {Passed: 4, Total: 4}},
+				"bob":   {"challenge-1-basic-cli": {Passed: 5, Total: 5}, "challenge-2-flags": {Passed: 1, Total: 4}},
+			},
+		},
+		{
+			Package:    "go-redis",
+			Challenges: []string{"challenge-1-basic-operations"},
+			Completed:  map[string]map[string]bool{},
+			Scores: map[string]map[string]models.TestScore{
+				"carol": {"challenge-1-basic-operations": {Passed: 2, Total: 6}},
+			},
+		},
+	}
+}
+
+func TestRenderLeaderboards(t *testing.T) {
+	leaderboard, challengeIDs := testLeaderboard()
+	tests := map[string]string{
+		"classic":          RenderClassicLeaderboard(leaderboard, challengeIDs),
+		"classic-empty":    RenderClassicLeaderboard(nil, challengeIDs),
+		"packages":         RenderPackageLeaderboard(testPackages()),
+		"packages-empty":   RenderPackageLeaderboard(testPackages()[1:]),
+		"packages-nothing": RenderPackageLeaderboard(nil),
+	}
+	for name, got := range tests {
+		checkGolden(t, filepath.Join("testdata", "readme", "golden", name+".md"), []byte(got))
+	}
+}
+
+// TestUpdateReadme replaces the sections of every README in
+// testdata/readme, and compares the result with the golden files
+func TestUpdateReadme(t *testing.T) {
+	leaderboard, challengeIDs := testLeaderboard()
+	classic := RenderClassicLeaderboard(leaderboard, challengeIDs)
+	packages := RenderPackageLeaderboard(testPackages())
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "readme", "*.md"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no READMEs in testdata/readme: %v", err)
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".md")
+		t.Run(name, func(t *testing.T) {
+			content, err := ioutil.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			updated, err := UpdateReadme(string(content), classic, packages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join("testdata", "readme", "golden", name+".md"), []byte(updated))
+
+			// Up to date once updated
+			if again, err := UpdateReadme(updated, classic, packages); err != nil || again != updated {
+				t.Errorf("a second update changed the README: %v", err)
+			}
+			if stale := StaleReadmeSections(updated, classic, packages); len(stale) != 0 {
+				t.Errorf("stale sections %q after the update", stale)
+			}
+		})
+	}
+
+	if _, err := UpdateReadme("# No place for the leaderboards\n", classic, packages); err == nil {
+		t.Error("a README without the sections, Key Features or another anchor was updated")
+	}
+}
+
+// TestStaleReadmeSections is the drift `scoreboard --check` reports
+func TestStaleReadmeSections(t *testing.T) {
+	leaderboard, challengeIDs := testLeaderboard()
+	classic := RenderClassicLeaderboard(leaderboard, challengeIDs)
+	packages := RenderPackageLeaderboard(testPackages())
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "readme", "golden", "markers.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme := string(content)
+
+	tests := []struct {
+		name              string
+		classic, packages string
+		want              []string
+	}{
+		{"up to date", classic, packages, nil},
+		{"new classic solve", RenderClassicLeaderboard(leaderboard[:3], challengeIDs), packages, []string{"classic leaderboard"}},
+		{"new package solve", classic, RenderPackageLeaderboard(testPackages()[:1]), []string{"package leaderboard"}},
+		{"both", RenderClassicLeaderboard(nil, challengeIDs), RenderPackageLeaderboard(nil), []string{"classic leaderboard", "package leaderboard"}},
+	}
+	for _, tt := range tests {
+		if got := StaleReadmeSections(readme, tt.classic, tt.packages); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: stale %q, want %q", tt.name, got, tt.want)
+		}
+		updated, err := UpdateReadme(readme, tt.classic, tt.packages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed := updated != readme; changed != (tt.want != nil) {
+			t.Errorf("%s: update changed the README %v, want %v", tt.name, changed, tt.want != nil)
+		}
+	}
+}
+
+// checkGolden compares output with a golden file, or rewrites it with -update
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
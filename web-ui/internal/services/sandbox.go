@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// Default execution limits applied when a challenge does not set its own
+const (
+	DefaultTimeoutSeconds = 120
+	DefaultCPUSeconds     = 300
+	DefaultMemoryMB       = 2048
+)
+
+// KillReason describes why a run was terminated before it finished
+type KillReason string
+
+const (
+	KillNone      KillReason = ""
+	KillTimeout   KillReason = "timeout"            // Wall-clock or CPU limit exceeded
+	KillOOM       KillReason = "oom"                // Memory limit exceeded
+	KillSyscall   KillReason = "disallowed-syscall" // Blocked by the seccomp filter
+	KillCancelled KillReason = "cancelled"          // Caller cancelled the run
+)
+
+// SandboxCommand describes a command to run inside a sandbox
+type SandboxCommand struct {
+	Dir    string                 // Working directory containing the workspace files
+	Args   []string               // Command and arguments, e.g. ["go", "test", "-v"]
+	Env    []string               // Complete environment for the command
+	Limits models.ExecutionLimits // Resource limits (already resolved against defaults)
+	Stream io.Writer              // Optional writer that receives output as it is produced
+
+	// OutputDir is a subdirectory of Dir whose contents are kept after the
+	// run, for files such as coverage profiles that the caller reads back.
+	// Everything else the command writes is discarded. The command controls
+	// what is left there, so read it back with ReadOutputFile and
+	// ReadOutputDir only.
+	OutputDir string
+
+	// NoAddressSpaceLimit drops the virtual memory rlimit, which the race
+	// detector needs because it reserves terabytes of address space for its
+	// shadow memory. The tmpfs size and CPU limits still apply.
+	NoAddressSpaceLimit bool
+}
+
+// MaxOutputFileBytes caps the size of a file read back from a sandbox's
+// OutputDir
+const MaxOutputFileBytes = 4 << 20
+
+// ErrUnsafeOutputFile is returned for a path in a sandbox's output that is
+// not a plain file or directory, such as a symlink or a FIFO
+var ErrUnsafeOutputFile = errors.New("not a regular file")
+
+// ReadOutputFile reads a file a sandboxed command left in its OutputDir.
+// name is relative to the command's Dir, which it cannot leave. Only a
+// regular file of at most MaxOutputFileBytes is read: symlinks are refused
+// rather than followed, so they cannot pull host files into results, and
+// FIFOs and devices are refused without blocking on them.
+func ReadOutputFile(dir, name string) ([]byte, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnsafeOutputFile)
+	}
+
+	// O_NONBLOCK keeps the open from waiting for a writer should the file
+	// have been swapped for a FIFO since the Lstat
+	file, err := root.OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	opened, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !opened.Mode().IsRegular() || !os.SameFile(info, opened) {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnsafeOutputFile)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(file, MaxOutputFileBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > MaxOutputFileBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, MaxOutputFileBytes)
+	}
+	return content, nil
+}
+
+// ReadOutputDir lists the regular files of a directory in a sandboxed
+// command's OutputDir, sorted by name. Like ReadOutputFile it stays inside
+// dir; symlinks and other special files are left out.
+func ReadOutputDir(dir, name string) ([]string, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnsafeOutputFile)
+	}
+	directory, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer directory.Close()
+	entries, err := directory.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SandboxResult is the outcome of a sandboxed command
+type SandboxResult struct {
+	Output   []byte     // Combined stdout and stderr
+	ExitCode int        // Exit code of the command, -1 if it did not exit normally
+	Killed   KillReason // Why the command was terminated, if it was
+	Err      error      // Set when the command could not be started at all
+}
+
+// Sandbox runs untrusted commands with resource limits
+type Sandbox interface {
+	// Name identifies the sandbox implementation in results and logs
+	Name() string
+	// Run executes the command and waits for it to finish
+	Run(ctx context.Context, cmd SandboxCommand) SandboxResult
+}
+
+// NewSandbox selects a sandbox implementation based on the EXECUTION_SANDBOX
+// environment variable ("namespace", "none" or empty for auto-detection)
+func NewSandbox() Sandbox {
+	mode := strings.ToLower(os.Getenv("EXECUTION_SANDBOX"))
+
+	switch mode {
+	case "none", "unsandboxed":
+		log.Println("Warning: code execution is not sandboxed (EXECUTION_SANDBOX=none)")
+		return NewUnsandboxedRunner()
+	case "", "auto", "namespace":
+		sandbox, err := NewNamespaceSandbox()
+		if err == nil {
+			return sandbox
+		}
+		if mode == "namespace" {
+			log.Fatalf("Namespace sandbox requested but unavailable: %v", err)
+		}
+		log.Printf("Warning: namespace sandbox unavailable, falling back to unsandboxed execution: %v", err)
+		return NewUnsandboxedRunner()
+	default:
+		log.Printf("Warning: unknown EXECUTION_SANDBOX %q, falling back to unsandboxed execution", mode)
+		return NewUnsandboxedRunner()
+	}
+}
+
+// ResolveLimits fills unset limits with the service defaults
+func ResolveLimits(limits models.ExecutionLimits) models.ExecutionLimits {
+	if limits.TimeoutSeconds <= 0 {
+		limits.TimeoutSeconds = DefaultTimeoutSeconds
+	}
+	if limits.CPUSeconds <= 0 {
+		limits.CPUSeconds = DefaultCPUSeconds
+	}
+	if limits.MemoryMB <= 0 {
+		limits.MemoryMB = DefaultMemoryMB
+	}
+	return limits
+}
+
+// DescribeKill returns a human-readable explanation of a kill reason
+func DescribeKill(reason KillReason, limits models.ExecutionLimits) string {
+	switch reason {
+	case KillTimeout:
+		return fmt.Sprintf("Run killed: time limit exceeded (%ds wall-clock, %ds CPU)", limits.TimeoutSeconds, limits.CPUSeconds)
+	case KillOOM:
+		return fmt.Sprintf("Run killed: memory limit exceeded (%d MB)", limits.MemoryMB)
+	case KillSyscall:
+		return "Run killed: the program made a system call that is not allowed in the sandbox"
+	case KillCancelled:
+		return "Run cancelled"
+	default:
+		return ""
+	}
+}
+
+// UnsandboxedRunner runs commands directly on the host with only a wall-clock
+// limit. It is the fallback when no isolation mechanism is available.
+type UnsandboxedRunner struct{}
+
+// NewUnsandboxedRunner creates a runner without isolation
+func NewUnsandboxedRunner() *UnsandboxedRunner {
+	return &UnsandboxedRunner{}
+}
+
+// Name returns the runner name
+func (r *UnsandboxedRunner) Name() string {
+	return "none"
+}
+
+// Run executes the command on the host
+func (r *UnsandboxedRunner) Run(ctx context.Context, command SandboxCommand) SandboxResult {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(command.Limits.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command.Args[0], command.Args[1:]...)
+	cmd.Dir = command.Dir
+	cmd.Env = command.Env
+	cmd.WaitDelay = 5 * time.Second
+	killProcessGroup(cmd)
+
+	output, err := runCapturingOutput(cmd, command.Stream)
+	return finishSandboxResult(ctx, output, err)
+}
+
+// runCapturingOutput runs cmd and returns its combined output, copying it to
+// stream as it is produced when stream is non-nil
+func runCapturingOutput(cmd *exec.Cmd, stream io.Writer) ([]byte, error) {
+	if stream == nil {
+		return cmd.CombinedOutput()
+	}
+
+	// Using the same writer for stdout and stderr makes exec copy both
+	// through a single goroutine, so writes never interleave mid-line
+	var buffer bytes.Buffer
+	writer := io.MultiWriter(&buffer, stream)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	err := cmd.Run()
+	return buffer.Bytes(), err
+}
+
+// finishSandboxResult converts the outcome of a finished command into a
+// SandboxResult, classifying why it was killed
+func finishSandboxResult(ctx context.Context, output []byte, err error) SandboxResult {
+	result := SandboxResult{Output: output}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+		if ctx.Err() == nil {
+			result.Err = err
+		}
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Killed = KillTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		result.Killed = KillCancelled
+	default:
+		result.Killed = classifyKillFromOutput(string(output))
+	}
+
+	return result
+}
+
+// classifyKillFromOutput detects limit violations reported by the Go runtime
+// or the go command when a child process was killed by a signal
+func classifyKillFromOutput(output string) KillReason {
+	switch {
+	case strings.Contains(output, "signal: bad system call"):
+		return KillSyscall
+	case strings.Contains(output, "runtime: out of memory"),
+		strings.Contains(output, "fatal error: out of memory"),
+		strings.Contains(output, "cannot allocate memory"):
+		return KillOOM
+	case strings.Contains(output, "signal: CPU time limit exceeded"),
+		strings.Contains(output, "panic: test timed out"):
+		return KillTimeout
+	default:
+		return KillNone
+	}
+}
+
+// goEnv returns the value of a go env variable, or "" if it cannot be read
+func goEnv(key string) string {
+	output, err := exec.Command("go", "env", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// overrideEnv returns env with the given variables replaced or added
+func overrideEnv(env []string, overrides map[string]string) []string {
+	result := make([]string, 0, len(env)+len(overrides))
+	for _, entry := range env {
+		key := entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			key = entry[:i]
+		}
+		if _, replaced := overrides[key]; !replaced {
+			result = append(result, entry)
+		}
+	}
+	for key, value := range overrides {
+		result = append(result, key+"="+value)
+	}
+	return result
+}
+
+// lookupEnv returns the value of key in env, or "" if it is not set
+func lookupEnv(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return env[i][len(key)+1:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,704 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"web-ui/internal/models"
+)
+
+// sandboxChildArg is passed to the re-executed binary to make it act as the
+// sandbox init process instead of starting the web server
+const sandboxChildArg = "__sandbox_child"
+
+// sandboxSpecEnv carries the JSON-encoded sandboxChildSpec to the child
+const sandboxSpecEnv = "WEBUI_SANDBOX_SPEC"
+
+// sandboxChildExitCode is used when the child fails before exec'ing the command
+const sandboxChildExitCode = 125
+
+// sandboxChildSpec is everything the sandbox init process needs to set up
+// the environment and exec the command
+type sandboxChildSpec struct {
+	Source   string   `json:"source"`    // Host directory with the workspace files
+	Output   string   `json:"output"`    // Subdirectory of Source bind-mounted writable into the workspace
+	Root     string   `json:"root"`      // Empty host directory the sandbox root tmpfs is mounted on
+	Args     []string `json:"args"`      // Command to exec
+	Env      []string `json:"env"`       // Command environment
+	GoCache  string   `json:"go_cache"`  // Host GOCACHE to expose read-only through an overlay
+	GoRoot   string   `json:"go_root"`   // Host GOROOT, bound read-only
+	ModCache string   `json:"mod_cache"` // Host GOMODCACHE, bound read-only
+	MemoryMB int      `json:"memory_mb"` // RLIMIT_AS and tmpfs size
+	CPUSecs  int      `json:"cpu_secs"`  // RLIMIT_CPU
+	NoASLim  bool     `json:"no_as_lim"` // Skip RLIMIT_AS, for the race detector
+}
+
+// NamespaceSandbox isolates commands with unprivileged Linux namespaces.
+// The command runs in fresh user, mount, PID, network, IPC and UTS
+// namespaces. Its root is a size-limited tmpfs that only holds the
+// workspace and read-only binds of sandboxRootPaths, GOROOT and GOMODCACHE,
+// so the rest of the host, such as $HOME and the databases in the
+// workspace cache directory, does not exist for it. Rlimits cap CPU and
+// memory, and a seccomp filter denies privileged system calls.
+type NamespaceSandbox struct {
+	executable string
+	goCache    string
+	goRoot     string
+	modCache   string
+}
+
+// sandboxRootPaths are the host paths bound read-only into the sandbox
+// root besides GOROOT and GOMODCACHE: the C toolchain and shared libraries
+// that cgo and the race detector need. Paths the host does not have are
+// skipped.
+var sandboxRootPaths = []string{
+	"/usr",
+	"/bin",
+	"/sbin",
+	"/lib",
+	"/lib32",
+	"/lib64",
+	"/libx32",
+	"/etc/alternatives",
+	"/etc/ld.so.cache",
+}
+
+// sandboxDevices are the device nodes bound into the sandbox's /dev
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+// Paths of the workspace, temporary directory and home inside the sandbox
+const (
+	sandboxWorkDir = "/work"
+	sandboxTmpDir  = "/tmp"
+	sandboxHomeDir = "/home/sandbox"
+)
+
+// NewNamespaceSandbox creates a namespace sandbox after verifying that the
+// kernel allows unprivileged user namespaces
+func NewNamespaceSandbox() (*NamespaceSandbox, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate own executable: %v", err)
+	}
+
+	sandbox := &NamespaceSandbox{
+		executable: executable,
+		goCache:    goEnv("GOCACHE"),
+		goRoot:     goEnv("GOROOT"),
+		modCache:   goEnv("GOMODCACHE"),
+	}
+
+	// Probe by running a trivial command through the full setup
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	probeDir, err := os.MkdirTemp("", "sandbox-probe")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(probeDir)
+
+	result := sandbox.Run(ctx, SandboxCommand{
+		Dir:    probeDir,
+		Args:   []string{"go", "version"},
+		Env:    os.Environ(),
+		Limits: ResolveLimits(models.ExecutionLimits{}),
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("probe failed: %s", strings.TrimSpace(string(result.Output)))
+	}
+
+	return sandbox, nil
+}
+
+// Name returns the sandbox name
+func (s *NamespaceSandbox) Name() string {
+	return "namespace"
+}
+
+// Run executes the command inside fresh namespaces
+func (s *NamespaceSandbox) Run(ctx context.Context, command SandboxCommand) SandboxResult {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(command.Limits.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	root, err := os.MkdirTemp("", "sandbox-root")
+	if err != nil {
+		return SandboxResult{ExitCode: -1, Err: fmt.Errorf("failed to create sandbox root: %v", err)}
+	}
+	defer os.RemoveAll(root)
+
+	spec, err := json.Marshal(sandboxChildSpec{
+		Source:   command.Dir,
+		Output:   command.OutputDir,
+		Root:     root,
+		Args:     command.Args,
+		Env:      command.Env,
+		GoCache:  s.envOr(command.Env, "GOCACHE", s.goCache),
+		GoRoot:   s.envOr(command.Env, "GOROOT", s.goRoot),
+		ModCache: s.envOr(command.Env, "GOMODCACHE", s.modCache),
+		MemoryMB: command.Limits.MemoryMB,
+		CPUSecs:  command.Limits.CPUSeconds,
+		NoASLim:  command.NoAddressSpaceLimit,
+	})
+	if err != nil {
+		return SandboxResult{ExitCode: -1, Err: err}
+	}
+
+	cmd := exec.CommandContext(ctx, s.executable, sandboxChildArg)
+	cmd.Env = []string{sandboxSpecEnv + "=" + string(spec)}
+	cmd.WaitDelay = 5 * time.Second
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	output, err := runCapturingOutput(cmd, command.Stream)
+	result := finishSandboxResult(ctx, output, err)
+
+	// The child reports setup failures with a dedicated exit code
+	if result.ExitCode == sandboxChildExitCode && strings.Contains(string(output), "sandbox setup:") {
+		result.Err = fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+
+	// A process killed directly by the seccomp filter exits with SIGSYS
+	var exitErr *exec.ExitError
+	if result.Killed == KillNone && errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			switch status.Signal() {
+			case syscall.SIGSYS:
+				result.Killed = KillSyscall
+			case syscall.SIGXCPU:
+				result.Killed = KillTimeout
+			}
+		}
+	}
+
+	return result
+}
+
+// envOr returns a go setting of a command, such as GOCACHE: the one set in
+// its environment, or the host default
+func (s *NamespaceSandbox) envOr(env []string, key, hostDefault string) string {
+	if value := lookupEnv(env, key); value != "" {
+		return value
+	}
+	return hostDefault
+}
+
+// MaybeRunSandboxChild turns the current process into the sandbox init
+// process when it was re-executed by NamespaceSandbox. It must be called at
+// the very start of main and does not return in that case.
+func MaybeRunSandboxChild() {
+	if len(os.Args) < 2 || os.Args[1] != sandboxChildArg {
+		return
+	}
+
+	// The seccomp filter is per-thread, so stay on the thread that execs
+	runtime.LockOSThread()
+
+	if err := runSandboxChild(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox setup: %v\n", err)
+		os.Exit(sandboxChildExitCode)
+	}
+}
+
+// runSandboxChild prepares the namespaces and execs the command
+func runSandboxChild() error {
+	var spec sandboxChildSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxSpecEnv)), &spec); err != nil {
+		return fmt.Errorf("invalid spec: %v", err)
+	}
+	if len(spec.Args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+
+	// Keep our mount changes out of the parent namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+
+	// The new root is a size-limited tmpfs, which is also the only
+	// writable location: the workspace, /tmp and $HOME live in it
+	root := spec.Root
+	tmpfsOptions := fmt.Sprintf("size=%dm,mode=0755", spec.MemoryMB)
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, tmpfsOptions); err != nil {
+		return fmt.Errorf("mount root tmpfs: %v", err)
+	}
+
+	workDir := filepath.Join(root, sandboxWorkDir)
+	for _, dir := range []string{workDir, filepath.Join(root, sandboxHomeDir), filepath.Join(root, "dev"), filepath.Join(root, "proc")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, sandboxTmpDir), 0777|os.ModeSticky); err != nil {
+		return err
+	}
+	// Mkdir applies the umask, and the sticky bit needs an explicit chmod
+	if err := os.Chmod(filepath.Join(root, sandboxTmpDir), 0777|os.ModeSticky); err != nil {
+		return err
+	}
+	if err := copyTree(spec.Source, workDir); err != nil {
+		return fmt.Errorf("copy workspace: %v", err)
+	}
+
+	// Files the caller reads back go straight to the host directory
+	if spec.Output != "" {
+		source := filepath.Join(spec.Source, spec.Output)
+		target := filepath.Join(workDir, spec.Output)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		flags := uintptr(syscall.MS_BIND | syscall.MS_NOSUID | syscall.MS_NODEV)
+		if err := syscall.Mount(source, target, "", flags, ""); err != nil {
+			return fmt.Errorf("mount output directory: %v", err)
+		}
+		if err := syscall.Mount("", target, "", flags|syscall.MS_REMOUNT, ""); err != nil {
+			return fmt.Errorf("remount output directory: %v", err)
+		}
+	}
+
+	// The toolchain, GOROOT and the module cache are the only parts of the
+	// host the command sees, all read-only
+	for _, path := range append(slices.Clone(sandboxRootPaths), spec.GoRoot, spec.ModCache) {
+		if err := bindReadOnly(root, path); err != nil {
+			return err
+		}
+	}
+	for _, device := range sandboxDevices {
+		if err := bindPath(root, device); err != nil {
+			return err
+		}
+	}
+
+	// Give the build a private view of the host build cache
+	goCache := mountGoCacheOverlay(root, spec.GoCache)
+
+	// A fresh /proc matching the new PID namespace; not fatal if refused,
+	// e.g. in containers that mask parts of the host's /proc
+	syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+
+	// Switch to the new root and detach the host filesystem from the
+	// namespace entirely
+	if err := pivotRoot(root); err != nil {
+		return err
+	}
+
+	env := overrideEnv(spec.Env, map[string]string{
+		"HOME":     sandboxHomeDir,
+		"TMPDIR":   sandboxTmpDir,
+		"GOTMPDIR": sandboxTmpDir,
+		"GOCACHE":  goCache,
+		"PWD":      sandboxWorkDir,
+	})
+
+	if err := os.Chdir(sandboxWorkDir); err != nil {
+		return err
+	}
+
+	// Resolve the binary before seccomp is installed
+	os.Setenv("PATH", lookupEnv(env, "PATH"))
+	binary, err := exec.LookPath(spec.Args[0])
+	if err != nil {
+		return fmt.Errorf("find %s: %v", spec.Args[0], err)
+	}
+
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_AS, uint64(spec.MemoryMB) << 20},
+		{syscall.RLIMIT_CPU, uint64(spec.CPUSecs)},
+		{syscall.RLIMIT_FSIZE, uint64(spec.MemoryMB) << 20},
+	}
+	for _, limit := range limits {
+		if limit.resource == syscall.RLIMIT_AS && spec.NoASLim {
+			continue
+		}
+		if err := syscall.Setrlimit(limit.resource, &syscall.Rlimit{Cur: limit.value, Max: limit.value}); err != nil {
+			return fmt.Errorf("setrlimit %d: %v", limit.resource, err)
+		}
+	}
+
+	if err := installSeccompFilter(); err != nil {
+		return err
+	}
+
+	return syscall.Exec(binary, spec.Args, env)
+}
+
+// bindReadOnly binds a host path into the sandbox root at the same path,
+// read-only along with every mount below it. A symlink, such as /bin on
+// merged-/usr systems, is recreated instead, and its target bound too.
+// Paths the host does not have are skipped.
+func bindReadOnly(root, path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("bind %s: %v", path, err)
+	}
+
+	target := filepath.Join(root, path)
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("bind %s: %v", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(link, target); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("bind %s: %v", path, err)
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return fmt.Errorf("bind %s: %v", path, err)
+		}
+		if _, err := os.Lstat(filepath.Join(root, resolved)); err == nil {
+			return nil // Already bound, e.g. /bin -> usr/bin
+		}
+		return bindReadOnly(root, resolved)
+	}
+
+	if err := bindPath(root, path); err != nil {
+		return err
+	}
+	return remountReadOnly(target)
+}
+
+// bindPath binds a host file or directory, with the mounts below it, into
+// the sandbox root at the same path
+func bindPath(root, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("bind %s: %v", path, err)
+	}
+
+	target := filepath.Join(root, path)
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+		var file *os.File
+		if file, err = os.Create(target); err == nil {
+			err = file.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("bind %s: %v", path, err)
+	}
+
+	if err := syscall.Mount(path, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", path, err)
+	}
+	return nil
+}
+
+// remountReadOnly remounts the mount at target, and every mount below it,
+// read-only. Locked flags inherited from the parent namespace must be
+// preserved, otherwise the kernel refuses the remount.
+func remountReadOnly(target string) error {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("read mountinfo: %v", err)
+	}
+	defer file.Close()
+
+	var mounts [][2]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if mountPoint == target || strings.HasPrefix(mountPoint, target+"/") {
+			mounts = append(mounts, [2]string{mountPoint, fields[5]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read mountinfo: %v", err)
+	}
+
+	for _, mount := range mounts {
+		mountPoint, options := mount[0], mount[1]
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case "nosuid":
+				flags |= syscall.MS_NOSUID
+			case "nodev":
+				flags |= syscall.MS_NODEV
+			case "noexec":
+				flags |= syscall.MS_NOEXEC
+			case "noatime":
+				flags |= syscall.MS_NOATIME
+			case "nodiratime":
+				flags |= syscall.MS_NODIRATIME
+			case "relatime":
+				flags |= syscall.MS_RELATIME
+			}
+		}
+
+		if err := syscall.Mount("", mountPoint, "", flags, ""); err != nil {
+			return fmt.Errorf("remount %s read-only: %v", mountPoint, err)
+		}
+	}
+
+	return nil
+}
+
+// pivotRoot makes root the root of the mount namespace and unmounts the
+// host's root from it
+func pivotRoot(root string) error {
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount host root: %v", err)
+	}
+	return os.Remove("/.oldroot")
+}
+
+// unescapeMountPath decodes the octal escapes used in mountinfo paths
+func unescapeMountPath(path string) string {
+	replacer := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	return replacer.Replace(path)
+}
+
+// mountGoCacheOverlay exposes the host build cache through an overlay whose
+// writable layer lives in the tmpfs, so builds can reuse cached packages
+// without being able to poison the shared cache. Falls back to an empty
+// private cache when the overlay cannot be mounted. It returns the cache's
+// path inside the sandbox.
+func mountGoCacheOverlay(root, hostCache string) string {
+	const sandboxCache = "/gocache"
+	cacheDir := filepath.Join(root, sandboxCache)
+	os.MkdirAll(cacheDir, 0755)
+
+	if hostCache == "" {
+		return sandboxCache
+	}
+	if _, err := os.Stat(hostCache); err != nil {
+		return sandboxCache
+	}
+
+	upper := filepath.Join(root, "gocache-upper")
+	work := filepath.Join(root, "gocache-work")
+	os.MkdirAll(upper, 0755)
+	os.MkdirAll(work, 0755)
+
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", hostCache, upper, work)
+	if err := syscall.Mount("overlay", cacheDir, "overlay", 0, options+",userxattr"); err != nil {
+		if err := syscall.Mount("overlay", cacheDir, "overlay", 0, options); err != nil {
+			return sandboxCache
+		}
+	}
+
+	return sandboxCache
+}
+
+// copyTree copies regular files and directories from src into dst
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// Seccomp and BPF constants from linux/seccomp.h, linux/filter.h and linux/prctl.h
+const (
+	prSetNoNewPrivs = 38
+	prSetSeccomp    = 22
+
+	seccompModeFilter      = 2
+	seccompRetKillProcess  = 0x80000000
+	seccompRetErrno        = 0x00050000
+	seccompRetAllow        = 0x7fff0000
+	seccompDataNrOffset    = 0
+	seccompDataArchOffset  = 4
+	seccompDataArg0Offset  = 16 // Low word of args[0] on little-endian architectures
+	x32SyscallBit          = 0x40000000
+	bpfLoadWordAbsolute    = 0x00 | 0x00 | 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJumpEqualConstant   = 0x05 | 0x10 | 0x00 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJumpGreaterEqualCon = 0x05 | 0x30 | 0x00 // BPF_JMP | BPF_JGE | BPF_K
+	bpfJumpSetConstant     = 0x05 | 0x40 | 0x00 // BPF_JMP | BPF_JSET | BPF_K
+	bpfReturn              = 0x06 | 0x00        // BPF_RET | BPF_K
+)
+
+// sysClone3 is clone3's number, the same on every architecture; the syscall
+// package predates it
+const sysClone3 = 435
+
+// cloneNamespaceFlags are the clone flags that create namespaces. A process
+// in its own user namespace regains every capability there, which the
+// sandbox's mounts and rlimits do not account for.
+const cloneNamespaceFlags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+	syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | syscall.CLONE_NEWCGROUP
+
+// deniedSyscalls are never needed to build or test a Go program and are
+// the usual building blocks of sandbox escapes
+var deniedSyscalls = []uintptr{
+	syscall.SYS_MOUNT,
+	syscall.SYS_UMOUNT2,
+	syscall.SYS_PIVOT_ROOT,
+	syscall.SYS_CHROOT,
+	syscall.SYS_PTRACE,
+	syscall.SYS_REBOOT,
+	syscall.SYS_KEXEC_LOAD,
+	syscall.SYS_INIT_MODULE,
+	syscall.SYS_DELETE_MODULE,
+	syscall.SYS_SWAPON,
+	syscall.SYS_SWAPOFF,
+	syscall.SYS_UNSHARE,
+	syscall.SYS_ACCT,
+	syscall.SYS_KEYCTL,
+	syscall.SYS_ADD_KEY,
+	syscall.SYS_REQUEST_KEY,
+	syscall.SYS_PERF_EVENT_OPEN,
+}
+
+// sockFilter mirrors struct sock_filter
+type sockFilter struct {
+	Code uint16
+	Jt   uint8
+	Jf   uint8
+	K    uint32
+}
+
+// sockFprog mirrors struct sock_fprog
+type sockFprog struct {
+	Len    uint16
+	Filter *sockFilter
+}
+
+// seccompAuditArch returns the AUDIT_ARCH value for the running architecture,
+// or 0 if the filter is not supported on it
+func seccompAuditArch() uint32 {
+	switch runtime.GOARCH {
+	case "amd64":
+		return 0xc000003e
+	case "arm64":
+		return 0xc00000b7
+	default:
+		return 0
+	}
+}
+
+// installSeccompFilter denies the syscalls in deniedSyscalls, and clone
+// with any of cloneNamespaceFlags, by killing the calling process, which the
+// parent reports as KillSyscall. clone3 passes its flags in memory the
+// filter cannot inspect, so it fails with ENOSYS instead, which makes libc
+// fall back to clone; the Go runtime only uses clone.
+func installSeccompFilter() error {
+	arch := seccompAuditArch()
+	if arch == 0 {
+		return nil
+	}
+
+	filter := []sockFilter{
+		{Code: bpfLoadWordAbsolute, K: seccompDataArchOffset},
+		{Code: bpfJumpEqualConstant, Jt: 1, Jf: 0, K: arch},
+		{Code: bpfReturn, K: seccompRetKillProcess},
+		{Code: bpfLoadWordAbsolute, K: seccompDataNrOffset},
+		{Code: bpfJumpGreaterEqualCon, Jt: 0, Jf: 1, K: x32SyscallBit},
+		{Code: bpfReturn, K: seccompRetKillProcess},
+	}
+	for _, nr := range deniedSyscalls {
+		filter = append(filter,
+			sockFilter{Code: bpfJumpEqualConstant, Jt: 0, Jf: 1, K: uint32(nr)},
+			sockFilter{Code: bpfReturn, K: seccompRetKillProcess},
+		)
+	}
+	filter = append(filter,
+		sockFilter{Code: bpfJumpEqualConstant, Jt: 0, Jf: 1, K: sysClone3},
+		sockFilter{Code: bpfReturn, K: seccompRetErrno | uint32(syscall.ENOSYS)},
+		sockFilter{Code: bpfJumpEqualConstant, Jt: 0, Jf: 3, K: syscall.SYS_CLONE},
+		sockFilter{Code: bpfLoadWordAbsolute, K: seccompDataArg0Offset},
+		sockFilter{Code: bpfJumpSetConstant, Jt: 0, Jf: 1, K: cloneNamespaceFlags},
+		sockFilter{Code: bpfReturn, K: seccompRetKillProcess},
+		sockFilter{Code: bpfReturn, K: seccompRetAllow},
+	)
+
+	program := sockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("prctl(PR_SET_NO_NEW_PRIVS): %v", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&program))); errno != 0 {
+		return fmt.Errorf("prctl(PR_SET_SECCOMP): %v", errno)
+	}
+
+	return nil
+}
+
+// killProcessGroup runs cmd in its own process group and kills the whole
+// group on cancellation, so the test binary started by `go test` does not
+// outlive a cancelled unsandboxed run
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// newTestSandbox returns a namespace sandbox, skipping the test when the
+// kernel does not allow unprivileged user namespaces
+func newTestSandbox(t *testing.T) *NamespaceSandbox {
+	t.Helper()
+	sandbox, err := NewNamespaceSandbox()
+	if err != nil {
+		t.Skipf("namespace sandbox unavailable: %v", err)
+	}
+	return sandbox
+}
+
+// runInSandbox runs a shell script in the sandbox, in a workspace holding
+// the given files
+func runInSandbox(t *testing.T, sandbox *NamespaceSandbox, files map[string]string, script string) SandboxResult {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := sandbox.Run(context.Background(), SandboxCommand{
+		Dir:    dir,
+		Args:   []string{"sh", "-c", script},
+		Env:    os.Environ(),
+		Limits: ResolveLimits(models.ExecutionLimits{TimeoutSeconds: 30}),
+	})
+	if result.Err != nil {
+		t.Fatalf("sandbox failed: %v", result.Err)
+	}
+	return result
+}
+
+func TestSandboxHidesHostFiles(t *testing.T) {
+	sandbox := newTestSandbox(t)
+
+	secret := filepath.Join(t.TempDir(), "accounts.db")
+	if err := os.WriteFile(secret, []byte("session-secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{secret, "/etc/passwd", "/etc/hostname", "/var", "/root/.bash_history"} {
+		result := runInSandbox(t, sandbox, nil, "cat '"+path+"'")
+		if result.ExitCode == 0 || strings.Contains(string(result.Output), "session-secret") {
+			t.Errorf("%s is readable in the sandbox: %s", path, result.Output)
+		}
+	}
+}
+
+func TestSandboxHidesWorkspaceCache(t *testing.T) {
+	sandbox := newTestSandbox(t)
+	workspaces := NewWorkspaceManager()
+
+	// Only the module cache, bound for builds, may show through; the
+	// databases next to it must not
+	result := runInSandbox(t, sandbox, nil, "ls -A '"+workspaces.root+"' 2>/dev/null; true")
+	for _, name := range strings.Fields(string(result.Output)) {
+		if name != filepath.Base(workspaces.modCache) {
+			t.Errorf("%s is visible in the sandbox", filepath.Join(workspaces.root, name))
+		}
+	}
+}
+
+func TestSandboxRunsGoInWritableWorkspace(t *testing.T) {
+	sandbox := newTestSandbox(t)
+
+	files := map[string]string{"input.txt": "hello"}
+	result := runInSandbox(t, sandbox, files, "cat input.txt > \"$TMPDIR/copy\" && cat \"$TMPDIR/copy\" && echo > out.txt && go version")
+	if result.ExitCode != 0 {
+		t.Fatalf("exit code %d: %s", result.ExitCode, result.Output)
+	}
+	if !strings.HasPrefix(string(result.Output), "hello") || !strings.Contains(string(result.Output), "go version") {
+		t.Errorf("unexpected output: %s", result.Output)
+	}
+
+	// Everything outside the workspace and the tmpfs is read-only
+	result = runInSandbox(t, sandbox, nil, "touch /usr/sandbox-test")
+	if result.ExitCode == 0 {
+		t.Error("/usr is writable in the sandbox")
+	}
+}
+
+// cloneProgram forks with the namespace flags named by its argument, or
+// calls clone3, and reports what happened
+const cloneProgram = `package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func main() {
+	if os.Args[1] == "clone3" {
+		_, _, errno := syscall.Syscall(435, 0, 0, 0)
+		fmt.Println("clone3:", errno == syscall.ENOSYS)
+		return
+	}
+
+	flags := map[string]uintptr{"none": 0, "user": syscall.CLONE_NEWUSER, "net": syscall.CLONE_NEWNET}[os.Args[1]]
+	attr := &syscall.ProcAttr{Files: []uintptr{0, 1, 2}, Sys: &syscall.SysProcAttr{Cloneflags: flags}}
+	pid, err := syscall.ForkExec("/bin/true", []string{"true"}, attr)
+	if err != nil {
+		fmt.Println("fork:", err)
+		os.Exit(1)
+	}
+	var status syscall.WaitStatus
+	syscall.Wait4(pid, &status, 0, nil)
+	fmt.Println("forked:", status.ExitStatus())
+}
+`
+
+func TestSandboxDeniesNamespaceClones(t *testing.T) {
+	sandbox := newTestSandbox(t)
+	files := map[string]string{"go.mod": "module clonetest\n\ngo 1.21\n", "main.go": cloneProgram}
+
+	tests := []struct {
+		flags  string
+		killed bool
+		output string
+	}{
+		{"none", false, "forked: 0"},
+		{"clone3", false, "clone3: true"},
+		{"user", true, ""},
+		{"net", true, ""},
+	}
+	for _, tt := range tests {
+		result := runInSandbox(t, sandbox, files, "go run . "+tt.flags)
+		if killed := result.Killed == KillSyscall; killed != tt.killed {
+			t.Errorf("%s: killed %v (%q), want %v: %s", tt.flags, killed, result.Killed, tt.killed, result.Output)
+		}
+		if tt.output != "" && !strings.Contains(string(result.Output), tt.output) {
+			t.Errorf("%s: output %q, want %q", tt.flags, result.Output, tt.output)
+		}
+	}
+}
+
+func TestReadOutputFileRefusesFIFO(t *testing.T) {
+	dir := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(dir, "coverage.out"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ReadOutputFile(dir, "coverage.out")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrUnsafeOutputFile) {
+			t.Errorf("reading a FIFO returned %v, want ErrUnsafeOutputFile", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reading a FIFO blocked")
+	}
+	if names, err := ReadOutputDir(dir, "."); err != nil || len(names) != 0 {
+		t.Errorf("ReadOutputDir listed %q, %v, want no files", names, err)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// NamespaceSandbox is only available on Linux
+type NamespaceSandbox struct{}
+
+// NewNamespaceSandbox always fails on non-Linux platforms
+func NewNamespaceSandbox() (*NamespaceSandbox, error) {
+	return nil, fmt.Errorf("namespace sandbox is not supported on %s", runtime.GOOS)
+}
+
+// Name returns the sandbox name
+func (s *NamespaceSandbox) Name() string {
+	return "namespace"
+}
+
+// Run is never reached because NewNamespaceSandbox always fails
+func (s *NamespaceSandbox) Run(ctx context.Context, command SandboxCommand) SandboxResult {
+	return SandboxResult{ExitCode: -1, Err: fmt.Errorf("namespace sandbox is not supported on %s", runtime.GOOS)}
+}
+
+// MaybeRunSandboxChild is a no-op on platforms without the namespace sandbox
+func MaybeRunSandboxChild() {}
+
+// killProcessGroup is a no-op on platforms without process group support;
+// cancellation only kills the go command itself
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newOutputWorkspace returns a workspace whose output directory holds what a
+// hostile command could leave there, next to a host file outside it
+func newOutputWorkspace(t *testing.T) (dir, secret string) {
+	t.Helper()
+	secret = filepath.Join(t.TempDir(), "session.key")
+	if err := os.WriteFile(secret, []byte("TOP-SECRET-HOST-DATA"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir = t.TempDir()
+	output := filepath.Join(dir, "out")
+	if err := os.MkdirAll(filepath.Join(output, "corpus"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"out/profile.txt":  "mode: set\n",
+		"out/corpus/a":     "go test fuzz v1\n",
+		"out/corpus/b":     "go test fuzz v1\n",
+		"out/large.txt":    strings.Repeat("x", MaxOutputFileBytes+1),
+		"inside.txt":       "workspace file\n",
+		"out/exactly.txt":  strings.Repeat("x", MaxOutputFileBytes),
+		"out/corpus/empty": "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"out/secret":        secret,
+		"out/corpus/secret": secret,
+		"out/relative":      "../inside.txt",
+		"out/host":          filepath.Dir(secret),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+	return dir, secret
+}
+
+func TestReadOutputFile(t *testing.T) {
+	dir, secret := newOutputWorkspace(t)
+	tests := []struct {
+		name string
+		want string // Content, or "" if the read must fail
+	}{
+		{"out/profile.txt", "mode: set\n"},
+		{"out/corpus/a", "go test fuzz v1\n"},
+		{"out/exactly.txt", strings.Repeat("x", MaxOutputFileBytes)},
+		{"out/large.txt", ""},
+		{"out/secret", ""},
+		{"out/corpus/secret", ""},
+		{"out/relative", ""},
+		{"out/host/session.key", ""},
+		{"out/missing", ""},
+		{"out/corpus", ""},
+		{"../" + filepath.Base(dir) + "/inside.txt", ""},
+		{secret, ""},
+	}
+	for _, tt := range tests {
+		content, err := ReadOutputFile(dir, tt.name)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ReadOutputFile(%q) read %.40q, want an error", tt.name, content)
+			}
+			continue
+		}
+		if err != nil || string(content) != tt.want {
+			t.Errorf("ReadOutputFile(%q) = %.40q, %v, want %.40q", tt.name, content, err, tt.want)
+		}
+	}
+
+	if _, err := ReadOutputFile(dir, "out/secret"); !errors.Is(err, ErrUnsafeOutputFile) {
+		t.Errorf("reading a symlink returned %v, want ErrUnsafeOutputFile", err)
+	}
+}
+
+func TestReadOutputDir(t *testing.T) {
+	dir, _ := newOutputWorkspace(t)
+	tests := []struct {
+		name  string
+		want  []string
+		valid bool
+	}{
+		{"out/corpus", []string{"a", "b", "empty"}, true},
+		{"out", []string{"exactly.txt", "large.txt", "profile.txt"}, true},
+		{"out/host", nil, false},
+		{"out/missing", nil, false},
+		{"out/profile.txt", nil, false},
+		{"..", nil, false},
+	}
+	for _, tt := range tests {
+		names, err := ReadOutputDir(dir, tt.name)
+		if (err == nil) != tt.valid || !slices.Equal(names, tt.want) {
+			t.Errorf("ReadOutputDir(%q) = %q, %v, want %q", tt.name, names, err, tt.want)
+		}
+	}
+}
@@ -1,150 +1,671 @@
 package services
 
 import (
-	"io/ioutil"
+	"fmt"
+	"os"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
 )
 
-// ScoreboardService handles scoreboard-related operations
+// maxScoreboardUpdates is how many recent updates are kept for browsers that
+// reconnect to the update stream
+const maxScoreboardUpdates = 100
+
+// ScoreboardService keeps the challenge scoreboards and the main leaderboard
+// in memory. Passing submissions are written to the challenge's
+// SCOREBOARD.md and published to listeners. It is safe for concurrent use.
 type ScoreboardService struct {
+	mu          sync.RWMutex
+	challenges  models.ChallengeMap
 	scoreboards models.ScoreboardMap
+	completions map[string]map[int]time.Time // username -> challenges with every test passed -> first solved, zero if unknown
+	solveTimes  SolveTimes                   // First solves known when loading, for reloads
+	hidden      []models.HiddenEntry         // Entries left out of the scoreboards and leaderboards
+	leaderboard []models.LeaderboardUser
+	builtAt     time.Time                 // When the leaderboard was built, as its streaks end on that day
+	updates     []models.ScoreboardUpdate // The most recent updates, oldest first
+	nextUpdate  int
+	changed     chan struct{} // Closed and replaced whenever an update is published
 }
 
 // NewScoreboardService creates a new scoreboard service
 func NewScoreboardService() *ScoreboardService {
 	return &ScoreboardService{
+		challenges:  make(models.ChallengeMap),
 		scoreboards: make(models.ScoreboardMap),
+		completions: make(map[string]map[int]time.Time),
+		changed:     make(chan struct{}),
 	}
 }
 
-// LoadScoreboards loads all scoreboards from the filesystem
-func (ss *ScoreboardService) LoadScoreboards(challenges models.ChallengeMap) error {
-	for id := range challenges {
-		challengeDir := filepath.Join("..", "challenge-"+strconv.Itoa(id))
-		ss.loadScoreboardForChallenge(id, challengeDir)
+// LoadScoreboards loads all scoreboards from the filesystem. Submission
+// times come from the scoreboards that record them, or else from
+// solveTimes, which may be nil; entries whose time is not known have a zero
+// SubmittedAt.
+func (ss *ScoreboardService) LoadScoreboards(challenges models.ChallengeMap, solveTimes SolveTimes) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.challenges = challenges
+	ss.solveTimes = solveTimes
+	for id, challenge := range challenges {
+		ss.loadScoreboardForChallenge(id, challenge, solveTimes)
 	}
+	ss.rebuildLeaderboard()
 	return nil
 }
 
-// loadScoreboardForChallenge loads the scoreboard for a specific challenge
-func (ss *ScoreboardService) loadScoreboardForChallenge(id int, dir string) {
-	scoreboardPath := filepath.Join(dir, "SCOREBOARD.md")
-	scoreboardContent, err := ioutil.ReadFile(scoreboardPath)
+// loadScoreboardForChallenge loads the scoreboard for a specific challenge;
+// ss.mu must be held
+func (ss *ScoreboardService) loadScoreboardForChallenge(id int, challenge *models.Challenge, solveTimes SolveTimes) {
+	board, err := scoreboard.Load(scoreboardPath(challenge))
 	if err != nil {
 		return
 	}
 
-	// Parse scoreboard markdown table
-	entries := ss.parseScoreboardMarkdown(string(scoreboardContent), id)
+	entries := []models.ScoreboardEntry{}
+	for _, row := range board.Rows {
+		if ss.isHidden(row.Username, id) {
+			continue
+		}
+		submittedAt := row.SubmittedAt
+		if solvedAt := solveTimes.Get(row.Username, id); submittedAt.IsZero() || (!solvedAt.IsZero() && solvedAt.Before(submittedAt)) {
+			submittedAt = solvedAt
+		}
+		entries = append(entries, models.ScoreboardEntry{
+			Username:    row.Username,
+			ChallengeID: id,
+			SubmittedAt: submittedAt,
+			PassedTests: row.Passed,
+			TotalTests:  row.Total,
+		})
+		if row.Completed() {
+			ss.markCompleted(row.Username, id, submittedAt)
+		}
+	}
 	ss.scoreboards[id] = entries
 }
 
-// parseScoreboardMarkdown parses the scoreboard markdown table
-func (ss *ScoreboardService) parseScoreboardMarkdown(content string, challengeID int) []models.ScoreboardEntry {
-	lines := strings.Split(content, "\n")
-	entries := []models.ScoreboardEntry{}
+// markCompleted records that a user passed every test of a challenge,
+// first at solvedAt, unless they already had; ss.mu must be held
+func (ss *ScoreboardService) markCompleted(username string, challengeID int, solvedAt time.Time) {
+	if ss.completions[username] == nil {
+		ss.completions[username] = make(map[int]time.Time)
+	}
+	if _, completed := ss.completions[username][challengeID]; !completed {
+		ss.completions[username][challengeID] = solvedAt
+	}
+}
 
-	// Determine format by looking at header line
-	var format int = 1 // Default to format 1
-	headerLine := ""
-	for i, line := range lines {
-		if i > 0 && strings.Contains(line, "|") {
-			headerLine = line
+// isHidden reports whether a user's entry for a challenge is hidden; ss.mu
+// must be held
+func (ss *ScoreboardService) isHidden(username string, challengeID int) bool {
+	for _, entry := range ss.hidden {
+		if strings.EqualFold(entry.Username, username) && (entry.ChallengeID == 0 || entry.ChallengeID == challengeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHidden hides entries from the scoreboards and leaderboards, in place of
+// the ones hidden before, and reloads the scoreboards. The SCOREBOARD.md rows
+// of hidden entries are kept, so they come back once no longer hidden.
+func (ss *ScoreboardService) SetHidden(hidden []models.HiddenEntry) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Keep the first solves recorded since loading, which only memory has
+	solveTimes := make(SolveTimes)
+	solveTimes.Merge(ss.solveTimes)
+	for username, completed := range ss.completions {
+		for id, solvedAt := range completed {
+			solveTimes.Add(username, id, solvedAt)
+		}
+	}
+	ss.solveTimes = solveTimes
+
+	ss.hidden = append([]models.HiddenEntry(nil), hidden...)
+	ss.scoreboards = make(models.ScoreboardMap)
+	ss.completions = make(map[string]map[int]time.Time)
+	for id, challenge := range ss.challenges {
+		ss.loadScoreboardForChallenge(id, challenge, ss.solveTimes)
+	}
+	ss.rebuildLeaderboard()
+}
+
+// RemoveEntry removes a user's row from a challenge's SCOREBOARD.md and
+// from the scoreboards and leaderboards, or returns ErrEntryNotFound.
+// Removals are not published; pages show them once reloaded.
+func (ss *ScoreboardService) RemoveEntry(challenge *models.Challenge, username string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	path := scoreboardPath(challenge)
+	board, err := scoreboard.Load(path)
+	if os.IsNotExist(err) {
+		return ErrEntryNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	row, found := board.Find(username)
+	if !found {
+		return ErrEntryNotFound
+	}
+	board.Remove(row.Username)
+	if err := board.Save(path); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	entries := ss.scoreboards[challenge.ID]
+	for i := range entries {
+		if entries[i].Username == row.Username {
+			entries = append(entries[:i], entries[i+1:]...)
 			break
 		}
 	}
+	ss.scoreboards[challenge.ID] = entries
+	if ss.completions[row.Username] != nil {
+		delete(ss.completions[row.Username], challenge.ID)
+	}
+	ss.rebuildLeaderboard()
+	return nil
+}
+
+// rebuildLeaderboard ranks the users again; ss.mu must be held
+func (ss *ScoreboardService) rebuildLeaderboard() {
+	ss.builtAt = time.Now()
+	ss.leaderboard = buildLeaderboard(ss.completions, len(ss.challenges), CountRanking{}, ss.builtAt)
+}
 
-	if strings.Contains(headerLine, "Rank") && strings.Contains(headerLine, "Username") {
-		format = 2
+// scoreboardPath returns the path of a challenge's SCOREBOARD.md
+func scoreboardPath(challenge *models.Challenge) string {
+	if challenge.Dir != "" {
+		return filepath.Join(challenge.Dir, scoreboard.FileName)
 	}
+	return ChallengeScoreboardPath(challenge.ID)
+}
 
-	for i, line := range lines {
-		// Skip header and separator lines
-		if i < 3 {
-			continue
+// ChallengeScoreboardPath returns the path of a challenge's SCOREBOARD.md,
+// trying the path relative to web-ui first and then the one relative to
+// the workspace root
+func ChallengeScoreboardPath(challengeID int) string {
+	path := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), scoreboard.FileName)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return filepath.Join(fmt.Sprintf("challenge-%d", challengeID), scoreboard.FileName)
+}
+
+// LoadChallengeScoreboard reads and parses a challenge's SCOREBOARD.md
+func LoadChallengeScoreboard(challengeID int) (*scoreboard.Board, error) {
+	return scoreboard.Load(ChallengeScoreboardPath(challengeID))
+}
+
+// GetScoreboard returns a copy of the scoreboard for a specific challenge
+func (ss *ScoreboardService) GetScoreboard(challengeID int) ([]models.ScoreboardEntry, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	entries, exists := ss.scoreboards[challengeID]
+	return append([]models.ScoreboardEntry(nil), entries...), exists
+}
+
+// GetAllScoreboards returns a copy of all scoreboards
+func (ss *ScoreboardService) GetAllScoreboards() models.ScoreboardMap {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	scoreboards := make(models.ScoreboardMap, len(ss.scoreboards))
+	for id, entries := range ss.scoreboards {
+		scoreboards[id] = append([]models.ScoreboardEntry(nil), entries...)
+	}
+	return scoreboards
+}
+
+// MainLeaderboard returns the main leaderboard: every user who completed a
+// challenge, by completed challenges, then by who reached that number
+// first, then by username
+func (ss *ScoreboardService) MainLeaderboard() []models.LeaderboardUser {
+	ss.mu.RLock()
+	if sameDay(ss.builtAt, time.Now()) {
+		defer ss.mu.RUnlock()
+		return ss.leaderboard
+	}
+	ss.mu.RUnlock()
+
+	// Streaks end today or yesterday, so they are recounted every day
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if !sameDay(ss.builtAt, time.Now()) {
+		ss.rebuildLeaderboard()
+	}
+	return ss.leaderboard
+}
+
+// Leaderboard returns the main leaderboard ranked by a strategy. The count
+// ranking is the cached MainLeaderboard; the others are built on each call.
+func (ss *ScoreboardService) Leaderboard(strategy RankingStrategy) []models.LeaderboardUser {
+	if strategy.Name() == RankingCount {
+		return ss.MainLeaderboard()
+	}
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return buildLeaderboard(ss.completions, len(ss.challenges), strategy, time.Now())
+}
+
+// SolveTimes returns when each user first solved each challenge they
+// completed, where it is known
+func (ss *ScoreboardService) SolveTimes() SolveTimes {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	times := make(SolveTimes)
+	for username, challenges := range ss.completions {
+		for id, solvedAt := range challenges {
+			times.Add(username, id, solvedAt)
 		}
+	}
+	return times
+}
 
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
+// MainRank returns a user's rank on the main leaderboard, or 0 if they have
+// completed no challenge
+func (ss *ScoreboardService) MainRank(username string) int {
+	for _, user := range ss.MainLeaderboard() {
+		if strings.EqualFold(user.Username, username) {
+			return user.Rank
 		}
+	}
+	return 0 // User is unranked
+}
 
-		// Skip separator lines
-		if strings.Contains(line, "---") {
-			continue
+// RecentlySolved returns the most recent first solves whose time is known,
+// newest first
+func (ss *ScoreboardService) RecentlySolved(limit int) []models.RecentSolve {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	solves := []models.RecentSolve{}
+	for username, completed := range ss.completions {
+		for id, solvedAt := range completed {
+			if solvedAt.IsZero() {
+				continue
+			}
+			solve := models.RecentSolve{Username: username, ChallengeID: id, SolvedAt: solvedAt}
+			if challenge, ok := ss.challenges[id]; ok {
+				solve.ChallengeTitle = challenge.Title
+			}
+			solves = append(solves, solve)
 		}
+	}
 
-		parts := strings.Split(line, "|")
-		if len(parts) < 3 {
-			continue
+	sort.Slice(solves, func(i, j int) bool {
+		if !solves[i].SolvedAt.Equal(solves[j].SolvedAt) {
+			return solves[i].SolvedAt.After(solves[j].SolvedAt)
+		}
+		if solves[i].Username != solves[j].Username {
+			return solves[i].Username < solves[j].Username
 		}
+		return solves[i].ChallengeID < solves[j].ChallengeID
+	})
+	return solves[:min(len(solves), limit)]
+}
 
-		var username string
+// FastestClimbers returns the users who gained the most places on the main
+// leaderboard since a time, by places gained, then by challenges solved
+// since then, then by rank. Solves whose time is not known count as older.
+func (ss *ScoreboardService) FastestClimbers(since time.Time, limit int) []models.Climber {
+	current := ss.MainLeaderboard()
 
-		if format == 1 {
-			// Format is: | Username | Passed Tests | Total Tests |
-			username = strings.TrimSpace(parts[1])
-		} else {
-			// Format is: | Rank | Username | Solution | Date Submitted |
-			username = strings.TrimSpace(parts[2])
+	ss.mu.RLock()
+	before := make(map[string]map[int]time.Time)
+	solved := make(map[string]int)
+	for username, completed := range ss.completions {
+		for id, solvedAt := range completed {
+			if solvedAt.Before(since) {
+				if before[username] == nil {
+					before[username] = make(map[int]time.Time)
+				}
+				before[username][id] = solvedAt
+			} else {
+				solved[username]++
+			}
 		}
+	}
+	previous := buildLeaderboard(before, len(ss.challenges), CountRanking{}, since)
+	ss.mu.RUnlock()
 
-		// Skip empty usernames or placeholders
-		if username == "" || username == "------" || ss.isNumeric(username) {
+	ranks := make(map[string]int, len(previous))
+	for _, user := range previous {
+		ranks[user.Username] = user.Rank
+	}
+
+	climbers := []models.Climber{}
+	for _, user := range current {
+		if solved[user.Username] == 0 {
+			continue
+		}
+		from := ranks[user.Username]
+		start := from
+		if from == 0 {
+			start = len(previous) + 1
+		}
+		if start <= user.Rank {
 			continue
 		}
+		climbers = append(climbers, models.Climber{
+			Username: user.Username,
+			Solved:   solved[user.Username],
+			From:     from,
+			To:       user.Rank,
+			Climbed:  start - user.Rank,
+		})
+	}
 
-		// Use current time for existing entries
-		entry := models.ScoreboardEntry{
-			Username:    username,
-			ChallengeID: challengeID,
-			SubmittedAt: time.Now(),
+	sort.Slice(climbers, func(i, j int) bool {
+		if climbers[i].Climbed != climbers[j].Climbed {
+			return climbers[i].Climbed > climbers[j].Climbed
+		}
+		if climbers[i].Solved != climbers[j].Solved {
+			return climbers[i].Solved > climbers[j].Solved
 		}
+		return climbers[i].To < climbers[j].To
+	})
+	return climbers[:min(len(climbers), limit)]
+}
+
+// RecordSubmission records a passing submission: the user's row in the
+// challenge's SCOREBOARD.md gets the submission's test counts and time, the
+// in-memory scoreboards are updated, and the update is published. The
+// row's other cells, such as the Solution cell of FormatRanked boards, are
+// kept; boards of that format also write the time in their date cell.
+// Submissions without a username are not recorded. Hidden entries are only
+// written to SCOREBOARD.md, and no update is returned for them.
+func (ss *ScoreboardService) RecordSubmission(challenge *models.Challenge, submission models.Submission) (*models.ScoreboardUpdate, error) {
+	if submission.Username == "" {
+		return nil, nil
+	}
+	if !ValidUsername(submission.Username) {
+		return nil, ErrInvalidUsername
+	}
 
+	entry := models.ScoreboardEntry{
+		Username:    submission.Username,
+		ChallengeID: challenge.ID,
+		SubmittedAt: submission.SubmittedAt,
+		PassedTests: submission.TestsPassed,
+		TotalTests:  submission.TestsTotal,
+	}
+	if submission.Coverage != nil {
+		entry.Coverage = submission.Coverage.Percent
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Reread the file so that changes made outside the server, such as a
+	// git pull, are kept
+	path := scoreboardPath(challenge)
+	board, err := scoreboard.Load(path)
+	if os.IsNotExist(err) {
+		board = scoreboard.New(fmt.Sprintf("challenge-%d", challenge.ID))
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	// Usernames ignore case; keep the spelling the scoreboard already has
+	row, found := board.Find(entry.Username)
+	if found {
+		entry.Username = row.Username
+	} else {
+		row = scoreboard.ScoreRow{Username: entry.Username}
+		if board.Format == scoreboard.FormatRanked {
+			row.Solution = fmt.Sprintf("[View](submissions/%s/%s)", entry.Username, MainFile)
+		}
+	}
+	row.Passed, row.Total = entry.PassedTests, entry.TotalTests
+	row.SubmittedAt = entry.SubmittedAt
+	board.Set(row)
+	if err := board.Save(path); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if ss.isHidden(entry.Username, challenge.ID) {
+		return nil, nil
+	}
+
+	entries := ss.scoreboards[challenge.ID]
+	replaced := false
+	for i := range entries {
+		if entries[i].Username == entry.Username {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
 		entries = append(entries, entry)
 	}
+	ss.scoreboards[challenge.ID] = entries
+
+	if row.Completed() {
+		ss.markCompleted(entry.Username, challenge.ID, entry.SubmittedAt)
+	} else if ss.completions[entry.Username] != nil {
+		delete(ss.completions[entry.Username], challenge.ID)
+	}
+
+	previous := ss.leaderboard
+	ss.rebuildLeaderboard()
+
+	update := models.ScoreboardUpdate{
+		ID:          ss.nextUpdate,
+		ChallengeID: challenge.ID,
+		Entry:       entry,
+		RankChanges: rankChanges(previous, ss.leaderboard),
+		Leaderboard: ss.leaderboard,
+	}
+	ss.nextUpdate++
+	ss.updates = append(ss.updates, update)
+	if len(ss.updates) > maxScoreboardUpdates {
+		ss.updates = ss.updates[len(ss.updates)-maxScoreboardUpdates:]
+	}
+	close(ss.changed)
+	ss.changed = make(chan struct{})
 
-	return entries
+	return &update, nil
 }
 
-// isNumeric checks if a string contains only digits
-func (ss *ScoreboardService) isNumeric(s string) bool {
-	for _, r := range s {
-		if r < '0' || r > '9' {
-			return false
+// UpdatesSince returns the kept updates with an ID of at least id, and a
+// channel that is closed when the next update is published
+func (ss *ScoreboardService) UpdatesSince(id int) ([]models.ScoreboardUpdate, <-chan struct{}) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	var updates []models.ScoreboardUpdate
+	for _, update := range ss.updates {
+		if update.ID >= id {
+			updates = append(updates, update)
 		}
 	}
-	return true
+	return updates, ss.changed
 }
 
-// GetScoreboard returns the scoreboard for a specific challenge
-func (ss *ScoreboardService) GetScoreboard(challengeID int) ([]models.ScoreboardEntry, bool) {
-	scoreboard, exists := ss.scoreboards[challengeID]
-	return scoreboard, exists
+// NextUpdateID returns the ID the next published update will have
+func (ss *ScoreboardService) NextUpdateID() int {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.nextUpdate
 }
 
-// GetAllScoreboards returns all scoreboards
-func (ss *ScoreboardService) GetAllScoreboards() models.ScoreboardMap {
-	return ss.scoreboards
+// AchievementTier is an achievement awarded from a number of completed
+// challenges
+type AchievementTier struct {
+	Min   int // Completed challenges needed
+	Emoji string
+	Name  string
 }
 
-// AddSubmission adds a submission to the scoreboard
-func (ss *ScoreboardService) AddSubmission(submission models.Submission) {
-	entry := models.ScoreboardEntry{
-		Username:    submission.Username,
-		ChallengeID: submission.ChallengeID,
-		SubmittedAt: submission.SubmittedAt,
+// String returns the emoji and the name, as the leaderboards show them
+func (t AchievementTier) String() string {
+	return t.Emoji + " " + t.Name
+}
+
+// mainAchievements are the main leaderboard's tiers, highest first
+var mainAchievements = []AchievementTier{
+	{20, "🔥", "Master"},
+	{15, "⭐", "Expert"},
+	{10, "💪", "Advanced"},
+	{5, "🚀", "Intermediate"},
+	{0, "🌱", "Beginner"},
+}
+
+// packageAchievements are the tiers for completed package challenges
+var packageAchievements = []AchievementTier{
+	{15, "🔥", "Package Master"},
+	{10, "⭐", "Package Expert"},
+	{5, "💪", "Package Advanced"},
+	{3, "🚀", "Package Intermediate"},
+	{0, "🌱", "Package Beginner"},
+}
+
+// MainAchievement returns the main leaderboard achievement for a number of
+// completed classic challenges
+func MainAchievement(completed int) AchievementTier {
+	return achievementFor(mainAchievements, completed)
+}
+
+// PackageAchievement returns the achievement for a number of completed
+// package challenges
+func PackageAchievement(completed int) AchievementTier {
+	return achievementFor(packageAchievements, completed)
+}
+
+// achievementFor returns the highest tier reached
+func achievementFor(tiers []AchievementTier, completed int) AchievementTier {
+	for _, tier := range tiers {
+		if completed >= tier.Min {
+			return tier
+		}
+	}
+	return tiers[len(tiers)-1]
+}
+
+// buildLeaderboard ranks users by the strategy's points, then by the least
+// penalty, then by when they first solved the last of their completed
+// challenges, then by username. Solves whose time is not known count as
+// older than any other, so users with none come first among ties. Streaks
+// are counted up to now. The leaderboard is never modified once built, so it
+// can be shared with readers.
+func buildLeaderboard(completions map[string]map[int]time.Time, totalChallenges int, strategy RankingStrategy, now time.Time) []models.LeaderboardUser {
+	var leaderboard []models.LeaderboardUser
+	for username, completed := range completions {
+		completedCount := len(completed)
+		if completedCount == 0 {
+			continue
+		}
+		challenges := make(map[int]bool, completedCount)
+		var completedAt time.Time
+		var solvedAt []time.Time
+		for id, at := range completed {
+			challenges[id] = true
+			if at.IsZero() {
+				continue
+			}
+			solvedAt = append(solvedAt, at)
+			if at.After(completedAt) {
+				completedAt = at
+			}
+		}
+
+		achievement := MainAchievement(completedCount)
+		current, longest := solveStreaks(solvedAt, now)
+		score := strategy.Score(username, completed)
+		leaderboard = append(leaderboard, models.LeaderboardUser{
+			Username:            username,
+			CompletedCount:      completedCount,
+			CompletionRate:      float64(completedCount) / float64(totalChallenges) * 100,
+			CompletedChallenges: challenges,
+			Achievement:         achievement.String(),
+			CompletedAt:         completedAt,
+			CurrentStreak:       current,
+			LongestStreak:       longest,
+			Score:               score.Points,
+			Penalty:             score.Penalty,
+		})
+	}
+
+	// Sort by score (descending), then by penalty, then by who got there
+	// first
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Score != leaderboard[j].Score {
+			return leaderboard[i].Score > leaderboard[j].Score
+		}
+		if leaderboard[i].Penalty != leaderboard[j].Penalty {
+			return leaderboard[i].Penalty < leaderboard[j].Penalty
+		}
+		if !leaderboard[i].CompletedAt.Equal(leaderboard[j].CompletedAt) {
+			return leaderboard[i].CompletedAt.Before(leaderboard[j].CompletedAt)
+		}
+		return leaderboard[i].Username < leaderboard[j].Username
+	})
+
+	// Assign ranks
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	return leaderboard
+}
+
+// solveStreaks counts the consecutive days, in UTC, with at least one
+// solve: the current streak, which ends today or yesterday, and the longest
+func solveStreaks(solvedAt []time.Time, now time.Time) (current, longest int) {
+	days := make(map[int64]bool, len(solvedAt))
+	for _, at := range solvedAt {
+		days[dayNumber(at)] = true
+	}
+	for day := range days {
+		if days[day-1] {
+			continue // Not the first day of a streak
+		}
+		length := 1
+		for days[day+int64(length)] {
+			length++
+		}
+		longest = max(longest, length)
+		if last := day + int64(length) - 1; last >= dayNumber(now)-1 {
+			current = length
+		}
 	}
+	return current, longest
+}
+
+// dayNumber returns the number of days from the Unix epoch to a time's day
+// in UTC
+func dayNumber(t time.Time) int64 {
+	return t.UTC().Truncate(24*time.Hour).Unix() / (24 * 60 * 60)
+}
 
-	// Add to the scoreboard for this challenge
-	if ss.scoreboards[submission.ChallengeID] == nil {
-		ss.scoreboards[submission.ChallengeID] = []models.ScoreboardEntry{}
+// sameDay reports whether two times fall on the same day in UTC
+func sameDay(a, b time.Time) bool {
+	return dayNumber(a) == dayNumber(b)
+}
+
+// rankChanges lists the users whose rank differs between two leaderboards
+func rankChanges(before, after []models.LeaderboardUser) []models.RankChange {
+	ranks := make(map[string]int, len(before))
+	for _, user := range before {
+		ranks[user.Username] = user.Rank
 	}
 
-	ss.scoreboards[submission.ChallengeID] = append(ss.scoreboards[submission.ChallengeID], entry)
+	changes := []models.RankChange{}
+	for _, user := range after {
+		if from := ranks[user.Username]; from != user.Rank {
+			changes = append(changes, models.RankChange{Username: user.Username, From: from, To: user.Rank})
+		}
+	}
+	return changes
 }
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-ui/internal/models"
+	"web-ui/internal/scoreboard"
+)
+
+// newTestScoreboardService loads a challenge whose SCOREBOARD.md has the
+// given content from a temporary directory
+func newTestScoreboardService(t *testing.T, content string) (*ScoreboardService, *models.Challenge) {
+	t.Helper()
+	challenge := &models.Challenge{ID: 2, Dir: t.TempDir()}
+	if err := os.WriteFile(scoreboardPath(challenge), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ss := NewScoreboardService()
+	if err := ss.LoadScoreboards(models.ChallengeMap{challenge.ID: challenge}, nil); err != nil {
+		t.Fatal(err)
+	}
+	return ss, challenge
+}
+
+func TestRecordSubmissionKeepsRankedCells(t *testing.T) {
+	ss, challenge := newTestScoreboardService(t, `# Scoreboard for challenge-2
+
+| Rank | Username | Solution | Date Submitted |
+|------|----------|----------|----------------|
+| 1 | alice | [View](submissions/alice/solution-template.go) | 2024-03-01 |
+| 2 | Bob | [View](submissions/Bob/main.go) | 2024-03-05 |
+`)
+
+	submittedAt := time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+	for _, username := range []string{"bob", "carol"} {
+		submission := models.Submission{Username: username, ChallengeID: challenge.ID, SubmittedAt: submittedAt, Passed: true, TestsPassed: 5, TestsTotal: 5}
+		if _, err := ss.RecordSubmission(challenge, submission); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	board, err := scoreboard.Load(filepath.Join(challenge.Dir, scoreboard.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []scoreboard.ScoreRow{
+		{Username: "alice", Solution: "[View](submissions/alice/solution-template.go)", SubmittedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Username: "Bob", Solution: "[View](submissions/Bob/main.go)", SubmittedAt: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)},
+		{Username: "carol", Solution: "[View](submissions/carol/solution-template.go)", SubmittedAt: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(board.Rows) != len(want) {
+		t.Fatalf("rows %+v, want %+v", board.Rows, want)
+	}
+	for i, row := range board.Rows {
+		if row != want[i] {
+			t.Errorf("row %d is %+v, want %+v", i, row, want[i])
+		}
+	}
+}
+
+func TestRecordSubmissionKeepsTestsFormat(t *testing.T) {
+	ss, challenge := newTestScoreboardService(t, `# Scoreboard for challenge-2
+| Username   | Passed Tests | Total Tests |
+|------------|--------------|-------------|
+| alice | 4 | 5 |
+| Bob | 3 | 5 |
+`)
+
+	submission := models.Submission{Username: "bob", ChallengeID: challenge.ID, SubmittedAt: time.Now(), Passed: true, TestsPassed: 5, TestsTotal: 5}
+	if _, err := ss.RecordSubmission(challenge, submission); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(scoreboardPath(challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Scoreboard for challenge-2
+| Username   | Passed Tests | Total Tests |
+|------------|--------------|-------------|
+| Bob | 5 | 5 |
+| alice | 4 | 5 |
+`
+	if string(content) != want {
+		t.Errorf("SCOREBOARD.md is\n%s\nwant\n%s", content, want)
+	}
+
+	// Ranks ignore the case of usernames, as scoreboards do
+	for _, username := range []string{"Bob", "bob", "BOB"} {
+		if rank := ss.MainRank(username); rank != 1 {
+			t.Errorf("MainRank(%q) is %d, want 1", username, rank)
+		}
+	}
+	if rank := ss.MainRank("carol"); rank != 0 {
+		t.Errorf("MainRank of a user without solves is %d, want 0", rank)
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SolveTimes records when users first solved classic challenges. Usernames
+// are compared ignoring case.
+type SolveTimes map[string]map[int]time.Time
+
+// submissionPathRe matches a file in a classic challenge's submission
+// directory, relative to the repository root
+var submissionPathRe = regexp.MustCompile(`^challenge-(\d+)/submissions/([^/]+)/`)
+
+// Add records that a user solved a challenge at a time, keeping the earliest
+// time recorded. Zero times are ignored.
+func (t SolveTimes) Add(username string, challengeID int, at time.Time) {
+	if at.IsZero() {
+		return
+	}
+	key := strings.ToLower(username)
+	if t[key] == nil {
+		t[key] = make(map[int]time.Time)
+	}
+	if existing, ok := t[key][challengeID]; !ok || at.Before(existing) {
+		t[key][challengeID] = at
+	}
+}
+
+// Get returns when a user first solved a challenge, or the zero time when
+// it is not known
+func (t SolveTimes) Get(username string, challengeID int) time.Time {
+	return t[strings.ToLower(username)][challengeID]
+}
+
+// Merge adds every time of other
+func (t SolveTimes) Merge(other SolveTimes) {
+	for username, challenges := range other {
+		for id, at := range challenges {
+			t.Add(username, id, at)
+		}
+	}
+}
+
+// GitSolveTimes reads when each classic submission directory was first
+// committed, from the commits that added files to it. Outside a git
+// checkout there are none.
+func GitSolveTimes() (SolveTimes, error) {
+	times := make(SolveTimes)
+	if _, err := gitOutput("rev-parse", "--git-dir"); err != nil {
+		return times, nil
+	}
+
+	// File names are listed relative to the repository root, under the
+	// time of the commit that added them
+	history, err := gitOutput("-c", "core.quotePath=false", "log", "--diff-filter=A", "--name-only", "--no-renames", "--format=%x1e%at",
+		"--", ":(top,glob)challenge-*/submissions/*/**")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the git log of the submissions: %v", err)
+	}
+
+	for _, commit := range strings.Split(history, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(commit), "\n")
+		seconds, err := strconv.ParseInt(lines[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		for _, file := range lines[1:] {
+			match := submissionPathRe.FindStringSubmatch(file)
+			if match == nil {
+				continue
+			}
+			id, _ := strconv.Atoi(match[1])
+			times.Add(match[2], id, time.Unix(seconds, 0))
+		}
+	}
+	return times, nil
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// defaultStages run when a challenge does not list its own. They are
+// advisory unless the challenge requires them.
+var defaultStages = []string{models.StageGofmt, models.StageVet, models.StageLint, models.StageFuzz}
+
+// stageMessages describe each stage in phase events
+var stageMessages = map[string]string{
+	models.StageGofmt: "Checking formatting",
+	models.StageVet:   "Running go vet",
+	models.StageRace:  "Running tests with the race detector",
+	models.StageLint:  "Running analyzers",
+	models.StageFuzz:  "Fuzzing",
+}
+
+// StagePlan is the resolved set of stages for a challenge
+type StagePlan struct {
+	enabled  map[string]bool
+	required map[string]bool
+}
+
+// ResolveStages applies a challenge's stage configuration to the defaults.
+// The test stage always runs and is always required; required stages run
+// even if they are not listed as enabled. Unknown stage names are ignored.
+func ResolveStages(config models.StageConfig) StagePlan {
+	plan := StagePlan{
+		enabled:  map[string]bool{models.StageTest: true},
+		required: map[string]bool{models.StageTest: true},
+	}
+
+	enabled := config.Enabled
+	if len(enabled) == 0 {
+		enabled = defaultStages
+	}
+	for _, name := range enabled {
+		plan.enabled[name] = true
+	}
+	for _, name := range config.Required {
+		plan.enabled[name] = true
+		plan.required[name] = true
+	}
+	return plan
+}
+
+// Enabled reports whether a stage runs
+func (p StagePlan) Enabled(name string) bool {
+	return p.enabled[name]
+}
+
+// Required reports whether a stage must pass for the run to pass
+func (p StagePlan) Required(name string) bool {
+	return p.required[name]
+}
+
+// requiredStagesPassed reports whether every required stage passed
+func requiredStagesPassed(stages []models.StageResult) bool {
+	for _, stage := range stages {
+		if stage.Required && stage.Status != models.StageStatusPass {
+			return false
+		}
+	}
+	return true
+}
+
+// runStage announces a stage, runs it and publishes its result
+func (es *ExecutionService) runStage(emit func(ExecutionEvent), plan StagePlan, name string, run func() models.StageResult) models.StageResult {
+	emit(ExecutionEvent{Type: EventPhase, Phase: name, Message: stageMessages[name]})
+
+	start := time.Now()
+	result := run()
+	result.Name = name
+	result.Required = plan.Required(name)
+	result.ElapsedMs = time.Since(start).Milliseconds()
+
+	emit(ExecutionEvent{Type: EventStage, Stage: &result})
+	return result
+}
+
+// skippedStage is the result of a stage that did not run
+func skippedStage(plan StagePlan, name, reason string) models.StageResult {
+	return models.StageResult{
+		Name:     name,
+		Status:   models.StageStatusSkip,
+		Required: plan.Required(name),
+		Output:   reason,
+	}
+}
+
+// checkFormatting compares each submitted file with its gofmt output and
+// points at the first line that differs
+func checkFormatting(files models.SourceFiles) models.StageResult {
+	var unformatted []string
+	var diagnostics []models.Diagnostic
+	for _, filename := range SortedPaths(files) {
+		code := files[filename]
+		formatted, err := format.Source([]byte(code))
+		if err != nil {
+			return models.StageResult{
+				Status: models.StageStatusError,
+				Output: fmt.Sprintf("%s: %v", filename, err),
+			}
+		}
+		if bytes.Equal(formatted, []byte(code)) {
+			continue
+		}
+
+		unformatted = append(unformatted, filename)
+		diagnostics = append(diagnostics, models.Diagnostic{
+			File:     filename,
+			Line:     firstDifferentLine(code, string(formatted)),
+			Category: models.StageGofmt,
+			Message:  "formatting differs from gofmt from this line on",
+		})
+	}
+	if len(unformatted) == 0 {
+		return models.StageResult{Status: models.StageStatusPass}
+	}
+
+	return models.StageResult{
+		Status:      models.StageStatusFail,
+		Output:      fmt.Sprintf("Not gofmt-formatted: %s. Run gofmt (or your editor's format command) on each file.", strings.Join(unformatted, ", ")),
+		Diagnostics: diagnostics,
+	}
+}
+
+// firstDifferentLine returns the 1-based number of the first line that
+// differs between two texts
+func firstDifferentLine(a, b string) int {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+	for i := 0; i < len(linesA) && i < len(linesB); i++ {
+		if linesA[i] != linesB[i] {
+			return i + 1
+		}
+	}
+	return min(len(linesA), len(linesB)) + 1
+}
+
+// vetFinding is one diagnostic in `go vet -json` output
+type vetFinding struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// runVet runs `go vet -json` in the sandbox. With -json vet exits zero even
+// when it reports findings, so a non-zero exit means the package did not
+// build.
+func (es *ExecutionService) runVet(ctx context.Context, tempDir string, files models.SourceFiles, limits models.ExecutionLimits) models.StageResult {
+	sandboxResult := es.sandbox.Run(ctx, SandboxCommand{
+		Dir:    tempDir,
+		Args:   []string{"go", "vet", "-json", "./..."},
+		Env:    es.testEnv(),
+		Limits: limits,
+	})
+	output := string(sandboxResult.Output)
+
+	switch {
+	case sandboxResult.Err != nil:
+		return models.StageResult{Status: models.StageStatusError, Output: sandboxResult.Err.Error()}
+	case sandboxResult.Killed != KillNone:
+		return models.StageResult{Status: models.StageStatusError, Output: DescribeKill(sandboxResult.Killed, limits)}
+	case sandboxResult.ExitCode != 0:
+		return models.StageResult{Status: models.StageStatusError, Output: output}
+	}
+
+	diagnostics := parseVetJSON(output, files)
+	if len(diagnostics) == 0 {
+		return models.StageResult{Status: models.StageStatusPass}
+	}
+	return models.StageResult{
+		Status:      models.StageStatusFail,
+		Output:      formatDiagnostics(diagnostics),
+		Diagnostics: diagnostics,
+	}
+}
+
+// parseVetJSON extracts the findings from `go vet -json` output: a JSON
+// object per package, keyed by package and then analyzer, each preceded by
+// a "# package" comment line. Findings reported for both the package and its
+// test variant are only kept once.
+func parseVetJSON(output string, files models.SourceFiles) []models.Diagnostic {
+	var text strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			text.WriteString(line)
+			text.WriteByte('\n')
+		}
+	}
+
+	var diagnostics []models.Diagnostic
+	seen := make(map[string]bool)
+	decoder := json.NewDecoder(strings.NewReader(text.String()))
+	for {
+		var packages map[string]map[string]json.RawMessage
+		if err := decoder.Decode(&packages); err != nil {
+			break
+		}
+		for _, analyzers := range packages {
+			for analyzer, raw := range analyzers {
+				// Analyzer failures are reported as an object, not a list
+				var findings []vetFinding
+				if json.Unmarshal(raw, &findings) != nil {
+					continue
+				}
+				for _, finding := range findings {
+					key := finding.Posn + finding.Message
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+
+					diagnostic := parsePosition(finding.Posn, files)
+					diagnostic.Category = analyzer
+					diagnostic.Message = finding.Message
+					diagnostics = append(diagnostics, diagnostic)
+				}
+			}
+		}
+	}
+
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// positionRe matches a "file:line" or "file:line:column" position
+var positionRe = regexp.MustCompile(`^(.*?):(\d+)(?::(\d+))?$`)
+
+// parsePosition converts a position into a diagnostic location, naming the
+// file by its path in the submission, or by its base name for other files
+func parsePosition(position string, files models.SourceFiles) models.Diagnostic {
+	match := positionRe.FindStringSubmatch(position)
+	if match == nil {
+		return models.Diagnostic{File: diagnosticFile(position, files)}
+	}
+
+	diagnostic := models.Diagnostic{File: diagnosticFile(match[1], files)}
+	diagnostic.Line, _ = strconv.Atoi(match[2])
+	diagnostic.Column, _ = strconv.Atoi(match[3])
+	return diagnostic
+}
+
+// diagnosticFile names a reported file by its path in the submission, or by
+// its base name if it was not submitted
+func diagnosticFile(reported string, files models.SourceFiles) string {
+	if name, ok := submittedPath(files, reported); ok {
+		return name
+	}
+	return filepath.Base(reported)
+}
+
+// sortDiagnostics orders diagnostics by position
+func sortDiagnostics(diagnostics []models.Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// raceHeader and raceSeparator delimit a race report in test output
+const (
+	raceHeader    = "WARNING: DATA RACE"
+	raceSeparator = "=================="
+)
+
+// raceAccessRe matches the access lines of a race report, e.g.
+// "Write at 0x00c000012345 by goroutine 8:"
+var raceAccessRe = regexp.MustCompile(`^(Previous )?(read|write|Read|Write|Atomic read|Atomic write)( at 0x[0-9a-f]+)? by ([^:]+):`)
+
+// raceFrameRe matches a stack frame location, e.g.
+// "      /tmp/sandbox-root/work/solution-template.go:25 +0x44"
+var raceFrameRe = regexp.MustCompile(`^\s+(\S+\.go):(\d+)`)
+
+// runRace runs the tests again with the race detector enabled
+func (es *ExecutionService) runRace(ctx context.Context, tempDir string, files models.SourceFiles, limits models.ExecutionLimits) (models.StageResult, KillReason) {
+	sandboxResult := es.sandbox.Run(ctx, SandboxCommand{
+		Dir:                 tempDir,
+		Args:                []string{"go", "test", "-race", "-json", fmt.Sprintf("-timeout=%ds", limits.TimeoutSeconds)},
+		Env:                 es.testEnv(),
+		Limits:              limits,
+		NoAddressSpaceLimit: true,
+	})
+	report := parseTestJSON(sandboxResult.Output)
+
+	switch {
+	case sandboxResult.Err != nil:
+		return models.StageResult{Status: models.StageStatusError, Output: sandboxResult.Err.Error()}, KillNone
+	case sandboxResult.Killed != KillNone:
+		return models.StageResult{
+			Status: models.StageStatusFail,
+			Output: DescribeKill(sandboxResult.Killed, limits),
+		}, sandboxResult.Killed
+	}
+
+	races, diagnostics := parseRaceReports(report.Output, files)
+	switch {
+	case len(races) > 0:
+		return models.StageResult{
+			Status:      models.StageStatusFail,
+			Output:      strings.Join(races, "\n"),
+			Diagnostics: diagnostics,
+		}, KillNone
+	case sandboxResult.ExitCode != 0:
+		return models.StageResult{Status: models.StageStatusFail, Output: report.Output}, KillNone
+	default:
+		return models.StageResult{Status: models.StageStatusPass}, KillNone
+	}
+}
+
+// parseRaceReports extracts each data race report from test output, with a
+// diagnostic pointing at the first frame in the submitted code. Races on the
+// same line get a single diagnostic.
+func parseRaceReports(output string, files models.SourceFiles) ([]string, []models.Diagnostic) {
+	var races []string
+	var diagnostics []models.Diagnostic
+	seenLines := make(map[string]bool)
+
+	var block []string
+	inRace := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case !inRace && strings.TrimSpace(line) == raceHeader:
+			inRace = true
+			block = []string{raceSeparator, line}
+		case inRace && strings.TrimSpace(line) == raceSeparator:
+			inRace = false
+			block = append(block, line)
+			races = append(races, strings.Join(block, "\n"))
+			if diagnostic := raceDiagnostic(block, files); !seenLines[diagnostic.File+":"+strconv.Itoa(diagnostic.Line)] {
+				seenLines[diagnostic.File+":"+strconv.Itoa(diagnostic.Line)] = true
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		case inRace:
+			block = append(block, line)
+		}
+	}
+
+	sortDiagnostics(diagnostics)
+	return races, diagnostics
+}
+
+// raceDiagnostic summarizes a race report as "write by goroutine 8, previous
+// read by goroutine 7", located at the first frame in the submitted code
+func raceDiagnostic(block []string, files models.SourceFiles) models.Diagnostic {
+	diagnostic := models.Diagnostic{Category: models.StageRace}
+
+	var accesses []string
+	for _, line := range block {
+		if match := raceAccessRe.FindStringSubmatch(line); match != nil {
+			accesses = append(accesses, strings.ToLower(match[1]+match[2])+" by "+match[4])
+			continue
+		}
+		if diagnostic.Line != 0 {
+			continue
+		}
+		match := raceFrameRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if name, ok := submittedPath(files, match[1]); ok {
+			diagnostic.File = name
+			diagnostic.Line, _ = strconv.Atoi(match[2])
+		}
+	}
+
+	diagnostic.Message = "data race"
+	if len(accesses) > 0 {
+		diagnostic.Message += ": " + strings.Join(accesses, ", ")
+	}
+	return diagnostic
+}
+
+// formatDiagnostics renders diagnostics one per line, like the go tools do
+func formatDiagnostics(diagnostics []models.Diagnostic) string {
+	var text strings.Builder
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(&text, "%s:%d", diagnostic.File, diagnostic.Line)
+		if diagnostic.Column > 0 {
+			fmt.Fprintf(&text, ":%d", diagnostic.Column)
+		}
+		fmt.Fprintf(&text, ": %s (%s)\n", diagnostic.Message, diagnostic.Category)
+	}
+	return text.String()
+}
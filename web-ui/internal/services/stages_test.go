@@ -0,0 +1,135 @@
+package services
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+func TestParseVetJSON(t *testing.T) {
+	files := models.SourceFiles{MainFile: "", "internal/store/store.go": ""}
+	tests := []struct {
+		name   string
+		output string
+		want   []models.Diagnostic
+	}{
+		{"no output", "", nil},
+		{"no findings", "# challenge14\n{}\n", nil},
+		{
+			"findings sorted by position",
+			`# challenge14
+{
+	"challenge14": {
+		"unusedresult": [
+			{"posn": "/tmp/sandbox-root/work/solution-template.go:30:2", "message": "result of fmt.Sprintf call not used"}
+		],
+		"printf": [
+			{"posn": "/tmp/sandbox-root/work/solution-template.go:12:3", "message": "fmt.Printf format %d has arg s of wrong type string"}
+		]
+	}
+}
+# challenge14/internal/store
+{
+	"challenge14/internal/store": {
+		"copylocks": [
+			{"posn": "/tmp/sandbox-root/work/internal/store/store.go:8:9", "message": "return copies lock value"}
+		]
+	}
+}
+`,
+			[]models.Diagnostic{
+				{File: "internal/store/store.go", Line: 8, Column: 9, Category: "copylocks", Message: "return copies lock value"},
+				{File: MainFile, Line: 12, Column: 3, Category: "printf", Message: "fmt.Printf format %d has arg s of wrong type string"},
+				{File: MainFile, Line: 30, Column: 2, Category: "unusedresult", Message: "result of fmt.Sprintf call not used"},
+			},
+		},
+		{
+			"package and test variant report the same finding",
+			`# challenge14
+{"challenge14": {"printf": [{"posn": "solution-template.go:12:3", "message": "bad format"}]}}
+# challenge14 [challenge14.test]
+{"challenge14 [challenge14.test]": {"printf": [{"posn": "solution-template.go:12:3", "message": "bad format"}]}}
+`,
+			[]models.Diagnostic{{File: MainFile, Line: 12, Column: 3, Category: "printf", Message: "bad format"}},
+		},
+		{
+			"findings in the challenge's tests and analyzer failures",
+			`# challenge14 [challenge14.test]
+{
+	"challenge14 [challenge14.test]": {
+		"tests": [{"posn": "/tmp/sandbox-root/work/solution-template_test.go:40:1", "message": "malformed example suffix"}],
+		"buildtag": {"error": "analysis failed"}
+	}
+}
+`,
+			[]models.Diagnostic{{File: "solution-template_test.go", Line: 40, Column: 1, Category: "tests", Message: "malformed example suffix"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseVetJSON(tt.output, files); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testRaceReport is a data race report as printed by the race detector
+func testRaceReport(line int) string {
+	return strings.ReplaceAll(`==================
+WARNING: DATA RACE
+Write at 0x00c000012345 by goroutine 8:
+  challenge14.(*Counter).Increment()
+      /tmp/sandbox-root/work/solution-template.go:LINE +0x44
+  challenge14.TestCounter.func1()
+      /tmp/sandbox-root/work/solution-template_test.go:20 +0x38
+
+Previous read at 0x00c000012345 by goroutine 7:
+  challenge14.(*Counter).Value()
+      /tmp/sandbox-root/work/solution-template.go:31 +0x3a
+
+Goroutine 8 (running) created at:
+  challenge14.TestCounter()
+      /tmp/sandbox-root/work/solution-template_test.go:18 +0x9c
+==================
+`, "LINE", strconv.Itoa(line))
+}
+
+func TestParseRaceReports(t *testing.T) {
+	files := models.SourceFiles{MainFile: ""}
+	diagnostic := func(line int) models.Diagnostic {
+		return models.Diagnostic{
+			File:     MainFile,
+			Line:     line,
+			Category: models.StageRace,
+			Message:  "data race: write by goroutine 8, previous read by goroutine 7",
+		}
+	}
+	tests := []struct {
+		name   string
+		output string
+		races  int
+		want   []models.Diagnostic
+	}{
+		{"no races", "=== RUN   TestCounter\n--- PASS: TestCounter (0.00s)\nPASS\n", 0, nil},
+		{"one race", "=== RUN   TestCounter\n" + testRaceReport(25) + "    testing.go:1490: race detected during execution of test\n--- FAIL: TestCounter (0.00s)\n", 1, []models.Diagnostic{diagnostic(25)}},
+		{"races on the same line", testRaceReport(25) + testRaceReport(25), 2, []models.Diagnostic{diagnostic(25)}},
+		{"races sorted by line", testRaceReport(40) + testRaceReport(25), 2, []models.Diagnostic{diagnostic(25), diagnostic(40)}},
+		{"unterminated report", "WARNING: DATA RACE\nWrite at 0x00c000012345 by goroutine 8:\n", 0, nil},
+	}
+	for _, tt := range tests {
+		races, diagnostics := parseRaceReports(tt.output, files)
+		if len(races) != tt.races {
+			t.Errorf("%s: %d races, want %d", tt.name, len(races), tt.races)
+		}
+		for _, race := range races {
+			if !strings.HasPrefix(race, raceSeparator+"\n"+raceHeader) || !strings.HasSuffix(race, raceSeparator) {
+				t.Errorf("%s: race report is not delimited:\n%s", tt.name, race)
+			}
+		}
+		if !slices.Equal(diagnostics, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, diagnostics, tt.want)
+		}
+	}
+}
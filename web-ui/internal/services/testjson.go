@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"web-ui/internal/models"
+)
+
+// testEvent is a single event emitted by `go test -json` (see `go doc test2json`)
+type testEvent struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"` // Seconds
+	Output  string    `json:"Output"`
+}
+
+// assertionLineRe matches messages logged by t.Error/t.Fatal, e.g.
+// "    solution_test.go:39: expected 5, got 4"
+var assertionLineRe = regexp.MustCompile(`^\s+\S+\.go:\d+: `)
+
+// TestReport is the structured form of a `go test -json` run
+type TestReport struct {
+	Tests   []*models.TestResult // Top-level tests with their subtests
+	Summary models.TestSummary
+	Output  string // Human-readable output equivalent to `go test -v`
+}
+
+// parseTestJSON converts `go test -json` output into a tree of test results.
+// Lines that are not JSON events (build errors written to stderr, sandbox
+// messages) are kept verbatim in the text output.
+func parseTestJSON(output []byte) TestReport {
+	var report TestReport
+	var text strings.Builder
+
+	tests := make(map[string]*models.TestResult)
+	var order []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		var event testEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &event) != nil {
+			text.Write(line)
+			text.WriteByte('\n')
+			continue
+		}
+
+		if event.Action == "output" || event.Action == "build-output" {
+			text.WriteString(event.Output)
+		}
+
+		if event.Test == "" {
+			continue
+		}
+
+		test, exists := tests[event.Test]
+		if !exists {
+			test = &models.TestResult{Name: event.Test, Status: models.TestStatusRunning}
+			tests[event.Test] = test
+			order = append(order, event.Test)
+		}
+
+		switch event.Action {
+		case "output":
+			test.Output += event.Output
+		case "pass":
+			test.Status = models.TestStatusPass
+			test.ElapsedMs = int64(event.Elapsed * 1000)
+		case "fail":
+			test.Status = models.TestStatusFail
+			test.ElapsedMs = int64(event.Elapsed * 1000)
+		case "skip":
+			test.Status = models.TestStatusSkip
+			test.ElapsedMs = int64(event.Elapsed * 1000)
+		}
+	}
+
+	// Link subtests to their parents in the order they started
+	for _, name := range order {
+		test := tests[name]
+		if test.Status == models.TestStatusFail {
+			test.FailureMessage = extractFailureMessage(test.Output)
+		}
+
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			if parent, ok := tests[name[:i]]; ok {
+				parent.Subtests = append(parent.Subtests, test)
+				continue
+			}
+		}
+		report.Tests = append(report.Tests, test)
+	}
+
+	report.Summary = summarizeTests(report.Tests)
+	report.Output = text.String()
+	return report
+}
+
+// extractFailureMessage collects the assertion messages and panic output
+// from a failed test's captured output
+func extractFailureMessage(output string) string {
+	var messages []string
+	inPanic := false
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "panic:"):
+			inPanic = true
+			messages = append(messages, trimmed)
+		case inPanic && trimmed != "":
+			// Keep the first frames of the panic for context
+			if len(messages) < 8 {
+				messages = append(messages, trimmed)
+			}
+		case assertionLineRe.MatchString(line):
+			messages = append(messages, trimmed)
+		case len(messages) > 0 && !inPanic && strings.HasPrefix(line, "        "):
+			// Continuation of a multi-line assertion message
+			messages[len(messages)-1] += "\n" + trimmed
+		}
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// summarizeTests counts results over the whole test tree
+func summarizeTests(tests []*models.TestResult) models.TestSummary {
+	var summary models.TestSummary
+
+	var walk func([]*models.TestResult)
+	walk = func(tests []*models.TestResult) {
+		for _, test := range tests {
+			switch test.Status {
+			case models.TestStatusPass:
+				summary.Passed++
+			case models.TestStatusFail:
+				summary.Failed++
+			case models.TestStatusSkip:
+				summary.Skipped++
+			}
+			walk(test.Subtests)
+		}
+	}
+	walk(tests)
+
+	summary.Total = summary.Passed + summary.Failed
+	return summary
+}
+
+// testEventWriter converts `go test -json` output into execution events as it
+// is written. It buffers partial lines until their newline arrives.
+type testEventWriter struct {
+	emit    func(ExecutionEvent)
+	pending []byte
+	started bool
+}
+
+// newTestEventWriter creates a writer that reports test progress to emit
+func newTestEventWriter(emit func(ExecutionEvent)) *testEventWriter {
+	return &testEventWriter{emit: emit}
+}
+
+// Write splits p into lines and emits an event for each complete line
+func (w *testEventWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.handleLine(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// handleLine emits the events for one line of output
+func (w *testEventWriter) handleLine(line []byte) {
+	var event testEvent
+	if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &event) != nil {
+		// Build errors and sandbox messages are not JSON
+		w.emit(ExecutionEvent{Type: EventOutput, Output: string(line) + "\n"})
+		return
+	}
+
+	switch event.Action {
+	case "build-output":
+		w.emit(ExecutionEvent{Type: EventBuild, Output: event.Output})
+	case "start":
+		// The test binary is built and running
+		if !w.started {
+			w.started = true
+			w.emit(ExecutionEvent{Type: EventPhase, Phase: PhaseTest, Message: "Running tests"})
+		}
+	case "run":
+		w.emit(ExecutionEvent{Type: EventTestStart, Test: event.Test})
+	case "output":
+		w.emit(ExecutionEvent{Type: EventOutput, Test: event.Test, Output: event.Output})
+	case "pass", "fail", "skip":
+		if event.Test != "" {
+			w.emit(ExecutionEvent{
+				Type:      EventTestFinish,
+				Test:      event.Test,
+				Status:    event.Action,
+				ElapsedMs: int64(event.Elapsed * 1000),
+			})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"web-ui/internal/models"
+)
+
+// readTestJSON reads a recorded `go test -json` run from testdata/testjson
+func readTestJSON(t *testing.T, name string) []byte {
+	t.Helper()
+	output, err := os.ReadFile(filepath.Join("testdata", "testjson", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestParseTestJSON(t *testing.T) {
+	report := parseTestJSON(readTestJSON(t, "failing.jsonl"))
+
+	// Every test and subtest that passed or failed counts once
+	want := models.TestSummary{Passed: 1, Failed: 3, Skipped: 1, Total: 4}
+	if report.Summary != want {
+		t.Errorf("summary %+v, want %+v", report.Summary, want)
+	}
+
+	tests := []struct {
+		path      []int // Index of the test, then of each subtest
+		name      string
+		status    string
+		elapsedMs int64
+		failure   string // Prefix of the failure message
+	}{
+		{[]int{0}, "TestSum", models.TestStatusFail, 120, ""},
+		{[]int{0, 0}, "TestSum/Positive", models.TestStatusPass, 50, ""},
+		{[]int{0, 1}, "TestSum/Negative", models.TestStatusFail, 0, "sum_test.go:8: Sum(-1, -2) = -4, want -3"},
+		{[]int{1}, "TestSkipped", models.TestStatusSkip, 0, ""},
+		{[]int{2}, "TestPanics", models.TestStatusFail, 0, "panic: assignment to entry in nil map"},
+	}
+	if len(report.Tests) != 3 {
+		t.Fatalf("%d top-level tests, want 3", len(report.Tests))
+	}
+	for _, tt := range tests {
+		test := report.Tests[tt.path[0]]
+		for _, i := range tt.path[1:] {
+			if i >= len(test.Subtests) {
+				t.Fatalf("%s has %d subtests, want %s", test.Name, len(test.Subtests), tt.name)
+			}
+			test = test.Subtests[i]
+		}
+		if test.Name != tt.name || test.Status != tt.status || test.ElapsedMs != tt.elapsedMs {
+			t.Errorf("got %s %s in %dms, want %s %s in %dms", test.Name, test.Status, test.ElapsedMs, tt.name, tt.status, tt.elapsedMs)
+		}
+		if !strings.HasPrefix(test.FailureMessage, tt.failure) || (tt.failure == "") != (test.FailureMessage == "") {
+			t.Errorf("%s: failure message %q, want %q", tt.name, test.FailureMessage, tt.failure)
+		}
+	}
+
+	// A panic keeps only its first frames
+	if lines := strings.Count(report.Tests[2].FailureMessage, "\n") + 1; lines > 8 {
+		t.Errorf("panic message has %d lines, want at most 8", lines)
+	}
+
+	// The text output reads like `go test -v`, with lines that are not
+	// events kept as they were
+	for _, line := range []string{"# sandbox: running go test\n", "=== RUN   TestSum/Negative\n", "--- FAIL: TestSum (0.00s)\n", "FAIL\tchallenge1\t"} {
+		if !strings.Contains(report.Output, line) {
+			t.Errorf("output is missing %q", line)
+		}
+	}
+	if strings.Contains(report.Output, `"Action"`) {
+		t.Error("output contains raw JSON events")
+	}
+}
+
+func TestParseTestJSONBuildFailure(t *testing.T) {
+	output := `{"ImportPath":"challenge1 [challenge1.test]","Action":"build-output","Output":"# challenge1 [challenge1.test]\n"}
+{"ImportPath":"challenge1 [challenge1.test]","Action":"build-output","Output":"./solution-template.go:5:2: undefined: x\n"}
+{"ImportPath":"challenge1 [challenge1.test]","Action":"build-fail"}
+{"Action":"start","Package":"challenge1"}
+{"Action":"output","Package":"challenge1","Output":"FAIL\tchallenge1 [build failed]\n"}
+{"Action":"fail","Package":"challenge1","Elapsed":0,"FailedBuild":"challenge1 [challenge1.test]"}
+`
+	report := parseTestJSON([]byte(output))
+	if len(report.Tests) != 0 || report.Summary != (models.TestSummary{}) {
+		t.Errorf("a build failure reported tests %+v, %+v", report.Tests, report.Summary)
+	}
+	if !strings.Contains(report.Output, "./solution-template.go:5:2: undefined: x\n") {
+		t.Errorf("output %q is missing the compiler error", report.Output)
+	}
+}
+
+func TestExtractFailureMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"no assertions", "=== RUN   TestSum\n--- FAIL: TestSum (0.00s)\n", ""},
+		{"one assertion", "    sum_test.go:8: got 4, want 5\n", "sum_test.go:8: got 4, want 5"},
+		{
+			"multi-line assertion",
+			"    sum_test.go:8: mismatch:\n        got:  [1 2]\n        want: [1 2 3]\n--- FAIL: TestSum (0.00s)\n",
+			"sum_test.go:8: mismatch:\ngot:  [1 2]\nwant: [1 2 3]",
+		},
+		{"several assertions", "    a_test.go:1: first\n    a_test.go:2: second\n", "a_test.go:1: first\na_test.go:2: second"},
+		{"log lines are not failures", "    a_test.go:1: first\nsome log output\n", "a_test.go:1: first"},
+	}
+	for _, tt := range tests {
+		if got := extractFailureMessage(tt.output); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTestEventWriter(t *testing.T) {
+	output := readTestJSON(t, "failing.jsonl")
+
+	// Events must not depend on how the output is split into writes
+	var whole, chunked []ExecutionEvent
+	newTestEventWriter(func(event ExecutionEvent) { whole = append(whole, event) }).Write(output)
+	writer := newTestEventWriter(func(event ExecutionEvent) { chunked = append(chunked, event) })
+	for i := 0; i < len(output); i += 7 {
+		writer.Write(output[i:min(i+7, len(output))])
+	}
+	if len(whole) != len(chunked) {
+		t.Fatalf("%d events from one write, %d from chunks", len(whole), len(chunked))
+	}
+	for i := range whole {
+		if whole[i] != chunked[i] {
+			t.Errorf("event %d is %+v from one write, %+v from chunks", i, whole[i], chunked[i])
+		}
+	}
+
+	if first := whole[0]; first.Type != EventOutput || first.Output != "# sandbox: running go test\n" {
+		t.Errorf("a line that is not an event became %+v", first)
+	}
+	if second := whole[1]; second.Type != EventPhase || second.Phase != PhaseTest {
+		t.Errorf("the start event became %+v, want the test phase", second)
+	}
+
+	var started, finished []string
+	for _, event := range whole {
+		switch event.Type {
+		case EventTestStart:
+			started = append(started, event.Test)
+		case EventTestFinish:
+			finished = append(finished, event.Test+" "+event.Status)
+		case EventPhase:
+			if event != whole[1] {
+				t.Errorf("extra phase event %+v", event)
+			}
+		}
+	}
+	wantStarted := []string{"TestSum", "TestSum/Positive", "TestSum/Negative", "TestSkipped", "TestPanics"}
+	wantFinished := []string{"TestSum/Positive pass", "TestSum/Negative fail", "TestSum fail", "TestSkipped skip", "TestPanics fail"}
+	if !slices.Equal(started, wantStarted) {
+		t.Errorf("started %q, want %q", started, wantStarted)
+	}
+	if !slices.Equal(finished, wantFinished) {
+		t.Errorf("finished %q, want %q", finished, wantFinished)
+	}
+}
@@ -2,11 +2,8 @@ package services
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
 
 	"web-ui/internal/models"
@@ -59,50 +56,38 @@ func (us *UserService) LoadUserAttempts(username string, challenges models.Chall
 	return userAttempt
 }
 
-// hasUserSubmission checks if a user has a submission for a challenge
+// hasUserSubmission checks if a user has a submission for a challenge: any
+// Go file in their submission directory, since multi-file submissions need
+// not have a solution-template.go
 func (us *UserService) hasUserSubmission(username string, challengeID int) bool {
-	// Try different path formats to handle potential path issues
-	// Absolute path
-	submissionDir := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "submissions", username)
-	submissionFile := filepath.Join(submissionDir, "solution-template.go")
-
-	// Check if the file exists
-	if _, err := os.Stat(submissionFile); err == nil {
-		return true
-	}
-
-	// Alternative path (direct from workspace root)
-	altSubmissionFile := filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
+	_, found := LatestSubmissionTime(us.submissionDir(username, challengeID))
+	return found
+}
 
-	if _, err := os.Stat(altSubmissionFile); err == nil {
-		return true
+// submissionDir returns a user's submission directory for a challenge,
+// trying the path relative to web-ui first and then the one relative to
+// the workspace root
+func (us *UserService) submissionDir(username string, challengeID int) string {
+	dir := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "submissions", username)
+	if _, err := os.Stat(dir); err == nil {
+		return dir
 	}
-
-	return false
+	return filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "submissions", username)
 }
 
-// GetExistingSolution returns the content of an existing solution file if it exists
+// GetExistingSolution returns the main file of an existing solution if it exists
 func (us *UserService) GetExistingSolution(username string, challengeID int) string {
-	if username == "" {
-		return ""
-	}
-
-	// Try different path formats
-	// First try the relative path from web-ui
-	submissionFile := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
-	content, err := ioutil.ReadFile(submissionFile)
-	if err == nil {
-		return string(content)
-	}
+	files := us.GetExistingSolutionFiles(username, challengeID)
+	return files[MainFileOf(files)]
+}
 
-	// Try alternative path from root directory
-	altSubmissionFile := filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "submissions", username, "solution-template.go")
-	content, err = ioutil.ReadFile(altSubmissionFile)
-	if err == nil {
-		return string(content)
+// GetExistingSolutionFiles returns the files of an existing solution, or nil
+// if the user has none
+func (us *UserService) GetExistingSolutionFiles(username string, challengeID int) models.SourceFiles {
+	if username == "" {
+		return nil
 	}
-
-	return ""
+	return ReadSubmissionFiles(us.submissionDir(username, challengeID))
 }
 
 // RefreshUserAttempts clears the cache for a user and reloads their attempts
@@ -126,46 +111,20 @@ func (us *UserService) GetUserAttempts(username string, challenges models.Challe
 	return us.LoadUserAttempts(username, challenges)
 }
 
-// calculateScore calculates the score for a user's submission for a challenge
+// calculateScore calculates the score for a user's submission for a
+// challenge: the percentage of tests their row in the challenge's
+// SCOREBOARD.md passed
 func (us *UserService) calculateScore(username string, challengeID int) int {
-	// Read the scoreboard file for this challenge
-	scoreboardPath := filepath.Join("..", fmt.Sprintf("challenge-%d", challengeID), "SCOREBOARD.md")
-	content, err := ioutil.ReadFile(scoreboardPath)
+	board, err := LoadChallengeScoreboard(challengeID)
 	if err != nil {
-		// Try alternative path
-		scoreboardPath = filepath.Join(fmt.Sprintf("challenge-%d", challengeID), "SCOREBOARD.md")
-		content, err = ioutil.ReadFile(scoreboardPath)
-		if err != nil {
-			// No scoreboard file, return default score
-			return 50
-		}
+		// No scoreboard file, return default score
+		return 50
 	}
 
-	scoreboardContent := string(content)
-
-	// Parse the scoreboard to find this user's results
-	lines := strings.Split(scoreboardContent, "\n")
-	for _, line := range lines {
-		// Look for lines that contain the username
-		if strings.Contains(line, username) && strings.Contains(line, "|") {
-			// Parse the table row: | Username | Passed Tests | Total Tests |
-			parts := strings.Split(line, "|")
-			if len(parts) >= 4 {
-				passedStr := strings.TrimSpace(parts[2])
-				totalStr := strings.TrimSpace(parts[3])
-
-				passed, err1 := strconv.Atoi(passedStr)
-				total, err2 := strconv.Atoi(totalStr)
-
-				if err1 == nil && err2 == nil && total > 0 {
-					// Calculate percentage score
-					score := (passed * 100) / total
-					return score
-				}
-			}
-		}
+	row, found := board.Find(username)
+	if !found || row.Total == 0 {
+		// User not found in scoreboard, return 0
+		return 0
 	}
-
-	// User not found in scoreboard, return 0
-	return 0
+	return (row.Passed * 100) / row.Total
 }
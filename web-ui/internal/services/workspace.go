@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// warmConcurrency is how many challenges are warmed at once; compiling
+// dependencies like grpc or gorm is CPU heavy
+const warmConcurrency = 2
+
+// warmTimeout bounds warming a single challenge, including downloads
+const warmTimeout = 10 * time.Minute
+
+// workspaceFiles are the challenge files copied into a workspace template.
+// The solution template and tests are only used to warm the build cache.
+var workspaceFiles = []string{"go.mod", "go.sum", "solution-template.go", "solution-template_test.go"}
+
+// WorkspaceTemplate is a challenge's module files with every dependency
+// already downloaded and compiled into the shared caches
+type WorkspaceTemplate struct {
+	Source string // Challenge directory the template was built from
+	Dir    string // Directory holding the resolved go.mod and go.sum
+}
+
+// WarmSource is a challenge directory to warm
+type WarmSource struct {
+	Dir  string
+	Race bool // Also compile dependencies with the race detector
+}
+
+// WarmFailure records a challenge that could not be warmed
+type WarmFailure struct {
+	Source string
+	Err    string
+}
+
+// WarmReport summarizes a warm-up run
+type WarmReport struct {
+	Ready    []string      // Challenge directories that run fully offline
+	Failed   []WarmFailure // Challenge directories that fall back to online resolution
+	Duration time.Duration
+}
+
+// WorkspaceManager owns the shared module and build caches used by every run
+// and the per-challenge workspace templates resolved into them
+type WorkspaceManager struct {
+	root         string
+	modCache     string // Shared GOMODCACHE
+	buildCache   string // Shared GOCACHE
+	templateRoot string
+
+	mu        sync.RWMutex
+	templates map[string]*WorkspaceTemplate // Keyed by cleaned challenge directory
+}
+
+// NewWorkspaceManager creates a workspace manager with its caches under
+// WORKSPACE_CACHE_DIR, or the user cache directory by default
+func NewWorkspaceManager() *WorkspaceManager {
+	root := os.Getenv("WORKSPACE_CACHE_DIR")
+	if root == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = os.TempDir()
+		}
+		root = filepath.Join(cacheDir, "go-interview-practice")
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		root = absRoot
+	}
+
+	wm := &WorkspaceManager{
+		root:         root,
+		modCache:     filepath.Join(root, "mod"),
+		buildCache:   filepath.Join(root, "build"),
+		templateRoot: filepath.Join(root, "templates"),
+		templates:    make(map[string]*WorkspaceTemplate),
+	}
+
+	for _, dir := range []string{wm.modCache, wm.buildCache, wm.templateRoot} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("Warning: could not create workspace cache %s: %v", dir, err)
+		}
+	}
+
+	return wm
+}
+
+// CacheEnv returns the environment overrides that point the go command at
+// the shared caches
+func (wm *WorkspaceManager) CacheEnv() map[string]string {
+	return map[string]string{
+		"GOMODCACHE": wm.modCache,
+		"GOCACHE":    wm.buildCache,
+	}
+}
+
+// Template returns the warmed workspace template for a challenge directory
+func (wm *WorkspaceManager) Template(source string) (*WorkspaceTemplate, bool) {
+	if source == "" {
+		return nil, false
+	}
+
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	template, exists := wm.templates[filepath.Clean(source)]
+	return template, exists
+}
+
+// Prepare copies a warmed template's go.mod and go.sum into a run's
+// workspace. It reports false when the challenge has no warmed template.
+func (wm *WorkspaceManager) Prepare(source, workDir string) (bool, error) {
+	template, exists := wm.Template(source)
+	if !exists {
+		return false, nil
+	}
+
+	for _, name := range []string{"go.mod", "go.sum"} {
+		content, err := ioutil.ReadFile(filepath.Join(template.Dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(workDir, name), content, 0644); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Warm resolves every challenge directory that has a go.mod: it downloads
+// the full module graph into the shared GOMODCACHE and compiles the
+// challenge's dependencies into the shared GOCACHE, with race instrumentation
+// too for challenges that run the race stage. Challenges that fail keep
+// using online dependency resolution.
+func (wm *WorkspaceManager) Warm(sources []WarmSource) WarmReport {
+	start := time.Now()
+	var report WarmReport
+	var reportMu sync.Mutex
+
+	work := make(chan WarmSource)
+	var wg sync.WaitGroup
+	for i := 0; i < warmConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for source := range work {
+				err := wm.warmOne(source)
+
+				reportMu.Lock()
+				if err != nil {
+					report.Failed = append(report.Failed, WarmFailure{Source: source.Dir, Err: err.Error()})
+				} else {
+					report.Ready = append(report.Ready, source.Dir)
+				}
+				reportMu.Unlock()
+			}
+		}()
+	}
+
+	for _, source := range sources {
+		if _, err := os.Stat(filepath.Join(source.Dir, "go.mod")); err != nil {
+			continue // Nothing to resolve
+		}
+		work <- source
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Strings(report.Ready)
+	sort.Slice(report.Failed, func(i, j int) bool { return report.Failed[i].Source < report.Failed[j].Source })
+	report.Duration = time.Since(start)
+	return report
+}
+
+// warmOne builds the workspace template for a single challenge
+func (wm *WorkspaceManager) warmOne(source WarmSource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+	defer cancel()
+
+	// Build into a fresh directory so a half-warmed template is never used
+	dir := filepath.Join(wm.templateRoot, templateName(source.Dir))
+	staging := dir + ".tmp"
+	os.RemoveAll(staging)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	for _, name := range workspaceFiles {
+		content, err := ioutil.ReadFile(filepath.Join(source.Dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(staging, name), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	env := overrideEnv(os.Environ(), wm.CacheEnv())
+	env = overrideEnv(env, map[string]string{"GOFLAGS": "-mod=mod"})
+
+	// Download the whole module graph, recording every checksum in go.sum,
+	// so any package the module requires can be imported offline later
+	if output, err := runGo(ctx, staging, env, "mod", "download", "all"); err != nil {
+		return fmt.Errorf("go mod download: %s", lastLine(output, err))
+	}
+
+	// Compile the dependencies. The template itself may not build or pass,
+	// which is fine: dependencies are compiled and cached before that.
+	runGo(ctx, staging, env, "test", "-count=1", "-run", "^$", ".")
+	if source.Race {
+		runGo(ctx, staging, env, "test", "-race", "-count=1", "-run", "^$", ".")
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out compiling dependencies")
+	}
+
+	os.RemoveAll(dir)
+	if err := os.Rename(staging, dir); err != nil {
+		return err
+	}
+
+	wm.mu.Lock()
+	wm.templates[filepath.Clean(source.Dir)] = &WorkspaceTemplate{Source: source.Dir, Dir: dir}
+	wm.mu.Unlock()
+	return nil
+}
+
+// LogReport writes the outcome of a warm-up run to the server log
+func (report WarmReport) LogReport() {
+	log.Printf("Workspace warm-up finished in %s: %d challenges ready for offline runs, %d failed",
+		report.Duration.Round(time.Second), len(report.Ready), len(report.Failed))
+	for _, failure := range report.Failed {
+		log.Printf("  could not warm %s: %s", failure.Source, failure.Err)
+	}
+}
+
+// runGo runs a go subcommand and returns its combined output
+func runGo(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	return cmd.CombinedOutput()
+}
+
+// templateName derives a stable directory name for a challenge's template
+func templateName(source string) string {
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		abs = source
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Base(abs) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// lastLine returns the last line of a command's output, where the go
+// command reports the actual error, or the error itself if there is none
+func lastLine(output []byte, err error) string {
+	text := strings.TrimSpace(string(output))
+	if text == "" {
+		return err.Error()
+	}
+	return text[strings.LastIndex(text, "\n")+1:]
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestWorkspaceManager returns a workspace manager with its templates
+// and module cache in a temporary directory. It shares the host's build
+// cache, so warming does not compile the standard library again.
+func newTestWorkspaceManager(t *testing.T) *WorkspaceManager {
+	t.Helper()
+	t.Setenv("WORKSPACE_CACHE_DIR", t.TempDir())
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "")
+	wm := NewWorkspaceManager()
+	if cache := goEnv("GOCACHE"); cache != "" {
+		wm.buildCache = cache
+	}
+	return wm
+}
+
+// newTestChallengeDir creates a challenge directory with the given files
+func newTestChallengeDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWarmWorkspaceTemplates(t *testing.T) {
+	wm := newTestWorkspaceManager(t)
+	standard := newTestChallengeDir(t, map[string]string{
+		"go.mod":                    "module challenge1\n\ngo 1.21\n",
+		"solution-template.go":      "package main\n\nfunc Sum(a, b int) int { return a + b }\n",
+		"solution-template_test.go": "package main\n\nimport \"testing\"\n\nfunc TestSum(t *testing.T) {}\n",
+	})
+	broken := newTestChallengeDir(t, map[string]string{
+		"go.mod":               "module challenge2\n\ngo 1.21\n\nrequire example.com/missing v1.0.0\n",
+		"solution-template.go": "package main\n",
+	})
+	noModule := newTestChallengeDir(t, map[string]string{"solution-template.go": "package main\n"})
+
+	report := wm.Warm([]WarmSource{{Dir: standard}, {Dir: broken}, {Dir: noModule}})
+	if !slices.Equal(report.Ready, []string{standard}) {
+		t.Errorf("ready %q, want %q", report.Ready, standard)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Source != broken || report.Failed[0].Err == "" {
+		t.Errorf("failed %+v, want %s with its error", report.Failed, broken)
+	}
+
+	// A warmed challenge's runs start from its template
+	workDir := t.TempDir()
+	warm, err := wm.Prepare(standard+"/", workDir)
+	if err != nil || !warm {
+		t.Fatalf("Prepare = %v, %v, want a warm workspace", warm, err)
+	}
+	goMod, err := os.ReadFile(filepath.Join(workDir, "go.mod"))
+	if err != nil || !strings.HasPrefix(string(goMod), "module challenge1\n") {
+		t.Errorf("workspace go.mod %q, %v", goMod, err)
+	}
+	for _, name := range []string{"solution-template.go", "solution-template_test.go"} {
+		if _, err := os.Stat(filepath.Join(workDir, name)); err == nil {
+			t.Errorf("Prepare copied %s into the workspace", name)
+		}
+	}
+	if template, ok := wm.Template(standard); !ok || !strings.HasPrefix(template.Dir, wm.templateRoot) {
+		t.Errorf("template %+v, %v, want one under %s", template, ok, wm.templateRoot)
+	} else if _, err := os.Stat(template.Dir + ".tmp"); err == nil {
+		t.Error("the staging directory was left behind")
+	}
+
+	// The others resolve their dependencies for each run
+	for _, dir := range []string{broken, noModule, ""} {
+		if warm, err := wm.Prepare(dir, t.TempDir()); warm || err != nil {
+			t.Errorf("Prepare(%q) = %v, %v, want no template", dir, warm, err)
+		}
+	}
+}
+
+func TestTemplateName(t *testing.T) {
+	a := filepath.Join(t.TempDir(), "challenge-1")
+	b := filepath.Join(t.TempDir(), "challenge-1")
+	if templateName(a) != templateName(a+"/") {
+		t.Errorf("templateName depends on a trailing slash: %s, %s", templateName(a), templateName(a+"/"))
+	}
+	if templateName(a) == templateName(b) {
+		t.Errorf("challenges in different directories share the template %s", templateName(a))
+	}
+	if !strings.HasPrefix(templateName(a), "challenge-1-") {
+		t.Errorf("templateName(%q) = %s, want it named after the challenge", a, templateName(a))
+	}
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"embed"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 
+	"web-ui/internal/models"
 	"web-ui/internal/server"
 	"web-ui/internal/services"
 )
@@ -17,6 +20,15 @@ import (
 var content embed.FS
 
 func main() {
+	// When re-executed as the code execution sandbox, set it up and exec the
+	// test command instead of starting the server
+	services.MaybeRunSandboxChild()
+
+	// The scoreboard subcommand regenerates the README leaderboards and exits
+	if len(os.Args) > 1 && os.Args[1] == "scoreboard" {
+		os.Exit(runScoreboardCommand(os.Args[2:]))
+	}
+
 	// Load environment variables from .env file
 	loadEnvFile()
 
@@ -24,9 +36,48 @@ func main() {
 	challengeService := services.NewChallengeService()
 	scoreboardService := services.NewScoreboardService()
 	userService := services.NewUserService()
-	executionService := services.NewExecutionService()
+	workspaceManager := services.NewWorkspaceManager()
+	executionService := services.NewExecutionService(workspaceManager)
 	packageService := services.NewPackageService()
 	aiService := services.NewAIService()
+	executionQueue := services.NewExecutionQueue(executionService)
+
+	submissionStore, err := services.NewSubmissionStore(workspaceManager)
+	if err != nil {
+		log.Fatalf("Failed to open submission history: %v", err)
+	}
+	defer submissionStore.Close()
+	attemptService := services.NewAttemptService(userService, submissionStore)
+
+	accountStore, err := services.NewAccountStore(workspaceManager)
+	if err != nil {
+		log.Fatalf("Failed to open accounts: %v", err)
+	}
+	defer accountStore.Close()
+	identityProviders, err := services.NewIdentityProviders(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up identity providers: %v", err)
+	}
+	authService, err := services.NewAuthService(accountStore, identityProviders)
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+
+	cohortStore, err := services.NewCohortStore(workspaceManager)
+	if err != nil {
+		log.Fatalf("Failed to open cohorts: %v", err)
+	}
+	defer cohortStore.Close()
+	cohortService := services.NewCohortService(cohortStore, challengeService, packageService, scoreboardService, submissionStore)
+	profileService := services.NewProfileService(challengeService, userService, packageService, scoreboardService, submissionStore)
+
+	adminStore, err := services.NewAdminStore(workspaceManager)
+	if err != nil {
+		log.Fatalf("Failed to open the admin console: %v", err)
+	}
+	defer adminStore.Close()
+	adminService := services.NewAdminService(adminStore, authService, challengeService, packageService,
+		userService, scoreboardService, executionQueue)
 
 	// Load data
 	log.Println("Loading challenges...")
@@ -34,8 +85,20 @@ func main() {
 		log.Fatalf("Failed to load challenges: %v", err)
 	}
 
+	// First solves are dated by the commits that added the submissions and
+	// by the submission history
 	log.Println("Loading scoreboards...")
-	if err := scoreboardService.LoadScoreboards(challengeService.GetChallenges()); err != nil {
+	solveTimes, err := services.GitSolveTimes()
+	if err != nil {
+		log.Printf("Failed to read solve times from git: %v", err)
+		solveTimes = make(services.SolveTimes)
+	}
+	if firstSolves, err := submissionStore.FirstSolves(); err != nil {
+		log.Printf("Failed to read solve times from the submission history: %v", err)
+	} else {
+		solveTimes.Merge(firstSolves)
+	}
+	if err := scoreboardService.LoadScoreboards(challengeService.GetChallenges(), solveTimes); err != nil {
 		log.Fatalf("Failed to load scoreboards: %v", err)
 	}
 
@@ -44,6 +107,22 @@ func main() {
 		log.Fatalf("Failed to load packages: %v", err)
 	}
 
+	// Hidden entries and challenge statuses set in the admin console apply
+	// on top of the scoreboards and challenges on disk
+	if err := adminService.Load(); err != nil {
+		log.Fatalf("Failed to apply the admin console's changes: %v", err)
+	}
+
+	// Resolve challenge dependencies into the shared caches in the
+	// background; runs use online resolution until their challenge is warm
+	if !strings.EqualFold(os.Getenv("WORKSPACE_WARM"), "off") {
+		sources := workspaceSources(challengeService, packageService)
+		log.Printf("Warming workspaces for %d challenges in the background...", len(sources))
+		go func() {
+			workspaceManager.Warm(sources).LogReport()
+		}()
+	}
+
 	// Initialize server
 	srv := server.NewServer(
 		content,
@@ -53,15 +132,52 @@ func main() {
 		executionService,
 		packageService,
 		aiService,
+		executionQueue,
+		submissionStore,
+		attemptService,
+		authService,
+		cohortService,
+		profileService,
+		adminService,
 	)
 
 	// Setup routes
-	mux := srv.SetupRoutes()
+	handler := srv.SetupRoutes()
 
 	// Start server
 	port := 8080
-	log.Printf("Server starting on http://localhost:%d", port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), mux))
+	addr := fmt.Sprintf(":%d", port)
+	if warning := authService.ListenWarning(addr); warning != "" {
+		log.Printf("WARNING: %s", warning)
+	}
+	log.Printf("Server starting on http://localhost:%d (auth mode %s)", port, authService.Mode())
+	log.Fatal(http.ListenAndServe(addr, handler))
+}
+
+// workspaceSources lists the directories of all classic and package
+// challenges, whose go.mod files the workspace manager resolves
+func workspaceSources(challengeService *services.ChallengeService, packageService *services.PackageService) []services.WarmSource {
+	var sources []services.WarmSource
+	for _, challenge := range challengeService.GetChallenges() {
+		sources = append(sources, services.WarmSource{
+			Dir:  challenge.Dir,
+			Race: services.ResolveStages(challenge.Stages).Enabled(models.StageRace),
+		})
+	}
+	for packageName := range packageService.GetPackages() {
+		challenges, err := packageService.GetPackageChallenges(packageName)
+		if err != nil {
+			continue
+		}
+		for _, challenge := range challenges {
+			sources = append(sources, services.WarmSource{
+				Dir:  challenge.Dir,
+				Race: services.ResolveStages(challenge.Stages).Enabled(models.StageRace),
+			})
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Dir < sources[j].Dir })
+	return sources
 }
 
 // loadEnvFile loads environment variables from a .env file
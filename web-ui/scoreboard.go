@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"web-ui/internal/services"
+)
+
+// runScoreboardCommand regenerates the leaderboard sections of the
+// repository README from the rankings the web UI serves. With --check it
+// changes nothing and fails when the README is stale. Like the server, it
+// runs from the web-ui directory.
+func runScoreboardCommand(args []string) int {
+	flags := flag.NewFlagSet("scoreboard", flag.ContinueOnError)
+	check := flags.Bool("check", false, "exit with status 1 if the README is stale instead of updating it")
+	readmePath := flags.String("readme", filepath.Join("..", "README.md"), "README whose leaderboard sections are generated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	challengeService := services.NewChallengeService()
+	if err := challengeService.LoadChallenges(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load challenges: %v\n", err)
+		return 1
+	}
+	challenges := challengeService.GetChallenges()
+
+	// Ties are broken by first-solve times, which only git history records
+	// outside the server
+	solveTimes, err := services.GitSolveTimes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read solve times: %v\n", err)
+		return 1
+	}
+
+	scoreboardService := services.NewScoreboardService()
+	if err := scoreboardService.LoadScoreboards(challenges, solveTimes); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load scoreboards: %v\n", err)
+		return 1
+	}
+
+	packages, err := services.NewPackageService().LoadCompletions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load package scoreboards: %v\n", err)
+		return 1
+	}
+
+	challengeIDs := make([]int, 0, len(challenges))
+	for id := range challenges {
+		challengeIDs = append(challengeIDs, id)
+	}
+	sort.Ints(challengeIDs)
+
+	classic := services.RenderClassicLeaderboard(scoreboardService.MainLeaderboard(), challengeIDs)
+	packageSections := services.RenderPackageLeaderboard(packages)
+
+	content, err := ioutil.ReadFile(*readmePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read README: %v\n", err)
+		return 1
+	}
+	updated, err := services.UpdateReadme(string(content), classic, packageSections)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update %s: %v\n", *readmePath, err)
+		return 1
+	}
+
+	if updated == string(content) {
+		fmt.Printf("%s is up to date\n", *readmePath)
+		return 0
+	}
+
+	if *check {
+		stale := services.StaleReadmeSections(string(content), classic, packageSections)
+		fmt.Fprintf(os.Stderr, "%s is stale (%s); run `go run . scoreboard` in web-ui to update it\n",
+			*readmePath, strings.Join(stale, ", "))
+		return 1
+	}
+
+	if err := ioutil.WriteFile(*readmePath, []byte(updated), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write README: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Updated %s\n", *readmePath)
+	return 0
+}
@@ -462,6 +462,132 @@ function createEditor(elementId, code, isReadOnly = false) {
     return editor;
 }
 
+// Limits on a multi-file submission, matching the server's
+const MAX_SUBMISSION_FILES = 20;
+
+// Manage the files of a multi-file submission in one Ace editor: each file
+// gets its own session (with its own undo history) and a tab in tabsElement.
+// mainFile is the file single-file submissions use; it cannot be removed.
+// handlers may define onChange() for any file being edited, added or
+// removed, and onError(message) for a file that could not be added.
+function createFileWorkspace(editor, tabsElement, mainFile, handlers = {}) {
+    const onChange = () => handlers.onChange && handlers.onChange();
+    const sessions = {};
+    let current = mainFile;
+
+    function newSession(content) {
+        const session = ace.createEditSession(content || '', 'ace/mode/golang');
+        session.on('change', onChange);
+        return session;
+    }
+
+    function paths() {
+        return Object.keys(sessions).sort((a, b) => a === mainFile ? -1 : (b === mainFile ? 1 : a.localeCompare(b)));
+    }
+
+    function render() {
+        tabsElement.innerHTML = paths().map(path => `<li class="nav-item">
+            <a class="nav-link py-1 px-2 ${path === current ? 'active' : ''}" href="#" data-file="${escapeHtml(path)}">
+                <code>${escapeHtml(path)}</code>${path === mainFile ? '' : ` <span class="ms-1 text-muted" data-remove="${escapeHtml(path)}" title="Remove file">&times;</span>`}
+            </a>
+        </li>`).join('') + `<li class="nav-item">
+            <a class="nav-link py-1 px-2" href="#" data-add-file title="Add a file or a file in a subpackage, e.g. store.go or store/store.go">+</a>
+        </li>`;
+    }
+
+    // Paths follow the server's rules so mistakes show up before a run
+    function checkPath(path) {
+        if (!/^[A-Za-z0-9][\w.-]*(\/[A-Za-z0-9][\w.-]*)*\.go$/.test(path) || path.includes('..')) {
+            return 'Use a relative path to a .go file, like store.go or store/store.go';
+        }
+        if (path.endsWith('_test.go')) return "Test files can't be added; the challenge's own tests are used";
+        if (path.split('/').some(part => part === 'testdata' || part === 'vendor')) return 'testdata and vendor directories are not allowed';
+        if (sessions[path]) return `${path} already exists`;
+        if (Object.keys(sessions).length >= MAX_SUBMISSION_FILES) return `A submission can have at most ${MAX_SUBMISSION_FILES} files`;
+        return '';
+    }
+
+    const workspace = {
+        // Replace every file; a missing main file starts out empty
+        setFiles(files) {
+            Object.keys(sessions).forEach(path => delete sessions[path]);
+            Object.keys(files).forEach(path => { sessions[path] = newSession(files[path]); });
+            if (!sessions[mainFile]) sessions[mainFile] = newSession('');
+            workspace.open(mainFile);
+        },
+        // Show a file in the editor
+        open(path) {
+            if (!sessions[path]) return;
+            current = path;
+            editor.setSession(sessions[path]);
+            render();
+        },
+        // Add a file and open it; returns an error message if the path is not allowed
+        add(path, content) {
+            const problem = checkPath(path);
+            if (problem) return problem;
+            const pkg = path.includes('/') ? path.split('/').slice(-2, -1)[0] : '';
+            sessions[path] = newSession(content !== undefined ? content : (pkg ? `package ${pkg}\n` : 'package main\n'));
+            workspace.open(path);
+            onChange();
+            return '';
+        },
+        // Remove a file other than the main file
+        remove(path) {
+            if (path === mainFile || !sessions[path]) return;
+            delete sessions[path];
+            if (current === path) workspace.open(mainFile); else render();
+            onChange();
+        },
+        session(path) {
+            return sessions[path];
+        },
+        current() {
+            return current;
+        },
+        // Every file's content, keyed by path
+        files() {
+            const files = {};
+            paths().forEach(path => { files[path] = sessions[path].getValue(); });
+            return files;
+        },
+        isMultiFile() {
+            return Object.keys(sessions).length > 1;
+        },
+        // The part of a request that carries the code: "code" for a single
+        // file, so existing endpoints and saved submissions are unchanged,
+        // and "files" otherwise
+        payload() {
+            return workspace.isMultiFile() ? { files: workspace.files() } : { code: sessions[mainFile].getValue() };
+        }
+    };
+
+    tabsElement.addEventListener('click', event => {
+        const remove = event.target.closest('[data-remove]');
+        const tab = event.target.closest('[data-file]');
+        const add = event.target.closest('[data-add-file]');
+        if (!remove && !tab && !add) return;
+        event.preventDefault();
+
+        if (remove) {
+            const path = remove.dataset.remove;
+            if (confirm(`Remove ${path}?`)) workspace.remove(path);
+        } else if (tab) {
+            workspace.open(tab.dataset.file);
+        } else {
+            const path = prompt('File path, e.g. store.go or store/store.go');
+            if (!path) return;
+            const problem = workspace.add(path.trim());
+            if (problem && handlers.onError) handlers.onError(problem);
+        }
+    });
+
+    sessions[mainFile] = editor.session;
+    editor.session.on('change', onChange);
+    render();
+    return workspace;
+}
+
 // Save editor content in localStorage to prevent loss on page refresh
 function saveEditorContent(key, content) {
     localStorage.setItem(`editor_${key}`, content);
@@ -484,6 +610,350 @@ function formatTestOutput(output) {
         .replace(/--- PASS/g, '<span class="text-success">--- PASS</span>');
 }
 
+// Render structured per-test results (ExecutionResult.tests) as a nested list
+function renderTestResults(tests) {
+    if (!tests || tests.length === 0) return '';
+
+    const icons = {
+        pass: '<i class="bi bi-check-circle-fill text-success"></i>',
+        fail: '<i class="bi bi-x-circle-fill text-danger"></i>',
+        skip: '<i class="bi bi-dash-circle text-muted"></i>',
+        run: '<i class="bi bi-hourglass-split text-warning"></i>'
+    };
+
+    function renderList(tests, depth) {
+        let html = `<ul class="list-unstyled mb-0 ${depth > 0 ? 'ms-4' : ''}">`;
+        tests.forEach(test => {
+            const name = depth > 0 ? test.name.substring(test.name.lastIndexOf('/') + 1) : test.name;
+            html += `<li class="py-1">
+                ${icons[test.status] || icons.run}
+                <span class="${test.status === 'fail' ? 'text-danger fw-bold' : ''}">${escapeHtml(name.replace(/_/g, ' '))}</span>
+                <small class="text-muted ms-1">${formatExecutionTime(test.elapsedMs || 0)}</small>`;
+            if (test.failureMessage) {
+                html += `<pre class="bg-light text-danger small p-2 mt-1 mb-0 rounded">${escapeHtml(test.failureMessage)}</pre>`;
+            }
+            if (test.subtests && test.subtests.length > 0) {
+                html += renderList(test.subtests, depth + 1);
+            }
+            html += '</li>';
+        });
+        return html + '</ul>';
+    }
+
+    return `<div class="card mb-3">
+        <div class="card-header">Test Results</div>
+        <div class="card-body">${renderList(tests, 0)}</div>
+    </div>`;
+}
+
+// Render the result of each run stage (ExecutionResult.stages): gofmt, vet,
+// test, race and lint, with their diagnostics
+function renderStageResults(stages) {
+    if (!stages || stages.length === 0) return '';
+
+    const icons = {
+        pass: '<i class="bi bi-check-circle-fill text-success"></i>',
+        fail: '<i class="bi bi-x-circle-fill text-danger"></i>',
+        skip: '<i class="bi bi-dash-circle text-muted"></i>',
+        error: '<i class="bi bi-exclamation-triangle-fill text-warning"></i>'
+    };
+
+    let html = '<ul class="list-unstyled mb-0">';
+    stages.forEach(stage => {
+        html += `<li class="py-1">
+            ${icons[stage.status] || icons.skip}
+            <span class="fw-bold">${escapeHtml(stage.name)}</span>
+            <span class="badge ${stage.required ? 'bg-primary' : 'bg-secondary'} ms-1">${stage.required ? 'required' : 'advisory'}</span>
+            <small class="text-muted ms-1">${formatExecutionTime(stage.elapsedMs || 0)}</small>`;
+        if (stage.diagnostics && stage.diagnostics.length > 0) {
+            html += '<ul class="small mb-0">';
+            stage.diagnostics.forEach(d => {
+                const position = d.line ? `${d.file}:${d.line}${d.column ? ':' + d.column : ''}` : d.file;
+                html += `<li><code>${escapeHtml(position || '')}</code> ${escapeHtml(d.message)} <span class="text-muted">(${escapeHtml(d.category || stage.name)})</span></li>`;
+            });
+            html += '</ul>';
+        } else if (stage.output && stage.name !== 'test') {
+            html += `<pre class="bg-light small p-2 mt-1 mb-0 rounded" style="max-height: 200px; overflow-y: auto;">${escapeHtml(stage.output)}</pre>`;
+        }
+        html += '</li>';
+    });
+    html += '</ul>';
+
+    return `<div class="card mb-3">
+        <div class="card-header">Checks</div>
+        <div class="card-body">${html}</div>
+    </div>`;
+}
+
+// Shade the lines of an Ace editor by test coverage (ExecutionResult.coverage):
+// green for lines the tests executed, red for lines they never reached and
+// yellow for lines with both. The shading is removed on the next edit, since
+// line numbers no longer match the run. With a file workspace, every file of
+// a multi-file submission is shaded in its own session. Returns a function
+// that removes the shading.
+function showCoverageInEditor(editor, coverage, workspace) {
+    if (!coverage || !coverage.blocks) return () => {};
+
+    if (workspace && coverage.files) {
+        const clears = coverage.files
+            .filter(file => workspace.session(file.file))
+            .map(file => shadeCoverage(workspace.session(file.file), file.blocks));
+        return () => clears.forEach(clear => clear());
+    }
+    return shadeCoverage(editor.session, coverage.blocks);
+}
+
+// Shade the lines of one Ace session by coverage blocks
+function shadeCoverage(session, blocks) {
+    const Range = ace.require('ace/range').Range;
+    const lines = {};
+    blocks.forEach(block => {
+        for (let line = block.startLine; line <= block.endLine; line++) {
+            const state = lines[line] || { covered: false, uncovered: false };
+            if (block.count > 0) state.covered = true; else state.uncovered = true;
+            lines[line] = state;
+        }
+    });
+
+    const markers = Object.keys(lines).map(line => {
+        const state = lines[line];
+        const cls = state.covered && state.uncovered ? 'coverage-partial' : (state.covered ? 'coverage-covered' : 'coverage-uncovered');
+        return session.addMarker(new Range(line - 1, 0, line - 1, 1), cls, 'fullLine');
+    });
+
+    function clear() {
+        markers.forEach(id => session.removeMarker(id));
+        markers.length = 0;
+        session.off('change', clear);
+    }
+    session.on('change', clear);
+    return clear;
+}
+
+// Render a coverage summary card with the statement percentage
+function renderCoverageSummary(coverage) {
+    if (!coverage) return '';
+
+    const percent = coverage.percent.toFixed(1);
+    const color = coverage.percent >= 80 ? 'bg-success' : (coverage.percent >= 50 ? 'bg-warning' : 'bg-danger');
+    return `<div class="card mb-3">
+        <div class="card-header">Coverage</div>
+        <div class="card-body">
+            <div class="progress mb-2" style="height: 8px;">
+                <div class="progress-bar ${color}" role="progressbar" style="width: ${percent}%"></div>
+            </div>
+            <small class="text-muted">${percent}% of statements (${coverage.covered}/${coverage.statements}) reached by the tests. Lines the tests never reach are shaded red in the editor.</small>
+            ${(coverage.files || []).map(file => `<div class="d-flex justify-content-between small mt-1">
+                <code>${escapeHtml(file.file)}</code>
+                <span>${file.percent.toFixed(1)}% (${file.covered}/${file.statements})</span>
+            </div>`).join('')}
+        </div>
+    </div>`;
+}
+
+// Render the failing inputs found by the fuzz stage, each with the corpus
+// file to save and the command that replays it
+function renderFuzzFailures(failures) {
+    if (!failures || failures.length === 0) return '';
+
+    let html = `<div class="card mb-3 border-danger">
+        <div class="card-header text-danger">Fuzzing Found a Failing Input</div>
+        <div class="card-body">
+            <p class="small text-muted">The input below is saved and replayed with your next runs until your code handles it. To reproduce it locally, save the file under your challenge directory and run the command.</p>`;
+    failures.forEach(failure => {
+        html += `<div class="mb-3">
+            <h6><code>${escapeHtml(failure.target)}</code></h6>
+            <pre class="bg-light p-2 mb-2"><code>${escapeHtml(failure.message)}</code></pre>
+            <div class="small mb-1"><strong>${escapeHtml(failure.file)}</strong></div>
+            <pre class="bg-light p-2 mb-2"><code>${escapeHtml(failure.input)}</code></pre>
+            <div class="small">Replay: <code>${escapeHtml(failure.replay)}</code></div>
+        </div>`;
+    });
+    html += '</div></div>';
+    return html;
+}
+
+// Render benchmark results as a table per benchmark: the median of every
+// metric with its confidence interval and, when the challenge has a
+// baseline, the change from it
+function renderBenchmarkResults(benchmarks) {
+    if (!benchmarks || benchmarks.length === 0) return '';
+
+    const verdicts = {
+        improvement: '<span class="badge bg-success">faster</span>',
+        regression: '<span class="badge bg-danger">slower</span>',
+        unchanged: '<span class="badge bg-secondary">no change</span>'
+    };
+    const formatValue = value => Number.isInteger(value) ? value.toLocaleString() : value.toPrecision(4);
+
+    let html = '<div class="card mb-3"><div class="card-header">Benchmarks</div><div class="card-body p-0">';
+    html += '<table class="table table-sm mb-0"><thead><tr><th>Benchmark</th><th>Metric</th><th>Median</th><th>Baseline</th><th>Change</th><th></th></tr></thead><tbody>';
+    benchmarks.forEach(benchmark => {
+        benchmark.metrics.forEach((metric, i) => {
+            const baseline = metric.baseline;
+            html += `<tr>
+                <td>${i === 0 ? `<code>${escapeHtml(benchmark.name)}</code>` : ''}</td>
+                <td>${escapeHtml(metric.unit)}</td>
+                <td>${formatValue(metric.median)} <small class="text-muted">±${escapeHtml(metric.range)}</small></td>
+                <td>${baseline ? `${formatValue(baseline.median)} <small class="text-muted">±${escapeHtml(baseline.range)}</small>` : '<small class="text-muted">none</small>'}</td>
+                <td>${baseline ? `${escapeHtml(baseline.delta)} <small class="text-muted">(p=${baseline.p.toFixed(3)})</small>` : ''}</td>
+                <td>${baseline ? (verdicts[baseline.verdict] || '') : ''}</td>
+            </tr>`;
+        });
+    });
+    html += '</tbody></table></div>';
+    html += '<div class="card-footer"><small class="text-muted">Medians over all runs with 95% confidence intervals. "~" means the difference from the baseline is not statistically significant. Timings depend on the server\'s hardware and load.</small></div></div>';
+    return html;
+}
+
+// Parse a JSON API response, turning error responses (such as 429 when the
+// execution queue is full) into a rejected promise with the server's message
+function parseJSONResponse(response) {
+    if (!response.ok) {
+        return response.text().then(text => { throw new Error(text.trim() || response.statusText); });
+    }
+    return response.json();
+}
+
+// Start a streaming test run and follow its progress over Server-Sent Events.
+// handlers may define onEvent(event) for progress and onResult(result) /
+// onError(message) for the outcome. Returns an object with cancel().
+function startStreamingRun(request, handlers) {
+    let source = null;
+    let cancelUrl = null;
+    let finished = false;
+
+    function fail(message) {
+        if (finished) return;
+        finished = true;
+        if (source) source.close();
+        if (handlers.onError) handlers.onError(message);
+    }
+
+    fetch('/api/run/stream', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(request)
+    })
+    .then(parseJSONResponse)
+    .then(job => {
+        cancelUrl = job.cancelUrl;
+        if (finished) {
+            // Cancelled before the job was created
+            fetch(cancelUrl, { method: 'POST' });
+            return;
+        }
+
+        source = new EventSource(job.eventsUrl);
+        ['queued', 'phase', 'build', 'test-start', 'test-finish', 'output', 'stage'].forEach(type => {
+            source.addEventListener(type, e => {
+                if (handlers.onEvent) handlers.onEvent(JSON.parse(e.data));
+            });
+        });
+        source.addEventListener('result', e => {
+            finished = true;
+            source.close();
+            if (handlers.onResult) handlers.onResult(JSON.parse(e.data).result);
+        });
+        source.onerror = () => {
+            // EventSource reconnects on its own while the connection can be
+            // re-established; give up only once it is closed for good
+            if (source.readyState === EventSource.CLOSED) {
+                fail('Lost connection to the test run');
+            }
+        };
+    })
+    .catch(error => fail(error.message));
+
+    return {
+        cancel() {
+            if (cancelUrl) {
+                fetch(cancelUrl, { method: 'POST' });
+            } else {
+                fail('Run cancelled');
+            }
+        }
+    };
+}
+
+// Live view of a streaming test run: the current phase, each stage and test
+// as it finishes, and the output so far
+function createLiveRunView(container) {
+    const icons = {
+        pass: '<i class="bi bi-check-circle-fill text-success"></i>',
+        fail: '<i class="bi bi-x-circle-fill text-danger"></i>',
+        skip: '<i class="bi bi-dash-circle text-muted"></i>',
+        run: '<span class="spinner-border spinner-border-sm text-primary" role="status"></span>'
+    };
+
+    container.innerHTML = `
+        <div class="d-flex align-items-center mb-3">
+            <div class="spinner-border spinner-border-sm text-primary me-2" role="status"></div>
+            <span class="live-phase">Starting...</span>
+        </div>
+        <div class="live-stages mb-2"></div>
+        <ul class="list-unstyled live-tests mb-3"></ul>
+        <div class="card">
+            <div class="card-header">Live Output</div>
+            <div class="card-body">
+                <pre class="live-output mb-0" style="max-height: 400px; overflow-y: auto;"></pre>
+            </div>
+        </div>`;
+
+    const phaseEl = container.querySelector('.live-phase');
+    const stagesEl = container.querySelector('.live-stages');
+    const testsEl = container.querySelector('.live-tests');
+    const outputEl = container.querySelector('.live-output');
+    const testItems = {};
+
+    return {
+        handle(event) {
+            switch (event.type) {
+                case 'queued':
+                    phaseEl.textContent = `Queued (position ${event.position})...`;
+                    break;
+                case 'phase':
+                    phaseEl.textContent = event.message + '...';
+                    break;
+                case 'test-start': {
+                    const depth = event.test.split('/').length - 1;
+                    const name = event.test.substring(event.test.lastIndexOf('/') + 1).replace(/_/g, ' ');
+                    const item = document.createElement('li');
+                    item.className = 'py-1';
+                    item.style.marginLeft = (depth * 1.5) + 'rem';
+                    item.innerHTML = `<span class="live-status">${icons.run}</span> ${escapeHtml(name)} <small class="text-muted live-time"></small>`;
+                    testsEl.appendChild(item);
+                    testItems[event.test] = item;
+                    break;
+                }
+                case 'test-finish': {
+                    const item = testItems[event.test];
+                    if (item) {
+                        item.querySelector('.live-status').innerHTML = icons[event.status] || icons.run;
+                        item.querySelector('.live-time').textContent = formatExecutionTime(event.elapsedMs || 0);
+                    }
+                    break;
+                }
+                case 'stage': {
+                    const badge = document.createElement('span');
+                    const colors = { pass: 'bg-success', fail: 'bg-danger', error: 'bg-warning text-dark' };
+                    badge.className = `badge ${colors[event.stage.status] || 'bg-secondary'} me-1`;
+                    badge.textContent = `${event.stage.name}: ${event.stage.status}`;
+                    stagesEl.appendChild(badge);
+                    break;
+                }
+                case 'build':
+                case 'output': {
+                    const atBottom = outputEl.scrollTop + outputEl.clientHeight >= outputEl.scrollHeight - 5;
+                    outputEl.appendChild(document.createTextNode(event.output));
+                    if (atBottom) outputEl.scrollTop = outputEl.scrollHeight;
+                    break;
+                }
+            }
+        }
+    };
+}
+
 // Handle form submissions with AJAX
 function handleFormSubmit(formElement, successCallback, errorCallback) {
     formElement.addEventListener('submit', function(e) {